@@ -5,10 +5,15 @@ import (
 	"fmt"
 	_ "github.com/alexvishnevskiy/twitter-clone/follow/docs"
 	"github.com/alexvishnevskiy/twitter-clone/follow/internal/controller"
+	usersGateway "github.com/alexvishnevskiy/twitter-clone/follow/internal/gateway/users/grpc"
 	grpchandler "github.com/alexvishnevskiy/twitter-clone/follow/internal/handler/grpc"
 	httphandler "github.com/alexvishnevskiy/twitter-clone/follow/internal/handler/http"
 	"github.com/alexvishnevskiy/twitter-clone/follow/internal/repository/mysql"
 	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/follow"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	authmysql "github.com/alexvishnevskiy/twitter-clone/internal/auth/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/soheilhy/cmux"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"google.golang.org/grpc"
@@ -22,18 +27,40 @@ import (
 // @version		1.0.0
 // @host			localhost:8082
 // @description	This is API for follow service
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
 func main() {
-	var port int
+	var (
+		port       int
+		users_port int
+	)
 	flag.IntVar(&port, "port", 8082, "API handler port")
+	flag.IntVar(&users_port, "users_port", 8084, "users API handler port")
 	flag.Parse()
 	log.Printf("Starting the follow service on port %d", port)
 
+	// tokens are verified with public keys published by users service
+	jwt.SetKeySource(jwt.NewRemoteKeySet(fmt.Sprintf("http://localhost:%d%s", users_port, jwt.JWKSPath)))
+
+	// access tokens are checked in the database shared with users service
+	tokenStore, err := authmysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
+	if err != nil {
+		log.Fatalf("failed to open access tokens: %v", err)
+	}
+	auth.SetTokenStore(tokenStore)
+	// revoked access tokens and sessions are shared through the database
+	auth.SetSessionStore(tokenStore)
+	jwt.SetDenylist(tokenStore)
+
 	// probably use configs for driverName and dataSourceName
 	repository, err := mysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 	}
-	ctrl := controller.New(repository)
+	// users service tells which accounts are protected
+	usersService := usersGateway.New(fmt.Sprintf("localhost:%d", users_port))
+	ctrl := controller.New(repository, usersService)
 
 	// setup the main listener
 	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
@@ -48,7 +75,8 @@ func main() {
 	httpL := m.Match(cmux.HTTP1Fast())
 
 	// grpc and http server
-	srv := grpc.NewServer()
+	// follower counts are public, users service refreshes them for search without a user
+	srv := grpc.NewServer(auth.ServerOptions(gen.FollowService_GetFollowerCounts_FullMethodName)...)
 	reflection.Register(srv)
 	httpS := &http.Server{}
 
@@ -61,10 +89,30 @@ func main() {
 	gen.RegisterFollowServiceServer(srv, grpch)
 	// http handler
 	httph := httphandler.New(ctrl)
-	http.Handle("/follow", http.HandlerFunc(httph.Follow))
-	http.Handle("/unfollow", http.HandlerFunc(httph.Unfollow))
+	// personal access tokens are accepted only with the scope of the route
+	scoped := func(scope types.Scope, handler http.HandlerFunc) http.Handler {
+		return auth.AllowScope(scope, auth.Middleware(handler))
+	}
+	// creating content requires verified email
+	verified := func(scope types.Scope, handler http.HandlerFunc) http.Handler {
+		return auth.AllowScope(scope, auth.Middleware(auth.RequireVerified(handler)))
+	}
+	http.Handle("/follow", verified(types.ScopeFollowWrite, httph.Follow))
+	http.Handle("/unfollow", scoped(types.ScopeFollowWrite, httph.Unfollow))
 	http.Handle("/user_followers", http.HandlerFunc(httph.GetUserFollowers))
 	http.Handle("/following_user", http.HandlerFunc(httph.GetFollowingUser))
+	http.Handle("/follow_requests/incoming", scoped(types.ScopeRead, httph.GetIncomingRequests))
+	http.Handle("/follow_requests/outgoing", scoped(types.ScopeRead, httph.GetOutgoingRequests))
+	http.Handle("/follow_requests/approve", scoped(types.ScopeFollowWrite, httph.ApproveRequest))
+	http.Handle("/follow_requests/reject", scoped(types.ScopeFollowWrite, httph.RejectRequest))
+	http.Handle("/follow_requests/cancel", scoped(types.ScopeFollowWrite, httph.CancelRequest))
+	http.Handle("/block", scoped(types.ScopeFollowWrite, httph.Block))
+	http.Handle("/unblock", scoped(types.ScopeFollowWrite, httph.Unblock))
+	http.Handle("/mute", scoped(types.ScopeFollowWrite, httph.Mute))
+	http.Handle("/unmute", scoped(types.ScopeFollowWrite, httph.Unmute))
+	http.Handle("/blocked", scoped(types.ScopeRead, httph.GetBlocked))
+	http.Handle("/muted", scoped(types.ScopeRead, httph.GetMuted))
+	http.Handle("/relationships", scoped(types.ScopeRead, httph.GetRelationships))
 	http.HandleFunc("/swagger/", httpSwagger.WrapHandler)
 	// Start serving!
 	log.Fatal(m.Serve())
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 )
 
@@ -11,24 +12,64 @@ type followRepository interface {
 	Unfollow(ctx context.Context, userId types.UserId, followId types.UserId) error
 	GetUserFollowers(ctx context.Context, userId types.UserId) ([]types.UserId, error)
 	GetFollowingUser(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+	GetFollowerCounts(ctx context.Context, userIds ...types.UserId) (map[types.UserId]int, error)
+	PutFollowRequest(ctx context.Context, userId types.UserId, followId types.UserId) error
+	GetOutgoingRequests(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+	GetIncomingRequests(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+	DeleteFollowRequest(ctx context.Context, userId types.UserId, followId types.UserId) error
+	ApproveFollowRequest(ctx context.Context, userId types.UserId, followId types.UserId) error
+	Block(ctx context.Context, userId types.UserId, blockedId types.UserId) error
+	Unblock(ctx context.Context, userId types.UserId, blockedId types.UserId) error
+	Mute(ctx context.Context, userId types.UserId, mutedId types.UserId) error
+	Unmute(ctx context.Context, userId types.UserId, mutedId types.UserId) error
+	GetBlocked(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+	GetMuted(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+	GetRelationships(
+		ctx context.Context,
+		userId types.UserId,
+		targetIds ...types.UserId,
+	) (map[types.UserId]model.Relationship, error)
+}
+
+// users service is used to check if account is protected
+type usersGateway interface {
+	IsProtected(ctx context.Context, userId types.UserId) (bool, error)
 }
 
 type Controller struct {
-	repo followRepository
+	repo  followRepository
+	users usersGateway
 }
 
-func New(repo followRepository) *Controller {
-	return &Controller{repo: repo}
+func New(repo followRepository, users usersGateway) *Controller {
+	return &Controller{repo: repo, users: users}
 }
 
-// follow to new user
+// follow to new user, protected accounts have to approve the request first.
+// Outputs true if request is pending
 func (ctrl *Controller) Follow(
 	ctx context.Context,
 	userId types.UserId,
 	followId types.UserId,
-) error {
-	err := ctrl.repo.Follow(ctx, userId, followId)
-	return err
+) (bool, error) {
+	relationships, err := ctrl.repo.GetRelationships(ctx, userId, followId)
+	if err != nil {
+		return false, err
+	}
+	if relationships[followId].Blocked() {
+		return false, ErrBlocked
+	}
+
+	protected, err := ctrl.users.IsProtected(ctx, followId)
+	if err != nil {
+		return false, err
+	}
+	if protected && userId != followId {
+		err = ctrl.repo.PutFollowRequest(ctx, userId, followId)
+		return err == nil, err
+	}
+	err = ctrl.repo.Follow(ctx, userId, followId)
+	return false, err
 }
 
 // unfollow from specific user
@@ -58,3 +99,134 @@ func (ctrl *Controller) GetFollowingUser(
 	followers, err := ctrl.repo.GetFollowingUser(ctx, userId)
 	return followers, err
 }
+
+// get number of followers of every user
+func (ctrl *Controller) GetFollowerCounts(
+	ctx context.Context,
+	userIds ...types.UserId,
+) (map[types.UserId]int, error) {
+	counts, err := ctrl.repo.GetFollowerCounts(ctx, userIds...)
+	return counts, err
+}
+
+// get protected accounts that user asked to follow
+func (ctrl *Controller) GetOutgoingRequests(
+	ctx context.Context,
+	userId types.UserId,
+) ([]types.UserId, error) {
+	users, err := ctrl.repo.GetOutgoingRequests(ctx, userId)
+	return users, err
+}
+
+// get users waiting for approval to follow user
+func (ctrl *Controller) GetIncomingRequests(
+	ctx context.Context,
+	userId types.UserId,
+) ([]types.UserId, error) {
+	users, err := ctrl.repo.GetIncomingRequests(ctx, userId)
+	return users, err
+}
+
+// approve request of follower, follow is created atomically with removal of the request
+func (ctrl *Controller) ApproveRequest(
+	ctx context.Context,
+	userId types.UserId,
+	followerId types.UserId,
+) error {
+	err := ctrl.repo.ApproveFollowRequest(ctx, followerId, userId)
+	return err
+}
+
+// reject request of follower
+func (ctrl *Controller) RejectRequest(
+	ctx context.Context,
+	userId types.UserId,
+	followerId types.UserId,
+) error {
+	err := ctrl.repo.DeleteFollowRequest(ctx, followerId, userId)
+	return err
+}
+
+// cancel own request to follow protected account
+func (ctrl *Controller) CancelRequest(
+	ctx context.Context,
+	userId types.UserId,
+	followId types.UserId,
+) error {
+	err := ctrl.repo.DeleteFollowRequest(ctx, userId, followId)
+	return err
+}
+
+// block user, they stop following each other and can not see each other's tweets
+func (ctrl *Controller) Block(
+	ctx context.Context,
+	userId types.UserId,
+	blockedId types.UserId,
+) error {
+	if userId == blockedId {
+		return ErrSelfRelationship
+	}
+	err := ctrl.repo.Block(ctx, userId, blockedId)
+	return err
+}
+
+// unblock user, previous follows are not restored
+func (ctrl *Controller) Unblock(
+	ctx context.Context,
+	userId types.UserId,
+	blockedId types.UserId,
+) error {
+	err := ctrl.repo.Unblock(ctx, userId, blockedId)
+	return err
+}
+
+// mute user, their tweets are hidden from home timeline of the user only
+func (ctrl *Controller) Mute(
+	ctx context.Context,
+	userId types.UserId,
+	mutedId types.UserId,
+) error {
+	if userId == mutedId {
+		return ErrSelfRelationship
+	}
+	err := ctrl.repo.Mute(ctx, userId, mutedId)
+	return err
+}
+
+// unmute user
+func (ctrl *Controller) Unmute(
+	ctx context.Context,
+	userId types.UserId,
+	mutedId types.UserId,
+) error {
+	err := ctrl.repo.Unmute(ctx, userId, mutedId)
+	return err
+}
+
+// get users blocked by user
+func (ctrl *Controller) GetBlocked(
+	ctx context.Context,
+	userId types.UserId,
+) ([]types.UserId, error) {
+	users, err := ctrl.repo.GetBlocked(ctx, userId)
+	return users, err
+}
+
+// get users muted by user
+func (ctrl *Controller) GetMuted(
+	ctx context.Context,
+	userId types.UserId,
+) ([]types.UserId, error) {
+	users, err := ctrl.repo.GetMuted(ctx, userId)
+	return users, err
+}
+
+// get relationship of user to every target in one call
+func (ctrl *Controller) GetRelationships(
+	ctx context.Context,
+	userId types.UserId,
+	targetIds ...types.UserId,
+) (map[types.UserId]model.Relationship, error) {
+	relationships, err := ctrl.repo.GetRelationships(ctx, userId, targetIds...)
+	return relationships, err
+}
@@ -0,0 +1,9 @@
+package controller
+
+import "errors"
+
+// ErrBlocked is returned when one of the users blocked the other.
+var ErrBlocked = errors.New("user is blocked")
+
+// ErrSelfRelationship is returned when user tries to block or mute themselves.
+var ErrSelfRelationship = errors.New("users can not block or mute themselves")
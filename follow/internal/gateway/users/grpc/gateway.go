@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"context"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/users"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"google.golang.org/grpc"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// check with users service whether account of the user is protected
+func (g *Gateway) IsProtected(ctx context.Context, userId types.UserId) (bool, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	client := gen.NewUsersServiceClient(conn)
+	profile, err := client.GetUser(ctx, &gen.GetUserRequest{UserId: int32(userId)})
+	if err != nil {
+		return false, err
+	}
+	return profile.GetProtected(), nil
+}
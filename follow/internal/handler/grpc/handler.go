@@ -2,7 +2,10 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"github.com/alexvishnevskiy/twitter-clone/follow/internal/controller"
+	"github.com/alexvishnevskiy/twitter-clone/follow/internal/repository/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/follow"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"google.golang.org/grpc/codes"
@@ -33,8 +36,11 @@ func (h *Handler) GetUserFollowers(ctx context.Context, req *gen.UserId) (*gen.G
 	}
 
 	users, err := h.ctrl.GetUserFollowers(ctx, types.UserId(req.UserId))
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	}
 	if err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.Internal, err.Error())
 	}
 
 	protoUsers := usersToProto(users)
@@ -49,8 +55,11 @@ func (h *Handler) GetFollowingUser(ctx context.Context, req *gen.UserId) (*gen.G
 	}
 
 	users, err := h.ctrl.GetFollowingUser(ctx, types.UserId(req.UserId))
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	}
 	if err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.Internal, err.Error())
 	}
 
 	protoUsers := usersToProto(users)
@@ -58,3 +67,54 @@ func (h *Handler) GetFollowingUser(ctx context.Context, req *gen.UserId) (*gen.G
 		UserId: protoUsers,
 	}, nil
 }
+
+func (h *Handler) GetFollowerCounts(
+	ctx context.Context,
+	req *gen.GetFollowerCountsRequest,
+) (*gen.GetFollowerCountsResponse, error) {
+	if req == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req")
+	}
+
+	userIds := make([]types.UserId, len(req.UserId))
+	for i, id := range req.UserId {
+		userIds[i] = types.UserId(id)
+	}
+	counts, err := h.ctrl.GetFollowerCounts(ctx, userIds...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	response := &gen.GetFollowerCountsResponse{}
+	for _, id := range userIds {
+		response.Counts = append(response.Counts, &gen.FollowerCount{UserId: int32(id), Count: int64(counts[id])})
+	}
+	return response, nil
+}
+
+// GetRelationships check relationship of user to every target, response keeps order of targets
+func (h *Handler) GetRelationships(
+	ctx context.Context,
+	req *gen.GetRelationshipsRequest,
+) (*gen.GetRelationshipsResponse, error) {
+	if req == nil || len(req.TargetId) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty target_id")
+	}
+
+	targetIds := make([]types.UserId, len(req.TargetId))
+	for i, id := range req.TargetId {
+		targetIds[i] = types.UserId(id)
+	}
+	relationships, err := h.ctrl.GetRelationships(ctx, types.UserId(req.UserId), targetIds...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	response := &gen.GetRelationshipsResponse{}
+	for _, id := range targetIds {
+		relationship := relationships[id]
+		relationship.UserId = id
+		response.Relationships = append(response.Relationships, model.RelationshipToProto(&relationship))
+	}
+	return response, nil
+}
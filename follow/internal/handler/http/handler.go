@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"github.com/alexvishnevskiy/twitter-clone/follow/internal/controller"
 	"github.com/alexvishnevskiy/twitter-clone/follow/internal/repository/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"io/ioutil"
 	"net/http"
@@ -21,21 +23,32 @@ type Handler struct {
 type retrieveFunc func(context.Context, types.UserId) ([]types.UserId, error)
 
 type PostRequest struct {
-	UserId   string `json:"user_id"`
 	FollowId string `json:"following_id"`
 }
 
+type UserRequest struct {
+	UserId string `json:"user_id"`
+}
+
+type followResponse struct {
+	Pending bool `json:"pending"`
+}
+
 func New(ctrl *controller.Controller) *Handler {
 	return &Handler{ctrl}
 }
 
 // Follow handle follow requests
 //
-//	@description	Follow specific user
-//	@Param			user_id			body		int	true	"User ID"
+//	@description	Follow specific user, following protected account creates pending request.
+//	@description	Email of the user should be verified
+//	@Security		BearerAuth
 //	@Param			following_id	body		int	true	"Following ID"
 //	@Success		200				{object}	int
+//	@Success		202				{object}	followResponse
 //	@Failure		400				{object}	int
+//	@Failure		401				{object}	int
+//	@Failure		403				{object}	int
 //	@Failure		404				{object}	int
 //	@Failure		405				{object}	int
 //	@Failure		500				{object}	int
@@ -45,6 +58,10 @@ func (h *Handler) Follow(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
+	userID, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
 	var requestData PostRequest
 
 	// read and unmarshal data from request
@@ -61,15 +78,9 @@ func (h *Handler) Follow(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// user_id and follow_id are non nil fields
-	if requestData.UserId == "" && requestData.FollowId == "" {
-		http.Error(w, "user_id and following_id are empty", http.StatusBadRequest)
-		return
-	}
-
-	user, err := strconv.Atoi(requestData.UserId)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to parse user_id: %s", requestData.UserId), http.StatusBadRequest)
+	// follow_id is non nil field
+	if requestData.FollowId == "" {
+		http.Error(w, "following_id is empty", http.StatusBadRequest)
 		return
 	}
 
@@ -79,23 +90,35 @@ func (h *Handler) Follow(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	userID := types.UserId(user)
 	followerID := types.UserId(follower)
 
 	// user controller to make request
-	err = h.ctrl.Follow(req.Context(), userID, followerID)
+	pending, err := h.ctrl.Follow(req.Context(), userID, followerID)
+	if err != nil && errors.Is(err, controller.ErrBlocked) {
+		http.Error(w, "user is blocked", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to follow a tweet: %s", err), http.StatusInternalServerError)
+		return
+	}
+	// protected account has to approve the request
+	if pending {
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(followResponse{Pending: true}); err != nil {
+			http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		}
 	}
 }
 
 // Unfollow handle unfollow requests
 //
 //	@description	Unfollow specific user
-//	@Param			user_id			query		int	true	"User ID"
+//	@Security		BearerAuth
 //	@Param			following_id	query		int	true	"Following ID"
 //	@Success		200				{object}	int
 //	@Failure		400				{object}	int
+//	@Failure		401				{object}	int
 //	@Failure		404				{object}	int
 //	@Failure		405				{object}	int
 //	@Failure		500				{object}	int
@@ -106,14 +129,12 @@ func (h *Handler) Unfollow(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// retrieve user_id and following_id
-	user_id := req.FormValue("user_id")
-	userid, err := strconv.Atoi(user_id)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("user_id is invalid: %s", user_id), http.StatusBadRequest)
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
 		return
 	}
 
+	// retrieve following_id
 	following_id := req.FormValue("following_id")
 	followingid, err := strconv.Atoi(following_id)
 	if err != nil {
@@ -122,7 +143,6 @@ func (h *Handler) Unfollow(w http.ResponseWriter, req *http.Request) {
 	}
 
 	// handle request with controller
-	userId := types.UserId(userid)
 	followingId := types.UserId(followingid)
 	err = h.ctrl.Unfollow(req.Context(), userId, followingId)
 	if err != nil {
@@ -189,3 +209,308 @@ func (h *Handler) GetUserFollowers(w http.ResponseWriter, req *http.Request) {
 func (h *Handler) GetFollowingUser(w http.ResponseWriter, req *http.Request) {
 	getUsers(w, req, h.ctrl.GetFollowingUser)
 }
+
+// helper function to get users related to authenticated user with function f
+func getOwnUsers(w http.ResponseWriter, req *http.Request, f retrieveFunc) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	users, err := f(req.Context(), userId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s", err), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		http.Error(w, "failed to encode users", http.StatusInternalServerError)
+	}
+}
+
+// GetIncomingRequests get pending requests to follow user
+//
+//	@description	Retrieve users waiting for approval to follow authenticated user
+//	@Security		BearerAuth
+//	@Success		200	{object}	[]types.UserId
+//	@Failure		401	{object}	int
+//	@Failure		405	{object}	int
+//	@Failure		500	{object}	int
+//	@Router			/follow_requests/incoming [get]
+func (h *Handler) GetIncomingRequests(w http.ResponseWriter, req *http.Request) {
+	getOwnUsers(w, req, h.ctrl.GetIncomingRequests)
+}
+
+// GetOutgoingRequests get pending requests of user
+//
+//	@description	Retrieve protected accounts that authenticated user asked to follow
+//	@Security		BearerAuth
+//	@Success		200	{object}	[]types.UserId
+//	@Failure		401	{object}	int
+//	@Failure		405	{object}	int
+//	@Failure		500	{object}	int
+//	@Router			/follow_requests/outgoing [get]
+func (h *Handler) GetOutgoingRequests(w http.ResponseWriter, req *http.Request) {
+	getOwnUsers(w, req, h.ctrl.GetOutgoingRequests)
+}
+
+type actionFunc func(context.Context, types.UserId, types.UserId) error
+
+// helper function to apply action f of authenticated user to user_id from request body
+func userAction(w http.ResponseWriter, req *http.Request, f actionFunc) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+	var requestData UserRequest
+
+	// read and unmarshal data from request
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = json.Unmarshal(bodyBytes, &requestData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	other, err := strconv.Atoi(requestData.UserId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("user_id is invalid: %s", requestData.UserId), http.StatusBadRequest)
+		return
+	}
+
+	err = f(req.Context(), userId, types.UserId(other))
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "follow request is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrSelfRelationship) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s", err), http.StatusInternalServerError)
+	}
+}
+
+// ApproveRequest approve request to follow user
+//
+//	@description	Approve pending request, the user starts following authenticated user
+//	@Security		BearerAuth
+//	@Param			user_id	body		int	true	"User ID of the follower"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/follow_requests/approve [post]
+func (h *Handler) ApproveRequest(w http.ResponseWriter, req *http.Request) {
+	userAction(w, req, h.ctrl.ApproveRequest)
+}
+
+// RejectRequest reject request to follow user
+//
+//	@description	Reject pending request to follow authenticated user
+//	@Security		BearerAuth
+//	@Param			user_id	body		int	true	"User ID of the follower"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/follow_requests/reject [post]
+func (h *Handler) RejectRequest(w http.ResponseWriter, req *http.Request) {
+	userAction(w, req, h.ctrl.RejectRequest)
+}
+
+// CancelRequest cancel own request to follow
+//
+//	@description	Cancel pending request to follow protected account
+//	@Security		BearerAuth
+//	@Param			following_id	query		int	true	"Following ID"
+//	@Success		200				{object}	int
+//	@Failure		400				{object}	int
+//	@Failure		401				{object}	int
+//	@Failure		404				{object}	int
+//	@Failure		405				{object}	int
+//	@Failure		500				{object}	int
+//	@Router			/follow_requests/cancel [delete]
+func (h *Handler) CancelRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	following_id := req.FormValue("following_id")
+	followingid, err := strconv.Atoi(following_id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("following_id is invalid: %s", following_id), http.StatusBadRequest)
+		return
+	}
+
+	err = h.ctrl.CancelRequest(req.Context(), userId, types.UserId(followingid))
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "follow request is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s", err), http.StatusInternalServerError)
+	}
+}
+
+// Block block user
+//
+//	@description	Block user, follows in both directions are removed and users do not see each other's tweets
+//	@Security		BearerAuth
+//	@Param			user_id	body		int	true	"User ID"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/block [post]
+func (h *Handler) Block(w http.ResponseWriter, req *http.Request) {
+	userAction(w, req, h.ctrl.Block)
+}
+
+// Unblock unblock user
+//
+//	@description	Unblock user, previous follows are not restored
+//	@Security		BearerAuth
+//	@Param			user_id	body		int	true	"User ID"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/unblock [post]
+func (h *Handler) Unblock(w http.ResponseWriter, req *http.Request) {
+	userAction(w, req, h.ctrl.Unblock)
+}
+
+// Mute mute user
+//
+//	@description	Mute user, their tweets are hidden from home timeline
+//	@Security		BearerAuth
+//	@Param			user_id	body		int	true	"User ID"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/mute [post]
+func (h *Handler) Mute(w http.ResponseWriter, req *http.Request) {
+	userAction(w, req, h.ctrl.Mute)
+}
+
+// Unmute unmute user
+//
+//	@description	Unmute user
+//	@Security		BearerAuth
+//	@Param			user_id	body		int	true	"User ID"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/unmute [post]
+func (h *Handler) Unmute(w http.ResponseWriter, req *http.Request) {
+	userAction(w, req, h.ctrl.Unmute)
+}
+
+// GetBlocked get blocked users
+//
+//	@description	Retrieve users blocked by authenticated user
+//	@Security		BearerAuth
+//	@Success		200	{object}	[]types.UserId
+//	@Failure		401	{object}	int
+//	@Failure		405	{object}	int
+//	@Failure		500	{object}	int
+//	@Router			/blocked [get]
+func (h *Handler) GetBlocked(w http.ResponseWriter, req *http.Request) {
+	getOwnUsers(w, req, h.ctrl.GetBlocked)
+}
+
+// GetMuted get muted users
+//
+//	@description	Retrieve users muted by authenticated user
+//	@Security		BearerAuth
+//	@Success		200	{object}	[]types.UserId
+//	@Failure		401	{object}	int
+//	@Failure		405	{object}	int
+//	@Failure		500	{object}	int
+//	@Router			/muted [get]
+func (h *Handler) GetMuted(w http.ResponseWriter, req *http.Request) {
+	getOwnUsers(w, req, h.ctrl.GetMuted)
+}
+
+// GetRelationships get relationships of authenticated user
+//
+//	@description	Retrieve relationship of authenticated user to every user
+//	@Security		BearerAuth
+//	@Param			user_id	query		[]int	true	"User IDs"
+//	@Success		200		{object}	[]model.Relationship
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/relationships [get]
+func (h *Handler) GetRelationships(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	// parse all user ids
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var targetIds []types.UserId
+	for _, user_id := range req.Form["user_id"] {
+		userid, err := strconv.Atoi(user_id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("user_id is invalid: %s", user_id), http.StatusBadRequest)
+			return
+		}
+		targetIds = append(targetIds, types.UserId(userid))
+	}
+	if len(targetIds) == 0 {
+		http.Error(w, "user_id is empty", http.StatusBadRequest)
+		return
+	}
+
+	relationships, err := h.ctrl.GetRelationships(req.Context(), userId, targetIds...)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s", err), http.StatusInternalServerError)
+		return
+	}
+	res := make([]model.Relationship, len(targetIds))
+	for i, id := range targetIds {
+		res[i] = relationships[id]
+		res[i].UserId = id
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		http.Error(w, "failed to encode relationships", http.StatusInternalServerError)
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/alexvishnevskiy/twitter-clone/follow/internal/controller"
+	"github.com/alexvishnevskiy/twitter-clone/follow/internal/repository/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	mock_controller "github.com/alexvishnevskiy/twitter-clone/gen/controller/follow"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
@@ -16,28 +19,32 @@ import (
 )
 
 func TestHandler_Follow(t *testing.T) {
-	ctx := context.Background()
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	mockFollowRepo := mock_controller.NewMockfollowRepository(mockCtrl)
+	mockFollowRepo.EXPECT().GetRelationships(ctx, types.UserId(1), types.UserId(2)).Return(
+		map[types.UserId]model.Relationship{2: {UserId: 2}}, nil,
+	)
 	mockFollowRepo.EXPECT().Follow(ctx, types.UserId(1), types.UserId(2)).Return(nil)
-	followCtrl := controller.New(mockFollowRepo)
+	mockUsers := mock_controller.NewMockusersGateway(mockCtrl)
+	mockUsers.EXPECT().IsProtected(ctx, types.UserId(2)).Return(false, nil)
+	followCtrl := controller.New(mockFollowRepo, mockUsers)
 	followHandler := New(followCtrl)
 
 	// make json for body request
 	payloadBytes, err := json.Marshal(
 		struct {
-			UserId   string `json:"user_id"`
 			FollowId string `json:"following_id"`
-		}{"1", "2"},
+		}{"2"},
 	)
 	if err != nil {
 		log.Fatalf("Failed to marshal payload: %v", err)
 	}
 	body := bytes.NewReader(payloadBytes)
 
-	req, err := http.NewRequest("POST", "/follow", body)
+	req, err := http.NewRequestWithContext(ctx, "POST", "/follow", body)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -51,19 +58,177 @@ func TestHandler_Follow(t *testing.T) {
 			"handler returned wrong status code",
 		)
 	}
+
+	// user is taken from token, not from request
+	req, err = http.NewRequest("POST", "/follow", bytes.NewReader(payloadBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("unauthenticated request should be rejected, got status %d", status)
+	}
+}
+
+func TestHandler_FollowProtected(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// protected account gets pending request instead of follower
+	mockFollowRepo := mock_controller.NewMockfollowRepository(mockCtrl)
+	mockFollowRepo.EXPECT().GetRelationships(ctx, types.UserId(1), types.UserId(2)).Return(
+		map[types.UserId]model.Relationship{2: {UserId: 2}}, nil,
+	)
+	mockFollowRepo.EXPECT().PutFollowRequest(ctx, types.UserId(1), types.UserId(2)).Return(nil)
+	mockUsers := mock_controller.NewMockusersGateway(mockCtrl)
+	mockUsers.EXPECT().IsProtected(ctx, types.UserId(2)).Return(true, nil)
+	followHandler := New(controller.New(mockFollowRepo, mockUsers))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "/follow", bytes.NewReader([]byte(`{"following_id": "2"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(followHandler.Follow).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusAccepted {
+		t.Errorf("handler returned wrong status code %d", status)
+	}
+	var res followResponse
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil || !res.Pending {
+		t.Errorf("request should be pending, got %+v", res)
+	}
+}
+
+func TestHandler_FollowBlocked(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// blocked users can not follow each other, the block is checked before anything else
+	mockFollowRepo := mock_controller.NewMockfollowRepository(mockCtrl)
+	mockFollowRepo.EXPECT().GetRelationships(ctx, types.UserId(1), types.UserId(2)).Return(
+		map[types.UserId]model.Relationship{2: {UserId: 2, BlockedBy: true}}, nil,
+	)
+	followHandler := New(controller.New(mockFollowRepo, nil))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "/follow", bytes.NewReader([]byte(`{"following_id": "2"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(followHandler.Follow).ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code %d", status)
+	}
+}
+
+func TestHandler_Block(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFollowRepo := mock_controller.NewMockfollowRepository(mockCtrl)
+	mockFollowRepo.EXPECT().Block(ctx, types.UserId(1), types.UserId(2)).Return(nil)
+	followHandler := New(controller.New(mockFollowRepo, nil))
+	handler := http.HandlerFunc(followHandler.Block)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"user_id": "2"}`, http.StatusOK},
+		{`{"user_id": "1"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequestWithContext(ctx, "POST", "/block", bytes.NewReader([]byte(tt.body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if status := rr.Code; status != tt.want {
+			t.Errorf("%s: got status %d want %d", tt.body, status, tt.want)
+		}
+	}
+}
+
+func TestHandler_GetRelationships(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFollowRepo := mock_controller.NewMockfollowRepository(mockCtrl)
+	mockFollowRepo.EXPECT().GetRelationships(ctx, types.UserId(1), types.UserId(2), types.UserId(3)).Return(
+		map[types.UserId]model.Relationship{2: {UserId: 2, Following: true, Muting: true}}, nil,
+	)
+	followHandler := New(controller.New(mockFollowRepo, nil))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", "/relationships?user_id=2&user_id=3", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(followHandler.GetRelationships).ServeHTTP(rr, req)
+
+	// relationships keep order of request, unrelated users are returned too
+	want := []model.Relationship{{UserId: 2, Following: true, Muting: true}, {UserId: 3}}
+	var res []model.Relationship
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Errorf("failed to unmarshal result request")
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHandler_ApproveRequest(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 2})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockFollowRepo := mock_controller.NewMockfollowRepository(mockCtrl)
+	gomock.InOrder(
+		mockFollowRepo.EXPECT().ApproveFollowRequest(ctx, types.UserId(1), types.UserId(2)).Return(nil),
+		mockFollowRepo.EXPECT().ApproveFollowRequest(ctx, types.UserId(3), types.UserId(2)).Return(mysql.ErrNotFound),
+	)
+	followHandler := New(controller.New(mockFollowRepo, nil))
+	handler := http.HandlerFunc(followHandler.ApproveRequest)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"user_id": "1"}`, http.StatusOK},
+		{`{"user_id": "3"}`, http.StatusNotFound},
+		{`{"user_id": "abc"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequestWithContext(ctx, "POST", "/follow_requests/approve", bytes.NewReader([]byte(tt.body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if status := rr.Code; status != tt.want {
+			t.Errorf("%s: got status %d want %d", tt.body, status, tt.want)
+		}
+	}
 }
 
 func TestHandler_Unfollow(t *testing.T) {
-	ctx := context.Background()
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	mockFollowRepo := mock_controller.NewMockfollowRepository(mockCtrl)
 	mockFollowRepo.EXPECT().Unfollow(ctx, types.UserId(1), types.UserId(2)).Return(nil)
-	followCtrl := controller.New(mockFollowRepo)
+	followCtrl := controller.New(mockFollowRepo, nil)
 	followHandler := New(followCtrl)
 
-	req, err := http.NewRequest("DELETE", "/unfollow?user_id=1&following_id=2", nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", "/unfollow?following_id=2", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -93,7 +258,7 @@ func testGetFunction(t *testing.T, funcType int) {
 	}
 
 	mockFollowRepo := mock_controller.NewMockfollowRepository(mockCtrl)
-	followCtrl := controller.New(mockFollowRepo)
+	followCtrl := controller.New(mockFollowRepo, nil)
 	followHandler := New(followCtrl)
 
 	switch funcType {
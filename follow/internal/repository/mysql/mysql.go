@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	_ "github.com/go-sql-driver/mysql"
+	"strings"
 )
 
 type Repository struct {
@@ -85,7 +87,7 @@ func get(ctx context.Context, r *Repository, userId types.UserId, retrieveType i
 		res = append(res, id)
 	}
 	if len(res) == 0 {
-		return nil, fmt.Errorf("there is no data for your request")
+		return nil, ErrNotFound
 	}
 	return res, nil
 }
@@ -99,3 +101,269 @@ func (r *Repository) GetFollowingUser(ctx context.Context, userId types.UserId)
 	followers, err := get(ctx, r, userId, 1)
 	return followers, err
 }
+
+// outputs number of followers of every user, users without followers are skipped
+func (r *Repository) GetFollowerCounts(ctx context.Context, userIds ...types.UserId) (map[types.UserId]int, error) {
+	counts := make(map[types.UserId]int)
+	if len(userIds) == 0 {
+		return counts, nil
+	}
+
+	args := make([]interface{}, len(userIds))
+	for i, id := range userIds {
+		args[i] = id
+	}
+	query := fmt.Sprintf(
+		"SELECT following_id, COUNT(*) FROM Followers WHERE following_id IN (?%s) GROUP BY following_id",
+		strings.Repeat(", ?", len(userIds)-1),
+	)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id    types.UserId
+			count int
+		)
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		counts[id] = count
+	}
+	return counts, rows.Err()
+}
+
+// save pending follow to protected account, repeated requests are ignored
+func (r *Repository) PutFollowRequest(ctx context.Context, userId types.UserId, followId types.UserId) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"INSERT IGNORE INTO FollowRequests (user_id, following_id) VALUES (?, ?)", userId, followId,
+	)
+	return err
+}
+
+// helper function to retrieve pending requests, the oldest go first
+func getRequests(ctx context.Context, r *Repository, userId types.UserId, retrieveType int) ([]types.UserId, error) {
+	var query string
+	// GetOutgoingRequests or GetIncomingRequests
+	switch retrieveType {
+	case 0:
+		query = "SELECT following_id FROM FollowRequests WHERE user_id = ? ORDER BY created_at"
+	case 1:
+		query = "SELECT user_id FROM FollowRequests WHERE following_id = ? ORDER BY created_at"
+	}
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []types.UserId{}
+	for rows.Next() {
+		var id types.UserId
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	return res, rows.Err()
+}
+
+// users that user asked to follow
+func (r *Repository) GetOutgoingRequests(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	users, err := getRequests(ctx, r, userId, 0)
+	return users, err
+}
+
+// users that asked to follow user
+func (r *Repository) GetIncomingRequests(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	users, err := getRequests(ctx, r, userId, 1)
+	return users, err
+}
+
+// remove pending request of userId to follow followId, ErrNotFound if there is no such request
+func (r *Repository) DeleteFollowRequest(ctx context.Context, userId types.UserId, followId types.UserId) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		"DELETE FROM FollowRequests WHERE user_id = ? AND following_id = ?", userId, followId,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// move pending request of userId to followers of followId in one transaction
+func (r *Repository) ApproveFollowRequest(ctx context.Context, userId types.UserId, followId types.UserId) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(
+		ctx,
+		"DELETE FROM FollowRequests WHERE user_id = ? AND following_id = ?", userId, followId,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err = tx.ExecContext(
+		ctx,
+		"INSERT IGNORE INTO Followers (user_id, following_id) VALUES (?, ?)", userId, followId,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// block user, follows and pending requests in both directions are removed in the same transaction
+func (r *Repository) Block(ctx context.Context, userId types.UserId, blockedId types.UserId) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(
+		ctx,
+		"INSERT IGNORE INTO Blocks (user_id, blocked_id) VALUES (?, ?)", userId, blockedId,
+	); err != nil {
+		return err
+	}
+	for _, table := range []string{"Followers", "FollowRequests"} {
+		if _, err = tx.ExecContext(
+			ctx,
+			fmt.Sprintf(
+				"DELETE FROM %s WHERE (user_id = ? AND following_id = ?) OR (user_id = ? AND following_id = ?)", table,
+			),
+			userId, blockedId, blockedId, userId,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) Unblock(ctx context.Context, userId types.UserId, blockedId types.UserId) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM Blocks WHERE user_id = ? AND blocked_id = ?", userId, blockedId)
+	return err
+}
+
+func (r *Repository) Mute(ctx context.Context, userId types.UserId, mutedId types.UserId) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"INSERT IGNORE INTO Mutes (user_id, muted_id) VALUES (?, ?)", userId, mutedId,
+	)
+	return err
+}
+
+func (r *Repository) Unmute(ctx context.Context, userId types.UserId, mutedId types.UserId) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM Mutes WHERE user_id = ? AND muted_id = ?", userId, mutedId)
+	return err
+}
+
+// helper function to retrieve users blocked or muted by user, the latest go first
+func getRelated(ctx context.Context, r *Repository, query string, userId types.UserId) ([]types.UserId, error) {
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []types.UserId{}
+	for rows.Next() {
+		var id types.UserId
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	return res, rows.Err()
+}
+
+func (r *Repository) GetBlocked(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	users, err := getRelated(
+		ctx, r, "SELECT blocked_id FROM Blocks WHERE user_id = ? ORDER BY created_at DESC", userId,
+	)
+	return users, err
+}
+
+func (r *Repository) GetMuted(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	users, err := getRelated(
+		ctx, r, "SELECT muted_id FROM Mutes WHERE user_id = ? ORDER BY created_at DESC", userId,
+	)
+	return users, err
+}
+
+// outputs relationship of user to every target, all of them are checked in one query
+func (r *Repository) GetRelationships(
+	ctx context.Context,
+	userId types.UserId,
+	targetIds ...types.UserId,
+) (map[types.UserId]model.Relationship, error) {
+	relationships := make(map[types.UserId]model.Relationship)
+	if len(targetIds) == 0 {
+		return relationships, nil
+	}
+	for _, id := range targetIds {
+		relationships[id] = model.Relationship{UserId: id}
+	}
+
+	in := fmt.Sprintf("(?%s)", strings.Repeat(", ?", len(targetIds)-1))
+	parts := []string{
+		"SELECT following_id, 'following' FROM Followers WHERE user_id = ? AND following_id IN " + in,
+		"SELECT user_id, 'followed_by' FROM Followers WHERE following_id = ? AND user_id IN " + in,
+		"SELECT blocked_id, 'blocking' FROM Blocks WHERE user_id = ? AND blocked_id IN " + in,
+		"SELECT user_id, 'blocked_by' FROM Blocks WHERE blocked_id = ? AND user_id IN " + in,
+		"SELECT muted_id, 'muting' FROM Mutes WHERE user_id = ? AND muted_id IN " + in,
+	}
+	var args []interface{}
+	for range parts {
+		args = append(args, userId)
+		for _, id := range targetIds {
+			args = append(args, id)
+		}
+	}
+
+	rows, err := r.db.QueryContext(ctx, strings.Join(parts, " UNION ALL "), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   types.UserId
+			kind string
+		)
+		if err := rows.Scan(&id, &kind); err != nil {
+			return nil, err
+		}
+		relationship := relationships[id]
+		switch kind {
+		case "following":
+			relationship.Following = true
+		case "followed_by":
+			relationship.FollowedBy = true
+		case "blocking":
+			relationship.Blocking = true
+		case "blocked_by":
+			relationship.BlockedBy = true
+		case "muting":
+			relationship.Muting = true
+		}
+		relationships[id] = relationship
+	}
+	return relationships, rows.Err()
+}
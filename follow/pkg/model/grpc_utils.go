@@ -0,0 +1,32 @@
+package model
+
+import (
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/follow"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+)
+
+// RelationshipToProto converts a Relationship struct into a
+// generated proto counterpart.
+func RelationshipToProto(r *Relationship) *gen.Relationship {
+	return &gen.Relationship{
+		UserId:     int32(r.UserId),
+		Following:  r.Following,
+		FollowedBy: r.FollowedBy,
+		Blocking:   r.Blocking,
+		BlockedBy:  r.BlockedBy,
+		Muting:     r.Muting,
+	}
+}
+
+// RelationshipFromProto converts a proto struct into a
+// relationship counterpart.
+func RelationshipFromProto(r *gen.Relationship) *Relationship {
+	return &Relationship{
+		UserId:     types.UserId(r.UserId),
+		Following:  r.Following,
+		FollowedBy: r.FollowedBy,
+		Blocking:   r.Blocking,
+		BlockedBy:  r.BlockedBy,
+		Muting:     r.Muting,
+	}
+}
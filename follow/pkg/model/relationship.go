@@ -0,0 +1,19 @@
+package model
+
+import "github.com/alexvishnevskiy/twitter-clone/internal/types"
+
+// Relationship describes how user is related to another user
+type Relationship struct {
+	UserId     types.UserId `json:"user_id"`
+	Following  bool         `json:"following"`
+	FollowedBy bool         `json:"followed_by"`
+	Blocking   bool         `json:"blocking"`
+	BlockedBy  bool         `json:"blocked_by"`
+	Muting     bool         `json:"muting"`
+}
+
+// Blocked reports whether one of the users blocked the other,
+// blocked users do not see each other's content
+func (r Relationship) Blocked() bool {
+	return r.Blocking || r.BlockedBy
+}
@@ -19,8 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	FollowService_GetUserFollowers_FullMethodName = "/follow.FollowService/GetUserFollowers"
-	FollowService_GetFollowingUser_FullMethodName = "/follow.FollowService/GetFollowingUser"
+	FollowService_GetUserFollowers_FullMethodName  = "/follow.FollowService/GetUserFollowers"
+	FollowService_GetFollowingUser_FullMethodName  = "/follow.FollowService/GetFollowingUser"
+	FollowService_GetFollowerCounts_FullMethodName = "/follow.FollowService/GetFollowerCounts"
+	FollowService_GetRelationships_FullMethodName  = "/follow.FollowService/GetRelationships"
 )
 
 // FollowServiceClient is the client API for FollowService service.
@@ -29,6 +31,8 @@ const (
 type FollowServiceClient interface {
 	GetUserFollowers(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*GetResponse, error)
 	GetFollowingUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*GetResponse, error)
+	GetFollowerCounts(ctx context.Context, in *GetFollowerCountsRequest, opts ...grpc.CallOption) (*GetFollowerCountsResponse, error)
+	GetRelationships(ctx context.Context, in *GetRelationshipsRequest, opts ...grpc.CallOption) (*GetRelationshipsResponse, error)
 }
 
 type followServiceClient struct {
@@ -57,12 +61,32 @@ func (c *followServiceClient) GetFollowingUser(ctx context.Context, in *UserId,
 	return out, nil
 }
 
+func (c *followServiceClient) GetFollowerCounts(ctx context.Context, in *GetFollowerCountsRequest, opts ...grpc.CallOption) (*GetFollowerCountsResponse, error) {
+	out := new(GetFollowerCountsResponse)
+	err := c.cc.Invoke(ctx, FollowService_GetFollowerCounts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *followServiceClient) GetRelationships(ctx context.Context, in *GetRelationshipsRequest, opts ...grpc.CallOption) (*GetRelationshipsResponse, error) {
+	out := new(GetRelationshipsResponse)
+	err := c.cc.Invoke(ctx, FollowService_GetRelationships_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FollowServiceServer is the server API for FollowService service.
 // All implementations must embed UnimplementedFollowServiceServer
 // for forward compatibility
 type FollowServiceServer interface {
 	GetUserFollowers(context.Context, *UserId) (*GetResponse, error)
 	GetFollowingUser(context.Context, *UserId) (*GetResponse, error)
+	GetFollowerCounts(context.Context, *GetFollowerCountsRequest) (*GetFollowerCountsResponse, error)
+	GetRelationships(context.Context, *GetRelationshipsRequest) (*GetRelationshipsResponse, error)
 	mustEmbedUnimplementedFollowServiceServer()
 }
 
@@ -76,6 +100,12 @@ func (UnimplementedFollowServiceServer) GetUserFollowers(context.Context, *UserI
 func (UnimplementedFollowServiceServer) GetFollowingUser(context.Context, *UserId) (*GetResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetFollowingUser not implemented")
 }
+func (UnimplementedFollowServiceServer) GetFollowerCounts(context.Context, *GetFollowerCountsRequest) (*GetFollowerCountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFollowerCounts not implemented")
+}
+func (UnimplementedFollowServiceServer) GetRelationships(context.Context, *GetRelationshipsRequest) (*GetRelationshipsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRelationships not implemented")
+}
 func (UnimplementedFollowServiceServer) mustEmbedUnimplementedFollowServiceServer() {}
 
 // UnsafeFollowServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -125,6 +155,42 @@ func _FollowService_GetFollowingUser_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FollowService_GetFollowerCounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFollowerCountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FollowServiceServer).GetFollowerCounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FollowService_GetFollowerCounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FollowServiceServer).GetFollowerCounts(ctx, req.(*GetFollowerCountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FollowService_GetRelationships_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRelationshipsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FollowServiceServer).GetRelationships(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FollowService_GetRelationships_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FollowServiceServer).GetRelationships(ctx, req.(*GetRelationshipsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FollowService_ServiceDesc is the grpc.ServiceDesc for FollowService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -140,6 +206,14 @@ var FollowService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetFollowingUser",
 			Handler:    _FollowService_GetFollowingUser_Handler,
 		},
+		{
+			MethodName: "GetFollowerCounts",
+			Handler:    _FollowService_GetFollowerCounts_Handler,
+		},
+		{
+			MethodName: "GetRelationships",
+			Handler:    _FollowService_GetRelationships_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "follow.proto",
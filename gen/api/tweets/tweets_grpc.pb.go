@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	TweetsService_Retrieve_FullMethodName = "/tweets.TweetsService/Retrieve"
+	TweetsService_Retrieve_FullMethodName   = "/tweets.TweetsService/Retrieve"
+	TweetsService_GetAuthors_FullMethodName = "/tweets.TweetsService/GetAuthors"
 )
 
 // TweetsServiceClient is the client API for TweetsService service.
@@ -27,6 +28,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type TweetsServiceClient interface {
 	Retrieve(ctx context.Context, in *RetrieveRequest, opts ...grpc.CallOption) (*RetrieveResponse, error)
+	GetAuthors(ctx context.Context, in *GetAuthorsRequest, opts ...grpc.CallOption) (*GetAuthorsResponse, error)
 }
 
 type tweetsServiceClient struct {
@@ -46,11 +48,21 @@ func (c *tweetsServiceClient) Retrieve(ctx context.Context, in *RetrieveRequest,
 	return out, nil
 }
 
+func (c *tweetsServiceClient) GetAuthors(ctx context.Context, in *GetAuthorsRequest, opts ...grpc.CallOption) (*GetAuthorsResponse, error) {
+	out := new(GetAuthorsResponse)
+	err := c.cc.Invoke(ctx, TweetsService_GetAuthors_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TweetsServiceServer is the server API for TweetsService service.
 // All implementations must embed UnimplementedTweetsServiceServer
 // for forward compatibility
 type TweetsServiceServer interface {
 	Retrieve(context.Context, *RetrieveRequest) (*RetrieveResponse, error)
+	GetAuthors(context.Context, *GetAuthorsRequest) (*GetAuthorsResponse, error)
 	mustEmbedUnimplementedTweetsServiceServer()
 }
 
@@ -61,6 +73,9 @@ type UnimplementedTweetsServiceServer struct {
 func (UnimplementedTweetsServiceServer) Retrieve(context.Context, *RetrieveRequest) (*RetrieveResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Retrieve not implemented")
 }
+func (UnimplementedTweetsServiceServer) GetAuthors(context.Context, *GetAuthorsRequest) (*GetAuthorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAuthors not implemented")
+}
 func (UnimplementedTweetsServiceServer) mustEmbedUnimplementedTweetsServiceServer() {}
 
 // UnsafeTweetsServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +107,24 @@ func _TweetsService_Retrieve_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TweetsService_GetAuthors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAuthorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TweetsServiceServer).GetAuthors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TweetsService_GetAuthors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TweetsServiceServer).GetAuthors(ctx, req.(*GetAuthorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // TweetsService_ServiceDesc is the grpc.ServiceDesc for TweetsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,6 +136,10 @@ var TweetsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Retrieve",
 			Handler:    _TweetsService_Retrieve_Handler,
 		},
+		{
+			MethodName: "GetAuthors",
+			Handler:    _TweetsService_GetAuthors_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "tweets.proto",
@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	model "github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	types "github.com/alexvishnevskiy/twitter-clone/internal/types"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,6 +36,48 @@ func (m *MockfollowRepository) EXPECT() *MockfollowRepositoryMockRecorder {
 	return m.recorder
 }
 
+// ApproveFollowRequest mocks base method.
+func (m *MockfollowRepository) ApproveFollowRequest(ctx context.Context, userId, followId types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveFollowRequest", ctx, userId, followId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApproveFollowRequest indicates an expected call of ApproveFollowRequest.
+func (mr *MockfollowRepositoryMockRecorder) ApproveFollowRequest(ctx, userId, followId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveFollowRequest", reflect.TypeOf((*MockfollowRepository)(nil).ApproveFollowRequest), ctx, userId, followId)
+}
+
+// Block mocks base method.
+func (m *MockfollowRepository) Block(ctx context.Context, userId, blockedId types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Block", ctx, userId, blockedId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Block indicates an expected call of Block.
+func (mr *MockfollowRepositoryMockRecorder) Block(ctx, userId, blockedId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Block", reflect.TypeOf((*MockfollowRepository)(nil).Block), ctx, userId, blockedId)
+}
+
+// DeleteFollowRequest mocks base method.
+func (m *MockfollowRepository) DeleteFollowRequest(ctx context.Context, userId, followId types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFollowRequest", ctx, userId, followId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFollowRequest indicates an expected call of DeleteFollowRequest.
+func (mr *MockfollowRepositoryMockRecorder) DeleteFollowRequest(ctx, userId, followId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFollowRequest", reflect.TypeOf((*MockfollowRepository)(nil).DeleteFollowRequest), ctx, userId, followId)
+}
+
 // Follow mocks base method.
 func (m *MockfollowRepository) Follow(ctx context.Context, userId, followId types.UserId) error {
 	m.ctrl.T.Helper()
@@ -49,6 +92,41 @@ func (mr *MockfollowRepositoryMockRecorder) Follow(ctx, userId, followId interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Follow", reflect.TypeOf((*MockfollowRepository)(nil).Follow), ctx, userId, followId)
 }
 
+// GetBlocked mocks base method.
+func (m *MockfollowRepository) GetBlocked(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlocked", ctx, userId)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlocked indicates an expected call of GetBlocked.
+func (mr *MockfollowRepositoryMockRecorder) GetBlocked(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlocked", reflect.TypeOf((*MockfollowRepository)(nil).GetBlocked), ctx, userId)
+}
+
+// GetFollowerCounts mocks base method.
+func (m *MockfollowRepository) GetFollowerCounts(ctx context.Context, userIds ...types.UserId) (map[types.UserId]int, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range userIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetFollowerCounts", varargs...)
+	ret0, _ := ret[0].(map[types.UserId]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowerCounts indicates an expected call of GetFollowerCounts.
+func (mr *MockfollowRepositoryMockRecorder) GetFollowerCounts(ctx interface{}, userIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, userIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowerCounts", reflect.TypeOf((*MockfollowRepository)(nil).GetFollowerCounts), varargs...)
+}
+
 // GetFollowingUser mocks base method.
 func (m *MockfollowRepository) GetFollowingUser(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
 	m.ctrl.T.Helper()
@@ -64,6 +142,71 @@ func (mr *MockfollowRepositoryMockRecorder) GetFollowingUser(ctx, userId interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowingUser", reflect.TypeOf((*MockfollowRepository)(nil).GetFollowingUser), ctx, userId)
 }
 
+// GetIncomingRequests mocks base method.
+func (m *MockfollowRepository) GetIncomingRequests(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIncomingRequests", ctx, userId)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIncomingRequests indicates an expected call of GetIncomingRequests.
+func (mr *MockfollowRepositoryMockRecorder) GetIncomingRequests(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIncomingRequests", reflect.TypeOf((*MockfollowRepository)(nil).GetIncomingRequests), ctx, userId)
+}
+
+// GetMuted mocks base method.
+func (m *MockfollowRepository) GetMuted(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMuted", ctx, userId)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMuted indicates an expected call of GetMuted.
+func (mr *MockfollowRepositoryMockRecorder) GetMuted(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMuted", reflect.TypeOf((*MockfollowRepository)(nil).GetMuted), ctx, userId)
+}
+
+// GetOutgoingRequests mocks base method.
+func (m *MockfollowRepository) GetOutgoingRequests(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutgoingRequests", ctx, userId)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutgoingRequests indicates an expected call of GetOutgoingRequests.
+func (mr *MockfollowRepositoryMockRecorder) GetOutgoingRequests(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutgoingRequests", reflect.TypeOf((*MockfollowRepository)(nil).GetOutgoingRequests), ctx, userId)
+}
+
+// GetRelationships mocks base method.
+func (m *MockfollowRepository) GetRelationships(ctx context.Context, userId types.UserId, targetIds ...types.UserId) (map[types.UserId]model.Relationship, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, userId}
+	for _, a := range targetIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetRelationships", varargs...)
+	ret0, _ := ret[0].(map[types.UserId]model.Relationship)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRelationships indicates an expected call of GetRelationships.
+func (mr *MockfollowRepositoryMockRecorder) GetRelationships(ctx, userId interface{}, targetIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, userId}, targetIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRelationships", reflect.TypeOf((*MockfollowRepository)(nil).GetRelationships), varargs...)
+}
+
 // GetUserFollowers mocks base method.
 func (m *MockfollowRepository) GetUserFollowers(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
 	m.ctrl.T.Helper()
@@ -79,6 +222,48 @@ func (mr *MockfollowRepositoryMockRecorder) GetUserFollowers(ctx, userId interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserFollowers", reflect.TypeOf((*MockfollowRepository)(nil).GetUserFollowers), ctx, userId)
 }
 
+// Mute mocks base method.
+func (m *MockfollowRepository) Mute(ctx context.Context, userId, mutedId types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mute", ctx, userId, mutedId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Mute indicates an expected call of Mute.
+func (mr *MockfollowRepositoryMockRecorder) Mute(ctx, userId, mutedId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mute", reflect.TypeOf((*MockfollowRepository)(nil).Mute), ctx, userId, mutedId)
+}
+
+// PutFollowRequest mocks base method.
+func (m *MockfollowRepository) PutFollowRequest(ctx context.Context, userId, followId types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutFollowRequest", ctx, userId, followId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutFollowRequest indicates an expected call of PutFollowRequest.
+func (mr *MockfollowRepositoryMockRecorder) PutFollowRequest(ctx, userId, followId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutFollowRequest", reflect.TypeOf((*MockfollowRepository)(nil).PutFollowRequest), ctx, userId, followId)
+}
+
+// Unblock mocks base method.
+func (m *MockfollowRepository) Unblock(ctx context.Context, userId, blockedId types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unblock", ctx, userId, blockedId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unblock indicates an expected call of Unblock.
+func (mr *MockfollowRepositoryMockRecorder) Unblock(ctx, userId, blockedId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unblock", reflect.TypeOf((*MockfollowRepository)(nil).Unblock), ctx, userId, blockedId)
+}
+
 // Unfollow mocks base method.
 func (m *MockfollowRepository) Unfollow(ctx context.Context, userId, followId types.UserId) error {
 	m.ctrl.T.Helper()
@@ -92,3 +277,55 @@ func (mr *MockfollowRepositoryMockRecorder) Unfollow(ctx, userId, followId inter
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unfollow", reflect.TypeOf((*MockfollowRepository)(nil).Unfollow), ctx, userId, followId)
 }
+
+// Unmute mocks base method.
+func (m *MockfollowRepository) Unmute(ctx context.Context, userId, mutedId types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unmute", ctx, userId, mutedId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unmute indicates an expected call of Unmute.
+func (mr *MockfollowRepositoryMockRecorder) Unmute(ctx, userId, mutedId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmute", reflect.TypeOf((*MockfollowRepository)(nil).Unmute), ctx, userId, mutedId)
+}
+
+// MockusersGateway is a mock of usersGateway interface.
+type MockusersGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockusersGatewayMockRecorder
+}
+
+// MockusersGatewayMockRecorder is the mock recorder for MockusersGateway.
+type MockusersGatewayMockRecorder struct {
+	mock *MockusersGateway
+}
+
+// NewMockusersGateway creates a new mock instance.
+func NewMockusersGateway(ctrl *gomock.Controller) *MockusersGateway {
+	mock := &MockusersGateway{ctrl: ctrl}
+	mock.recorder = &MockusersGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockusersGateway) EXPECT() *MockusersGatewayMockRecorder {
+	return m.recorder
+}
+
+// IsProtected mocks base method.
+func (m *MockusersGateway) IsProtected(ctx context.Context, userId types.UserId) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsProtected", ctx, userId)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsProtected indicates an expected call of IsProtected.
+func (mr *MockusersGatewayMockRecorder) IsProtected(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsProtected", reflect.TypeOf((*MockusersGateway)(nil).IsProtected), ctx, userId)
+}
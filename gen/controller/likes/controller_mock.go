@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	model "github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	types "github.com/alexvishnevskiy/twitter-clone/internal/types"
 	gomock "github.com/golang/mock/gomock"
 )
@@ -92,3 +93,89 @@ func (mr *MocklikesRepositoryMockRecorder) Unlike(ctx, userId, tweetId interface
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlike", reflect.TypeOf((*MocklikesRepository)(nil).Unlike), ctx, userId, tweetId)
 }
+
+// MocktweetsGateway is a mock of tweetsGateway interface.
+type MocktweetsGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MocktweetsGatewayMockRecorder
+}
+
+// MocktweetsGatewayMockRecorder is the mock recorder for MocktweetsGateway.
+type MocktweetsGatewayMockRecorder struct {
+	mock *MocktweetsGateway
+}
+
+// NewMocktweetsGateway creates a new mock instance.
+func NewMocktweetsGateway(ctrl *gomock.Controller) *MocktweetsGateway {
+	mock := &MocktweetsGateway{ctrl: ctrl}
+	mock.recorder = &MocktweetsGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktweetsGateway) EXPECT() *MocktweetsGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetAuthors mocks base method.
+func (m *MocktweetsGateway) GetAuthors(ctx context.Context, tweetIds ...types.TweetId) (map[types.TweetId]types.UserId, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range tweetIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetAuthors", varargs...)
+	ret0, _ := ret[0].(map[types.TweetId]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAuthors indicates an expected call of GetAuthors.
+func (mr *MocktweetsGatewayMockRecorder) GetAuthors(ctx interface{}, tweetIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, tweetIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAuthors", reflect.TypeOf((*MocktweetsGateway)(nil).GetAuthors), varargs...)
+}
+
+// MockfollowGateway is a mock of followGateway interface.
+type MockfollowGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockfollowGatewayMockRecorder
+}
+
+// MockfollowGatewayMockRecorder is the mock recorder for MockfollowGateway.
+type MockfollowGatewayMockRecorder struct {
+	mock *MockfollowGateway
+}
+
+// NewMockfollowGateway creates a new mock instance.
+func NewMockfollowGateway(ctrl *gomock.Controller) *MockfollowGateway {
+	mock := &MockfollowGateway{ctrl: ctrl}
+	mock.recorder = &MockfollowGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockfollowGateway) EXPECT() *MockfollowGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetRelationships mocks base method.
+func (m *MockfollowGateway) GetRelationships(ctx context.Context, userId types.UserId, targetIds ...types.UserId) (map[types.UserId]model.Relationship, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, userId}
+	for _, a := range targetIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetRelationships", varargs...)
+	ret0, _ := ret[0].(map[types.UserId]model.Relationship)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRelationships indicates an expected call of GetRelationships.
+func (mr *MockfollowGatewayMockRecorder) GetRelationships(ctx, userId interface{}, targetIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, userId}, targetIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRelationships", reflect.TypeOf((*MockfollowGateway)(nil).GetRelationships), varargs...)
+}
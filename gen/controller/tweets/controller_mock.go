@@ -9,8 +9,10 @@ import (
 	reflect "reflect"
 	time "time"
 
+	model "github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	types "github.com/alexvishnevskiy/twitter-clone/internal/types"
-	model "github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	model0 "github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	model1 "github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -52,14 +54,14 @@ func (mr *MocktweetsRepositoryMockRecorder) DeletePost(ctx, postId interface{})
 }
 
 // GetByTweet mocks base method.
-func (m *MocktweetsRepository) GetByTweet(ctx context.Context, tweetIds ...types.TweetId) ([]model.Tweet, error) {
+func (m *MocktweetsRepository) GetByTweet(ctx context.Context, tweetIds ...types.TweetId) ([]model0.Tweet, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx}
 	for _, a := range tweetIds {
 		varargs = append(varargs, a)
 	}
 	ret := m.ctrl.Call(m, "GetByTweet", varargs...)
-	ret0, _ := ret[0].([]model.Tweet)
+	ret0, _ := ret[0].([]model0.Tweet)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -72,14 +74,14 @@ func (mr *MocktweetsRepositoryMockRecorder) GetByTweet(ctx interface{}, tweetIds
 }
 
 // GetByUser mocks base method.
-func (m *MocktweetsRepository) GetByUser(ctx context.Context, userIds ...types.UserId) ([]model.Tweet, error) {
+func (m *MocktweetsRepository) GetByUser(ctx context.Context, userIds ...types.UserId) ([]model0.Tweet, error) {
 	m.ctrl.T.Helper()
 	varargs := []interface{}{ctx}
 	for _, a := range userIds {
 		varargs = append(varargs, a)
 	}
 	ret := m.ctrl.Call(m, "GetByUser", varargs...)
-	ret0, _ := ret[0].([]model.Tweet)
+	ret0, _ := ret[0].([]model0.Tweet)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -91,10 +93,40 @@ func (mr *MocktweetsRepositoryMockRecorder) GetByUser(ctx interface{}, userIds .
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUser", reflect.TypeOf((*MocktweetsRepository)(nil).GetByUser), varargs...)
 }
 
+// GetMentions mocks base method.
+func (m *MocktweetsRepository) GetMentions(ctx context.Context, tweetId types.TweetId) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMentions", ctx, tweetId)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMentions indicates an expected call of GetMentions.
+func (mr *MocktweetsRepositoryMockRecorder) GetMentions(ctx, tweetId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMentions", reflect.TypeOf((*MocktweetsRepository)(nil).GetMentions), ctx, tweetId)
+}
+
+// GetOpenReports mocks base method.
+func (m *MocktweetsRepository) GetOpenReports(ctx context.Context) ([]model0.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOpenReports", ctx)
+	ret0, _ := ret[0].([]model0.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOpenReports indicates an expected call of GetOpenReports.
+func (mr *MocktweetsRepositoryMockRecorder) GetOpenReports(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOpenReports", reflect.TypeOf((*MocktweetsRepository)(nil).GetOpenReports), ctx)
+}
+
 // Put mocks base method.
-func (m *MocktweetsRepository) Put(ctx context.Context, userId types.UserId, content string, mediaUrl *string, retweetId *types.TweetId) (types.TweetId, time.Time, error) {
+func (m *MocktweetsRepository) Put(ctx context.Context, tweet model0.Tweet) (types.TweetId, time.Time, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Put", ctx, userId, content, mediaUrl, retweetId)
+	ret := m.ctrl.Call(m, "Put", ctx, tweet)
 	ret0, _ := ret[0].(types.TweetId)
 	ret1, _ := ret[1].(time.Time)
 	ret2, _ := ret[2].(error)
@@ -102,7 +134,164 @@ func (m *MocktweetsRepository) Put(ctx context.Context, userId types.UserId, con
 }
 
 // Put indicates an expected call of Put.
-func (mr *MocktweetsRepositoryMockRecorder) Put(ctx, userId, content, mediaUrl, retweetId interface{}) *gomock.Call {
+func (mr *MocktweetsRepositoryMockRecorder) Put(ctx, tweet interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MocktweetsRepository)(nil).Put), ctx, tweet)
+}
+
+// PutReport mocks base method.
+func (m *MocktweetsRepository) PutReport(ctx context.Context, report model0.Report) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutReport", ctx, report)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutReport indicates an expected call of PutReport.
+func (mr *MocktweetsRepositoryMockRecorder) PutReport(ctx, report interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutReport", reflect.TypeOf((*MocktweetsRepository)(nil).PutReport), ctx, report)
+}
+
+// ResolveReports mocks base method.
+func (m *MocktweetsRepository) ResolveReports(ctx context.Context, tweetId types.TweetId, resolvedBy types.UserId, resolution model0.Resolution, resolvedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveReports", ctx, tweetId, resolvedBy, resolution, resolvedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResolveReports indicates an expected call of ResolveReports.
+func (mr *MocktweetsRepositoryMockRecorder) ResolveReports(ctx, tweetId, resolvedBy, resolution, resolvedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveReports", reflect.TypeOf((*MocktweetsRepository)(nil).ResolveReports), ctx, tweetId, resolvedBy, resolution, resolvedAt)
+}
+
+// UpdateAltText mocks base method.
+func (m *MocktweetsRepository) UpdateAltText(ctx context.Context, tweetId types.TweetId, altText *string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAltText", ctx, tweetId, altText)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAltText indicates an expected call of UpdateAltText.
+func (mr *MocktweetsRepositoryMockRecorder) UpdateAltText(ctx, tweetId, altText interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MocktweetsRepository)(nil).Put), ctx, userId, content, mediaUrl, retweetId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAltText", reflect.TypeOf((*MocktweetsRepository)(nil).UpdateAltText), ctx, tweetId, altText)
+}
+
+// UpdateSensitive mocks base method.
+func (m *MocktweetsRepository) UpdateSensitive(ctx context.Context, tweetId types.TweetId, sensitive bool, contentWarning *string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSensitive", ctx, tweetId, sensitive, contentWarning)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSensitive indicates an expected call of UpdateSensitive.
+func (mr *MocktweetsRepositoryMockRecorder) UpdateSensitive(ctx, tweetId, sensitive, contentWarning interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSensitive", reflect.TypeOf((*MocktweetsRepository)(nil).UpdateSensitive), ctx, tweetId, sensitive, contentWarning)
+}
+
+// MockfollowGateway is a mock of followGateway interface.
+type MockfollowGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockfollowGatewayMockRecorder
+}
+
+// MockfollowGatewayMockRecorder is the mock recorder for MockfollowGateway.
+type MockfollowGatewayMockRecorder struct {
+	mock *MockfollowGateway
+}
+
+// NewMockfollowGateway creates a new mock instance.
+func NewMockfollowGateway(ctrl *gomock.Controller) *MockfollowGateway {
+	mock := &MockfollowGateway{ctrl: ctrl}
+	mock.recorder = &MockfollowGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockfollowGateway) EXPECT() *MockfollowGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetRelationships mocks base method.
+func (m *MockfollowGateway) GetRelationships(ctx context.Context, userId types.UserId, targetIds ...types.UserId) (map[types.UserId]model.Relationship, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, userId}
+	for _, a := range targetIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetRelationships", varargs...)
+	ret0, _ := ret[0].(map[types.UserId]model.Relationship)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRelationships indicates an expected call of GetRelationships.
+func (mr *MockfollowGatewayMockRecorder) GetRelationships(ctx, userId interface{}, targetIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, userId}, targetIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRelationships", reflect.TypeOf((*MockfollowGateway)(nil).GetRelationships), varargs...)
+}
+
+// GetUsers mocks base method.
+func (m *MockfollowGateway) GetUsers(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsers", ctx, userId)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockfollowGatewayMockRecorder) GetUsers(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockfollowGateway)(nil).GetUsers), ctx, userId)
+}
+
+// MockusersGateway is a mock of usersGateway interface.
+type MockusersGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockusersGatewayMockRecorder
+}
+
+// MockusersGatewayMockRecorder is the mock recorder for MockusersGateway.
+type MockusersGatewayMockRecorder struct {
+	mock *MockusersGateway
+}
+
+// NewMockusersGateway creates a new mock instance.
+func NewMockusersGateway(ctrl *gomock.Controller) *MockusersGateway {
+	mock := &MockusersGateway{ctrl: ctrl}
+	mock.recorder = &MockusersGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockusersGateway) EXPECT() *MockusersGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetProfiles mocks base method.
+func (m *MockusersGateway) GetProfiles(ctx context.Context, userIds ...types.UserId) (map[types.UserId]model1.Profile, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range userIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetProfiles", varargs...)
+	ret0, _ := ret[0].(map[types.UserId]model1.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfiles indicates an expected call of GetProfiles.
+func (mr *MockusersGatewayMockRecorder) GetProfiles(ctx interface{}, userIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, userIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfiles", reflect.TypeOf((*MockusersGateway)(nil).GetProfiles), varargs...)
 }
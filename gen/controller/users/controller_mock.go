@@ -0,0 +1,1095 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: users/internal/controller/controller.go
+
+// Package controller is a generated GoMock package.
+package controller
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	types "github.com/alexvishnevskiy/twitter-clone/internal/types"
+	model "github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	model0 "github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockusersRepository is a mock of usersRepository interface.
+type MockusersRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockusersRepositoryMockRecorder
+}
+
+// MockusersRepositoryMockRecorder is the mock recorder for MockusersRepository.
+type MockusersRepositoryMockRecorder struct {
+	mock *MockusersRepository
+}
+
+// NewMockusersRepository creates a new mock instance.
+func NewMockusersRepository(ctrl *gomock.Controller) *MockusersRepository {
+	mock := &MockusersRepository{ctrl: ctrl}
+	mock.recorder = &MockusersRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockusersRepository) EXPECT() *MockusersRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CompleteExport mocks base method.
+func (m *MockusersRepository) CompleteExport(ctx context.Context, exportId int, tokenHash, storagePath string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteExport", ctx, exportId, tokenHash, storagePath, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CompleteExport indicates an expected call of CompleteExport.
+func (mr *MockusersRepositoryMockRecorder) CompleteExport(ctx, exportId, tokenHash, storagePath, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteExport", reflect.TypeOf((*MockusersRepository)(nil).CompleteExport), ctx, exportId, tokenHash, storagePath, expiresAt)
+}
+
+// ConsumePasswordReset mocks base method.
+func (m *MockusersRepository) ConsumePasswordReset(ctx context.Context, tokenHash string) (types.UserId, time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumePasswordReset", ctx, tokenHash)
+	ret0, _ := ret[0].(types.UserId)
+	ret1, _ := ret[1].(time.Time)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ConsumePasswordReset indicates an expected call of ConsumePasswordReset.
+func (mr *MockusersRepositoryMockRecorder) ConsumePasswordReset(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumePasswordReset", reflect.TypeOf((*MockusersRepository)(nil).ConsumePasswordReset), ctx, tokenHash)
+}
+
+// CountNicknameChanges mocks base method.
+func (m *MockusersRepository) CountNicknameChanges(ctx context.Context, userid types.UserId, since time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountNicknameChanges", ctx, userid, since)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountNicknameChanges indicates an expected call of CountNicknameChanges.
+func (mr *MockusersRepositoryMockRecorder) CountNicknameChanges(ctx, userid, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountNicknameChanges", reflect.TypeOf((*MockusersRepository)(nil).CountNicknameChanges), ctx, userid, since)
+}
+
+// CreateAccessToken mocks base method.
+func (m *MockusersRepository) CreateAccessToken(ctx context.Context, token model0.AccessToken, tokenHash string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccessToken", ctx, token, tokenHash)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccessToken indicates an expected call of CreateAccessToken.
+func (mr *MockusersRepositoryMockRecorder) CreateAccessToken(ctx, token, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccessToken", reflect.TypeOf((*MockusersRepository)(nil).CreateAccessToken), ctx, token, tokenHash)
+}
+
+// CreateExport mocks base method.
+func (m *MockusersRepository) CreateExport(ctx context.Context, userid types.UserId, createdAt time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateExport", ctx, userid, createdAt)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateExport indicates an expected call of CreateExport.
+func (mr *MockusersRepositoryMockRecorder) CreateExport(ctx, userid, createdAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExport", reflect.TypeOf((*MockusersRepository)(nil).CreateExport), ctx, userid, createdAt)
+}
+
+// CreateSession mocks base method.
+func (m *MockusersRepository) CreateSession(ctx context.Context, session model0.Session, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, session, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockusersRepositoryMockRecorder) CreateSession(ctx, session, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockusersRepository)(nil).CreateSession), ctx, session, tokenHash, expiresAt)
+}
+
+// Deactivate mocks base method.
+func (m *MockusersRepository) Deactivate(ctx context.Context, userid types.UserId, deactivatedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Deactivate", ctx, userid, deactivatedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Deactivate indicates an expected call of Deactivate.
+func (mr *MockusersRepositoryMockRecorder) Deactivate(ctx, userid, deactivatedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deactivate", reflect.TypeOf((*MockusersRepository)(nil).Deactivate), ctx, userid, deactivatedAt)
+}
+
+// DeleteAccessToken mocks base method.
+func (m *MockusersRepository) DeleteAccessToken(ctx context.Context, userid types.UserId, tokenId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccessToken", ctx, userid, tokenId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccessToken indicates an expected call of DeleteAccessToken.
+func (mr *MockusersRepositoryMockRecorder) DeleteAccessToken(ctx, userid, tokenId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccessToken", reflect.TypeOf((*MockusersRepository)(nil).DeleteAccessToken), ctx, userid, tokenId)
+}
+
+// DeleteExport mocks base method.
+func (m *MockusersRepository) DeleteExport(ctx context.Context, exportId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExport", ctx, exportId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteExport indicates an expected call of DeleteExport.
+func (mr *MockusersRepositoryMockRecorder) DeleteExport(ctx, exportId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExport", reflect.TypeOf((*MockusersRepository)(nil).DeleteExport), ctx, exportId)
+}
+
+// DeleteRefreshFamily mocks base method.
+func (m *MockusersRepository) DeleteRefreshFamily(ctx context.Context, family string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRefreshFamily", ctx, family)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRefreshFamily indicates an expected call of DeleteRefreshFamily.
+func (mr *MockusersRepositoryMockRecorder) DeleteRefreshFamily(ctx, family interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRefreshFamily", reflect.TypeOf((*MockusersRepository)(nil).DeleteRefreshFamily), ctx, family)
+}
+
+// DeleteSession mocks base method.
+func (m *MockusersRepository) DeleteSession(ctx context.Context, userid types.UserId, sessionId string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, userid, sessionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockusersRepositoryMockRecorder) DeleteSession(ctx, userid, sessionId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockusersRepository)(nil).DeleteSession), ctx, userid, sessionId)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockusersRepository) DisableTOTP(ctx context.Context, userid types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, userid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockusersRepositoryMockRecorder) DisableTOTP(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockusersRepository)(nil).DisableTOTP), ctx, userid)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockusersRepository) EnableTOTP(ctx context.Context, userid types.UserId, step int64, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, userid, step, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockusersRepositoryMockRecorder) EnableTOTP(ctx, userid, step, codeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockusersRepository)(nil).EnableTOTP), ctx, userid, step, codeHashes)
+}
+
+// FailExport mocks base method.
+func (m *MockusersRepository) FailExport(ctx context.Context, exportId int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailExport", ctx, exportId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailExport indicates an expected call of FailExport.
+func (mr *MockusersRepositoryMockRecorder) FailExport(ctx, exportId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailExport", reflect.TypeOf((*MockusersRepository)(nil).FailExport), ctx, exportId)
+}
+
+// GetAccessTokens mocks base method.
+func (m *MockusersRepository) GetAccessTokens(ctx context.Context, userid types.UserId) ([]model0.AccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccessTokens", ctx, userid)
+	ret0, _ := ret[0].([]model0.AccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccessTokens indicates an expected call of GetAccessTokens.
+func (mr *MockusersRepositoryMockRecorder) GetAccessTokens(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccessTokens", reflect.TypeOf((*MockusersRepository)(nil).GetAccessTokens), ctx, userid)
+}
+
+// GetAccount mocks base method.
+func (m *MockusersRepository) GetAccount(ctx context.Context, userid types.UserId) (model0.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", ctx, userid)
+	ret0, _ := ret[0].(model0.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockusersRepositoryMockRecorder) GetAccount(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockusersRepository)(nil).GetAccount), ctx, userid)
+}
+
+// GetAllUsers mocks base method.
+func (m *MockusersRepository) GetAllUsers(ctx context.Context) ([]model0.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllUsers", ctx)
+	ret0, _ := ret[0].([]model0.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllUsers indicates an expected call of GetAllUsers.
+func (mr *MockusersRepositoryMockRecorder) GetAllUsers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllUsers", reflect.TypeOf((*MockusersRepository)(nil).GetAllUsers), ctx)
+}
+
+// GetById mocks base method.
+func (m *MockusersRepository) GetById(ctx context.Context, userid types.UserId) (model0.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetById", ctx, userid)
+	ret0, _ := ret[0].(model0.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetById indicates an expected call of GetById.
+func (mr *MockusersRepositoryMockRecorder) GetById(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockusersRepository)(nil).GetById), ctx, userid)
+}
+
+// GetByNickname mocks base method.
+func (m *MockusersRepository) GetByNickname(ctx context.Context, canonical string) (model0.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByNickname", ctx, canonical)
+	ret0, _ := ret[0].(model0.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByNickname indicates an expected call of GetByNickname.
+func (mr *MockusersRepositoryMockRecorder) GetByNickname(ctx, canonical interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByNickname", reflect.TypeOf((*MockusersRepository)(nil).GetByNickname), ctx, canonical)
+}
+
+// GetDeactivatedAt mocks base method.
+func (m *MockusersRepository) GetDeactivatedAt(ctx context.Context, userid types.UserId) (*time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeactivatedAt", ctx, userid)
+	ret0, _ := ret[0].(*time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeactivatedAt indicates an expected call of GetDeactivatedAt.
+func (mr *MockusersRepositoryMockRecorder) GetDeactivatedAt(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeactivatedAt", reflect.TypeOf((*MockusersRepository)(nil).GetDeactivatedAt), ctx, userid)
+}
+
+// GetDeactivatedBefore mocks base method.
+func (m *MockusersRepository) GetDeactivatedBefore(ctx context.Context, before time.Time) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeactivatedBefore", ctx, before)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeactivatedBefore indicates an expected call of GetDeactivatedBefore.
+func (mr *MockusersRepositoryMockRecorder) GetDeactivatedBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeactivatedBefore", reflect.TypeOf((*MockusersRepository)(nil).GetDeactivatedBefore), ctx, before)
+}
+
+// GetEmail mocks base method.
+func (m *MockusersRepository) GetEmail(ctx context.Context, userid types.UserId) (string, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEmail", ctx, userid)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetEmail indicates an expected call of GetEmail.
+func (mr *MockusersRepositoryMockRecorder) GetEmail(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmail", reflect.TypeOf((*MockusersRepository)(nil).GetEmail), ctx, userid)
+}
+
+// GetExpiredExports mocks base method.
+func (m *MockusersRepository) GetExpiredExports(ctx context.Context, before time.Time) ([]model0.Export, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExpiredExports", ctx, before)
+	ret0, _ := ret[0].([]model0.Export)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExpiredExports indicates an expected call of GetExpiredExports.
+func (mr *MockusersRepositoryMockRecorder) GetExpiredExports(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExpiredExports", reflect.TypeOf((*MockusersRepository)(nil).GetExpiredExports), ctx, before)
+}
+
+// GetExportByToken mocks base method.
+func (m *MockusersRepository) GetExportByToken(ctx context.Context, tokenHash string) (model0.Export, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExportByToken", ctx, tokenHash)
+	ret0, _ := ret[0].(model0.Export)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExportByToken indicates an expected call of GetExportByToken.
+func (mr *MockusersRepositoryMockRecorder) GetExportByToken(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExportByToken", reflect.TypeOf((*MockusersRepository)(nil).GetExportByToken), ctx, tokenHash)
+}
+
+// GetExports mocks base method.
+func (m *MockusersRepository) GetExports(ctx context.Context, userid types.UserId) ([]model0.Export, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExports", ctx, userid)
+	ret0, _ := ret[0].([]model0.Export)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExports indicates an expected call of GetExports.
+func (mr *MockusersRepositoryMockRecorder) GetExports(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExports", reflect.TypeOf((*MockusersRepository)(nil).GetExports), ctx, userid)
+}
+
+// GetIdByEmail mocks base method.
+func (m *MockusersRepository) GetIdByEmail(ctx context.Context, email string) (types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIdByEmail", ctx, email)
+	ret0, _ := ret[0].(types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIdByEmail indicates an expected call of GetIdByEmail.
+func (mr *MockusersRepositoryMockRecorder) GetIdByEmail(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIdByEmail", reflect.TypeOf((*MockusersRepository)(nil).GetIdByEmail), ctx, email)
+}
+
+// GetMediaUrls mocks base method.
+func (m *MockusersRepository) GetMediaUrls(ctx context.Context, userid types.UserId) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMediaUrls", ctx, userid)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMediaUrls indicates an expected call of GetMediaUrls.
+func (mr *MockusersRepositoryMockRecorder) GetMediaUrls(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMediaUrls", reflect.TypeOf((*MockusersRepository)(nil).GetMediaUrls), ctx, userid)
+}
+
+// GetNicknameOwner mocks base method.
+func (m *MockusersRepository) GetNicknameOwner(ctx context.Context, canonical string, since time.Time) (types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNicknameOwner", ctx, canonical, since)
+	ret0, _ := ret[0].(types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNicknameOwner indicates an expected call of GetNicknameOwner.
+func (mr *MockusersRepositoryMockRecorder) GetNicknameOwner(ctx, canonical, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNicknameOwner", reflect.TypeOf((*MockusersRepository)(nil).GetNicknameOwner), ctx, canonical, since)
+}
+
+// GetPreferredLanguages mocks base method.
+func (m *MockusersRepository) GetPreferredLanguages(ctx context.Context, userid types.UserId) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPreferredLanguages", ctx, userid)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPreferredLanguages indicates an expected call of GetPreferredLanguages.
+func (mr *MockusersRepositoryMockRecorder) GetPreferredLanguages(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPreferredLanguages", reflect.TypeOf((*MockusersRepository)(nil).GetPreferredLanguages), ctx, userid)
+}
+
+// GetRefreshToken mocks base method.
+func (m *MockusersRepository) GetRefreshToken(ctx context.Context, tokenHash string) (model0.RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRefreshToken", ctx, tokenHash)
+	ret0, _ := ret[0].(model0.RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRefreshToken indicates an expected call of GetRefreshToken.
+func (mr *MockusersRepositoryMockRecorder) GetRefreshToken(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefreshToken", reflect.TypeOf((*MockusersRepository)(nil).GetRefreshToken), ctx, tokenHash)
+}
+
+// GetRole mocks base method.
+func (m *MockusersRepository) GetRole(ctx context.Context, userid types.UserId) (types.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRole", ctx, userid)
+	ret0, _ := ret[0].(types.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRole indicates an expected call of GetRole.
+func (mr *MockusersRepositoryMockRecorder) GetRole(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRole", reflect.TypeOf((*MockusersRepository)(nil).GetRole), ctx, userid)
+}
+
+// GetSensitiveMedia mocks base method.
+func (m *MockusersRepository) GetSensitiveMedia(ctx context.Context, userid types.UserId) (model0.SensitiveMedia, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSensitiveMedia", ctx, userid)
+	ret0, _ := ret[0].(model0.SensitiveMedia)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSensitiveMedia indicates an expected call of GetSensitiveMedia.
+func (mr *MockusersRepositoryMockRecorder) GetSensitiveMedia(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSensitiveMedia", reflect.TypeOf((*MockusersRepository)(nil).GetSensitiveMedia), ctx, userid)
+}
+
+// GetSession mocks base method.
+func (m *MockusersRepository) GetSession(ctx context.Context, sessionId string) (model0.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSession", ctx, sessionId)
+	ret0, _ := ret[0].(model0.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSession indicates an expected call of GetSession.
+func (mr *MockusersRepositoryMockRecorder) GetSession(ctx, sessionId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockusersRepository)(nil).GetSession), ctx, sessionId)
+}
+
+// GetSessions mocks base method.
+func (m *MockusersRepository) GetSessions(ctx context.Context, userid types.UserId) ([]model0.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userid)
+	ret0, _ := ret[0].([]model0.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockusersRepositoryMockRecorder) GetSessions(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockusersRepository)(nil).GetSessions), ctx, userid)
+}
+
+// GetTOTP mocks base method.
+func (m *MockusersRepository) GetTOTP(ctx context.Context, userid types.UserId) (model0.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", ctx, userid)
+	ret0, _ := ret[0].(model0.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP.
+func (mr *MockusersRepositoryMockRecorder) GetTOTP(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockusersRepository)(nil).GetTOTP), ctx, userid)
+}
+
+// GetUsers mocks base method.
+func (m *MockusersRepository) GetUsers(ctx context.Context, userIds ...types.UserId) ([]model0.Profile, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range userIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetUsers", varargs...)
+	ret0, _ := ret[0].([]model0.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsers indicates an expected call of GetUsers.
+func (mr *MockusersRepositoryMockRecorder) GetUsers(ctx interface{}, userIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, userIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsers", reflect.TypeOf((*MockusersRepository)(nil).GetUsers), varargs...)
+}
+
+// IsSuspended mocks base method.
+func (m *MockusersRepository) IsSuspended(ctx context.Context, userid types.UserId) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSuspended", ctx, userid)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSuspended indicates an expected call of IsSuspended.
+func (mr *MockusersRepositoryMockRecorder) IsSuspended(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSuspended", reflect.TypeOf((*MockusersRepository)(nil).IsSuspended), ctx, userid)
+}
+
+// Purge mocks base method.
+func (m *MockusersRepository) Purge(ctx context.Context, userid types.UserId, before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, userid, before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockusersRepositoryMockRecorder) Purge(ctx, userid, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockusersRepository)(nil).Purge), ctx, userid, before)
+}
+
+// PurgeNicknameHistory mocks base method.
+func (m *MockusersRepository) PurgeNicknameHistory(ctx context.Context, before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeNicknameHistory", ctx, before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeNicknameHistory indicates an expected call of PurgeNicknameHistory.
+func (mr *MockusersRepositoryMockRecorder) PurgeNicknameHistory(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeNicknameHistory", reflect.TypeOf((*MockusersRepository)(nil).PurgeNicknameHistory), ctx, before)
+}
+
+// PutPasswordReset mocks base method.
+func (m *MockusersRepository) PutPasswordReset(ctx context.Context, userid types.UserId, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutPasswordReset", ctx, userid, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutPasswordReset indicates an expected call of PutPasswordReset.
+func (mr *MockusersRepositoryMockRecorder) PutPasswordReset(ctx, userid, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutPasswordReset", reflect.TypeOf((*MockusersRepository)(nil).PutPasswordReset), ctx, userid, tokenHash, expiresAt)
+}
+
+// PutRefreshToken mocks base method.
+func (m *MockusersRepository) PutRefreshToken(ctx context.Context, userid types.UserId, tokenHash, family string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutRefreshToken", ctx, userid, tokenHash, family, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PutRefreshToken indicates an expected call of PutRefreshToken.
+func (mr *MockusersRepositoryMockRecorder) PutRefreshToken(ctx, userid, tokenHash, family, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutRefreshToken", reflect.TypeOf((*MockusersRepository)(nil).PutRefreshToken), ctx, userid, tokenHash, family, expiresAt)
+}
+
+// Reactivate mocks base method.
+func (m *MockusersRepository) Reactivate(ctx context.Context, userid types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reactivate", ctx, userid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reactivate indicates an expected call of Reactivate.
+func (mr *MockusersRepositoryMockRecorder) Reactivate(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reactivate", reflect.TypeOf((*MockusersRepository)(nil).Reactivate), ctx, userid)
+}
+
+// Register mocks base method.
+func (m *MockusersRepository) Register(ctx context.Context, nickname, canonical, firstname, lastname, email, password string) (types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Register", ctx, nickname, canonical, firstname, lastname, email, password)
+	ret0, _ := ret[0].(types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockusersRepositoryMockRecorder) Register(ctx, nickname, canonical, firstname, lastname, email, password interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockusersRepository)(nil).Register), ctx, nickname, canonical, firstname, lastname, email, password)
+}
+
+// ReplaceRecoveryCodes mocks base method.
+func (m *MockusersRepository) ReplaceRecoveryCodes(ctx context.Context, userid types.UserId, codeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceRecoveryCodes", ctx, userid, codeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceRecoveryCodes indicates an expected call of ReplaceRecoveryCodes.
+func (mr *MockusersRepositoryMockRecorder) ReplaceRecoveryCodes(ctx, userid, codeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRecoveryCodes", reflect.TypeOf((*MockusersRepository)(nil).ReplaceRecoveryCodes), ctx, userid, codeHashes)
+}
+
+// ResetPassword mocks base method.
+func (m *MockusersRepository) ResetPassword(ctx context.Context, userid types.UserId, password string, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, userid, password, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockusersRepositoryMockRecorder) ResetPassword(ctx, userid, password, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockusersRepository)(nil).ResetPassword), ctx, userid, password, revokedAt)
+}
+
+// RetrievePassword mocks base method.
+func (m *MockusersRepository) RetrievePassword(ctx context.Context, email string) (types.UserId, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrievePassword", ctx, email)
+	ret0, _ := ret[0].(types.UserId)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RetrievePassword indicates an expected call of RetrievePassword.
+func (mr *MockusersRepositoryMockRecorder) RetrievePassword(ctx, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrievePassword", reflect.TypeOf((*MockusersRepository)(nil).RetrievePassword), ctx, email)
+}
+
+// RevokeSessions mocks base method.
+func (m *MockusersRepository) RevokeSessions(ctx context.Context, userid types.UserId, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSessions", ctx, userid, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSessions indicates an expected call of RevokeSessions.
+func (mr *MockusersRepositoryMockRecorder) RevokeSessions(ctx, userid, revokedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSessions", reflect.TypeOf((*MockusersRepository)(nil).RevokeSessions), ctx, userid, revokedAt)
+}
+
+// SetEmailVerified mocks base method.
+func (m *MockusersRepository) SetEmailVerified(ctx context.Context, userid types.UserId, verified bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetEmailVerified", ctx, userid, verified)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetEmailVerified indicates an expected call of SetEmailVerified.
+func (mr *MockusersRepositoryMockRecorder) SetEmailVerified(ctx, userid, verified interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEmailVerified", reflect.TypeOf((*MockusersRepository)(nil).SetEmailVerified), ctx, userid, verified)
+}
+
+// SetLastSeen mocks base method.
+func (m *MockusersRepository) SetLastSeen(ctx context.Context, lastSeen map[string]time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLastSeen", ctx, lastSeen)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetLastSeen indicates an expected call of SetLastSeen.
+func (mr *MockusersRepositoryMockRecorder) SetLastSeen(ctx, lastSeen interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLastSeen", reflect.TypeOf((*MockusersRepository)(nil).SetLastSeen), ctx, lastSeen)
+}
+
+// SetPreferredLanguages mocks base method.
+func (m *MockusersRepository) SetPreferredLanguages(ctx context.Context, userid types.UserId, langs []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPreferredLanguages", ctx, userid, langs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetPreferredLanguages indicates an expected call of SetPreferredLanguages.
+func (mr *MockusersRepositoryMockRecorder) SetPreferredLanguages(ctx, userid, langs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPreferredLanguages", reflect.TypeOf((*MockusersRepository)(nil).SetPreferredLanguages), ctx, userid, langs)
+}
+
+// SetProtected mocks base method.
+func (m *MockusersRepository) SetProtected(ctx context.Context, userid types.UserId, protected bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetProtected", ctx, userid, protected)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetProtected indicates an expected call of SetProtected.
+func (mr *MockusersRepositoryMockRecorder) SetProtected(ctx, userid, protected interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProtected", reflect.TypeOf((*MockusersRepository)(nil).SetProtected), ctx, userid, protected)
+}
+
+// SetRole mocks base method.
+func (m *MockusersRepository) SetRole(ctx context.Context, userid types.UserId, role types.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRole", ctx, userid, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockusersRepositoryMockRecorder) SetRole(ctx, userid, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockusersRepository)(nil).SetRole), ctx, userid, role)
+}
+
+// SetSensitiveMedia mocks base method.
+func (m *MockusersRepository) SetSensitiveMedia(ctx context.Context, userid types.UserId, preference model0.SensitiveMedia) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSensitiveMedia", ctx, userid, preference)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetSensitiveMedia indicates an expected call of SetSensitiveMedia.
+func (mr *MockusersRepositoryMockRecorder) SetSensitiveMedia(ctx, userid, preference interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSensitiveMedia", reflect.TypeOf((*MockusersRepository)(nil).SetSensitiveMedia), ctx, userid, preference)
+}
+
+// SetTOTPSecret mocks base method.
+func (m *MockusersRepository) SetTOTPSecret(ctx context.Context, userid types.UserId, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTOTPSecret", ctx, userid, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTOTPSecret indicates an expected call of SetTOTPSecret.
+func (mr *MockusersRepositoryMockRecorder) SetTOTPSecret(ctx, userid, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTOTPSecret", reflect.TypeOf((*MockusersRepository)(nil).SetTOTPSecret), ctx, userid, secret)
+}
+
+// Suspend mocks base method.
+func (m *MockusersRepository) Suspend(ctx context.Context, userid types.UserId, suspendedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Suspend", ctx, userid, suspendedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Suspend indicates an expected call of Suspend.
+func (mr *MockusersRepositoryMockRecorder) Suspend(ctx, userid, suspendedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Suspend", reflect.TypeOf((*MockusersRepository)(nil).Suspend), ctx, userid, suspendedAt)
+}
+
+// Unsuspend mocks base method.
+func (m *MockusersRepository) Unsuspend(ctx context.Context, userid types.UserId) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unsuspend", ctx, userid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unsuspend indicates an expected call of Unsuspend.
+func (mr *MockusersRepositoryMockRecorder) Unsuspend(ctx, userid interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unsuspend", reflect.TypeOf((*MockusersRepository)(nil).Unsuspend), ctx, userid)
+}
+
+// Update mocks base method.
+func (m *MockusersRepository) Update(ctx context.Context, userid types.UserId, update model0.UserUpdate) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, userid, update)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockusersRepositoryMockRecorder) Update(ctx, userid, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockusersRepository)(nil).Update), ctx, userid, update)
+}
+
+// UpdateImage mocks base method.
+func (m *MockusersRepository) UpdateImage(ctx context.Context, userid types.UserId, kind model0.ImageKind, url string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateImage", ctx, userid, kind, url)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateImage indicates an expected call of UpdateImage.
+func (mr *MockusersRepositoryMockRecorder) UpdateImage(ctx, userid, kind, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImage", reflect.TypeOf((*MockusersRepository)(nil).UpdateImage), ctx, userid, kind, url)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockusersRepository) UpdateProfile(ctx context.Context, userid types.UserId, profile model0.ProfileUpdate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, userid, profile)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockusersRepositoryMockRecorder) UpdateProfile(ctx, userid, profile interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockusersRepository)(nil).UpdateProfile), ctx, userid, profile)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockusersRepository) UseRecoveryCode(ctx context.Context, userid types.UserId, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userid, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockusersRepositoryMockRecorder) UseRecoveryCode(ctx, userid, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockusersRepository)(nil).UseRecoveryCode), ctx, userid, codeHash)
+}
+
+// UseRefreshToken mocks base method.
+func (m *MockusersRepository) UseRefreshToken(ctx context.Context, tokenHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRefreshToken", ctx, tokenHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRefreshToken indicates an expected call of UseRefreshToken.
+func (mr *MockusersRepositoryMockRecorder) UseRefreshToken(ctx, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRefreshToken", reflect.TypeOf((*MockusersRepository)(nil).UseRefreshToken), ctx, tokenHash)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockusersRepository) UseTOTPStep(ctx context.Context, userid types.UserId, step int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", ctx, userid, step)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockusersRepositoryMockRecorder) UseTOTPStep(ctx, userid, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockusersRepository)(nil).UseTOTPStep), ctx, userid, step)
+}
+
+// MockfollowGateway is a mock of followGateway interface.
+type MockfollowGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MockfollowGatewayMockRecorder
+}
+
+// MockfollowGatewayMockRecorder is the mock recorder for MockfollowGateway.
+type MockfollowGatewayMockRecorder struct {
+	mock *MockfollowGateway
+}
+
+// NewMockfollowGateway creates a new mock instance.
+func NewMockfollowGateway(ctrl *gomock.Controller) *MockfollowGateway {
+	mock := &MockfollowGateway{ctrl: ctrl}
+	mock.recorder = &MockfollowGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockfollowGateway) EXPECT() *MockfollowGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetFollowerCounts mocks base method.
+func (m *MockfollowGateway) GetFollowerCounts(ctx context.Context, userIds ...types.UserId) (map[types.UserId]int, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range userIds {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "GetFollowerCounts", varargs...)
+	ret0, _ := ret[0].(map[types.UserId]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowerCounts indicates an expected call of GetFollowerCounts.
+func (mr *MockfollowGatewayMockRecorder) GetFollowerCounts(ctx interface{}, userIds ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, userIds...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowerCounts", reflect.TypeOf((*MockfollowGateway)(nil).GetFollowerCounts), varargs...)
+}
+
+// GetFollowers mocks base method.
+func (m *MockfollowGateway) GetFollowers(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowers", ctx, userId)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowers indicates an expected call of GetFollowers.
+func (mr *MockfollowGatewayMockRecorder) GetFollowers(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowers", reflect.TypeOf((*MockfollowGateway)(nil).GetFollowers), ctx, userId)
+}
+
+// GetFollowing mocks base method.
+func (m *MockfollowGateway) GetFollowing(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowing", ctx, userId)
+	ret0, _ := ret[0].([]types.UserId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowing indicates an expected call of GetFollowing.
+func (mr *MockfollowGatewayMockRecorder) GetFollowing(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowing", reflect.TypeOf((*MockfollowGateway)(nil).GetFollowing), ctx, userId)
+}
+
+// MocktweetsGateway is a mock of tweetsGateway interface.
+type MocktweetsGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MocktweetsGatewayMockRecorder
+}
+
+// MocktweetsGatewayMockRecorder is the mock recorder for MocktweetsGateway.
+type MocktweetsGatewayMockRecorder struct {
+	mock *MocktweetsGateway
+}
+
+// NewMocktweetsGateway creates a new mock instance.
+func NewMocktweetsGateway(ctrl *gomock.Controller) *MocktweetsGateway {
+	mock := &MocktweetsGateway{ctrl: ctrl}
+	mock.recorder = &MocktweetsGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocktweetsGateway) EXPECT() *MocktweetsGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetTweets mocks base method.
+func (m *MocktweetsGateway) GetTweets(ctx context.Context, userId types.UserId) ([]model.Media, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTweets", ctx, userId)
+	ret0, _ := ret[0].([]model.Media)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTweets indicates an expected call of GetTweets.
+func (mr *MocktweetsGatewayMockRecorder) GetTweets(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTweets", reflect.TypeOf((*MocktweetsGateway)(nil).GetTweets), ctx, userId)
+}
+
+// MocklikesGateway is a mock of likesGateway interface.
+type MocklikesGateway struct {
+	ctrl     *gomock.Controller
+	recorder *MocklikesGatewayMockRecorder
+}
+
+// MocklikesGatewayMockRecorder is the mock recorder for MocklikesGateway.
+type MocklikesGatewayMockRecorder struct {
+	mock *MocklikesGateway
+}
+
+// NewMocklikesGateway creates a new mock instance.
+func NewMocklikesGateway(ctrl *gomock.Controller) *MocklikesGateway {
+	mock := &MocklikesGateway{ctrl: ctrl}
+	mock.recorder = &MocklikesGatewayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MocklikesGateway) EXPECT() *MocklikesGatewayMockRecorder {
+	return m.recorder
+}
+
+// GetLikedTweets mocks base method.
+func (m *MocklikesGateway) GetLikedTweets(ctx context.Context, userId types.UserId) ([]types.TweetId, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLikedTweets", ctx, userId)
+	ret0, _ := ret[0].([]types.TweetId)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLikedTweets indicates an expected call of GetLikedTweets.
+func (mr *MocklikesGatewayMockRecorder) GetLikedTweets(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLikedTweets", reflect.TypeOf((*MocklikesGateway)(nil).GetLikedTweets), ctx, userId)
+}
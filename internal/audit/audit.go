@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"time"
+)
+
+// types of security events
+const (
+	LoginSucceeded = "login_succeeded"
+	LoginFailed    = "login_failed"
+	// attempt was rejected because email or ip is locked
+	LoginBlocked = "login_blocked"
+	// too many failures locked email or ip
+	LoginLocked = "login_locked"
+	// admin forgot failed logins, actor of the event is the admin
+	LockoutCleared = "lockout_cleared"
+	// device was logged out from the list of sessions
+	SessionRevoked = "session_revoked"
+	// account lifecycle
+	AccountDeactivated = "account_deactivated"
+	AccountReactivated = "account_reactivated"
+	AccountPurged      = "account_purged"
+	// archive of personal data
+	DataExportRequested  = "data_export_requested"
+	DataExportDownloaded = "data_export_downloaded"
+	// moderation, actor of the event is the moderator or admin
+	AccountSuspended     = "account_suspended"
+	AccountUnsuspended   = "account_unsuspended"
+	RoleChanged          = "role_changed"
+	TweetDeleted         = "tweet_deleted"
+	TweetReportResolved  = "tweet_report_resolved"
+	TweetMarkedSensitive = "tweet_marked_sensitive"
+	// personal access tokens of bots and scripts
+	AccessTokenCreated = "access_token_created"
+	AccessTokenRevoked = "access_token_revoked"
+)
+
+// Event is a security relevant action
+type Event struct {
+	Time time.Time `json:"time"`
+	Type string    `json:"type"`
+	// zero if user is unknown, e.g. login with unregistered email
+	UserId types.UserId `json:"user_id,omitempty"`
+	// user that performed the action if it is not the user of the event
+	ActorId types.UserId `json:"actor_id,omitempty"`
+	IP      string       `json:"ip,omitempty"`
+	Detail  string       `json:"detail,omitempty"`
+}
+
+// interface to record audit events
+type Recorder interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Filter of audit history, zero fields match every event
+type Filter struct {
+	// events of the user or performed by the user
+	UserId types.UserId
+	Type   string
+	Limit  int
+}
+
+// interface to read audit history
+type Reader interface {
+	// Events outputs events that match the filter, newest first
+	Events(ctx context.Context, filter Filter) ([]Event, error)
+}
+
+type multiRecorder []Recorder
+
+// Multi records events with every recorder, first error is returned
+func Multi(recorders ...Recorder) Recorder {
+	return multiRecorder(recorders)
+}
+
+func (m multiRecorder) Record(ctx context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	var firstErr error
+	for _, r := range m {
+		if err := r.Record(ctx, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,46 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileRecorder writes audit events to file or any writer as JSON lines
+type FileRecorder struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New appends events to the file
+func New(path string) (*FileRecorder, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRecorder{w: f}, nil
+}
+
+// NewWriter writes events to w, e.g. os.Stderr to log them
+func NewWriter(w io.Writer) *FileRecorder {
+	return &FileRecorder{w: w}
+}
+
+func (r *FileRecorder) Record(_ context.Context, event audit.Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = r.w.Write(append(line, '\n'))
+	return err
+}
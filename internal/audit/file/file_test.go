@@ -0,0 +1,27 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	"testing"
+)
+
+func TestFileRecorder_Record(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewWriter(&buf)
+
+	event := audit.Event{Type: audit.LoginFailed, UserId: 1, IP: "127.0.0.1"}
+	if err := recorder.Record(context.Background(), event); err != nil {
+		t.Fatalf("error was not expected while recording event: %s", err)
+	}
+
+	var got audit.Event
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("event should be written as json line: %s", err)
+	}
+	if got.Type != event.Type || got.UserId != event.UserId || got.IP != event.IP || got.Time.IsZero() {
+		t.Errorf("wrong event: %+v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// number of events by default and at most
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// HistoryHandler serves audit history filtered by user_id, type and limit query parameters,
+// it should be wrapped by role check
+func HistoryHandler(reader Reader) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet {
+				http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+				return
+			}
+
+			query := req.URL.Query()
+			filter := Filter{Type: query.Get("type"), Limit: defaultLimit}
+			if userId := query.Get("user_id"); userId != "" {
+				id, err := strconv.Atoi(userId)
+				if err != nil {
+					http.Error(w, "invalid user_id", http.StatusBadRequest)
+					return
+				}
+				filter.UserId = types.UserId(id)
+			}
+			if limit := query.Get("limit"); limit != "" {
+				n, err := strconv.Atoi(limit)
+				if err != nil || n <= 0 {
+					http.Error(w, "invalid limit", http.StatusBadRequest)
+					return
+				}
+				if n > maxLimit {
+					n = maxLimit
+				}
+				filter.Limit = n
+			}
+
+			events, err := reader.Events(req.Context(), filter)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if events == nil {
+				events = []Event{}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if err = json.NewEncoder(w).Encode(events); err != nil {
+				http.Error(w, "failed to encode events", http.StatusInternalServerError)
+			}
+		},
+	)
+}
@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	_ "github.com/go-sql-driver/mysql"
+	"strings"
+	"time"
+)
+
+// time layout
+const layout = "2006-01-02 15:04:05"
+
+// Log stores audit events in the database, so history of every service can be read in one place
+type Log struct {
+	db *sql.DB
+}
+
+func New(driverName string, dataSourceName string) (*Log, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+	return &Log{db}, nil
+}
+
+func (l *Log) Record(ctx context.Context, event audit.Event) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	_, err := l.db.ExecContext(
+		ctx,
+		"INSERT INTO AuditEvents (created_at, type, user_id, actor_id, ip, detail) VALUES (?, ?, ?, ?, ?, ?)",
+		event.Time.UTC().Format(layout), event.Type, event.UserId, event.ActorId, event.IP, event.Detail,
+	)
+	return err
+}
+
+// Events outputs events that match the filter, newest first
+func (l *Log) Events(ctx context.Context, filter audit.Filter) ([]audit.Event, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if filter.UserId != 0 {
+		conditions = append(conditions, "(user_id = ? OR actor_id = ?)")
+		args = append(args, filter.UserId, filter.UserId)
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, filter.Type)
+	}
+	query := "SELECT created_at, type, user_id, actor_id, ip, detail FROM AuditEvents"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY event_id DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []audit.Event
+	for rows.Next() {
+		var (
+			event     audit.Event
+			createdAt string
+		)
+		if err := rows.Scan(&createdAt, &event.Type, &event.UserId, &event.ActorId, &event.IP, &event.Detail); err != nil {
+			return nil, err
+		}
+		if event.Time, err = time.Parse(layout, createdAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
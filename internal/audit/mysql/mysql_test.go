@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	auditLog := Log{db}
+	ctx := context.Background()
+	event := audit.Event{
+		Time:    time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+		Type:    audit.AccountSuspended,
+		UserId:  1,
+		ActorId: 2,
+		Detail:  "spam",
+	}
+
+	mock.ExpectExec("INSERT INTO AuditEvents").
+		WithArgs("2023-01-02 03:04:05", event.Type, event.UserId, event.ActorId, "", event.Detail).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if err = auditLog.Record(ctx, event); err != nil {
+		t.Errorf("error was not expected while recording event: %s", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"created_at", "type", "user_id", "actor_id", "ip", "detail"}).
+		AddRow("2023-01-02 03:04:05", event.Type, 1, 2, "", "spam")
+	mock.ExpectQuery(
+		"SELECT created_at, type, user_id, actor_id, ip, detail FROM AuditEvents "+
+			"WHERE \\(user_id = \\? OR actor_id = \\?\\) AND type = \\? ORDER BY event_id DESC LIMIT 10",
+	).
+		WithArgs(1, 1, event.Type).
+		WillReturnRows(rows)
+	events, err := auditLog.Events(ctx, audit.Filter{UserId: 1, Type: event.Type, Limit: 10})
+	if err != nil {
+		t.Errorf("error was not expected while reading events: %s", err)
+	}
+	if diff := cmp.Diff([]audit.Event{event}, events); diff != "" {
+		t.Errorf("wrong events (-want +got):\n%s", diff)
+	}
+
+	mock.ExpectQuery("SELECT created_at, type, user_id, actor_id, ip, detail FROM AuditEvents ORDER BY event_id DESC$").
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "type", "user_id", "actor_id", "ip", "detail"}))
+	if _, err = auditLog.Events(ctx, audit.Filter{}); err != nil {
+		t.Errorf("error was not expected while reading events: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"strings"
+	"time"
+)
+
+// ErrMissingToken is returned when request has no access token
+var ErrMissingToken = errors.New("authorization token is missing")
+
+// SessionStore checks sessions of access tokens shared by services
+type SessionStore interface {
+	// CheckSession returns jwt.ErrTokenRevoked if token issued at the moment was revoked by password reset,
+	// logout from all devices, suspension or change of role, or if its session was revoked
+	CheckSession(ctx context.Context, userId types.UserId, sessionId string, issuedAt time.Time) error
+}
+
+// sessions aren't checked until store is set
+var sessionStore SessionStore
+
+// SetSessionStore checks sessions of access tokens in the store
+func SetSessionStore(store SessionStore) {
+	sessionStore = store
+}
+
+// Principal is the user authenticated by access token
+type Principal struct {
+	UserId types.UserId
+	// validated claims, used to revoke the token. Nil for personal access tokens
+	Claims *jwt.Claims
+	// scopes of personal access token, nil for session tokens that have every scope
+	Scopes []types.Scope
+	// raw token forwarded to other services
+	Token string
+}
+
+type ctxKey struct{ name string }
+
+var principalCtxKey = &ctxKey{"principal"}
+
+// NewContext returns context with authenticated principal
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey, principal)
+}
+
+// FromContext returns principal put by Middleware or interceptors
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalCtxKey).(*Principal)
+	return principal, ok
+}
+
+// UserId returns id of the authenticated user
+func UserId(ctx context.Context) (types.UserId, bool) {
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return principal.UserId, true
+}
+
+// HasRole reports whether the authenticated user has privileges of the role
+func HasRole(ctx context.Context, role types.Role) bool {
+	principal, ok := FromContext(ctx)
+	return ok && principal.Claims != nil && principal.Claims.Role.Allows(role)
+}
+
+// EmailVerified reports whether the user verified email, personal access tokens
+// are issued only to verified users
+func (p *Principal) EmailVerified() bool {
+	return p.Claims == nil || p.Claims.EmailVerified
+}
+
+// Authenticate validates token, it may start with "Bearer "
+func Authenticate(ctx context.Context, token string) (*Principal, error) {
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+	if IsPersonalToken(token) {
+		return authenticatePersonal(ctx, token)
+	}
+	claims, err := jwt.ParseToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if sessionStore != nil {
+		err = sessionStore.CheckSession(ctx, claims.UserId, claims.SessionId, time.Unix(claims.IssuedAt, 0))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Principal{UserId: claims.UserId, Claims: claims, Token: token}, nil
+}
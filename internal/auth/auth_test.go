@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"context"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	ks, err := jwt.GenerateKeySet()
+	if err != nil {
+		panic(err)
+	}
+	jwt.SetKeySet(ks)
+	os.Exit(m.Run())
+}
+
+func TestMiddleware(t *testing.T) {
+	token, err := jwt.GenerateJWT(types.UserId(1), "", types.RoleUser, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Middleware(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, req *http.Request) {
+				userId, ok := RequireUser(w, req)
+				if !ok || userId != 1 {
+					t.Errorf("principal should be in request context, got %d", userId)
+				}
+			},
+		),
+	)
+	serve := func(setup func(req *http.Request)) int {
+		// user_id of the request is not trusted
+		req := httptest.NewRequest("POST", "/update?user_id=2", nil)
+		setup(req)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := serve(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }); code != http.StatusOK {
+		t.Errorf("token from header should be accepted, got status %d", code)
+	}
+	if code := serve(func(req *http.Request) { req.AddCookie(&http.Cookie{Name: jwt.CookieName, Value: token}) }); code != http.StatusOK {
+		t.Errorf("token from cookie should be accepted, got status %d", code)
+	}
+	if code := serve(func(req *http.Request) {}); code != http.StatusUnauthorized {
+		t.Errorf("request without token should be rejected, got status %d", code)
+	}
+	if code := serve(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token+"x") }); code != http.StatusUnauthorized {
+		t.Errorf("invalid token should be rejected, got status %d", code)
+	}
+
+	principal, err := Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = jwt.Revoke(context.Background(), principal.Claims); err != nil {
+		t.Fatal(err)
+	}
+	if code := serve(func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }); code != http.StatusUnauthorized {
+		t.Errorf("revoked token should be rejected, got status %d", code)
+	}
+}
+
+func TestOptionalMiddleware(t *testing.T) {
+	token, err := jwt.GenerateJWT(types.UserId(1), "", types.RoleUser, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		userId types.UserId
+		ok     bool
+	)
+	handler := OptionalMiddleware(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, req *http.Request) {
+				userId, ok = UserId(req.Context())
+			},
+		),
+	)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !ok || userId != 1 {
+		t.Errorf("principal should be in request context, got status %d user %d", rr.Code, userId)
+	}
+
+	// token is forwarded to the next service
+	principal, _ := Authenticate(context.Background(), token)
+	outgoing := httptest.NewRequest("GET", "/", nil).WithContext(NewContext(context.Background(), principal))
+	ForwardToken(outgoing)
+	if got := outgoing.Header.Get("Authorization"); got != "Bearer "+token {
+		t.Errorf("token should be forwarded, got %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusOK || ok {
+		t.Errorf("request without token should be anonymous, got status %d", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token+"x")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("invalid token should be rejected, got status %d", rr.Code)
+	}
+}
+
+func TestRequireUser(t *testing.T) {
+	rr := httptest.NewRecorder()
+	if _, ok := RequireUser(rr, httptest.NewRequest("GET", "/", nil)); ok || rr.Code != http.StatusUnauthorized {
+		t.Errorf("request without principal should be rejected, got status %d", rr.Code)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := Middleware(RequireRole(types.RoleModerator, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+	serve := func(role types.Role) int {
+		token, err := jwt.GenerateJWT(types.UserId(1), "", role, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	for role, want := range map[types.Role]int{
+		"":                  http.StatusForbidden,
+		types.RoleUser:      http.StatusForbidden,
+		types.RoleModerator: http.StatusOK,
+		types.RoleAdmin:     http.StatusOK,
+	} {
+		if code := serve(role); code != want {
+			t.Errorf("role %q: got status %d want %d", role, code, want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	RequireRole(types.RoleUser, handler).ServeHTTP(rr, httptest.NewRequest("GET", "/admin", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("request without principal should be rejected, got status %d", rr.Code)
+	}
+}
+
+func TestRequireVerified(t *testing.T) {
+	handler := Middleware(RequireVerified(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+	for verified, want := range map[bool]int{false: http.StatusForbidden, true: http.StatusOK} {
+		token, err := jwt.GenerateJWT(types.UserId(1), "", types.RoleUser, verified)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("POST", "/post_tweet", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("verified %v: got status %d want %d", verified, rr.Code, want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	RequireVerified(handler).ServeHTTP(rr, httptest.NewRequest("POST", "/post_tweet", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("request without principal should be rejected, got status %d", rr.Code)
+	}
+}
+
+type fakeTokenStore map[string][]types.Scope
+
+func (s fakeTokenStore) LookupToken(_ context.Context, tokenHash string) (types.UserId, []types.Scope, error) {
+	scopes, ok := s[tokenHash]
+	if !ok {
+		return 0, nil, jwt.ErrInvalidToken
+	}
+	return types.UserId(1), scopes, nil
+}
+
+func TestPersonalToken(t *testing.T) {
+	SetTokenStore(fakeTokenStore{HashPersonalToken("pat_writer"): {types.ScopeRead, types.ScopeTweetsWrite}})
+	defer SetTokenStore(nil)
+
+	next := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if userId, ok := UserId(req.Context()); !ok || userId != 1 {
+				t.Errorf("principal should be in request context, got %d", userId)
+			}
+		},
+	)
+	serve := func(handler http.Handler, token string) int {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	for _, tc := range []struct {
+		name    string
+		handler http.Handler
+		token   string
+		want    int
+	}{
+		{"allowed scope", AllowScope(types.ScopeTweetsWrite, Middleware(next)), "pat_writer", http.StatusOK},
+		{"optional", AllowScope(types.ScopeRead, OptionalMiddleware(next)), "pat_writer", http.StatusOK},
+		{"missing scope", AllowScope(types.ScopeLikesWrite, Middleware(next)), "pat_writer", http.StatusForbidden},
+		{"route without scope", Middleware(next), "pat_writer", http.StatusForbidden},
+		{"unknown token", AllowScope(types.ScopeTweetsWrite, Middleware(next)), "pat_unknown", http.StatusUnauthorized},
+	} {
+		if code := serve(tc.handler, tc.token); code != tc.want {
+			t.Errorf("%s: got status %d want %d", tc.name, code, tc.want)
+		}
+	}
+
+	// session tokens have every scope
+	token, err := jwt.GenerateJWT(types.UserId(1), "", types.RoleUser, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := serve(AllowScope(types.ScopeLikesWrite, Middleware(next)), token); code != http.StatusOK {
+		t.Errorf("session token should be accepted, got status %d", code)
+	}
+}
+
+// sessions revoked in the database shared by services
+type fakeSessionStore map[string]bool
+
+func (s fakeSessionStore) CheckSession(_ context.Context, _ types.UserId, sessionId string, _ time.Time) error {
+	if s[sessionId] {
+		return jwt.ErrTokenRevoked
+	}
+	return nil
+}
+
+func TestSessionStore(t *testing.T) {
+	SetSessionStore(fakeSessionStore{"revoked": true})
+	defer SetSessionStore(nil)
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	for _, tc := range []struct {
+		session string
+		want    int
+	}{
+		{"active", http.StatusOK},
+		{"revoked", http.StatusUnauthorized},
+	} {
+		token, err := jwt.GenerateJWT(types.UserId(1), tc.session, types.RoleUser, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tc.want {
+			t.Errorf("%s session: got status %d want %d", tc.session, rr.Code, tc.want)
+		}
+	}
+}
+
+func TestGRPCInterceptors(t *testing.T) {
+	token, err := jwt.GenerateJWT(types.UserId(1), "", types.RoleUser, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, err := Authenticate(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// client forwards token of the principal as metadata
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	ctx := NewContext(context.Background(), principal)
+	if err = UnaryClientInterceptor()(ctx, "/test", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+
+	server := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/tweets.TweetsService/Retrieve"}
+	handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+		userId, _ := UserId(ctx)
+		return userId, nil
+	}
+	res, err := server(metadata.NewIncomingContext(context.Background(), outgoing), nil, info, handler)
+	if err != nil {
+		t.Fatalf("forwarded token should be accepted: %s", err)
+	}
+	if res != types.UserId(1) {
+		t.Errorf("principal should be in context, got %v", res)
+	}
+
+	_, err = server(context.Background(), nil, info, handler)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("call without token should be rejected, got %v", err)
+	}
+
+	// public methods are served anonymously, but invalid token is still rejected
+	public := UnaryServerInterceptor(info.FullMethod)
+	res, err = public(context.Background(), nil, info, handler)
+	if err != nil {
+		t.Errorf("public method should not require token: %s", err)
+	}
+	if res != types.UserId(0) {
+		t.Errorf("anonymous call should have no principal, got %v", res)
+	}
+	invalid := metadata.NewIncomingContext(context.Background(), metadata.Pairs(metadataKey, "Bearer invalid"))
+	if _, err = public(invalid, nil, info, handler); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("public method with invalid token should be rejected, got %v", err)
+	}
+
+	// reflection doesn't need token
+	reflection := &grpc.UnaryServerInfo{FullMethod: "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"}
+	if _, err = server(context.Background(), nil, reflection, handler); err != nil {
+		t.Errorf("reflection should not require token: %s", err)
+	}
+}
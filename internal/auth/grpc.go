@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// metadata key with access token, same as http header
+const metadataKey = "authorization"
+
+// reflection is used by tools like grpcurl and doesn't expose user data
+const reflectionPrefix = "/grpc.reflection."
+
+// methods are read lookups that services make on behalf of the user, so personal access
+// tokens of any scope are accepted, their scopes are checked by http routes.
+// Public methods are served without principal when token is missing, like OptionalMiddleware
+func authenticateGRPC(ctx context.Context, public bool) (context.Context, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(metadataKey); len(values) > 0 {
+			token = values[0]
+		}
+	}
+
+	principal, err := Authenticate(ctx, token)
+	switch {
+	case err == nil:
+		return NewContext(ctx, principal), nil
+	case public && errors.Is(err, ErrMissingToken):
+		return ctx, nil
+	case errors.Is(err, ErrMissingToken), errors.Is(err, jwt.ErrInvalidToken),
+		errors.Is(err, jwt.ErrTokenExpired), errors.Is(err, jwt.ErrTokenRevoked):
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+}
+
+// set of full method names
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		set[method] = true
+	}
+	return set
+}
+
+// UnaryServerInterceptor rejects calls without valid access token and puts principal to context,
+// public methods can be called anonymously, e.g. lookups of public profiles for anonymous readers
+func UnaryServerInterceptor(public ...string) grpc.UnaryServerInterceptor {
+	publicSet := methodSet(public)
+	return func(
+		ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, reflectionPrefix) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticateGRPC(ctx, publicSet[info.FullMethod])
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// stream with authenticated context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming calls
+func StreamServerInterceptor(public ...string) grpc.StreamServerInterceptor {
+	publicSet := methodSet(public)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if strings.HasPrefix(info.FullMethod, reflectionPrefix) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticateGRPC(ss.Context(), publicSet[info.FullMethod])
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ss, ctx})
+	}
+}
+
+// forward token of the principal, services call each other on behalf of the user
+func outgoingContext(ctx context.Context) context.Context {
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, metadataKey, "Bearer "+principal.Token)
+}
+
+// UnaryClientInterceptor forwards access token of the principal from context
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor is UnaryClientInterceptor for streaming calls
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(
+		ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+		method string, streamer grpc.Streamer, opts ...grpc.CallOption,
+	) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+// DialOptions forward access token on every call of the connection
+func DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor()),
+	}
+}
+
+// ServerOptions authenticate every call to the server except anonymous calls of public methods
+func ServerOptions(public ...string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(UnaryServerInterceptor(public...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(public...)),
+	}
+}
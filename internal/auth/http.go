@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"errors"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"net/http"
+)
+
+// TokenFromRequest returns token from Authorization header, browsers send it in HttpOnly cookie
+func TokenFromRequest(req *http.Request) string {
+	if token := req.Header.Get("Authorization"); token != "" {
+		return token
+	}
+	if cookie, err := req.Cookie(jwt.CookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Middleware rejects requests without valid access token and puts principal to request context
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			principal, err := Authenticate(req.Context(), TokenFromRequest(req))
+			if err == nil {
+				err = checkScope(req.Context(), principal)
+			}
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), principal)))
+		},
+	)
+}
+
+// OptionalMiddleware puts principal to request context if request has a token,
+// requests without token are served anonymously but invalid tokens are still rejected
+func OptionalMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			token := TokenFromRequest(req)
+			if token == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			principal, err := Authenticate(req.Context(), token)
+			if err == nil {
+				err = checkScope(req.Context(), principal)
+			}
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), principal)))
+		},
+	)
+}
+
+// ForwardToken sets access token of the principal from request context to outgoing request
+func ForwardToken(req *http.Request) {
+	if principal, ok := FromContext(req.Context()); ok {
+		req.Header.Set("Authorization", "Bearer "+principal.Token)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Authorization header is missing", http.StatusUnauthorized)
+	case errors.Is(err, jwt.ErrTokenExpired):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Token is expired", http.StatusUnauthorized)
+	case errors.Is(err, jwt.ErrTokenRevoked):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Token was revoked", http.StatusUnauthorized)
+	case errors.Is(err, ErrInsufficientScope):
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, jwt.ErrInvalidToken):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	default:
+		http.Error(w, "failed to check token", http.StatusInternalServerError)
+	}
+}
+
+// RequireUser returns authenticated user, request is rejected if handler is not wrapped by Middleware
+func RequireUser(w http.ResponseWriter, req *http.Request) (types.UserId, bool) {
+	userId, ok := UserId(req.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}
+	return userId, ok
+}
+
+// RequireRole rejects requests of users without privileges of the role,
+// it should be wrapped by Middleware
+func RequireRole(role types.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if _, ok := RequireUser(w, req); !ok {
+				return
+			}
+			if !HasRole(req.Context(), role) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, req)
+		},
+	)
+}
+
+// RequireVerified rejects requests of users that didn't verify email,
+// it should be wrapped by Middleware
+func RequireVerified(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if _, ok := RequireUser(w, req); !ok {
+				return
+			}
+			if principal, _ := FromContext(req.Context()); !principal.EmailVerified() {
+				http.Error(w, "Email is not verified", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, req)
+		},
+	)
+}
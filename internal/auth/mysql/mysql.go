@@ -0,0 +1,173 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	_ "github.com/go-sql-driver/mysql"
+	"strings"
+	"sync"
+	"time"
+)
+
+// time layout
+const layout = "2006-01-02 15:04:05"
+
+// last use of the token is saved at most once per interval, so requests don't write to the database
+const touchInterval = time.Minute
+
+// Store looks up personal access tokens in the database shared by services
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+
+	mu sync.Mutex
+	// when last use of the token was saved
+	touched map[int]time.Time
+}
+
+func New(driverName string, dataSourceName string) (*Store, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+	return newStore(db), nil
+}
+
+func newStore(db *sql.DB) *Store {
+	return &Store{db: db, now: time.Now, touched: make(map[int]time.Time)}
+}
+
+// LookupToken outputs owner and scopes of the token, tokens of deactivated
+// and suspended users are rejected
+func (s *Store) LookupToken(ctx context.Context, tokenHash string) (types.UserId, []types.Scope, error) {
+	var (
+		tokenId   int
+		userId    types.UserId
+		scopes    string
+		expiresAt string
+	)
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT t.token_id, t.user_id, t.scopes, t.expires_at FROM AccessTokens t JOIN User u ON u.user_id = t.user_id "+
+			"WHERE t.token_hash = ? AND u.deactivated_at IS NULL AND u.suspended_at IS NULL",
+		tokenHash,
+	)
+	err := row.Scan(&tokenId, &userId, &scopes, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, jwt.ErrInvalidToken
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	expires, err := time.Parse(layout, expiresAt)
+	if err != nil {
+		return 0, nil, err
+	}
+	now := s.now().UTC()
+	if !now.Before(expires) {
+		return 0, nil, jwt.ErrTokenExpired
+	}
+	if err = s.touch(ctx, tokenId, now); err != nil {
+		return 0, nil, err
+	}
+
+	result := []types.Scope{}
+	for _, scope := range strings.Fields(scopes) {
+		result = append(result, types.Scope(scope))
+	}
+	return userId, result, nil
+}
+
+// save last use of the token unless it was saved recently
+func (s *Store) touch(ctx context.Context, tokenId int, now time.Time) error {
+	s.mu.Lock()
+	if last, ok := s.touched[tokenId]; ok && now.Sub(last) < touchInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	// forget old entries from time to time to keep memory bounded
+	if len(s.touched) > 10000 {
+		for id, last := range s.touched {
+			if now.Sub(last) >= touchInterval {
+				delete(s.touched, id)
+			}
+		}
+	}
+	s.touched[tokenId] = now
+	s.mu.Unlock()
+
+	_, err := s.db.ExecContext(
+		ctx, "UPDATE AccessTokens SET last_used_at = ? WHERE token_id = ?", now.Format(layout), tokenId,
+	)
+	return err
+}
+
+// CheckSession rejects tokens issued before sessions of the user were revoked, tokens of
+// suspended and missing users and tokens of revoked sessions, implements auth.SessionStore
+func (s *Store) CheckSession(ctx context.Context, userId types.UserId, sessionId string, issuedAt time.Time) error {
+	var (
+		revokedAt, suspendedAt sql.NullString
+		sessionUser            sql.NullInt64
+	)
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT u.sessions_revoked_at, u.suspended_at, s.user_id FROM User u "+
+			"LEFT JOIN Sessions s ON s.session_id = ? AND s.user_id = u.user_id WHERE u.user_id = ?",
+		sessionId, userId,
+	)
+	err := row.Scan(&revokedAt, &suspendedAt, &sessionUser)
+	if errors.Is(err, sql.ErrNoRows) {
+		return jwt.ErrTokenRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if suspendedAt.Valid {
+		return jwt.ErrTokenRevoked
+	}
+	if revokedAt.Valid {
+		revoked, err := time.Parse(layout, revokedAt.String)
+		if err != nil {
+			return err
+		}
+		if issuedAt.Before(revoked) {
+			return jwt.ErrTokenRevoked
+		}
+	}
+	// tokens issued before sessions were tracked have no session
+	if sessionId != "" && !sessionUser.Valid {
+		return jwt.ErrTokenRevoked
+	}
+	return nil
+}
+
+// Revoke adds access token to the denylist, implements jwt.Denylist
+func (s *Store) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	now := s.now().UTC().Format(layout)
+	// expired tokens are rejected anyway
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM RevokedTokens WHERE expires_at < ?", now); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(
+		ctx, "INSERT IGNORE INTO RevokedTokens (jti, expires_at) VALUES (?, ?)",
+		jti, expiresAt.UTC().Format(layout),
+	)
+	return err
+}
+
+// IsRevoked checks the denylist, implements jwt.Denylist
+func (s *Store) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int
+	row := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM RevokedTokens WHERE jti = ?", jti)
+	err := row.Scan(&count)
+	return count > 0, err
+}
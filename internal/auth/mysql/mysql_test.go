@@ -0,0 +1,133 @@
+package mysql
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func TestStore_LookupToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	store := newStore(db)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	columns := []string{"token_id", "user_id", "scopes", "expires_at"}
+	query := "SELECT t.token_id, t.user_id, t.scopes, t.expires_at FROM AccessTokens t JOIN User u .* " +
+		"WHERE t.token_hash = \\? AND u.deactivated_at IS NULL AND u.suspended_at IS NULL"
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).AddRow(1, 2, "read tweets:write", "2023-02-01 00:00:00")
+	}
+	mock.ExpectQuery(query).WithArgs("hash").WillReturnRows(rows())
+	mock.ExpectExec("UPDATE AccessTokens SET last_used_at = \\? WHERE token_id = \\?").
+		WithArgs("2023-01-02 03:04:05", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// use within a minute isn't saved again
+	mock.ExpectQuery(query).WithArgs("hash").WillReturnRows(rows())
+	mock.ExpectQuery(query).
+		WithArgs("expired").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, 2, "read", "2023-01-01 00:00:00"))
+	mock.ExpectQuery(query).
+		WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	for i := 0; i < 2; i++ {
+		userId, scopes, err := store.LookupToken(ctx, "hash")
+		if err != nil {
+			t.Fatalf("error was not expected while looking up token: %s", err)
+		}
+		if diff := cmp.Diff([]types.Scope{types.ScopeRead, types.ScopeTweetsWrite}, scopes); userId != 2 || diff != "" {
+			t.Errorf("wrong token of user %d, scopes (-want +got):\n%s", userId, diff)
+		}
+	}
+	if _, _, err = store.LookupToken(ctx, "expired"); err != jwt.ErrTokenExpired {
+		t.Errorf("expected ErrTokenExpired, got: %v", err)
+	}
+	if _, _, err = store.LookupToken(ctx, "unknown"); err != jwt.ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStore_CheckSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	store := newStore(db)
+	ctx := context.Background()
+	issuedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	columns := []string{"sessions_revoked_at", "suspended_at", "user_id"}
+	query := "SELECT u.sessions_revoked_at, u.suspended_at, s.user_id FROM User u " +
+		"LEFT JOIN Sessions s ON s.session_id = \\? AND s.user_id = u.user_id WHERE u.user_id = \\?"
+
+	testCases := []struct {
+		name      string
+		sessionId string
+		rows      *sqlmock.Rows
+		want      error
+	}{
+		{
+			name:      "active",
+			sessionId: "session",
+			rows:      sqlmock.NewRows(columns).AddRow("2023-01-01 00:00:00", nil, 1),
+		},
+		{
+			name: "without session",
+			rows: sqlmock.NewRows(columns).AddRow(nil, nil, nil),
+		},
+		{
+			name:      "logout from all devices",
+			sessionId: "session",
+			rows:      sqlmock.NewRows(columns).AddRow("2023-01-02 03:04:06", nil, 1),
+			want:      jwt.ErrTokenRevoked,
+		},
+		{
+			name:      "suspended",
+			sessionId: "session",
+			rows:      sqlmock.NewRows(columns).AddRow(nil, "2023-01-02 03:04:06", 1),
+			want:      jwt.ErrTokenRevoked,
+		},
+		{
+			name:      "revoked session",
+			sessionId: "session",
+			rows:      sqlmock.NewRows(columns).AddRow(nil, nil, nil),
+			want:      jwt.ErrTokenRevoked,
+		},
+		{
+			name:      "missing user",
+			sessionId: "session",
+			rows:      sqlmock.NewRows(columns),
+			want:      jwt.ErrTokenRevoked,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				mock.ExpectQuery(query).WithArgs(tc.sessionId, 1).WillReturnRows(tc.rows)
+				if err := store.CheckSession(ctx, types.UserId(1), tc.sessionId, issuedAt); err != tc.want {
+					t.Errorf("expected %v, got: %v", tc.want, err)
+				}
+			},
+		)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
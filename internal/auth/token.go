@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"net/http"
+	"strings"
+)
+
+// PersonalTokenPrefix tells personal access tokens from JWTs
+const PersonalTokenPrefix = "pat_"
+
+// ErrInsufficientScope is returned when personal access token doesn't have scope of the route
+var ErrInsufficientScope = errors.New("token doesn't have the required scope")
+
+// TokenStore looks up personal access tokens by hash
+type TokenStore interface {
+	// LookupToken outputs owner and scopes of the token, jwt.ErrInvalidToken is returned
+	// if token is unknown or its owner can't log in, jwt.ErrTokenExpired if it is expired
+	LookupToken(ctx context.Context, tokenHash string) (types.UserId, []types.Scope, error)
+}
+
+// personal access tokens are rejected until store is set
+var tokenStore TokenStore
+
+// SetTokenStore accepts personal access tokens from the store
+func SetTokenStore(store TokenStore) {
+	tokenStore = store
+}
+
+// HashPersonalToken returns hash the token is stored by
+func HashPersonalToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+var scopeCtxKey = &ctxKey{"scope"}
+
+// AllowScope lets personal access tokens with the scope use the route, it should wrap
+// Middleware or OptionalMiddleware. Routes without scope reject personal access tokens
+func AllowScope(scope types.Scope, next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), scopeCtxKey, scope)))
+		},
+	)
+}
+
+// check that personal access token has scope allowed by the route
+func checkScope(ctx context.Context, principal *Principal) error {
+	if !principal.Personal() {
+		return nil
+	}
+	scope, ok := ctx.Value(scopeCtxKey).(types.Scope)
+	if !ok || !principal.HasScope(scope) {
+		return ErrInsufficientScope
+	}
+	return nil
+}
+
+// authenticate personal access token
+func authenticatePersonal(ctx context.Context, token string) (*Principal, error) {
+	if tokenStore == nil {
+		return nil, jwt.ErrInvalidToken
+	}
+	userId, scopes, err := tokenStore.LookupToken(ctx, HashPersonalToken(token))
+	if err != nil {
+		return nil, err
+	}
+	// non-nil scopes mark personal token
+	if scopes == nil {
+		scopes = []types.Scope{}
+	}
+	return &Principal{UserId: userId, Token: token, Scopes: scopes}, nil
+}
+
+// Personal reports whether principal is authenticated by personal access token
+func (p *Principal) Personal() bool {
+	return p.Scopes != nil
+}
+
+// HasScope reports whether principal can act with the scope, session tokens have every scope
+func (p *Principal) HasScope(scope types.Scope) bool {
+	if !p.Personal() {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPersonalToken reports whether token looks like personal access token
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
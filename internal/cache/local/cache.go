@@ -13,9 +13,10 @@ import (
 type LRUCache struct {
 	capacity int
 	cache    map[string]*list.Element
-	trie     *Trie
-	items    *list.List
-	mutex    sync.Mutex
+	// user_id_tweet_id keys, used to get tweets of the user
+	trie  *Trie[string]
+	items *list.List
+	mutex sync.Mutex
 }
 
 type pair struct {
@@ -46,7 +47,7 @@ func New(capacity int) *LRUCache {
 		capacity: capacity,
 		cache:    make(map[string]*list.Element),
 		items:    list.New(),
-		trie:     NewTrie(),
+		trie:     NewTrie[string](),
 		mutex:    sync.Mutex{},
 	}
 }
@@ -55,7 +56,11 @@ func New(capacity int) *LRUCache {
 func (lru *LRUCache) Get(key string) (bool, []byte) {
 	lru.mutex.Lock()
 	defer lru.mutex.Unlock()
+	return lru.get(key)
+}
 
+// get value without lock
+func (lru *LRUCache) get(key string) (bool, []byte) {
 	if element, ok := lru.cache[key]; ok {
 		lru.items.MoveToFront(element)
 		return true, element.Value.(*pair).value
@@ -63,15 +68,21 @@ func (lru *LRUCache) Get(key string) (bool, []byte) {
 	return false, []byte{}
 }
 
+// remove key from the trie if it is user_id_tweet_id
+func (lru *LRUCache) untrack(key string) {
+	if err, _, _ := splitKey(key); err == nil {
+		lru.trie.Delete(key, key)
+	}
+}
+
 // put value for specific key
 func (lru *LRUCache) Put(key string, value []byte) error {
 	lru.mutex.Lock()
 	defer lru.mutex.Unlock()
 
 	// if the key is user_id_tweet_id
-	err, key1, key2 := splitKey(key)
-	if err == nil {
-		lru.trie.Insert(key1, key2)
+	if err, _, _ := splitKey(key); err == nil {
+		lru.trie.Insert(key, key)
 	}
 
 	// lru logic
@@ -83,6 +94,7 @@ func (lru *LRUCache) Put(key string, value []byte) error {
 			back := lru.items.Back()
 			delete(lru.cache, back.Value.(*pair).key)
 			lru.items.Remove(back)
+			lru.untrack(back.Value.(*pair).key)
 		}
 		pair := &pair{key, value}
 		element := lru.items.PushFront(pair)
@@ -101,10 +113,7 @@ func (lru *LRUCache) Remove(key string) error {
 		delete(lru.cache, element.Value.(*pair).key)
 		lru.items.Remove(element)
 		// delete from trie if key is user_id_tweet_id
-		err, key1, key2 := splitKey(key)
-		if err == nil {
-			lru.trie.Delete(key1, key2)
-		}
+		lru.untrack(key)
 	}
 	return nil
 }
@@ -117,11 +126,11 @@ func (lru *LRUCache) StartsWith(key string) (error, [][]byte) {
 	if lru.trie == nil {
 		return nil, [][]byte{}
 	}
-	// get all values for keys: first_word*
-	second_words := lru.trie.StartsWith(key)
-	result := make([][]byte, len(second_words))
-	for i, second_word := range second_words {
-		ok, res := lru.Get(mergeKeys(key, second_word))
+	// get all values for keys: first_word_tweet_id_*
+	keys := lru.trie.StartsWith(mergeKeys(key, "tweet_id_"))
+	result := make([][]byte, len(keys))
+	for i, fullKey := range keys {
+		ok, res := lru.get(fullKey)
 		if ok {
 			result[i] = res
 		} else {
@@ -0,0 +1,27 @@
+package local
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestLRUCache_StartsWith(t *testing.T) {
+	lru := New(2)
+	lru.Put("user_id_1_tweet_id_1", []byte("1"))
+	lru.Put("user_id_10_tweet_id_2", []byte("2"))
+
+	err, values := lru.StartsWith("user_id_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([][]byte{[]byte("1")}, values); diff != "" {
+		t.Errorf("tweets of other users should not match (-want +got):\n%s", diff)
+	}
+
+	// evicted keys are not returned
+	lru.Put("user_id_2_tweet_id_3", []byte("3"))
+	lru.Put("user_id_2_tweet_id_4", []byte("4"))
+	if err, values = lru.StartsWith("user_id_1"); err != nil || len(values) != 0 {
+		t.Errorf("evicted tweet should be forgotten, got %v %v", err, values)
+	}
+}
@@ -1,57 +1,116 @@
 package local
 
-type Node struct {
-	children map[string]*Node
+import "sort"
+
+// node of the trie, values are stored in the node of their key
+type node[V comparable] struct {
+	children map[rune]*node[V]
+	values   map[V]struct{}
 }
 
-type Trie struct {
-	root *Node
+func newNode[V comparable]() *node[V] {
+	return &node[V]{children: make(map[rune]*node[V])}
 }
 
-func NewNode() *Node {
-	p := &Node{}
-	p.children = make(map[string]*Node)
-	return p
+// Trie is a character trie that maps keys to sets of values,
+// it is not safe for concurrent use
+type Trie[V comparable] struct {
+	root *node[V]
 }
 
-func NewTrie() *Trie {
-	return &Trie{root: NewNode()}
+func NewTrie[V comparable]() *Trie[V] {
+	return &Trie[V]{root: newNode[V]()}
 }
 
-func (t *Trie) Insert(first_word string, second_word string) {
+// Insert adds value to the key
+func (t *Trie[V]) Insert(key string, value V) {
 	temp := t.root
+	for _, r := range key {
+		if temp.children[r] == nil {
+			temp.children[r] = newNode[V]()
+		}
+		temp = temp.children[r]
+	}
+	if temp.values == nil {
+		temp.values = make(map[V]struct{})
+	}
+	temp.values[value] = struct{}{}
+}
 
-	if temp.children[first_word] == nil {
-		temp.children[first_word] = NewNode()
+// Delete removes value from the key, empty nodes are pruned
+func (t *Trie[V]) Delete(key string, value V) {
+	runes := []rune(key)
+	path := make([]*node[V], 0, len(runes)+1)
+	temp := t.root
+	path = append(path, temp)
+	for _, r := range runes {
+		temp = temp.children[r]
+		if temp == nil {
+			return
+		}
+		path = append(path, temp)
 	}
+	delete(temp.values, value)
 
-	temp = temp.children[first_word]
-	temp.children[second_word] = NewNode()
+	// remove nodes that lead nowhere, from the leaf to the root
+	for i := len(runes); i > 0; i-- {
+		n := path[i]
+		if len(n.values) > 0 || len(n.children) > 0 {
+			break
+		}
+		delete(path[i-1].children, runes[i-1])
+	}
 }
 
-func (t *Trie) Delete(first_word string, second_word string) {
+// find node of the prefix
+func (t *Trie[V]) find(prefix string) *node[V] {
 	temp := t.root
-
-	if temp.children[first_word] != nil {
-		temp = temp.children[first_word]
-		temp.children[second_word] = nil
+	for _, r := range prefix {
+		temp = temp.children[r]
+		if temp == nil {
+			return nil
+		}
 	}
+	return temp
+}
 
-	temp = t.root
-	if len(temp.children[first_word].children) == 0 {
-		temp.children[first_word] = nil
+// Walk calls fn for values of keys that start with prefix, keys are visited
+// in lexicographical order, shorter keys first. Walk stops when fn returns false
+func (t *Trie[V]) Walk(prefix string, fn func(key string, value V) bool) {
+	n := t.find(prefix)
+	if n != nil {
+		walk(n, []rune(prefix), fn)
 	}
 }
 
-func (t *Trie) StartsWith(first_word string) []string {
-	var result []string
-	temp := t.root
+func walk[V comparable](n *node[V], key []rune, fn func(string, V) bool) bool {
+	for value := range n.values {
+		if !fn(string(key), value) {
+			return false
+		}
+	}
 
-	if temp.children[first_word] != nil {
-		temp = temp.children[first_word]
-		for child, _ := range temp.children {
-			result = append(result, child)
+	children := make([]rune, 0, len(n.children))
+	for r := range n.children {
+		children = append(children, r)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i] < children[j] })
+	for _, r := range children {
+		if !walk(n.children[r], append(key, r), fn) {
+			return false
 		}
 	}
+	return true
+}
+
+// StartsWith returns values of all keys that start with prefix
+func (t *Trie[V]) StartsWith(prefix string) []V {
+	var result []V
+	t.Walk(
+		prefix, func(_ string, value V) bool {
+			result = append(result, value)
+			return true
+		},
+	)
 	return result
 }
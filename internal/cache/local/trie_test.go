@@ -0,0 +1,50 @@
+package local
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestTrie(t *testing.T) {
+	trie := NewTrie[int]()
+	trie.Insert("alex", 1)
+	trie.Insert("alexis", 2)
+	trie.Insert("al", 3)
+	trie.Insert("bob", 4)
+	trie.Insert("alex", 5)
+
+	if diff := cmp.Diff([]int{3}, trie.StartsWith("al")[:1]); diff != "" {
+		t.Errorf("shorter keys should go first (-want +got):\n%s", diff)
+	}
+	if got := trie.StartsWith("alex"); len(got) != 3 {
+		t.Errorf("expected values of alex and alexis, got %v", got)
+	}
+	if got := trie.StartsWith("c"); len(got) != 0 {
+		t.Errorf("unknown prefix should have no values, got %v", got)
+	}
+
+	var keys []string
+	trie.Walk(
+		"", func(key string, _ int) bool {
+			keys = append(keys, key)
+			return len(keys) < 2
+		},
+	)
+	if diff := cmp.Diff([]string{"al", "alex"}, keys); diff != "" {
+		t.Errorf("walk should stop early in key order (-want +got):\n%s", diff)
+	}
+
+	trie.Delete("alexis", 2)
+	trie.Delete("alex", 1)
+	trie.Delete("unknown", 1)
+	if diff := cmp.Diff([]int{5}, trie.StartsWith("alex")); diff != "" {
+		t.Errorf("mismatch after delete (-want +got):\n%s", diff)
+	}
+	trie.Delete("alex", 5)
+	if trie.find("ale") != nil {
+		t.Errorf("empty nodes should be pruned")
+	}
+	if diff := cmp.Diff([]int{3}, trie.StartsWith("a")); diff != "" {
+		t.Errorf("other keys should be kept (-want +got):\n%s", diff)
+	}
+}
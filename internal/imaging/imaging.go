@@ -0,0 +1,83 @@
+package imaging
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// ErrInvalidImage is returned when data could not be decoded as image
+var ErrInvalidImage = errors.New("invalid image, expected jpeg, png or gif")
+
+// quality of encoded images
+const jpegQuality = 90
+
+// Fill decodes image, crops it around the center to the aspect ratio of
+// width x height and scales it to exactly width x height
+func Fill(r io.Reader, width int, height int) (image.Image, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	return resize(src, crop(src, width, height), width, height), nil
+}
+
+// EncodeJPEG writes image as jpeg
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+}
+
+// largest centered rectangle with the same aspect ratio as width x height
+func crop(src image.Image, width int, height int) image.Rectangle {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w*height > h*width {
+		// source is wider
+		cw := h * width / height
+		x := b.Min.X + (w-cw)/2
+		return image.Rect(x, b.Min.Y, x+cw, b.Max.Y)
+	}
+	ch := w * height / width
+	y := b.Min.Y + (h-ch)/2
+	return image.Rect(b.Min.X, y, b.Max.X, y+ch)
+}
+
+// scale region of the image with box filter, every destination pixel
+// averages the source pixels it covers
+func resize(src image.Image, r image.Rectangle, width int, height int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := r.Min.Y + y*r.Dy()/height
+		y1 := r.Min.Y + (y+1)*r.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := r.Min.X + x*r.Dx()/width
+			x1 := r.Min.X + (x+1)*r.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var red, green, blue, alpha, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					red, green, blue = red+uint64(cr), green+uint64(cg), blue+uint64(cb)
+					alpha += uint64(ca)
+					n++
+				}
+			}
+			dst.Set(
+				x, y, color.RGBA64{
+					R: uint16(red / n), G: uint16(green / n), B: uint16(blue / n), A: uint16(alpha / n),
+				},
+			)
+		}
+	}
+	return dst
+}
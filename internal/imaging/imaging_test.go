@@ -0,0 +1,48 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestFill(t *testing.T) {
+	// left half is red, right half is blue
+	src := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 400; x++ {
+			if x < 200 {
+				src.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				src.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Fill(&buf, 20, 20)
+	if err != nil {
+		t.Fatalf("error was not expected while resizing image: %s", err)
+	}
+	if size := img.Bounds().Size(); size != image.Pt(20, 20) {
+		t.Errorf("wrong size: got %v want %v", size, image.Pt(20, 20))
+	}
+	// square is cropped from the center so both colors are kept
+	if r, _, b, _ := img.At(0, 10).RGBA(); r>>8 != 255 || b != 0 {
+		t.Errorf("left side should be red, got %v", img.At(0, 10))
+	}
+	if r, _, b, _ := img.At(19, 10).RGBA(); r != 0 || b>>8 != 255 {
+		t.Errorf("right side should be blue, got %v", img.At(19, 10))
+	}
+}
+
+func TestFill_InvalidImage(t *testing.T) {
+	if _, err := Fill(bytes.NewReader([]byte("not an image")), 10, 10); err != ErrInvalidImage {
+		t.Errorf("expected ErrInvalidImage, got %v", err)
+	}
+}
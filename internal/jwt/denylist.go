@@ -0,0 +1,60 @@
+package jwt
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Denylist keeps ids of revoked tokens until they expire
+type Denylist interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// denylist consulted by ParseToken, services that share
+// tokens should replace it with shared storage
+var denylist Denylist = NewMemoryDenylist()
+
+// SetDenylist replaces denylist used by ParseToken
+func SetDenylist(d Denylist) {
+	denylist = d
+}
+
+// Revoke adds token to the denylist until it expires
+func Revoke(ctx context.Context, claims *Claims) error {
+	return denylist.Revoke(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0))
+}
+
+// MemoryDenylist keeps revoked tokens in memory of the process
+type MemoryDenylist struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{tokens: make(map[string]time.Time)}
+}
+
+func (d *MemoryDenylist) Revoke(_ context.Context, jti string, expiresAt time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// expired tokens are rejected anyway
+	now := time.Now()
+	for id, exp := range d.tokens {
+		if now.After(exp) {
+			delete(d.tokens, id)
+		}
+	}
+	d.tokens[jti] = expiresAt
+	return nil
+}
+
+func (d *MemoryDenylist) IsRevoked(_ context.Context, jti string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.tokens[jti]
+	return ok, nil
+}
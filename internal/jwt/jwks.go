@@ -0,0 +1,109 @@
+package jwt
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// path of the key set published by users service
+const JWKSPath = "/.well-known/jwks.json"
+
+const (
+	// how long fetched keys are trusted without refetch
+	jwksCacheTTL = 10 * time.Minute
+	// unknown kid triggers refetch at most once per interval
+	jwksMinRefresh = 30 * time.Second
+)
+
+// JWKSHandler serves public keys of the key set
+func JWKSHandler(ks *KeySet) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if req.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksCacheTTL.Seconds())))
+			if err := json.NewEncoder(w).Encode(ks.JWKS()); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		},
+	)
+}
+
+// RemoteKeySet fetches public keys from jwks endpoint and caches them,
+// services that only validate tokens use it instead of shared secret
+type RemoteKeySet struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// PublicKey implements KeySource, keys are refetched when cache expires
+// or token is signed with new key after rotation
+func (r *RemoteKeySet) PublicKey(kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[kid]
+	age := time.Since(r.fetched)
+	if (ok && age < jwksCacheTTL) || (!ok && age < jwksMinRefresh) {
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return key, nil
+	}
+
+	keys, err := r.fetch()
+	if err != nil {
+		// keep serving cached keys while users service is unavailable
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	r.keys, r.fetched = keys, time.Now()
+
+	if key, ok = r.keys[kid]; !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (r *RemoteKeySet) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks: status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// skip keys with unsupported algorithms
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
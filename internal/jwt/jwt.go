@@ -1,95 +1,244 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/dgrijalva/jwt-go"
-	"net/http"
-	"strconv"
-	"strings"
 	"time"
 )
 
-// TODO: put secret key to .env
-var jwtKey = []byte("my_secret_key")
+// key set that signs access tokens, configured only in users service
+var signingKeys *KeySet
+
+// keys that verify access tokens
+var verificationKeys KeySource
+
+// SetKeySet signs new tokens with the key set and verifies them with its keys
+func SetKeySet(ks *KeySet) {
+	signingKeys = ks
+	verificationKeys = ks
+}
+
+// SetKeySource verifies tokens with keys from source, e.g. RemoteKeySet
+func SetKeySource(source KeySource) {
+	verificationKeys = source
+}
+
+// access tokens are short-lived, sessions are extended with refresh tokens
+const AccessTokenTTL = 15 * time.Minute
+
+// name of cookie with access token
+const CookieName = "token"
+
+// subject of email tokens, they are signed with the key set of access tokens
+// so subject tells them apart and they can't be used as session tokens
+const emailSubject = "email"
 
 type Claims struct {
 	UserId types.UserId `json:"user_id"`
+	// login the token was issued for, empty for tokens issued before sessions were tracked
+	SessionId string `json:"sid,omitempty"`
+	// privileges of the user when the token was issued
+	Role types.Role `json:"role,omitempty"`
+	// whether email of the user was verified when the token was issued
+	EmailVerified bool `json:"email_verified,omitempty"`
 	jwt.StandardClaims
 }
 
-func GenerateJWT(id types.UserId) (string, error) {
-	var err error
+var (
+	// ErrInvalidToken is returned when token is malformed or signature is invalid
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is returned when token is expired, session can be extended with refresh token
+	ErrTokenExpired = errors.New("token is expired")
+	// ErrTokenRevoked is returned when token was revoked by logout
+	ErrTokenRevoked = errors.New("token was revoked")
+)
+
+// random id of the token used to revoke it
+func newTokenId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateJWT issues access token of the session that expires after AccessTokenTTL
+func GenerateJWT(id types.UserId, sessionId string, role types.Role, emailVerified bool) (string, error) {
+	jti, err := newTokenId()
+	if err != nil {
+		return "", fmt.Errorf("something went wrong: %s", err.Error())
+	}
 
 	// Create the Claims
 	claims := &Claims{
-		UserId: id,
+		UserId:    id,
+		SessionId: sessionId,
+		Role:          role,
+		EmailVerified: emailVerified,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
+			Id:        jti,
+			ExpiresAt: time.Now().Add(AccessTokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
 			Issuer:    "test",
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
-
+	if signingKeys == nil {
+		return "", errors.New("signing key is not configured")
+	}
+	tokenString, err := signingKeys.sign(claims)
 	if err != nil {
-		fmt.Errorf("something went wrong: %s", err.Error())
-		return "", err
+		return "", fmt.Errorf("something went wrong: %s", err.Error())
 	}
 
 	return tokenString, nil
 }
 
-func ValidateMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(
-		func(w http.ResponseWriter, r *http.Request) {
-			user, err := strconv.Atoi(r.FormValue("user_id"))
-			if err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				return
-			}
-			userID := types.UserId(user)
+// EmailClaims confirm that user owns the email
+type EmailClaims struct {
+	UserId types.UserId `json:"user_id"`
+	Email  string       `json:"email"`
+	jwt.StandardClaims
+}
 
-			// Get the JWT string from the header
-			tokenString := r.Header.Get("Authorization")
+// GenerateEmailToken signs email of the user, token expires after ttl
+func GenerateEmailToken(id types.UserId, email string, ttl time.Duration) (string, error) {
+	claims := &EmailClaims{
+		UserId: id,
+		Email:  email,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    "test",
+			Subject:   emailSubject,
+		},
+	}
 
-			// Check if the token is empty
-			if tokenString == "" {
-				http.Error(w, "Authorization header is missing", http.StatusUnauthorized)
-				return
-			}
+	if signingKeys == nil {
+		return "", errors.New("signing key is not configured")
+	}
+	tokenString, err := signingKeys.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("something went wrong: %s", err.Error())
+	}
+	return tokenString, nil
+}
 
-			// The token always starts with "Bearer "
-			// we need to remove this part in order to be able to parse the token correctly
-			tokenString = strings.TrimPrefix(tokenString, "Bearer ")
-
-			// Parse the token
-			claims := &Claims{}
-			token, err := jwt.ParseWithClaims(
-				tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-					return jwtKey, nil
-				},
-			)
-
-			// If the token is expited, redirect to /login
-			ve, ok := err.(*jwt.ValidationError)
-			if ok && (ve.Errors&jwt.ValidationErrorExpired != 0) {
-				http.Redirect(w, r, "/login", http.StatusSeeOther)
-				return
-			}
-			if err != nil || !token.Valid {
-				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
-				return
-			}
+// ParseEmailToken returns user and email from valid and not expired token
+func ParseEmailToken(tokenString string) (types.UserId, string, error) {
+	claims := &EmailClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc)
+	if err != nil || !token.Valid || claims.Subject != emailSubject {
+		return 0, "", fmt.Errorf("invalid or expired token: %v", err)
+	}
+	return claims.UserId, claims.Email, nil
+}
 
-			if claims.UserId != userID {
-				http.Error(w, "Invalid user_id", http.StatusUnauthorized)
-				return
-			}
+// challenge tokens are verified only by the service that issued them,
+// so the key is generated on start and pending logins are lost on restart
+var challengeKey = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return b
+}()
+
+// ChallengeClaims prove that user entered correct password and has to enter second factor
+type ChallengeClaims struct {
+	UserId types.UserId `json:"user_id"`
+	jwt.StandardClaims
+}
+
+// GenerateChallengeToken issues token for second step of the login, token expires after ttl
+func GenerateChallengeToken(id types.UserId, ttl time.Duration) (string, error) {
+	claims := &ChallengeClaims{
+		UserId: id,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(ttl).Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    "test",
+			Subject:   "2fa",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(challengeKey)
+	if err != nil {
+		return "", fmt.Errorf("something went wrong: %s", err.Error())
+	}
+	return tokenString, nil
+}
 
-			// If everything is OK, call the next handler
-			next.ServeHTTP(w, r)
+// ParseChallengeToken returns user from valid and not expired challenge token
+func ParseChallengeToken(tokenString string) (types.UserId, error) {
+	claims := &ChallengeClaims{}
+	token, err := jwt.ParseWithClaims(
+		tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return challengeKey, nil
 		},
 	)
+	if err != nil || !token.Valid || claims.Subject != "2fa" {
+		return 0, fmt.Errorf("invalid or expired token: %v", err)
+	}
+	return claims.UserId, nil
+}
+
+// keyFunc selects verification key by kid, algorithm must match the key
+// so that public key can't be used as HMAC secret
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	if verificationKeys == nil {
+		return nil, errors.New("verification keys are not configured")
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, err := verificationKeys.PublicKey(kid)
+	if err != nil {
+		return nil, err
+	}
+	method, err := signingMethod(key)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key, nil
+}
+
+// ParseToken validates signature and expiry of access token and checks that it wasn't revoked
+func ParseToken(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc)
+	ve, ok := err.(*jwt.ValidationError)
+	if ok && (ve.Errors&jwt.ValidationErrorExpired != 0) {
+		return nil, ErrTokenExpired
+	}
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	// tokens of other purposes are signed with the same keys
+	if claims.Subject != "" {
+		return nil, ErrInvalidToken
+	}
+
+	// token could be revoked by logout
+	if claims.Id == "" {
+		return nil, ErrTokenRevoked
+	}
+	revoked, err := denylist.IsRevoked(ctx, claims.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
 }
@@ -0,0 +1,142 @@
+package jwt
+
+import (
+	"context"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/dgrijalva/jwt-go"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	ks, err := GenerateKeySet()
+	if err != nil {
+		panic(err)
+	}
+	SetKeySet(ks)
+	os.Exit(m.Run())
+}
+
+func TestEmailToken(t *testing.T) {
+	token, err := GenerateEmailToken(types.UserId(1), "alex@mail.com", time.Hour)
+	if err != nil {
+		t.Fatalf("error was not expected while generating token: %s", err)
+	}
+	userId, email, err := ParseEmailToken(token)
+	if err != nil {
+		t.Fatalf("error was not expected while parsing token: %s", err)
+	}
+	if userId != 1 || email != "alex@mail.com" {
+		t.Errorf("wrong claims: got %d %s", userId, email)
+	}
+
+	expired, err := GenerateEmailToken(types.UserId(1), "alex@mail.com", -time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = ParseEmailToken(expired); err == nil {
+		t.Errorf("expired token should be rejected")
+	}
+
+	// session token is not accepted as email token and vice versa
+	session, err := GenerateJWT(types.UserId(1), "", types.RoleUser, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = ParseEmailToken(session); err == nil {
+		t.Errorf("session token should be rejected")
+	}
+
+	if _, err = ParseToken(context.Background(), token); err != ErrInvalidToken {
+		t.Errorf("email token should not authorize requests, got %v", err)
+	}
+
+	// token signed with a shared secret is rejected
+	forged, err := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		&EmailClaims{UserId: 1, Email: "alex@mail.com", StandardClaims: jwt.StandardClaims{Subject: emailSubject}},
+	).SignedString([]byte("my_email_secret_key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = ParseEmailToken(forged); err == nil {
+		t.Errorf("token signed with a shared secret should be rejected")
+	}
+}
+
+func TestChallengeToken(t *testing.T) {
+	token, err := GenerateChallengeToken(types.UserId(1), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userId, err := ParseChallengeToken(token)
+	if err != nil || userId != 1 {
+		t.Errorf("valid challenge should be accepted, got %d %v", userId, err)
+	}
+
+	expired, err := GenerateChallengeToken(types.UserId(1), -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseChallengeToken(expired); err == nil {
+		t.Errorf("expired challenge should be rejected")
+	}
+
+	// challenge doesn't authorize requests, email and session tokens don't pass the challenge
+	if _, err = ParseToken(context.Background(), token); err != ErrInvalidToken {
+		t.Errorf("challenge should not authorize requests, got %v", err)
+	}
+	email, err := GenerateEmailToken(types.UserId(1), "alex@mail.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := GenerateJWT(types.UserId(1), "", types.RoleUser, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range []string{email, session} {
+		if _, err = ParseChallengeToken(other); err == nil {
+			t.Errorf("token %s should not pass the challenge", other)
+		}
+	}
+}
+
+func TestParseToken_Revoked(t *testing.T) {
+	ctx := context.Background()
+	token, err := GenerateJWT(types.UserId(1), "session", types.RoleModerator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := ParseToken(ctx, token)
+	if err != nil {
+		t.Fatalf("valid token should be accepted: %s", err)
+	}
+	if claims.UserId != 1 || claims.SessionId != "session" || claims.Role != types.RoleModerator ||
+		!claims.EmailVerified || claims.Id == "" {
+		t.Errorf("wrong claims: %+v", claims)
+	}
+
+	if err = Revoke(ctx, claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseToken(ctx, token); err != ErrTokenRevoked {
+		t.Errorf("revoked token should be rejected, got %v", err)
+	}
+}
+
+func TestParseToken_Expired(t *testing.T) {
+	token, err := signingKeys.sign(
+		&Claims{
+			UserId:         1,
+			StandardClaims: jwt.StandardClaims{Id: "1", ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseToken(context.Background(), token); err != ErrTokenExpired {
+		t.Errorf("expired token should be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"os"
+	"sync"
+)
+
+// ErrUnknownKey is returned when token is signed with key that is not trusted
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySource returns public key that verifies tokens with kid
+type KeySource interface {
+	PublicKey(kid string) (crypto.PublicKey, error)
+}
+
+// KeySet keeps private key that signs new tokens and public keys
+// of previous keys that are still accepted during rotation
+type KeySet struct {
+	mu     sync.RWMutex
+	signer crypto.Signer
+	kid    string
+	keys   map[string]crypto.PublicKey
+	// keys in order they were added, used by jwks
+	kids []string
+}
+
+// NewKeySet signs tokens with signer, previous keys are only used for verification
+func NewKeySet(signer crypto.Signer, previous ...crypto.PublicKey) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]crypto.PublicKey)}
+	for _, key := range previous {
+		if err := ks.addPublicKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if err := ks.Rotate(signer); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// GenerateKeySet creates key set with new Ed25519 key, used when no key is configured
+func GenerateKeySet() (*KeySet, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySet(key)
+}
+
+func (ks *KeySet) addPublicKey(key crypto.PublicKey) error {
+	kid, err := Thumbprint(key)
+	if err != nil {
+		return err
+	}
+	if _, ok := ks.keys[kid]; !ok {
+		ks.kids = append(ks.kids, kid)
+	}
+	ks.keys[kid] = key
+	return nil
+}
+
+// Rotate starts signing with new key, tokens of the old key are still accepted
+func (ks *KeySet) Rotate(signer crypto.Signer) error {
+	if _, err := signingMethod(signer.Public()); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err := ks.addPublicKey(signer.Public()); err != nil {
+		return err
+	}
+	ks.signer = signer
+	ks.kid, _ = Thumbprint(signer.Public())
+	return nil
+}
+
+// Remove stops accepting tokens signed with the key, current signing key can't be removed
+func (ks *KeySet) Remove(kid string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if kid == ks.kid {
+		return
+	}
+	delete(ks.keys, kid)
+	for i, k := range ks.kids {
+		if k == kid {
+			ks.kids = append(ks.kids[:i], ks.kids[i+1:]...)
+			break
+		}
+	}
+}
+
+// PublicKey implements KeySource
+func (ks *KeySet) PublicKey(kid string) (crypto.PublicKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// sign claims with current key and put its kid to the header
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	signer, kid := ks.signer, ks.kid
+	ks.mu.RUnlock()
+
+	method, err := signingMethod(signer.Public())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(signer)
+}
+
+// JWKS returns public keys in JSON Web Key Set format
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	for _, kid := range ks.kids {
+		jwk, err := publicJWK(ks.keys[kid])
+		if err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// supported algorithms are RS256 and EdDSA
+func signingMethod(key crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T, expected RSA or Ed25519", key)
+}
+
+// JWK is public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is set of public keys served by /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func publicJWK(key crypto.PublicKey) (JWK, error) {
+	kid, err := Thumbprint(key)
+	if err != nil {
+		return JWK{}, err
+	}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+			N: encodeInt(k.N), E: encodeInt(big.NewInt(int64(k.E))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP", Kid: kid, Use: "sig", Alg: "EdDSA",
+			Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	}
+	return JWK{}, fmt.Errorf("unsupported key type %T", key)
+}
+
+// PublicKey decodes public key from JWK
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case j.Kty == "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case j.Kty == "OKP" && j.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", j.Kty)
+}
+
+// Thumbprint returns RFC 7638 thumbprint of the key, it is used as kid
+func Thumbprint(key crypto.PublicKey) (string, error) {
+	var members interface{}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		// members in lexicographic order
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{encodeInt(big.NewInt(int64(k.E))), "RSA", encodeInt(k.N)}
+	case ed25519.PublicKey:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{"Ed25519", "OKP", base64.RawURLEncoding.EncodeToString(k)}
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// ReadKey reads PEM from environment variable or from file if variable is empty
+func ReadKey(env string, path string) ([]byte, error) {
+	if value := os.Getenv(env); value != "" {
+		return []byte(value), nil
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return os.ReadFile(path)
+}
+
+// ParsePrivateKey parses PKCS#8 or PKCS#1 PEM encoded RSA or Ed25519 private key
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM private key")
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if _, err = signingMethod(signer.Public()); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// ParsePublicKey parses PKIX PEM encoded RSA or Ed25519 public key
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = signingMethod(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ParsePublicKeys parses all PEM blocks, used to pass several keys in one variable
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		key, err := ParsePublicKey(pem.EncodeToMemory(block))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		data = rest
+	}
+	return keys, nil
+}
@@ -0,0 +1,243 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func parse(t *testing.T, source KeySource, token string) error {
+	t.Helper()
+	prev := verificationKeys
+	defer func() { verificationKeys = prev }()
+	verificationKeys = source
+
+	_, err := jwt.ParseWithClaims(token, &Claims{}, keyFunc)
+	return err
+}
+
+func sign(t *testing.T, ks *KeySet) string {
+	t.Helper()
+	token, err := ks.sign(&Claims{UserId: 1, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestKeySet_Algorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for alg, key := range map[string]crypto.Signer{"RS256": rsaKey, "EdDSA": edKey} {
+		ks, err := NewKeySet(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := sign(t, ks)
+
+		parsed, _ := jwt.Parse(token, nil)
+		if parsed.Header["alg"] != alg {
+			t.Errorf("expected alg %s, got %v", alg, parsed.Header["alg"])
+		}
+		kid, _ := Thumbprint(key.Public())
+		if parsed.Header["kid"] != kid {
+			t.Errorf("expected kid %s, got %v", kid, parsed.Header["kid"])
+		}
+		if err = parse(t, ks, token); err != nil {
+			t.Errorf("%s token should be valid: %s", alg, err)
+		}
+	}
+}
+
+func TestKeySet_Rotate(t *testing.T) {
+	ks, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken := sign(t, ks)
+	oldKid := ks.kid
+
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ks.Rotate(newKey); err != nil {
+		t.Fatal(err)
+	}
+	newToken := sign(t, ks)
+
+	if err = parse(t, ks, oldToken); err != nil {
+		t.Errorf("token of previous key should be accepted during rotation: %s", err)
+	}
+	if err = parse(t, ks, newToken); err != nil {
+		t.Errorf("token of new key should be accepted: %s", err)
+	}
+	if len(ks.JWKS().Keys) != 2 {
+		t.Errorf("both keys should be published, got %d", len(ks.JWKS().Keys))
+	}
+
+	ks.Remove(oldKid)
+	if err = parse(t, ks, oldToken); err == nil {
+		t.Errorf("token of removed key should be rejected")
+	}
+	// current key can't be removed
+	ks.Remove(ks.kid)
+	if err = parse(t, ks, newToken); err != nil {
+		t.Errorf("current key should not be removed: %s", err)
+	}
+}
+
+func TestKeySet_AlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := NewKeySet(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// public key is known to everyone, it must not work as HMAC secret
+	publicKey, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserId: 1})
+	token.Header["kid"] = ks.kid
+	forged, err := token.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = parse(t, ks, forged); err == nil {
+		t.Errorf("HS256 token should be rejected")
+	}
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, &Claims{UserId: 1})
+	unsigned.Header["kid"] = ks.kid
+	none, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = parse(t, ks, none); err == nil {
+		t.Errorf("unsigned token should be rejected")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+	if err != nil {
+		t.Fatalf("error was not expected while parsing Ed25519 key: %s", err)
+	}
+	if !edKey.Public().(ed25519.PublicKey).Equal(signer.Public()) {
+		t.Errorf("wrong Ed25519 key was parsed")
+	}
+
+	pkcs1 := x509.MarshalPKCS1PrivateKey(rsaKey)
+	if _, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: pkcs1})); err != nil {
+		t.Errorf("error was not expected while parsing RSA key: %s", err)
+	}
+
+	pkix, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}))
+	if err != nil {
+		t.Fatalf("error was not expected while parsing public key: %s", err)
+	}
+	if !rsaKey.PublicKey.Equal(publicKey) {
+		t.Errorf("wrong RSA public key was parsed")
+	}
+
+	if _, err = ParsePrivateKey([]byte("not a key")); err == nil {
+		t.Errorf("invalid PEM should be rejected")
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	ks, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	handler := JWKSHandler(ks)
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, req *http.Request) {
+				requests++
+				handler.ServeHTTP(w, req)
+			},
+		),
+	)
+	defer server.Close()
+
+	remote := NewRemoteKeySet(server.URL + JWKSPath)
+	token := sign(t, ks)
+	for i := 0; i < 3; i++ {
+		if err = parse(t, remote, token); err != nil {
+			t.Fatalf("token should be verified with fetched keys: %s", err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("keys should be cached, got %d requests", requests)
+	}
+
+	// new key is fetched once it is used
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ks.Rotate(newKey); err != nil {
+		t.Fatal(err)
+	}
+	remote.fetched = time.Now().Add(-jwksMinRefresh)
+	if err = parse(t, remote, sign(t, ks)); err != nil {
+		t.Errorf("token of rotated key should be verified: %s", err)
+	}
+	if requests != 2 {
+		t.Errorf("unknown kid should trigger refetch, got %d requests", requests)
+	}
+
+	// unknown kids don't cause a request every time
+	other, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = parse(t, remote, sign(t, other)); err == nil {
+			t.Errorf("token of unknown key should be rejected")
+		}
+	}
+	if requests != 2 {
+		t.Errorf("refetch should be rate limited, got %d requests", requests)
+	}
+}
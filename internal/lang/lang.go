@@ -0,0 +1,238 @@
+package lang
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// sample texts used to build n-gram profiles, one file per ISO 639-1 code
+//
+//go:embed profiles/*.txt
+var corpora embed.FS
+
+// Undetermined is returned when language could not be detected
+const Undetermined = "und"
+
+const (
+	// maximum length of n-grams
+	maxNgram = 3
+	// number of top n-grams kept in every profile
+	profileSize = 400
+	// texts with less letters are too short to detect
+	minLetters = 8
+)
+
+// n-gram -> rank in profile
+type profile map[string]int
+
+// language profiles built from embedded corpora
+var profiles = map[string]profile{}
+
+// scripts that identify language without n-grams
+var scripts = []struct {
+	lang  string
+	table *unicode.RangeTable
+}{
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"ko", unicode.Hangul},
+	{"zh", unicode.Han},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"el", unicode.Greek},
+	{"hi", unicode.Devanagari},
+	{"th", unicode.Thai},
+}
+
+func init() {
+	files, err := corpora.ReadDir("profiles")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		data, err := corpora.ReadFile(path.Join("profiles", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		code := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+		profiles[code] = rank(count(string(data)), profileSize)
+	}
+}
+
+// Languages returns all languages that can be detected
+func Languages() []string {
+	var langs []string
+	for code := range profiles {
+		langs = append(langs, code)
+	}
+	for _, script := range scripts {
+		langs = append(langs, script.lang)
+	}
+	sort.Strings(langs)
+
+	// drop duplicates
+	res := langs[:0]
+	for i, code := range langs {
+		if i == 0 || langs[i-1] != code {
+			res = append(res, code)
+		}
+	}
+	return res
+}
+
+// remove mentions, hashtags and links that do not carry language
+func clean(text string) []string {
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		if strings.HasPrefix(word, "@") || strings.HasPrefix(word, "#") ||
+			strings.HasPrefix(word, "http://") || strings.HasPrefix(word, "https://") {
+			continue
+		}
+		word = strings.Map(
+			func(r rune) rune {
+				if unicode.IsLetter(r) || r == '\'' {
+					return r
+				}
+				return ' '
+			}, word,
+		)
+		words = append(words, strings.Fields(word)...)
+	}
+	return words
+}
+
+// count all n-grams of words padded with spaces
+func count(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range clean(text) {
+		runes := []rune(" " + word + " ")
+		for n := 1; n <= maxNgram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				gram := string(runes[i : i+n])
+				if gram == " " {
+					continue
+				}
+				counts[gram]++
+			}
+		}
+	}
+	return counts
+}
+
+// keep top n-grams ordered by frequency
+func rank(counts map[string]int, size int) profile {
+	grams := make([]string, 0, len(counts))
+	for gram := range counts {
+		grams = append(grams, gram)
+	}
+	sort.Slice(
+		grams, func(i, j int) bool {
+			if counts[grams[i]] != counts[grams[j]] {
+				return counts[grams[i]] > counts[grams[j]]
+			}
+			return grams[i] < grams[j]
+		},
+	)
+	if len(grams) > size {
+		grams = grams[:size]
+	}
+
+	p := make(profile, len(grams))
+	for i, gram := range grams {
+		p[gram] = i
+	}
+	return p
+}
+
+// detect language by dominant script, returns empty string for latin and cyrillic
+func detectScript(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, script := range scripts {
+			if unicode.Is(script.table, r) {
+				counts[script.lang]++
+				break
+			}
+		}
+	}
+
+	// kana is mixed with kanji in japanese texts
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+	for _, script := range scripts {
+		if counts[script.lang]*2 > letters {
+			return script.lang
+		}
+	}
+	return ""
+}
+
+// Detect returns ISO 639-1 code of the text language or Undetermined
+func Detect(text string) string {
+	if code := detectScript(text); code != "" {
+		return code
+	}
+
+	letters := 0
+	for _, word := range clean(text) {
+		letters += len([]rune(word))
+	}
+	if letters < minLetters {
+		return Undetermined
+	}
+
+	// out-of-place distance between text profile and language profiles
+	doc := rank(count(text), profileSize)
+	best, bestDistance := Undetermined, -1
+	for code, p := range profiles {
+		distance := 0
+		for gram, i := range doc {
+			j, ok := p[gram]
+			if !ok {
+				distance += profileSize
+				continue
+			}
+			if i > j {
+				distance += i - j
+			} else {
+				distance += j - i
+			}
+		}
+		if bestDistance < 0 || distance < bestDistance || (distance == bestDistance && code < best) {
+			best, bestDistance = code, distance
+		}
+	}
+	return best
+}
+
+// Normalize lowercases language code and drops region, e.g. "en-US" -> "en"
+func Normalize(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+// Match checks if language is accepted by filter, undetermined texts are always accepted
+func Match(code string, langs []string) bool {
+	if len(langs) == 0 || code == "" || code == Undetermined {
+		return true
+	}
+	for _, l := range langs {
+		if Normalize(l) == code {
+			return true
+		}
+	}
+	return false
+}
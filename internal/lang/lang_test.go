@@ -0,0 +1,50 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		text string
+		want string
+	}{
+		{"Just finished reading a great book about the history of the city, highly recommend it", "en"},
+		{"Mañana vamos a ir al cine con mis padres, ¿quieres venir con nosotros?", "es"},
+		{"Je suis vraiment content de vous voir demain soir au restaurant", "fr"},
+		{"Ich habe heute keine Zeit, aber morgen können wir uns treffen", "de"},
+		{"Domani andiamo al ristorante con gli amici della scuola", "it"},
+		{"Hoje eu fui ao mercado e comprei muitas frutas para a família", "pt"},
+		{"Morgen gaan we met de trein naar Amsterdam om het museum te bezoeken", "nl"},
+		{"Сегодня мы ходили в театр и смотрели очень интересный спектакль", "ru"},
+		{"Сьогодні ми ходили до театру і дивилися дуже цікаву виставу", "uk"},
+		{"Jutro idziemy do kina z przyjaciółmi, czy chcesz pójść z nami?", "pl"},
+		{"Yarın arkadaşlarımla sinemaya gideceğim, sen de gelmek ister misin?", "tr"},
+		{"I morgon ska vi åka till landet och hälsa på min mormor", "sv"},
+		{"今日はとても良い天気ですね", "ja"},
+		{"今天天气很好", "zh"},
+		{"오늘 날씨가 정말 좋네요", "ko"},
+		{"ok @alex", Undetermined},
+		{"https://example.com #go", Undetermined},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.text, func(t *testing.T) {
+				if got := Detect(tc.text); got != tc.want {
+					t.Errorf("wrong language for %q: got %v want %v", tc.text, got, tc.want)
+				}
+			},
+		)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	if !Match("en", []string{"en-US", "fr"}) {
+		t.Errorf("en should match en-US")
+	}
+	if Match("de", []string{"en", "fr"}) {
+		t.Errorf("de should not match en and fr")
+	}
+	if !Match(Undetermined, []string{"en"}) {
+		t.Errorf("undetermined language should always match")
+	}
+}
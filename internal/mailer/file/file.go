@@ -0,0 +1,41 @@
+package file
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileMailer writes emails to file or any writer instead of sending them,
+// used for development and tests
+type FileMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// New appends emails to the file
+func New(path string) (*FileMailer, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileMailer{w: f}, nil
+}
+
+// NewWriter writes emails to w, e.g. os.Stderr to log them
+func NewWriter(w io.Writer) *FileMailer {
+	return &FileMailer{w: w}
+}
+
+func (m *FileMailer) Send(to string, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(
+		m.w, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), to, subject, body,
+	)
+	return err
+}
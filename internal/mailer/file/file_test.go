@@ -0,0 +1,21 @@
+package file
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewWriter(&buf)
+
+	if err := mailer.Send("alex@mail.com", "Hello", "body text"); err != nil {
+		t.Fatalf("error was not expected while sending email: %s", err)
+	}
+	for _, want := range []string{"To: alex@mail.com", "Subject: Hello", "body text"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("email should contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
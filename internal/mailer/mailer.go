@@ -0,0 +1,6 @@
+package mailer
+
+// interface to send emails
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
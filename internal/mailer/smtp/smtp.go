@@ -0,0 +1,42 @@
+package smtp
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+type SmtpMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func New(host string, port int, username string, password string, from string) *SmtpMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SmtpMailer{addr: fmt.Sprintf("%s:%d", host, port), from: from, auth: auth}
+}
+
+// send plain text email
+func (m *SmtpMailer) Send(to string, subject string, body string) error {
+	// headers must not contain line breaks
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+
+	msg := strings.Join(
+		[]string{
+			fmt.Sprintf("From: %s", m.from),
+			fmt.Sprintf("To: %s", to),
+			fmt.Sprintf("Subject: %s", subject),
+			"MIME-Version: 1.0",
+			"Content-Type: text/plain; charset=UTF-8",
+			"",
+			body,
+		}, "\r\n",
+	)
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(msg))
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// failed attempts of one key
+type failures struct {
+	count int
+	last  time.Time
+	until time.Time
+}
+
+// Lockout is the state of key that failed too many times
+type Lockout struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// Backoff locks key after free failures, every next failure doubles
+// the lock from base up to max. Failures are forgotten after max without new ones
+type Backoff struct {
+	mu       sync.Mutex
+	free     int
+	base     time.Duration
+	max      time.Duration
+	failures map[string]*failures
+	now      func() time.Time
+}
+
+func NewBackoff(free int, base time.Duration, max time.Duration) *Backoff {
+	return &Backoff{
+		free:     free,
+		base:     base,
+		max:      max,
+		failures: make(map[string]*failures),
+		now:      time.Now,
+	}
+}
+
+// get failures of the key, expired ones are dropped
+func (b *Backoff) get(key string, now time.Time) (*failures, bool) {
+	f, ok := b.failures[key]
+	if ok && now.After(f.until) && now.Sub(f.last) >= b.max {
+		delete(b.failures, key)
+		return nil, false
+	}
+	return f, ok
+}
+
+// Check returns time left until key is unlocked, zero if it is not locked
+func (b *Backoff) Check(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	f, ok := b.get(key, now)
+	if !ok || !now.Before(f.until) {
+		return 0
+	}
+	return f.until.Sub(now)
+}
+
+// Fail counts failure of the key and returns duration of the new lock, zero if key is not locked
+func (b *Backoff) Fail(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	f, ok := b.get(key, now)
+	if !ok {
+		// drop expired keys from time to time to keep memory bounded
+		if len(b.failures) > 10000 {
+			b.cleanup(now)
+		}
+		f = &failures{}
+		b.failures[key] = f
+	}
+	f.count++
+	f.last = now
+	if f.count <= b.free {
+		return 0
+	}
+
+	lock := b.max
+	if shift := f.count - b.free - 1; shift < 32 && b.base<<shift < b.max {
+		lock = b.base << shift
+	}
+	f.until = now.Add(lock)
+	return lock
+}
+
+// Reset forgets failures of the key, e.g. after successful attempt or by admin
+func (b *Backoff) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, key)
+}
+
+// Lockouts returns currently locked keys sorted by key
+func (b *Backoff) Lockouts() []Lockout {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	lockouts := []Lockout{}
+	for key, f := range b.failures {
+		if now.Before(f.until) {
+			lockouts = append(lockouts, Lockout{Key: key, Failures: f.count, LockedUntil: f.until})
+		}
+	}
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].Key < lockouts[j].Key })
+	return lockouts
+}
+
+func (b *Backoff) cleanup(now time.Time) {
+	for key := range b.failures {
+		b.get(key, now)
+	}
+}
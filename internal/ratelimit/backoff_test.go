@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	now := time.Now()
+	b := NewBackoff(2, time.Second, 10*time.Second)
+	b.now = func() time.Time { return now }
+
+	// lock doubles after free failures and is capped by max
+	for i, want := range []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second} {
+		if got := b.Fail("a"); got != want {
+			t.Errorf("failure %d: got lock %v want %v", i, got, want)
+		}
+	}
+	if got := b.Check("a"); got != 10*time.Second {
+		t.Errorf("key should be locked, got %v", got)
+	}
+	if got := b.Check("b"); got != 0 {
+		t.Errorf("other keys should not be locked, got %v", got)
+	}
+	lockouts := b.Lockouts()
+	if len(lockouts) != 1 || lockouts[0].Key != "a" || lockouts[0].Failures != 7 {
+		t.Errorf("wrong lockouts: %+v", lockouts)
+	}
+
+	// lock expires, but failures are remembered
+	now = now.Add(10 * time.Second)
+	if got := b.Check("a"); got != 0 {
+		t.Errorf("lock should expire, got %v", got)
+	}
+	if got := b.Fail("a"); got != 10*time.Second {
+		t.Errorf("next failure should lock again, got %v", got)
+	}
+
+	// failures are forgotten after reset or max without new ones
+	b.Reset("a")
+	if got := b.Fail("a"); got != 0 {
+		t.Errorf("failures should be reset, got lock %v", got)
+	}
+	b.Fail("a")
+	now = now.Add(10 * time.Second)
+	if got := b.Fail("a"); got != 0 {
+		t.Errorf("old failures should be forgotten, got lock %v", got)
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requests made by one key in the current window
+type window struct {
+	start time.Time
+	count int
+}
+
+// Limiter allows limit requests per key in fixed time window
+type Limiter struct {
+	mu      sync.Mutex
+	limit   int
+	period  time.Duration
+	windows map[string]*window
+	now     func() time.Time
+}
+
+func New(limit int, period time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		period:  period,
+		windows: make(map[string]*window),
+		now:     time.Now,
+	}
+}
+
+// Allow counts request of the key and reports whether it is within the limit
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.period {
+		// drop expired windows from time to time to keep memory bounded
+		if len(l.windows) > 10000 {
+			l.cleanup(now)
+		}
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+	w.count++
+	return w.count <= l.limit
+}
+
+func (l *Limiter) cleanup(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.period {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// ClientIP returns address of the client without port
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Middleware rejects requests with 429 when client ip exceeds the limit
+func Middleware(l *Limiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if !l.Allow(ClientIP(r)) {
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		},
+	)
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i, want := range []bool{true, true, false} {
+		if got := l.Allow("a"); got != want {
+			t.Errorf("request %d: got %v want %v", i, got, want)
+		}
+	}
+	// other keys have their own limit
+	if !l.Allow("b") {
+		t.Errorf("request of other key should be allowed")
+	}
+	// limit is reset after the window
+	now = now.Add(time.Minute)
+	if !l.Allow("a") {
+		t.Errorf("request after window should be allowed")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(New(1, time.Minute), http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for _, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest("POST", "/forgot_password", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, want)
+		}
+	}
+}
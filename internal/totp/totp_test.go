@@ -0,0 +1,78 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// secret "12345678901234567890" from RFC 6238 appendix B
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// RFC 6238 test vectors truncated to 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("code at %d: got %s want %s", tt.unix, code, tt.code)
+		}
+	}
+
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Errorf("invalid secret should be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	code, err := Code(secret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := Validate(secret, code, now)
+	if !ok || step != Step(now) {
+		t.Errorf("current code should be accepted")
+	}
+	// authenticator clock may drift by one period
+	if _, ok = Validate(secret, code, now.Add(Period)); !ok {
+		t.Errorf("code of the previous period should be accepted")
+	}
+	if _, ok = Validate(secret, code, now.Add(3*Period)); ok {
+		t.Errorf("outdated code should be rejected")
+	}
+	if _, ok = Validate(secret, "12345", now); ok {
+		t.Errorf("short code should be rejected")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Twitter", "alex@mail.com", "ABC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Twitter:alex@mail.com" {
+		t.Errorf("wrong uri: %s", uri)
+	}
+	if query := uri.Query(); query.Get("secret") != "ABC" || query.Get("issuer") != "Twitter" {
+		t.Errorf("wrong query: %s", uri.RawQuery)
+	}
+}
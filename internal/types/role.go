@@ -0,0 +1,22 @@
+package types
+
+// Role of the user, every role has privileges of the previous ones
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{RoleUser: 0, RoleModerator: 1, RoleAdmin: 2}
+
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// Allows reports whether role has privileges of the required one, unknown roles are plain users
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
@@ -0,0 +1,19 @@
+package types
+
+// Scope limits what personal access token can do, session tokens have every scope
+type Scope string
+
+const (
+	ScopeRead        Scope = "read"
+	ScopeTweetsWrite Scope = "tweets:write"
+	ScopeLikesWrite  Scope = "likes:write"
+	ScopeFollowWrite Scope = "follow:write"
+)
+
+func (s Scope) Valid() bool {
+	switch s {
+	case ScopeRead, ScopeTweetsWrite, ScopeLikesWrite, ScopeFollowWrite:
+		return true
+	}
+	return false
+}
@@ -3,7 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	authmysql "github.com/alexvishnevskiy/twitter-clone/internal/auth/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/likes/internal/controller"
+	followGateway "github.com/alexvishnevskiy/twitter-clone/likes/internal/gateway/follow/grpc"
+	tweetsGateway "github.com/alexvishnevskiy/twitter-clone/likes/internal/gateway/tweets/grpc"
 	httphandler "github.com/alexvishnevskiy/twitter-clone/likes/internal/handler/http"
 	"github.com/alexvishnevskiy/twitter-clone/likes/internal/repository/mysql"
 	"log"
@@ -14,23 +20,58 @@ import (
 //	@version		1.0.0
 //	@host			localhost:8081
 //	@description	This is API for likes service
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
 func main() {
-	var port int
+	var (
+		port        int
+		users_port  int
+		tweets_port int
+		follow_port int
+	)
 	flag.IntVar(&port, "port", 8081, "API handler port")
+	flag.IntVar(&users_port, "users_port", 8084, "users API handler port")
+	flag.IntVar(&tweets_port, "tweets_port", 8080, "tweets API handler port")
+	flag.IntVar(&follow_port, "follow_port", 8082, "follow API handler port")
 	flag.Parse()
 	log.Printf("Starting the tweets service on port %d", port)
 
+	// tokens are verified with public keys published by users service
+	jwt.SetKeySource(jwt.NewRemoteKeySet(fmt.Sprintf("http://localhost:%d%s", users_port, jwt.JWKSPath)))
+
+	// access tokens are checked in the database shared with users service
+	tokenStore, err := authmysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
+	if err != nil {
+		log.Fatalf("failed to open access tokens: %v", err)
+	}
+	auth.SetTokenStore(tokenStore)
+	// revoked access tokens and sessions are shared through the database
+	auth.SetSessionStore(tokenStore)
+	jwt.SetDenylist(tokenStore)
+
 	// probably use configs for driverName and dataSourceName
 	repository, err := mysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 	}
 
-	ctrl := controller.New(repository)
+	// likes between blocked users are rejected
+	tweetsService := tweetsGateway.New(fmt.Sprintf("localhost:%d", tweets_port))
+	followService := followGateway.New(fmt.Sprintf("localhost:%d", follow_port))
+	ctrl := controller.New(repository, tweetsService, followService)
 	h := httphandler.New(ctrl)
 
-	http.Handle("/like_tweet", http.HandlerFunc(h.Like))
-	http.Handle("/unlike_tweet", http.HandlerFunc(h.Unlike))
+	// personal access tokens are accepted only with the scope of the route
+	scoped := func(scope types.Scope, handler http.HandlerFunc) http.Handler {
+		return auth.AllowScope(scope, auth.Middleware(handler))
+	}
+	// creating content requires verified email
+	verified := func(scope types.Scope, handler http.HandlerFunc) http.Handler {
+		return auth.AllowScope(scope, auth.Middleware(auth.RequireVerified(handler)))
+	}
+	http.Handle("/like_tweet", verified(types.ScopeLikesWrite, h.Like))
+	http.Handle("/unlike_tweet", scoped(types.ScopeLikesWrite, h.Unlike))
 	http.Handle("/users_tweet", http.HandlerFunc(h.GetUsersByTweet))
 	http.Handle("/tweets_user", http.HandlerFunc(h.GetTweetsByUser))
 
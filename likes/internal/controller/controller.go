@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 )
 
@@ -12,12 +13,55 @@ type likesRepository interface {
 	GetTweetsByUser(ctx context.Context, userId types.UserId) ([]types.TweetId, error)
 }
 
+// tweets service is used to find author of the tweet
+type tweetsGateway interface {
+	GetAuthors(ctx context.Context, tweetIds ...types.TweetId) (map[types.TweetId]types.UserId, error)
+}
+
+// follow service is used to check that users did not block one another
+type followGateway interface {
+	GetRelationships(
+		ctx context.Context,
+		userId types.UserId,
+		targetIds ...types.UserId,
+	) (map[types.UserId]model.Relationship, error)
+}
+
 type Controller struct {
-	repo likesRepository
+	repo   likesRepository
+	tweets tweetsGateway
+	follow followGateway
+}
+
+func New(repo likesRepository, tweets tweetsGateway, follow followGateway) *Controller {
+	return &Controller{repo: repo, tweets: tweets, follow: follow}
 }
 
-func New(repo likesRepository) *Controller {
-	return &Controller{repo: repo}
+// check that user and author of the tweet did not block one another
+func (ctrl *Controller) checkBlocked(
+	ctx context.Context,
+	userId types.UserId,
+	tweetId types.TweetId,
+) error {
+	if ctrl.tweets == nil || ctrl.follow == nil {
+		return nil
+	}
+	authors, err := ctrl.tweets.GetAuthors(ctx, tweetId)
+	if err != nil {
+		return err
+	}
+	author, ok := authors[tweetId]
+	if !ok || author == userId {
+		return nil
+	}
+	relationships, err := ctrl.follow.GetRelationships(ctx, userId, author)
+	if err != nil {
+		return err
+	}
+	if relationships[author].Blocked() {
+		return ErrBlocked
+	}
+	return nil
 }
 
 func (ctrl *Controller) LikeTweet(
@@ -25,6 +69,9 @@ func (ctrl *Controller) LikeTweet(
 	userId types.UserId,
 	tweetId types.TweetId,
 ) error {
+	if err := ctrl.checkBlocked(ctx, userId, tweetId); err != nil {
+		return err
+	}
 	err := ctrl.repo.Like(ctx, userId, tweetId)
 	return err
 }
@@ -0,0 +1,6 @@
+package controller
+
+import "errors"
+
+// ErrBlocked is returned when author of the tweet and user blocked one another.
+var ErrBlocked = errors.New("user is blocked")
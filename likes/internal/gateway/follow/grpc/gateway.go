@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/follow"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"google.golang.org/grpc"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// get relationships of user to targets from follow service in one call
+func (g *Gateway) GetRelationships(
+	ctx context.Context,
+	userId types.UserId,
+	targetIds ...types.UserId,
+) (map[types.UserId]model.Relationship, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := &gen.GetRelationshipsRequest{UserId: int32(userId)}
+	for _, id := range targetIds {
+		request.TargetId = append(request.TargetId, int32(id))
+	}
+	client := gen.NewFollowServiceClient(conn)
+	response, err := client.GetRelationships(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	relationships := make(map[types.UserId]model.Relationship)
+	for _, protoRelationship := range response.Relationships {
+		relationship := model.RelationshipFromProto(protoRelationship)
+		relationships[relationship.UserId] = *relationship
+	}
+	return relationships, nil
+}
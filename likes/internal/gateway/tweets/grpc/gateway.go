@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"context"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/tweets"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// get authors of the tweets from tweets service, unknown tweets are skipped
+func (g *Gateway) GetAuthors(ctx context.Context, tweetIds ...types.TweetId) (map[types.TweetId]types.UserId, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := &gen.GetAuthorsRequest{}
+	for _, id := range tweetIds {
+		request.TweetId = append(request.TweetId, int32(id))
+	}
+	client := gen.NewTweetsServiceClient(conn)
+	response, err := client.GetAuthors(ctx, request)
+	if status.Code(err) == codes.NotFound {
+		return map[types.TweetId]types.UserId{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	authors := make(map[types.TweetId]types.UserId)
+	for _, author := range response.Authors {
+		authors[types.TweetId(author.GetTweetId())] = types.UserId(author.GetUserId())
+	}
+	return authors, nil
+}
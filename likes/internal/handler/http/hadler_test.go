@@ -5,7 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	mock_controller "github.com/alexvishnevskiy/twitter-clone/gen/controller/likes"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/likes/internal/controller"
 	"github.com/golang/mock/gomock"
@@ -17,29 +19,28 @@ import (
 )
 
 func TestHandler_Like(t *testing.T) {
-	ctx := context.Background()
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	// mock likes controller
 	mockLikesRepo := mock_controller.NewMocklikesRepository(mockCtrl)
 	mockLikesRepo.EXPECT().Like(ctx, types.UserId(1), types.TweetId(1)).Return(nil)
-	tweetCtrl := controller.New(mockLikesRepo)
+	tweetCtrl := controller.New(mockLikesRepo, nil, nil)
 	likesHandler := New(tweetCtrl)
 
 	// make json for body request
 	payloadBytes, err := json.Marshal(
 		struct {
-			UserId  string `json:"user_id"`
 			TweetId string `json:"tweet_id"`
-		}{"1", "1"},
+		}{"1"},
 	)
 	if err != nil {
 		log.Fatalf("Failed to marshal payload: %v", err)
 	}
 	body := bytes.NewReader(payloadBytes)
 
-	req, err := http.NewRequest("POST", "/like_tweet", body)
+	req, err := http.NewRequestWithContext(ctx, "POST", "/like_tweet", body)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -53,10 +54,47 @@ func TestHandler_Like(t *testing.T) {
 			"handler returned wrong status code",
 		)
 	}
+
+	// user is taken from token, not from request
+	req, err = http.NewRequest("POST", "/like_tweet", bytes.NewReader(payloadBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("unauthenticated request should be rejected, got status %d", status)
+	}
+}
+
+func TestHandler_LikeBlocked(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// author of the tweet blocked the user, like is not saved
+	mockLikesRepo := mock_controller.NewMocklikesRepository(mockCtrl)
+	mockTweets := mock_controller.NewMocktweetsGateway(mockCtrl)
+	mockTweets.EXPECT().GetAuthors(ctx, types.TweetId(1)).Return(map[types.TweetId]types.UserId{1: 2}, nil)
+	mockFollow := mock_controller.NewMockfollowGateway(mockCtrl)
+	mockFollow.EXPECT().GetRelationships(ctx, types.UserId(1), types.UserId(2)).Return(
+		map[types.UserId]model.Relationship{2: {UserId: 2, BlockedBy: true}}, nil,
+	)
+	likesHandler := New(controller.New(mockLikesRepo, mockTweets, mockFollow))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "/like_tweet", bytes.NewReader([]byte(`{"tweet_id": "1"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(likesHandler.Like).ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusForbidden {
+		t.Errorf("handler returned wrong status code %d", status)
+	}
 }
 
 func TestHandler_Unlike(t *testing.T) {
-	ctx := context.Background()
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1})
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
@@ -64,11 +102,11 @@ func TestHandler_Unlike(t *testing.T) {
 	mockLikesRepo := mock_controller.NewMocklikesRepository(mockCtrl)
 	mockLikesRepo.EXPECT().Unlike(ctx, types.UserId(1), types.TweetId(1)).Return(nil)
 	mockLikesRepo.EXPECT().Unlike(ctx, types.UserId(1), types.TweetId(3)).Return(errors.New(""))
-	tweetCtrl := controller.New(mockLikesRepo)
+	tweetCtrl := controller.New(mockLikesRepo, nil, nil)
 	likesHandler := New(tweetCtrl)
 
-	req, err := http.NewRequest("DELETE", "/unlike_tweet?user_id=1&tweet_id=1", nil)
-	req1, err1 := http.NewRequest("DELETE", "/unlike_tweet?user_id=1&tweet_id=3", nil)
+	req, err := http.NewRequestWithContext(ctx, "DELETE", "/unlike_tweet?tweet_id=1", nil)
+	req1, err1 := http.NewRequestWithContext(ctx, "DELETE", "/unlike_tweet?tweet_id=3", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -102,7 +140,7 @@ func TestHandler_GetTweetsByUser(t *testing.T) {
 
 	// mock tweet controller
 	mockLikesRepo := mock_controller.NewMocklikesRepository(mockCtrl)
-	tweetCtrl := controller.New(mockLikesRepo)
+	tweetCtrl := controller.New(mockLikesRepo, nil, nil)
 	likesHandler := New(tweetCtrl)
 
 	want := []types.TweetId{
@@ -149,7 +187,7 @@ func TestHandler_GetUsersByTweet(t *testing.T) {
 
 	// mock tweet controller
 	mockLikesRepo := mock_controller.NewMocklikesRepository(mockCtrl)
-	tweetCtrl := controller.New(mockLikesRepo)
+	tweetCtrl := controller.New(mockLikesRepo, nil, nil)
 	likesHandler := New(tweetCtrl)
 
 	want := []types.UserId{
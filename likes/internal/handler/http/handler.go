@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/likes/internal/controller"
 	"github.com/alexvishnevskiy/twitter-clone/likes/internal/repository/mysql"
@@ -18,7 +19,6 @@ type Handler struct {
 
 // Define the structure of the request body data.
 type PostRequest struct {
-	UserId  string `json:"user_id"`
 	TweetId string `json:"tweet_id"`
 }
 
@@ -28,11 +28,13 @@ func New(ctrl *controller.Controller) *Handler {
 
 // Like handle like request
 //
-//	@description	Like specific tweet
-//	@Param			user_id		body		int	true	"User ID"
+//	@description	Like specific tweet, email of the user should be verified
+//	@Security		BearerAuth
 //	@Param			tweet_id	body		int	true	"Tweet ID"
 //	@Success		200			{object}	int
 //	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		403			{object}	int
 //	@Failure		404			{object}	int
 //	@Failure		405			{object}	int
 //	@Failure		500			{object}	int
@@ -42,6 +44,10 @@ func (h *Handler) Like(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
+	userID, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
 	var requestData PostRequest
 
 	// read all request data
@@ -58,8 +64,8 @@ func (h *Handler) Like(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if requestData.UserId == "" && requestData.TweetId == "" {
-		http.Error(w, "user_id and tweet_id are empty", http.StatusBadRequest)
+	if requestData.TweetId == "" {
+		http.Error(w, "tweet_id is empty", http.StatusBadRequest)
 		return
 	}
 
@@ -70,15 +76,12 @@ func (h *Handler) Like(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	user, err := strconv.Atoi(requestData.UserId)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to parse user_id: %s", requestData.UserId), http.StatusBadRequest)
-		return
-	}
-
 	tweetID := types.TweetId(tweet)
-	userID := types.UserId(user)
 	err = h.ctrl.LikeTweet(req.Context(), userID, tweetID)
+	if err != nil && errors.Is(err, controller.ErrBlocked) {
+		http.Error(w, "author of the tweet is blocked", http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to like a tweet: %s", err), http.StatusInternalServerError)
 	}
@@ -87,10 +90,11 @@ func (h *Handler) Like(w http.ResponseWriter, req *http.Request) {
 // Unlike handle unlike request
 //
 //	@description	Unlike specific tweet
-//	@Param			user_id		body		int	true	"User ID"
-//	@Param			tweet_id	body		int	true	"Tweet ID"
+//	@Security		BearerAuth
+//	@Param			tweet_id	query		int	true	"Tweet ID"
 //	@Success		200			{object}	int
 //	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
 //	@Failure		404			{object}	int
 //	@Failure		405			{object}	int
 //	@Failure		500			{object}	int
@@ -100,6 +104,10 @@ func (h *Handler) Unlike(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
+	userID, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
 
 	// read body data
 	req_tweet := req.FormValue("tweet_id")
@@ -109,15 +117,7 @@ func (h *Handler) Unlike(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	req_user := req.FormValue("user_id")
-	user, err := strconv.Atoi(req_user)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("user_id is invalid: %s", req_user), http.StatusBadRequest)
-		return
-	}
-
 	tweetID := types.TweetId(tweet)
-	userID := types.UserId(user)
 	// make request to controller
 	err = h.ctrl.UnlikeTweet(req.Context(), userID, tweetID)
 	if err != nil {
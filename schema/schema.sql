@@ -3,12 +3,136 @@ USE twitter;
 
 CREATE TABLE IF NOT EXISTS User (
     user_id INT NOT NULL AUTO_INCREMENT,
-    nickname VARCHAR(15) NOT NULL UNIQUE ,
+    nickname VARCHAR(15) NOT NULL,
+    nickname_canonical VARCHAR(15) NOT NULL UNIQUE,
     first_name VARCHAR(10) NOT NULL,
     last_name VARCHAR(15) NOT NULL,
     email VARCHAR(20) NOT NULL UNIQUE ,
     password VARCHAR(60) NOT NULL,
-    PRIMARY KEY (user_id)
+    sensitive_media VARCHAR(5) NOT NULL DEFAULT 'blur',
+    bio VARCHAR(160) NOT NULL DEFAULT '',
+    location VARCHAR(30) NOT NULL DEFAULT '',
+    website VARCHAR(100) NOT NULL DEFAULT '',
+    birthday DATE NULL,
+    avatar_url VARCHAR(255) NULL,
+    header_url VARCHAR(255) NULL,
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    sessions_revoked_at TIMESTAMP NULL,
+    totp_secret VARCHAR(32) NULL,
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT NOT NULL DEFAULT 0,
+    protected BOOLEAN NOT NULL DEFAULT FALSE,
+    deactivated_at TIMESTAMP NULL,
+    role VARCHAR(10) NOT NULL DEFAULT 'user',
+    suspended_at TIMESTAMP NULL,
+    version INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id),
+    INDEX (deactivated_at)
+);
+
+CREATE TABLE IF NOT EXISTS NicknameHistory (
+    history_id INT NOT NULL AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    nickname_canonical VARCHAR(15) NOT NULL,
+    changed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (history_id),
+    INDEX (nickname_canonical),
+    INDEX (user_id),
+    INDEX (changed_at),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS PasswordResets (
+    token_hash CHAR(64) NOT NULL,
+    user_id INT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (token_hash),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS RefreshTokens (
+    token_hash CHAR(64) NOT NULL,
+    user_id INT NOT NULL,
+    family CHAR(32) NOT NULL,
+    used BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (token_hash),
+    INDEX (family),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Sessions (
+    session_id CHAR(32) NOT NULL,
+    user_id INT NOT NULL,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (session_id),
+    INDEX (user_id),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS RecoveryCodes (
+    user_id INT NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS RevokedTokens (
+    jti CHAR(32) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (jti)
+);
+
+CREATE TABLE IF NOT EXISTS DataExports (
+    export_id INT NOT NULL AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    status VARCHAR(8) NOT NULL DEFAULT 'pending',
+    token_hash CHAR(64) NULL UNIQUE,
+    storage_path VARCHAR(255) NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NULL,
+    PRIMARY KEY (export_id),
+    INDEX (user_id),
+    INDEX (expires_at),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS AccessTokens (
+    token_id INT NOT NULL AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    scopes VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP NULL,
+    PRIMARY KEY (token_id),
+    INDEX (user_id),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS AuditEvents (
+    event_id BIGINT NOT NULL AUTO_INCREMENT,
+    created_at TIMESTAMP NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    user_id INT NOT NULL DEFAULT 0,
+    actor_id INT NOT NULL DEFAULT 0,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    detail VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (event_id),
+    INDEX (user_id),
+    INDEX (actor_id),
+    INDEX (type)
+);
+
+CREATE TABLE IF NOT EXISTS UserLanguages (
+    user_id INT NOT NULL,
+    lang VARCHAR(8) NOT NULL,
+    PRIMARY KEY (user_id, lang),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
 );
 
 CREATE TABLE IF NOT EXISTS Tweets (
@@ -18,15 +142,45 @@ CREATE TABLE IF NOT EXISTS Tweets (
     content VARCHAR(500) NOT NULL,
     media_url VARCHAR(100) NULL,
     created_at TIMESTAMP NOT NULL,
+    reply_id INT NULL,
+    reply_policy VARCHAR(10) NOT NULL DEFAULT 'everyone',
+    lang VARCHAR(8) NOT NULL DEFAULT 'und',
+    sensitive BOOLEAN NOT NULL DEFAULT FALSE,
+    content_warning VARCHAR(100) NULL,
+    media_alt_text VARCHAR(1000) NULL,
+    entities JSON NULL,
     PRIMARY KEY (tweet_id),
     UNIQUE (user_id, retweet_id, content, media_url),
     FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE,
-    FOREIGN KEY (retweet_id) REFERENCES Tweets(tweet_id) ON DELETE CASCADE
+    FOREIGN KEY (retweet_id) REFERENCES Tweets(tweet_id) ON DELETE CASCADE,
+    FOREIGN KEY (reply_id) REFERENCES Tweets(tweet_id) ON DELETE SET NULL
 );
 
 CREATE INDEX idx_tweets_created_at
     ON Tweets (created_at);
 
+CREATE TABLE IF NOT EXISTS TweetReports (
+    tweet_id INT NOT NULL,
+    user_id INT NOT NULL,
+    reason VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP NULL,
+    resolved_by INT NULL,
+    resolution VARCHAR(10) NULL,
+    PRIMARY KEY (tweet_id, user_id),
+    INDEX (resolved_at),
+    FOREIGN KEY (tweet_id) REFERENCES Tweets(tweet_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Mentions (
+    tweet_id INT NOT NULL,
+    user_id INT NOT NULL,
+    PRIMARY KEY (tweet_id, user_id),
+    FOREIGN KEY (tweet_id) REFERENCES Tweets(tweet_id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS Likes (
     user_id INT NOT NULL,
     tweet_id INT NOT NULL,
@@ -42,3 +196,32 @@ CREATE TABLE IF NOT EXISTS Followers (
     FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE,
     FOREIGN KEY (following_id) REFERENCES User(user_id) ON DELETE CASCADE
 );
+
+CREATE TABLE IF NOT EXISTS FollowRequests (
+    user_id INT NOT NULL,
+    following_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, following_id),
+    INDEX (following_id),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (following_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Blocks (
+    user_id INT NOT NULL,
+    blocked_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, blocked_id),
+    INDEX (blocked_id),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Mutes (
+    user_id INT NOT NULL,
+    muted_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, muted_id),
+    FOREIGN KEY (user_id) REFERENCES User(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (muted_id) REFERENCES User(user_id) ON DELETE CASCADE
+);
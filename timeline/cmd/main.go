@@ -3,9 +3,14 @@ package main
 import (
 	"flag"
 	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	authmysql "github.com/alexvishnevskiy/twitter-clone/internal/auth/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/timeline/internal/controller"
 	followGateway "github.com/alexvishnevskiy/twitter-clone/timeline/internal/gateway/follow/grpc"
 	tweetsGateway "github.com/alexvishnevskiy/twitter-clone/timeline/internal/gateway/tweets/grpc"
+	usersGateway "github.com/alexvishnevskiy/twitter-clone/timeline/internal/gateway/users/grpc"
 	httphandler "github.com/alexvishnevskiy/twitter-clone/timeline/internal/handler/http"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"log"
@@ -16,24 +21,43 @@ import (
 //	@version		1.0.0
 //	@host			localhost:8083
 //	@description	This is API for timeline service
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
 func main() {
 	var (
 		port        int
 		tweets_port int
 		follow_port int
+		users_port  int
 	)
 	flag.IntVar(&port, "port", 8083, "API handler port")
 	flag.IntVar(&tweets_port, "tweets_port", 8080, "tweets API handler port")
 	flag.IntVar(&follow_port, "follow_port", 8082, "follow API handler port")
+	flag.IntVar(&users_port, "users_port", 8084, "users API handler port")
 	flag.Parse()
 	log.Printf("Starting timeline service on port %d", port)
 
+	// tokens are verified with public keys published by users service
+	jwt.SetKeySource(jwt.NewRemoteKeySet(fmt.Sprintf("http://localhost:%d%s", users_port, jwt.JWKSPath)))
+
+	// access tokens are checked in the database shared with users service
+	tokenStore, err := authmysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
+	if err != nil {
+		log.Fatalf("failed to open access tokens: %v", err)
+	}
+	auth.SetTokenStore(tokenStore)
+	// revoked access tokens and sessions are shared through the database
+	auth.SetSessionStore(tokenStore)
+	jwt.SetDenylist(tokenStore)
+
 	tweetsService := tweetsGateway.New(fmt.Sprintf("localhost:%d", tweets_port))
 	followService := followGateway.New(fmt.Sprintf("localhost:%d", follow_port))
-	ctrl := controller.New(tweetsService, followService)
+	usersService := usersGateway.New(fmt.Sprintf("localhost:%d", users_port))
+	ctrl := controller.New(tweetsService, followService, usersService)
 	h := httphandler.New(ctrl)
 
-	http.Handle("/home_timeline", http.HandlerFunc(h.GetHomeTimeline))
+	http.Handle("/home_timeline", auth.AllowScope(types.ScopeRead, auth.Middleware(http.HandlerFunc(h.GetHomeTimeline))))
 	http.HandleFunc("/swagger/", httpSwagger.WrapHandler)
 	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil); err != nil {
 		panic(err)
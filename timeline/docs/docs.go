@@ -16,186 +16,23 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/delete_tweet": {
-            "delete": {
-                "description": "Delete by tweet_id",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Tweet ID",
-                        "name": "tweet_id",
-                        "in": "query",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "405": {
-                        "description": "Method Not Allowed",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    }
-                }
-            }
-        },
         "/home_timeline": {
             "get": {
-                "description": "Retrieve home timeline",
-                "parameters": [
+                "security": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "user_id",
-                        "in": "query",
-                        "required": true
+                        "BearerAuth": []
                     }
                 ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.Media"
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "405": {
-                        "description": "Method Not Allowed",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    }
-                }
-            }
-        },
-        "/post_tweet": {
-            "post": {
-                "description": "Post tweet",
+                "description": "Retrieve home timeline of the authenticated user",
                 "parameters": [
                     {
-                        "description": "User ID",
-                        "name": "user_id",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    {
-                        "description": "Content",
-                        "name": "content",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
+                        "type": "array",
+                        "items": {
                             "type": "string"
-                        }
-                    },
-                    {
-                        "description": "Retweet ID",
-                        "name": "retweet_id",
-                        "in": "body",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    {
-                        "type": "file",
-                        "description": "Media",
-                        "name": "media",
-                        "in": "formData"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "405": {
-                        "description": "Method Not Allowed",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    }
-                }
-            }
-        },
-        "/retrieve_tweet": {
-            "get": {
-                "description": "Retrieve either by tweet_id or user_id",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "user_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Tweet ID",
-                        "name": "tweet_id",
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Keep only tweets in these languages, preferred languages are used by default",
+                        "name": "lang",
                         "in": "query"
                     }
                 ],
@@ -215,6 +52,12 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -238,20 +81,91 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "model.Entity": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/model.EntityType"
+                }
+            }
+        },
+        "model.EntityType": {
+            "type": "string",
+            "enum": [
+                "url",
+                "mention",
+                "hashtag",
+                "cashtag"
+            ],
+            "x-enum-varnames": [
+                "EntityUrl",
+                "EntityMention",
+                "EntityHashtag",
+                "EntityCashtag"
+            ]
+        },
         "model.Media": {
             "type": "object",
             "properties": {
+                "alt_text": {
+                    "type": "string"
+                },
+                "blurred": {
+                    "type": "boolean"
+                },
                 "content": {
                     "type": "string"
                 },
+                "content_warning": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "entities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Entity"
+                    }
+                },
+                "lang": {
+                    "type": "string"
+                },
                 "media": {
                     "type": "string"
+                },
+                "reply_id": {
+                    "description": "set for replies and retweets",
+                    "type": "integer"
+                },
+                "retweet_id": {
+                    "type": "integer"
+                },
+                "sensitive": {
+                    "description": "set by author or moderator, blurred is set by timeline according to user preferences",
+                    "type": "boolean"
+                },
+                "tweet_id": {
+                    "type": "integer"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -2,37 +2,115 @@ package controller
 
 import (
 	"context"
+	followmodel "github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	usersmodel "github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
 )
 
 type tweetsGateway interface {
-	GetTweets(ctx context.Context, userId ...types.UserId) ([]model.Media, error)
+	GetTweets(ctx context.Context, langs []string, userId ...types.UserId) ([]model.Media, error)
 }
 
 type followGateway interface {
 	GetUsers(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+	GetRelationships(
+		ctx context.Context,
+		userId types.UserId,
+		targetIds ...types.UserId,
+	) (map[types.UserId]followmodel.Relationship, error)
+}
+
+type usersGateway interface {
+	GetPreferredLanguages(ctx context.Context, userId types.UserId) ([]string, error)
+	GetSensitiveMedia(ctx context.Context, userId types.UserId) (usersmodel.SensitiveMedia, error)
 }
 
 // controller for timeline
 type Controller struct {
 	TweetsService tweetsGateway
 	FollowService followGateway
+	UsersService  usersGateway
+}
+
+func New(tweets tweetsGateway, follow followGateway, users usersGateway) *Controller {
+	return &Controller{tweets, follow, users}
+}
+
+// hide or blur sensitive media according to user preference
+func applySensitiveMedia(tweets []model.Media, preference usersmodel.SensitiveMedia) []model.Media {
+	if preference == usersmodel.SensitiveShow {
+		return tweets
+	}
+
+	res := make([]model.Media, 0, len(tweets))
+	for _, tweet := range tweets {
+		if tweet.Sensitive {
+			if preference == usersmodel.SensitiveHide {
+				continue
+			}
+			tweet.Blurred = true
+		}
+		res = append(res, tweet)
+	}
+	return res
 }
 
-func New(tweets tweetsGateway, follow followGateway) *Controller {
-	return &Controller{tweets, follow}
+// drop users that are muted or blocked by user, mutes affect home timeline only
+func (ctrl *Controller) withoutMuted(ctx context.Context, userId types.UserId, users []types.UserId) ([]types.UserId, error) {
+	if len(users) == 0 {
+		return users, nil
+	}
+	relationships, err := ctrl.FollowService.GetRelationships(ctx, userId, users...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]types.UserId, 0, len(users))
+	for _, user := range users {
+		relationship := relationships[user]
+		if !relationship.Muting && !relationship.Blocked() {
+			res = append(res, user)
+		}
+	}
+	return res, nil
 }
 
 // get all tweets from the users who this user is following
-func (ctrl *Controller) GetHomeTimeline(ctx context.Context, userId types.UserId) ([]model.Media, error) {
+// if langs are empty, preferred languages of the user are used
+func (ctrl *Controller) GetHomeTimeline(ctx context.Context, userId types.UserId, langs []string) ([]model.Media, error) {
 	users, err := ctrl.FollowService.GetUsers(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
+	users, err = ctrl.withoutMuted(ctx, userId, users)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return []model.Media{}, nil
+	}
+
+	// sensitive media is blurred by default
+	preference := usersmodel.SensitiveBlur
+	if ctrl.UsersService != nil {
+		if len(langs) == 0 {
+			langs, err = ctrl.UsersService.GetPreferredLanguages(ctx, userId)
+			if err != nil {
+				return nil, err
+			}
+		}
+		preference, err = ctrl.UsersService.GetSensitiveMedia(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	tweets, err := ctrl.TweetsService.GetTweets(ctx, users...)
-	return tweets, err
+	tweets, err := ctrl.TweetsService.GetTweets(ctx, langs, users...)
+	if err != nil {
+		return nil, err
+	}
+	return applySensitiveMedia(tweets, preference), nil
 }
 
 func (ctrl *Controller) GetMentionsTimeline(ctx context.Context, userId types.UserId) ([]model.Tweet, error) {
@@ -3,6 +3,7 @@ package grpc
 import (
 	"context"
 	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/tweets"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
 	"google.golang.org/grpc"
@@ -16,10 +17,10 @@ func New(url string) *Gateway {
 	return &Gateway{url}
 }
 
-// get tweets from tweets service using user_ids
-func (g *Gateway) GetTweets(ctx context.Context, userId ...types.UserId) ([]model.Media, error) {
-	// Set up the connection to the gRPC server.
-	conn, err := grpc.Dial(g.Url, grpc.WithInsecure())
+// get tweets from tweets service using user_ids, langs filter tweets by language
+func (g *Gateway) GetTweets(ctx context.Context, langs []string, userId ...types.UserId) ([]model.Media, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +33,7 @@ func (g *Gateway) GetTweets(ctx context.Context, userId ...types.UserId) ([]mode
 		users[i] = int32(user)
 	}
 	// retrieve tweets
-	response, err := client.Retrieve(ctx, &gen.RetrieveRequest{UserId: users})
+	response, err := client.Retrieve(ctx, &gen.RetrieveRequest{UserId: users, Lang: langs})
 	if err != nil {
 		return nil, err
 	}
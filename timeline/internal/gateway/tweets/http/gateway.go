@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
 	"net/http"
@@ -20,8 +21,8 @@ func New(url string) *Gateway {
 	return &Gateway{url}
 }
 
-// get tweets from tweets service
-func (g *Gateway) GetTweets(ctx context.Context, userId ...types.UserId) ([]model.Media, error) {
+// get tweets from tweets service, langs filter tweets by language
+func (g *Gateway) GetTweets(ctx context.Context, langs []string, userId ...types.UserId) ([]model.Media, error) {
 	base, _ := url.Parse(g.Url)
 	newURL, _ := url.Parse(path.Join(base.Path, "/retrieve_tweet"))
 	base = base.ResolveReference(newURL)
@@ -33,10 +34,15 @@ func (g *Gateway) GetTweets(ctx context.Context, userId ...types.UserId) ([]mode
 	}
 
 	req = req.WithContext(ctx)
+	// protected tweets are shown only to approved followers
+	auth.ForwardToken(req)
 	values := req.URL.Query()
 	for _, user := range userId {
 		values.Add("user_id", strconv.Itoa(int(user)))
 	}
+	for _, lang := range langs {
+		values.Add("lang", lang)
+	}
 	req.URL.RawQuery = values.Encode()
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
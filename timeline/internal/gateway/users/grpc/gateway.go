@@ -0,0 +1,50 @@
+package grpc
+
+import (
+	"context"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/users"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"google.golang.org/grpc"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// helper function to get settings of the user from users service, the user is taken
+// from forwarded token, so settings of other users can't be requested
+func (g *Gateway) getPreferences(ctx context.Context) (*gen.Preferences, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := gen.NewUsersServiceClient(conn)
+	return client.GetPreferences(ctx, &gen.GetPreferencesRequest{})
+}
+
+// get preferred languages of the user from users service
+func (g *Gateway) GetPreferredLanguages(ctx context.Context, _ types.UserId) ([]string, error) {
+	preferences, err := g.getPreferences(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return preferences.Languages, nil
+}
+
+// get sensitive media preference of the user from users service
+func (g *Gateway) GetSensitiveMedia(ctx context.Context, _ types.UserId) (model.SensitiveMedia, error) {
+	preferences, err := g.getPreferences(ctx)
+	if err != nil {
+		return "", err
+	}
+	return model.SensitiveMedia(preferences.SensitiveMedia), nil
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"net/http"
+	"net/url"
+	"path"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// helper function to get settings of the user from users service and decode them to res,
+// the user is taken from forwarded token, so settings of other users can't be requested
+func (g *Gateway) get(ctx context.Context, endpoint string, res interface{}) error {
+	base, err := url.Parse(g.Url)
+	if err != nil {
+		return err
+	}
+	base.Path = path.Join(base.Path, endpoint)
+
+	req, err := http.NewRequest(http.MethodGet, base.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+	auth.ForwardToken(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("non-2xx response: %v", resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(res)
+}
+
+// get preferred languages of the user from users service
+func (g *Gateway) GetPreferredLanguages(ctx context.Context, _ types.UserId) ([]string, error) {
+	var langs []string
+	err := g.get(ctx, "/preferred_languages", &langs)
+	return langs, err
+}
+
+// get sensitive media preference of the user from users service
+func (g *Gateway) GetSensitiveMedia(ctx context.Context, _ types.UserId) (model.SensitiveMedia, error) {
+	var preference model.SensitiveMedia
+	err := g.get(ctx, "/sensitive_media", &preference)
+	return preference, err
+}
@@ -3,12 +3,11 @@ package http
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	_ "github.com/alexvishnevskiy/twitter-clone/timeline/docs"
 	"github.com/alexvishnevskiy/twitter-clone/timeline/internal/controller"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
 	"net/http"
-	"strconv"
 )
 
 type Hanlder struct {
@@ -21,10 +20,12 @@ func New(ctrl *controller.Controller) *Hanlder {
 
 // GetHomeTimeline get all tweets from the users who this user is following
 //
-//	@description	Retrieve home timeline
-//	@Param			user_id	query		int	true	"User ID"
+//	@description	Retrieve home timeline of the authenticated user
+//	@Security		BearerAuth
+//	@Param			lang	query		[]string	false	"Keep only tweets in these languages, preferred languages are used by default"
 //	@Success		200		{object}	[]model.Media
 //	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
 //	@Failure		404		{object}	int
 //	@Failure		405		{object}	int
 //	@Failure		500		{object}	int
@@ -36,17 +37,18 @@ func (h *Hanlder) GetHomeTimeline(w http.ResponseWriter, req *http.Request) {
 	}
 	var tweets []model.Media
 
-	// get user_id
-	user := req.FormValue("user_id")
-	user_id, err := strconv.Atoi(user)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("user_id is invalid %s", user), http.StatusBadRequest)
+	// timeline of the authenticated user
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// retrieve timeline
-	userId := types.UserId(user_id)
-	tweets, err = h.ctrl.GetHomeTimeline(req.Context(), userId)
+	tweets, err := h.ctrl.GetHomeTimeline(req.Context(), userId, req.Form["lang"])
 	if err != nil {
 		http.Error(w, fmt.Sprintf("%s", err), http.StatusInternalServerError)
 		return
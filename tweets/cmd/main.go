@@ -4,10 +4,19 @@ import (
 	"flag"
 	"fmt"
 	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/tweets"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	auditfile "github.com/alexvishnevskiy/twitter-clone/internal/audit/file"
+	auditmysql "github.com/alexvishnevskiy/twitter-clone/internal/audit/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	authmysql "github.com/alexvishnevskiy/twitter-clone/internal/auth/mysql"
 	localcache "github.com/alexvishnevskiy/twitter-clone/internal/cache/local"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
 	"github.com/alexvishnevskiy/twitter-clone/internal/storage/local"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	_ "github.com/alexvishnevskiy/twitter-clone/tweets/docs"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/internal/controller"
+	followGateway "github.com/alexvishnevskiy/twitter-clone/tweets/internal/gateway/follow/grpc"
+	usersGateway "github.com/alexvishnevskiy/twitter-clone/tweets/internal/gateway/users/grpc"
 	grpchandler "github.com/alexvishnevskiy/twitter-clone/tweets/internal/handler/grpc"
 	httphandler "github.com/alexvishnevskiy/twitter-clone/tweets/internal/handler/http"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/internal/repository/mysql"
@@ -18,33 +27,72 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"os"
 )
 
 // @title			Tweets API documentation
 // @version		1.0.0
 // @host			localhost:8080
 // @description	This is API for tweets service
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
 func main() {
 	var (
 		port        int
+		follow_port int
+		users_port  int
 		capacity    int
 		storagePath string
+		auditPath   string
 	)
 	flag.IntVar(&port, "port", 8080, "API handler port")
+	flag.IntVar(&follow_port, "follow_port", 8082, "follow API handler port")
+	flag.IntVar(&users_port, "users_port", 8084, "users API handler port")
 	flag.IntVar(&capacity, "capacity", 5000, "Capacity of cache")
 	flag.StringVar(&storagePath, "storage_path", getStoragePath(), "storage path")
+	flag.StringVar(&auditPath, "audit_path", "", "file to write audit events to, stderr if empty")
 	flag.Parse()
 	log.Printf("Starting the tweets service on port %d", port)
 
+	// tokens are verified with public keys published by users service
+	jwt.SetKeySource(jwt.NewRemoteKeySet(fmt.Sprintf("http://localhost:%d%s", users_port, jwt.JWKSPath)))
+
+	// access tokens are checked in the database shared with users service
+	tokenStore, err := authmysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
+	if err != nil {
+		log.Fatalf("failed to open access tokens: %v", err)
+	}
+	auth.SetTokenStore(tokenStore)
+	// revoked access tokens and sessions are shared through the database
+	auth.SetSessionStore(tokenStore)
+	jwt.SetDenylist(tokenStore)
+
 	// probably use configs for driverName and dataSourceName
 	repository, err := mysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
 	if err != nil {
 		log.Printf("Error: %v\n", err)
 	}
 
+	// moderation is written locally and to the history shared with users service
+	var recorder audit.Recorder = auditfile.NewWriter(os.Stderr)
+	if auditPath != "" {
+		recorder, err = auditfile.New(auditPath)
+		if err != nil {
+			log.Fatalf("failed to open audit file: %v", err)
+		}
+	}
+	auditLog, err := auditmysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
+	if err != nil {
+		log.Fatalf("failed to open audit log: %v", err)
+	}
+	recorder = audit.Multi(recorder, auditLog)
+
 	storage := local.New(storagePath)
 	cache := localcache.New(capacity)
-	ctrl := controller.New(repository, storage, cache)
+	followService := followGateway.New(fmt.Sprintf("localhost:%d", follow_port))
+	usersService := usersGateway.New(fmt.Sprintf("localhost:%d", users_port))
+	ctrl := controller.New(repository, storage, cache, followService, usersService, recorder)
 
 	// setup the main listener
 	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
@@ -59,7 +107,7 @@ func main() {
 	httpL := m.Match(cmux.HTTP1Fast())
 
 	// grpc and http server
-	srv := grpc.NewServer()
+	srv := grpc.NewServer(auth.ServerOptions()...)
 	reflection.Register(srv)
 	httpS := &http.Server{}
 
@@ -73,9 +121,27 @@ func main() {
 	gen.RegisterTweetsServiceServer(srv, grpch)
 	// http handler
 	httph := httphandler.New(ctrl)
-	http.Handle("/post_tweet", http.HandlerFunc(httph.Post))
-	http.Handle("/retrieve_tweet", http.HandlerFunc(httph.Retrieve))
-	http.Handle("/delete_tweet", http.HandlerFunc(httph.Delete))
+	// personal access tokens are accepted only with the scope of the route
+	scoped := func(scope types.Scope, handler http.HandlerFunc) http.Handler {
+		return auth.AllowScope(scope, auth.Middleware(handler))
+	}
+	// creating content requires verified email
+	verified := func(scope types.Scope, handler http.HandlerFunc) http.Handler {
+		return auth.AllowScope(scope, auth.Middleware(auth.RequireVerified(handler)))
+	}
+	http.Handle("/post_tweet", verified(types.ScopeTweetsWrite, httph.Post))
+	http.Handle("/retrieve_tweet", auth.AllowScope(types.ScopeRead, auth.OptionalMiddleware(http.HandlerFunc(httph.Retrieve))))
+	http.Handle("/delete_tweet", scoped(types.ScopeTweetsWrite, httph.Delete))
+	http.Handle("/mark_sensitive", scoped(types.ScopeTweetsWrite, httph.MarkSensitive))
+	http.Handle("/update_alt_text", scoped(types.ScopeTweetsWrite, httph.UpdateAltText))
+	http.Handle("/report_tweet", scoped(types.ScopeTweetsWrite, httph.ReportTweet))
+	// moderation requires moderator role
+	moderated := func(handler http.HandlerFunc) http.Handler {
+		return auth.Middleware(auth.RequireRole(types.RoleModerator, handler))
+	}
+	http.Handle("/admin/reports", moderated(httph.ReportedTweets))
+	http.Handle("/admin/resolve_report", moderated(httph.ResolveReports))
+	http.Handle("/admin/delete_tweet", moderated(httph.ForceDelete))
 	http.HandleFunc("/swagger/", httpSwagger.WrapHandler)
 	// Start serving!
 	log.Fatal(m.Serve())
@@ -16,9 +16,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/delete_tweet": {
+        "/admin/delete_tweet": {
             "delete": {
-                "description": "Delete by tweet_id",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete tweet of any user, requires moderator role",
                 "parameters": [
                     {
                         "type": "integer",
@@ -26,6 +31,12 @@ const docTemplate = `{
                         "name": "tweet_id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reason that is kept in audit history",
+                        "name": "reason",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -41,6 +52,18 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -62,19 +85,279 @@ const docTemplate = `{
                 }
             }
         },
-        "/post_tweet": {
+        "/admin/reports": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List tweets with open reports, most reported first, requires moderator role",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.ReportedTweet"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/resolve_report": {
             "post": {
-                "description": "Post tweet",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Close open reports of the tweet, the tweet is kept, marked sensitive or deleted. Requires moderator role",
                 "parameters": [
                     {
-                        "description": "User ID",
-                        "name": "user_id",
+                        "description": "Tweet ID",
+                        "name": "tweet_id",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "type": "integer"
                         }
                     },
+                    {
+                        "description": "dismiss, sensitive or delete",
+                        "name": "resolution",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/delete_tweet": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete by tweet_id, only author can delete tweet",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Tweet ID",
+                        "name": "tweet_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/mark_sensitive": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set sensitive flag and content warning after posting, only author or moderator can edit tweet",
+                "parameters": [
+                    {
+                        "description": "Tweet ID",
+                        "name": "tweet_id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Sensitive media",
+                        "name": "sensitive",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "boolean"
+                        }
+                    },
+                    {
+                        "description": "Content warning, implies sensitive",
+                        "name": "content_warning",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/post_tweet": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Post tweet of the authenticated user either as json body or as multipart form with media, email of the user should be verified",
+                "parameters": [
                     {
                         "description": "Content",
                         "name": "content",
@@ -92,11 +375,49 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
+                    {
+                        "description": "Reply ID",
+                        "name": "reply_id",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Reply policy: everyone, following or mentioned",
+                        "name": "reply_policy",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Sensitive media",
+                        "name": "sensitive",
+                        "in": "body",
+                        "schema": {
+                            "type": "boolean"
+                        }
+                    },
+                    {
+                        "description": "Content warning, implies sensitive",
+                        "name": "content_warning",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     {
                         "type": "file",
                         "description": "Media",
                         "name": "media",
                         "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Media description for screen readers",
+                        "name": "alt_text",
+                        "in": "formData"
                     }
                 ],
                 "responses": {
@@ -112,6 +433,85 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/report_tweet": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Report tweet to moderators, reporting the same tweet again replaces the reason",
+                "parameters": [
+                    {
+                        "description": "Tweet ID",
+                        "name": "tweet_id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Reason of the report",
+                        "name": "reason",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -148,6 +548,16 @@ const docTemplate = `{
                         "description": "Tweet ID",
                         "name": "tweet_id",
                         "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Keep only tweets in these languages",
+                        "name": "lang",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -156,7 +566,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.Media"
+                                "$ref": "#/definitions/github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Media"
                             }
                         }
                     },
@@ -186,23 +596,262 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/update_alt_text": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Edit media description after posting, only author can edit tweet",
+                "parameters": [
+                    {
+                        "description": "Tweet ID",
+                        "name": "tweet_id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Media description for screen readers",
+                        "name": "alt_text",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
-        "model.Media": {
+        "github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Entity": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.EntityType"
+                }
+            }
+        },
+        "github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.EntityType": {
+            "type": "string",
+            "enum": [
+                "url",
+                "mention",
+                "hashtag",
+                "cashtag"
+            ],
+            "x-enum-varnames": [
+                "EntityUrl",
+                "EntityMention",
+                "EntityHashtag",
+                "EntityCashtag"
+            ]
+        },
+        "github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Media": {
             "type": "object",
             "properties": {
+                "alt_text": {
+                    "type": "string"
+                },
+                "blurred": {
+                    "type": "boolean"
+                },
                 "content": {
                     "type": "string"
                 },
+                "content_warning": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "entities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Entity"
+                    }
+                },
+                "lang": {
+                    "type": "string"
+                },
                 "media": {
                     "type": "string"
+                },
+                "reply_id": {
+                    "description": "set for replies and retweets",
+                    "type": "integer"
+                },
+                "retweet_id": {
+                    "type": "integer"
+                },
+                "sensitive": {
+                    "description": "set by author or moderator, blurred is set by timeline according to user preferences",
+                    "type": "boolean"
+                },
+                "tweet_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.ReplyPolicy": {
+            "type": "string",
+            "enum": [
+                "everyone",
+                "following",
+                "mentioned"
+            ],
+            "x-enum-varnames": [
+                "ReplyEveryone",
+                "ReplyFollowing",
+                "ReplyMentioned"
+            ]
+        },
+        "github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Report": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "tweet_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.ReportedTweet": {
+            "type": "object",
+            "properties": {
+                "reports": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Report"
+                    }
+                },
+                "tweet": {
+                    "$ref": "#/definitions/github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Tweet"
+                }
+            }
+        },
+        "github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Tweet": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "content_warning": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "entities": {
+                    "description": "links, mentions, hashtags and cashtags parsed from content",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.Entity"
+                    }
+                },
+                "lang": {
+                    "type": "string"
+                },
+                "media_alt_text": {
+                    "description": "description of media for screen readers",
+                    "type": "string"
+                },
+                "media_url": {
+                    "type": "string"
+                },
+                "reply_id": {
+                    "type": "integer"
+                },
+                "reply_policy": {
+                    "$ref": "#/definitions/github_com_alexvishnevskiy_twitter-clone_tweets_pkg_model.ReplyPolicy"
+                },
+                "retweet_id": {
+                    "type": "integer"
+                },
+                "sensitive": {
+                    "description": "sensitive media is hidden behind content warning",
+                    "type": "boolean"
+                },
+                "tweet_id": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
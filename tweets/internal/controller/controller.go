@@ -3,23 +3,59 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	followmodel "github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	cachestorage "github.com/alexvishnevskiy/twitter-clone/internal/cache"
+	"github.com/alexvishnevskiy/twitter-clone/internal/lang"
 	"github.com/alexvishnevskiy/twitter-clone/internal/storage"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	usersmodel "github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"log"
 	"mime/multipart"
 	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // defines abstract methods for business logic (ex. handling requests to database)
 // message communication
 
 type tweetsRepository interface {
-	Put(ctx context.Context, userId types.UserId, content string, mediaUrl *string, retweetId *types.TweetId) (types.TweetId, time.Time, error)
+	Put(ctx context.Context, tweet model.Tweet) (types.TweetId, time.Time, error)
 	GetByTweet(ctx context.Context, tweetIds ...types.TweetId) ([]model.Tweet, error)
 	GetByUser(ctx context.Context, userIds ...types.UserId) ([]model.Tweet, error)
 	DeletePost(ctx context.Context, postId types.TweetId) error
+	UpdateSensitive(ctx context.Context, tweetId types.TweetId, sensitive bool, contentWarning *string) error
+	UpdateAltText(ctx context.Context, tweetId types.TweetId, altText *string) error
+	GetMentions(ctx context.Context, tweetId types.TweetId) ([]types.UserId, error)
+	PutReport(ctx context.Context, report model.Report) error
+	GetOpenReports(ctx context.Context) ([]model.Report, error)
+	ResolveReports(
+		ctx context.Context,
+		tweetId types.TweetId,
+		resolvedBy types.UserId,
+		resolution model.Resolution,
+		resolvedAt time.Time,
+	) error
+}
+
+// follow service is used to check reply policy and relationships of viewer to authors
+type followGateway interface {
+	GetUsers(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+	GetRelationships(
+		ctx context.Context,
+		userId types.UserId,
+		targetIds ...types.UserId,
+	) (map[types.UserId]followmodel.Relationship, error)
+}
+
+// users service is used to hide tweets of protected and deactivated accounts
+type usersGateway interface {
+	GetProfiles(ctx context.Context, userIds ...types.UserId) (map[types.UserId]usersmodel.Profile, error)
 }
 
 // controller for tweets
@@ -27,11 +63,42 @@ type Controller struct {
 	repo    tweetsRepository
 	storage storage.Storage
 	cache   cachestorage.Cache
+	follow  followGateway
+	users   usersGateway
+	// moderation is recorded if it is set
+	audit audit.Recorder
 }
 
 // Creates new tweets controller
-func New(repo tweetsRepository, storage storage.Storage, cache cachestorage.Cache) *Controller {
-	return &Controller{repo, storage, cache}
+func New(
+	repo tweetsRepository,
+	storage storage.Storage,
+	cache cachestorage.Cache,
+	follow followGateway,
+	users usersGateway,
+	audit audit.Recorder,
+) *Controller {
+	return &Controller{repo, storage, cache, follow, users, audit}
+}
+
+// record audit event, failure to record doesn't fail the request
+func (ctrl *Controller) record(ctx context.Context, event audit.Event) {
+	if ctrl.audit == nil {
+		return
+	}
+	if err := ctrl.audit.Record(ctx, event); err != nil {
+		log.Printf("failed to record audit event %s: %v", event.Type, err)
+	}
+}
+
+// check if user id is in the list
+func containsUser(users []types.UserId, userId types.UserId) bool {
+	for _, user := range users {
+		if user == userId {
+			return true
+		}
+	}
+	return false
 }
 
 // check one user_id from cache for tweets
@@ -145,40 +212,199 @@ func putTweetIdToCache(cache cachestorage.Cache, tweetId types.TweetId, tweet mo
 	return err
 }
 
+// get parent tweet either from cache or from db
+func (ctrl *Controller) getTweet(ctx context.Context, tweetId types.TweetId) (model.Tweet, error) {
+	if ctrl.cache != nil {
+		if tweet, err := getTweetIdFromCache(ctrl.cache, tweetId); err == nil {
+			return tweet, nil
+		}
+	}
+	tweets, err := ctrl.repo.GetByTweet(ctx, tweetId)
+	if err != nil {
+		return model.Tweet{}, err
+	}
+	return tweets[0], nil
+}
+
+// keep tweets that viewer from context is allowed to see, tweets of protected
+// accounts are shown only to the author and approved followers, blocked users
+// do not see each other's tweets, tweets of deactivated accounts are hidden
+func (ctrl *Controller) visibleTweets(ctx context.Context, tweets []model.Tweet) ([]model.Tweet, error) {
+	viewer, authenticated := auth.UserId(ctx)
+	seen := make(map[types.UserId]bool)
+	var authors []types.UserId
+	for _, tweet := range tweets {
+		if !seen[tweet.UserId] && !(authenticated && tweet.UserId == viewer) {
+			seen[tweet.UserId] = true
+			authors = append(authors, tweet.UserId)
+		}
+	}
+	if len(authors) == 0 {
+		return tweets, nil
+	}
+
+	// fail closed, hidden tweets must not leak when other services are down
+	var (
+		profiles      map[types.UserId]usersmodel.Profile
+		relationships map[types.UserId]followmodel.Relationship
+		err           error
+	)
+	if ctrl.users != nil {
+		if profiles, err = ctrl.users.GetProfiles(ctx, authors...); err != nil {
+			return nil, err
+		}
+	}
+	if authenticated && ctrl.follow != nil {
+		if relationships, err = ctrl.follow.GetRelationships(ctx, viewer, authors...); err != nil {
+			return nil, err
+		}
+	}
+
+	visible := make([]model.Tweet, 0, len(tweets))
+	for _, tweet := range tweets {
+		if authenticated && tweet.UserId == viewer {
+			visible = append(visible, tweet)
+			continue
+		}
+		relationship := relationships[tweet.UserId]
+		if relationship.Blocked() {
+			continue
+		}
+		if profiles != nil {
+			profile, active := profiles[tweet.UserId]
+			if !active || (profile.Protected && !relationship.Following) {
+				continue
+			}
+		}
+		visible = append(visible, tweet)
+	}
+	return visible, nil
+}
+
+// check that user is allowed to reply to the tweet
+func (ctrl *Controller) checkReplyPolicy(ctx context.Context, userId types.UserId, replyId types.TweetId) error {
+	parent, err := ctrl.getTweet(ctx, replyId)
+	if err != nil {
+		return err
+	}
+	// author can always reply to own tweet
+	if parent.UserId == userId {
+		return nil
+	}
+	// tweets of protected accounts or blocked users can be replied only by those who see them
+	visible, err := ctrl.visibleTweets(ctx, []model.Tweet{parent})
+	if err != nil {
+		return err
+	}
+	if len(visible) == 0 {
+		return ErrReplyNotAllowed
+	}
+
+	var allowed []types.UserId
+	switch parent.ReplyPolicy {
+	case model.ReplyFollowing:
+		if ctrl.follow == nil {
+			return ErrReplyNotAllowed
+		}
+		allowed, err = ctrl.follow.GetUsers(ctx, parent.UserId)
+	case model.ReplyMentioned:
+		allowed, err = ctrl.repo.GetMentions(ctx, parent.TweetId)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !containsUser(allowed, userId) {
+		return ErrReplyNotAllowed
+	}
+	return nil
+}
+
+// validate content warning, empty warning is stored as NULL
+func normalizeContentWarning(contentWarning *string) (*string, error) {
+	if contentWarning == nil {
+		return nil, nil
+	}
+	warning := strings.TrimSpace(*contentWarning)
+	if warning == "" {
+		return nil, nil
+	}
+	if utf8.RuneCountInString(warning) > model.MaxContentWarningLength {
+		return nil, ErrContentWarningTooLong
+	}
+	return &warning, nil
+}
+
+// validate media description, empty description is stored as NULL
+func normalizeAltText(altText *string) (*string, error) {
+	if altText == nil {
+		return nil, nil
+	}
+	text := strings.TrimSpace(*altText)
+	if text == "" {
+		return nil, nil
+	}
+	if utf8.RuneCountInString(text) > model.MaxAltTextLength {
+		return nil, ErrAltTextTooLong
+	}
+	return &text, nil
+}
+
+// PostNewTweet save tweet with optional media, tweet id and creation time are set by repository
 func (ctrl *Controller) PostNewTweet(
 	ctx context.Context,
 	file multipart.File,
 	handler *multipart.FileHeader,
-	userId types.UserId,
-	content string,
-	retweetId *types.TweetId,
+	tweet model.Tweet,
 ) (*types.TweetId, error) {
 	var (
-		MediaUrl *string = nil
-		url      string
-		err      error
+		url string
+		err error
 	)
 
+	if tweet.ReplyPolicy == "" {
+		tweet.ReplyPolicy = model.ReplyEveryone
+	}
+	if !tweet.ReplyPolicy.Valid() {
+		return nil, ErrInvalidReplyPolicy
+	}
+	tweet.ContentWarning, err = normalizeContentWarning(tweet.ContentWarning)
+	if err != nil {
+		return nil, err
+	}
+	// content warning always hides the media
+	if tweet.ContentWarning != nil {
+		tweet.Sensitive = true
+	}
+	tweet.MediaAltText, err = normalizeAltText(tweet.MediaAltText)
+	if err != nil {
+		return nil, err
+	}
+	if tweet.MediaAltText != nil && handler == nil {
+		return nil, ErrNoMedia
+	}
+	// enforce reply policy of the parent tweet
+	if tweet.ReplyId != nil {
+		if err = ctrl.checkReplyPolicy(ctx, tweet.UserId, *tweet.ReplyId); err != nil {
+			return nil, err
+		}
+	}
+
 	// save to storage
 	if handler != nil {
 		url, err = ctrl.storage.SaveImageFromRequest(file, handler)
-		MediaUrl = &url
+		tweet.MediaUrl = &url
 		if err != nil {
 			return nil, err
 		}
 	}
+	tweet.Lang = lang.Detect(tweet.Content)
+	tweet.Entities = model.ParseEntities(tweet.Content)
 
-	// save to db
-	tweetId, time, err := ctrl.repo.Put(ctx, userId, content, MediaUrl, retweetId)
-	// tweet metadata
-	tweet := model.Tweet{
-		userId,
-		tweetId,
-		retweetId,
-		content,
-		MediaUrl,
-		time,
-	}
+	// save to db with mentions
+	tweet.TweetId, tweet.CreatedAt, err = ctrl.repo.Put(ctx, tweet)
+	tweetId := tweet.TweetId
 
 	// save to cache
 	if ctrl.cache != nil && err == nil {
@@ -186,7 +412,7 @@ func (ctrl *Controller) PostNewTweet(
 		if err != nil {
 			return nil, err
 		}
-		err = putUserIdToCache(ctrl.cache, userId, tweet)
+		err = putUserIdToCache(ctrl.cache, tweet.UserId, tweet)
 		if err != nil {
 			return nil, err
 		}
@@ -194,6 +420,129 @@ func (ctrl *Controller) PostNewTweet(
 	return &tweetId, err
 }
 
+// refresh tweet in cache after update
+func (ctrl *Controller) refreshCache(tweet model.Tweet) error {
+	if ctrl.cache == nil {
+		return nil
+	}
+	err := putTweetIdToCache(ctrl.cache, tweet.TweetId, tweet)
+	if err != nil {
+		return err
+	}
+	return putUserIdToCache(ctrl.cache, tweet.UserId, tweet)
+}
+
+// get tweet that is modified by user, only author can modify tweet
+func (ctrl *Controller) getOwnTweet(ctx context.Context, userId types.UserId, tweetId types.TweetId) (model.Tweet, error) {
+	tweet, err := ctrl.getTweet(ctx, tweetId)
+	if err != nil {
+		return model.Tweet{}, err
+	}
+	if tweet.UserId != userId {
+		return model.Tweet{}, ErrNotAuthor
+	}
+	return tweet, nil
+}
+
+// SetAltText edit media description after posting
+func (ctrl *Controller) SetAltText(
+	ctx context.Context,
+	userId types.UserId,
+	tweetId types.TweetId,
+	altText *string,
+) error {
+	altText, err := normalizeAltText(altText)
+	if err != nil {
+		return err
+	}
+
+	tweet, err := ctrl.getOwnTweet(ctx, userId, tweetId)
+	if err != nil {
+		return err
+	}
+	if tweet.MediaUrl == nil || *tweet.MediaUrl == "" {
+		return ErrNoMedia
+	}
+	err = ctrl.repo.UpdateAltText(ctx, tweetId, altText)
+	if err != nil {
+		return err
+	}
+
+	tweet.MediaAltText = altText
+	return ctrl.refreshCache(tweet)
+}
+
+// SetSensitive mark tweet media as sensitive, used by authors after posting
+// and by moderators for tweets of any user, moderator is taken from the context
+func (ctrl *Controller) SetSensitive(
+	ctx context.Context,
+	userId types.UserId,
+	tweetId types.TweetId,
+	sensitive bool,
+	contentWarning *string,
+) error {
+	contentWarning, err := normalizeContentWarning(contentWarning)
+	if err != nil {
+		return err
+	}
+	if contentWarning != nil {
+		sensitive = true
+	}
+
+	tweet, err := ctrl.getTweet(ctx, tweetId)
+	if err != nil {
+		return err
+	}
+	moderated := tweet.UserId != userId
+	if moderated && !auth.HasRole(ctx, types.RoleModerator) {
+		return ErrNotAuthor
+	}
+	err = ctrl.repo.UpdateSensitive(ctx, tweetId, sensitive, contentWarning)
+	if err != nil {
+		return err
+	}
+	if moderated {
+		ctrl.record(
+			ctx, audit.Event{
+				Type: audit.TweetMarkedSensitive, UserId: tweet.UserId, ActorId: userId,
+				Detail: fmt.Sprintf("tweet %d sensitive=%t", tweetId, sensitive),
+			},
+		)
+	}
+
+	tweet.Sensitive = sensitive
+	tweet.ContentWarning = contentWarning
+	return ctrl.refreshCache(tweet)
+}
+
+// converting tweets to response objects
+func (ctrl *Controller) toMedia(tweets []model.Tweet) []model.Media {
+	tweetsMedia := make([]model.Media, len(tweets))
+	for i, tweet := range tweets {
+		var media string = ""
+		if tweet.MediaUrl != nil {
+			media, _ = ctrl.storage.ConvertImageFromStorage(*tweet.MediaUrl)
+		}
+
+		tweetsMedia[i].TweetId = tweet.TweetId
+		tweetsMedia[i].ReplyId = tweet.ReplyId
+		tweetsMedia[i].RetweetId = tweet.RetweetId
+		tweetsMedia[i].Content = tweet.Content
+		tweetsMedia[i].CreatedAt = tweet.CreatedAt
+		tweetsMedia[i].Media = media
+		tweetsMedia[i].Lang = tweet.Lang
+		tweetsMedia[i].Sensitive = tweet.Sensitive
+		if tweet.ContentWarning != nil {
+			tweetsMedia[i].ContentWarning = *tweet.ContentWarning
+		}
+		if tweet.MediaAltText != nil {
+			tweetsMedia[i].AltText = *tweet.MediaAltText
+		}
+		tweetsMedia[i].Entities = tweet.Entities
+	}
+	return tweetsMedia
+}
+
 func (ctrl *Controller) RetrieveByTweetID(ctx context.Context, tweetIds ...types.TweetId) ([]model.Media, error) {
 	var (
 		checkDb = true
@@ -225,19 +574,12 @@ func (ctrl *Controller) RetrieveByTweetID(ctx context.Context, tweetIds ...types
 		}
 	}
 
-	var media string = ""
-	tweetsMedia := make([]model.Media, len(tweets))
-	// converting to response object
-	for i, tweet := range tweets {
-		if tweet.MediaUrl != nil {
-			media, _ = ctrl.storage.ConvertImageFromStorage(*tweet.MediaUrl)
-		}
-
-		tweetsMedia[i].Content = tweet.Content
-		tweetsMedia[i].CreatedAt = tweet.CreatedAt
-		tweetsMedia[i].Media = media
+	// cache keeps all tweets, access is checked for every viewer
+	tweets, err := ctrl.visibleTweets(ctx, tweets)
+	if err != nil {
+		return nil, err
 	}
-	return tweetsMedia, nil
+	return ctrl.toMedia(tweets), nil
 }
 
 func (ctrl *Controller) RetrieveByUserID(ctx context.Context, userIds ...types.UserId) ([]model.Media, error) {
@@ -271,36 +613,51 @@ func (ctrl *Controller) RetrieveByUserID(ctx context.Context, userIds ...types.U
 		}
 	}
 
-	var media string = ""
-	tweetsMedia := make([]model.Media, len(tweets))
-	// converting to response object
-	for i, tweet := range tweets {
-		if tweet.MediaUrl != nil {
-			media, _ = ctrl.storage.ConvertImageFromStorage(*tweet.MediaUrl)
-		}
+	// cache keeps all tweets, access is checked for every viewer
+	tweets, err := ctrl.visibleTweets(ctx, tweets)
+	if err != nil {
+		return nil, err
+	}
+	return ctrl.toMedia(tweets), nil
+}
 
-		tweetsMedia[i].Content = tweet.Content
-		tweetsMedia[i].CreatedAt = tweet.CreatedAt
-		tweetsMedia[i].Media = media
+// GetAuthors outputs author of every tweet, other services use it to check relationships
+func (ctrl *Controller) GetAuthors(ctx context.Context, tweetIds ...types.TweetId) (map[types.TweetId]types.UserId, error) {
+	tweets, err := ctrl.repo.GetByTweet(ctx, tweetIds...)
+	if err != nil {
+		return nil, err
+	}
+	authors := make(map[types.TweetId]types.UserId, len(tweets))
+	for _, tweet := range tweets {
+		authors[tweet.TweetId] = tweet.UserId
 	}
-	return tweetsMedia, nil
+	return authors, nil
 }
 
-func (ctrl *Controller) DeletePost(ctx context.Context, postId types.TweetId) error {
+// DeletePost delete tweet of the user with its media
+func (ctrl *Controller) DeletePost(ctx context.Context, userId types.UserId, postId types.TweetId) error {
 	// get media url
 	tweetData, err := ctrl.repo.GetByTweet(ctx, postId)
 	if err != nil {
 		return err
 	}
+	if tweetData[0].UserId != userId {
+		return ErrNotAuthor
+	}
+	return ctrl.deleteTweet(ctx, tweetData[0])
+}
+
+// delete tweet from db, cache and storage
+func (ctrl *Controller) deleteTweet(ctx context.Context, tweet model.Tweet) error {
 	// delete from db
-	err = ctrl.repo.DeletePost(ctx, postId)
+	err := ctrl.repo.DeletePost(ctx, tweet.TweetId)
 	if err != nil {
 		return err
 	}
 	// remove from cache
 	if ctrl.cache != nil {
-		tweetId := cachestorage.GenerateTweetId(postId)
-		userTweetKey := cachestorage.GenerateUserToTweetId(tweetData[0].UserId, postId)
+		tweetId := cachestorage.GenerateTweetId(tweet.TweetId)
+		userTweetKey := cachestorage.GenerateUserToTweetId(tweet.UserId, tweet.TweetId)
 		err = ctrl.cache.Remove(tweetId)
 		if err != nil {
 			return err
@@ -312,9 +669,135 @@ func (ctrl *Controller) DeletePost(ctx context.Context, postId types.TweetId) er
 	}
 
 	// delete from storage
-	if tweetData[0].MediaUrl != nil && *tweetData[0].MediaUrl != "" {
-		err = ctrl.storage.Delete(*tweetData[0].MediaUrl)
+	if tweet.MediaUrl != nil && *tweet.MediaUrl != "" {
+		err = ctrl.storage.Delete(*tweet.MediaUrl)
+		return err
+	}
+	return nil
+}
+
+// ForceDelete delete tweet of any user, moderator is taken from the context
+func (ctrl *Controller) ForceDelete(ctx context.Context, tweetId types.TweetId, reason string) error {
+	tweetData, err := ctrl.repo.GetByTweet(ctx, tweetId)
+	if err != nil {
+		return err
+	}
+	if err = ctrl.deleteTweet(ctx, tweetData[0]); err != nil {
+		return err
+	}
+	actor, _ := auth.UserId(ctx)
+	ctrl.record(
+		ctx, audit.Event{
+			Type: audit.TweetDeleted, UserId: tweetData[0].UserId, ActorId: actor,
+			Detail: strings.TrimSpace(fmt.Sprintf("tweet %d %s", tweetId, reason)),
+		},
+	)
+	return nil
+}
+
+// ReportTweet ask moderators to review the tweet
+func (ctrl *Controller) ReportTweet(
+	ctx context.Context,
+	userId types.UserId,
+	tweetId types.TweetId,
+	reason string,
+) error {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > model.MaxReportReasonLength {
+		return ErrReportReasonTooLong
+	}
+	// tweet should exist and be visible to the user
+	tweet, err := ctrl.getTweet(ctx, tweetId)
+	if err != nil {
+		return err
+	}
+	visible, err := ctrl.visibleTweets(ctx, []model.Tweet{tweet})
+	if err != nil {
+		return err
+	}
+	if len(visible) == 0 {
+		return ErrTweetHidden
+	}
+	return ctrl.repo.PutReport(
+		ctx, model.Report{TweetId: tweetId, UserId: userId, Reason: reason, CreatedAt: time.Now()},
+	)
+}
+
+// GetReportedTweets outputs tweets with open reports, most reported first
+func (ctrl *Controller) GetReportedTweets(ctx context.Context) ([]model.ReportedTweet, error) {
+	reports, err := ctrl.repo.GetOpenReports(ctx)
+	if err != nil || len(reports) == 0 {
+		return []model.ReportedTweet{}, err
+	}
+
+	byTweet := make(map[types.TweetId][]model.Report)
+	var tweetIds []types.TweetId
+	for _, report := range reports {
+		if _, ok := byTweet[report.TweetId]; !ok {
+			tweetIds = append(tweetIds, report.TweetId)
+		}
+		byTweet[report.TweetId] = append(byTweet[report.TweetId], report)
+	}
+	tweets, err := ctrl.repo.GetByTweet(ctx, tweetIds...)
+	if err != nil {
+		return nil, err
+	}
+
+	reported := make([]model.ReportedTweet, 0, len(tweets))
+	for _, tweet := range tweets {
+		reported = append(reported, model.ReportedTweet{Tweet: tweet, Reports: byTweet[tweet.TweetId]})
+	}
+	// reports are ordered by time, so ties keep the oldest report first
+	sort.SliceStable(
+		reported, func(i, j int) bool {
+			if len(reported[i].Reports) != len(reported[j].Reports) {
+				return len(reported[i].Reports) > len(reported[j].Reports)
+			}
+			return reported[i].Reports[0].CreatedAt.Before(reported[j].Reports[0].CreatedAt)
+		},
+	)
+	return reported, nil
+}
+
+// ResolveReports close open reports of the tweet and apply the resolution,
+// moderator is taken from the context
+func (ctrl *Controller) ResolveReports(
+	ctx context.Context,
+	tweetId types.TweetId,
+	resolution model.Resolution,
+) error {
+	if !resolution.Valid() {
+		return ErrInvalidResolution
+	}
+	tweetData, err := ctrl.repo.GetByTweet(ctx, tweetId)
+	if err != nil {
 		return err
 	}
+	tweet := tweetData[0]
+
+	// reports are deleted with the tweet, so they are resolved first
+	actor, _ := auth.UserId(ctx)
+	if err = ctrl.repo.ResolveReports(ctx, tweetId, actor, resolution, time.Now()); err != nil {
+		return err
+	}
+	switch resolution {
+	case model.ResolutionSensitive:
+		if err = ctrl.repo.UpdateSensitive(ctx, tweetId, true, tweet.ContentWarning); err != nil {
+			return err
+		}
+		tweet.Sensitive = true
+		err = ctrl.refreshCache(tweet)
+	case model.ResolutionDelete:
+		err = ctrl.deleteTweet(ctx, tweet)
+	}
+	if err != nil {
+		return err
+	}
+	ctrl.record(
+		ctx, audit.Event{
+			Type: audit.TweetReportResolved, UserId: tweet.UserId, ActorId: actor,
+			Detail: fmt.Sprintf("tweet %d %s", tweetId, resolution),
+		},
+	)
 	return nil
 }
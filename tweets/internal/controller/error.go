@@ -0,0 +1,30 @@
+package controller
+
+import "errors"
+
+// ErrReplyNotAllowed is returned when reply policy of the parent tweet rejects the reply.
+var ErrReplyNotAllowed = errors.New("reply is not allowed by tweet author")
+
+// ErrInvalidReplyPolicy is returned when reply policy is unknown.
+var ErrInvalidReplyPolicy = errors.New("invalid reply policy")
+
+// ErrContentWarningTooLong is returned when content warning exceeds its column size.
+var ErrContentWarningTooLong = errors.New("content warning is too long")
+
+// ErrAltTextTooLong is returned when media description exceeds its column size.
+var ErrAltTextTooLong = errors.New("alt text is too long")
+
+// ErrNoMedia is returned when alt text is set for tweet without media.
+var ErrNoMedia = errors.New("tweet has no media")
+
+// ErrNotAuthor is returned when user modifies tweet of another user.
+var ErrNotAuthor = errors.New("tweet belongs to another user")
+
+// ErrReportReasonTooLong is returned when reason of the report exceeds its column size.
+var ErrReportReasonTooLong = errors.New("report reason is too long")
+
+// ErrInvalidResolution is returned when resolution of the report is unknown.
+var ErrInvalidResolution = errors.New("resolution should be dismiss, sensitive or delete")
+
+// ErrTweetHidden is returned when user reports tweet that is not visible to them.
+var ErrTweetHidden = errors.New("tweet is not visible")
@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/users"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"google.golang.org/grpc"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// get profiles of active users from users service, deactivated users are missing
+func (g *Gateway) GetProfiles(ctx context.Context, userIds ...types.UserId) (map[types.UserId]model.Profile, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := &gen.GetUsersRequest{}
+	for _, id := range userIds {
+		request.UserId = append(request.UserId, int32(id))
+	}
+	client := gen.NewUsersServiceClient(conn)
+	response, err := client.GetUsers(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[types.UserId]model.Profile)
+	for _, protoProfile := range response.Users {
+		profile := model.ProfileFromProto(protoProfile)
+		profiles[profile.UserId] = *profile
+	}
+	return profiles, nil
+}
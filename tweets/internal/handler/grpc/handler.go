@@ -2,9 +2,11 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/tweets"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/internal/controller"
+	"github.com/alexvishnevskiy/twitter-clone/tweets/internal/repository/mysql"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -46,6 +48,9 @@ func (h *Handler) Retrieve(ctx context.Context, req *gen.RetrieveRequest) (*gen.
 			userIds[i] = types.UserId(id)
 		}
 		tweetsData, err = h.ctrl.RetrieveByUserID(ctx, userIds...)
+		if errors.Is(err, mysql.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "no tweets of the users")
+		}
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, err.Error())
 		}
@@ -55,6 +60,9 @@ func (h *Handler) Retrieve(ctx context.Context, req *gen.RetrieveRequest) (*gen.
 		return nil, status.Errorf(codes.InvalidArgument, "something wrong with either user_id or tweet_id")
 	}
 
+	// filter by language
+	tweetsData = model.FilterByLang(tweetsData, req.Lang)
+
 	// convert to proto and write response
 	var protoResponse []*gen.Media
 	for _, id := range tweetsData {
@@ -66,3 +74,30 @@ func (h *Handler) Retrieve(ctx context.Context, req *gen.RetrieveRequest) (*gen.
 		MediaContent: protoResponse,
 	}, nil
 }
+
+// GetAuthors retrieve authors of the tweets
+func (h *Handler) GetAuthors(ctx context.Context, req *gen.GetAuthorsRequest) (*gen.GetAuthorsResponse, error) {
+	if req == nil || len(req.TweetId) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+
+	tweetIds := make([]types.TweetId, len(req.TweetId))
+	for i, id := range req.TweetId {
+		tweetIds[i] = types.TweetId(id)
+	}
+	authors, err := h.ctrl.GetAuthors(ctx, tweetIds...)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	response := &gen.GetAuthorsResponse{}
+	for _, id := range tweetIds {
+		if userId, ok := authors[id]; ok {
+			response.Authors = append(response.Authors, &gen.TweetAuthor{TweetId: int32(id), UserId: int32(userId)})
+		}
+	}
+	return response, nil
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/internal/controller"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/internal/repository/mysql"
@@ -29,6 +30,7 @@ func New(ctrl *controller.Controller) *Handler {
 //	@description	Retrieve either by tweet_id or user_id
 //	@Param			user_id		query		int	false	"User ID"
 //	@Param			tweet_id	query		int	false	"Tweet ID"
+//	@Param			lang		query		[]string	false	"Keep only tweets in these languages"
 //	@Success		200			{object}	[]model.Media
 //	@Failure		400			{object}	int
 //	@Failure		404			{object}	int
@@ -99,6 +101,9 @@ func (h *Handler) Retrieve(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
+	// filter by language
+	tweetsData = model.FilterByLang(tweetsData, req.Form["lang"])
+
 	jsonData, err := json.Marshal(tweetsData)
 	if err != nil {
 		http.Error(w, "Could not convert data to JSON", http.StatusInternalServerError)
@@ -112,10 +117,13 @@ func (h *Handler) Retrieve(w http.ResponseWriter, req *http.Request) {
 
 // Delete by tweet id
 //
-//	@description	Delete by tweet_id
+//	@description	Delete by tweet_id, only author can delete tweet
+//	@Security		BearerAuth
 //	@Param			tweet_id	query		int	true	"Tweet ID"
 //	@Success		200			{object}	int
 //	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		403			{object}	int
 //	@Failure		404			{object}	int
 //	@Failure		405			{object}	int
 //	@Failure		500			{object}	int
@@ -125,6 +133,10 @@ func (h *Handler) Delete(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
 
 	tweet, err := strconv.Atoi(req.FormValue("tweet_id"))
 	if err != nil {
@@ -133,22 +145,87 @@ func (h *Handler) Delete(w http.ResponseWriter, req *http.Request) {
 	}
 
 	tweetID := types.TweetId(tweet)
-	err = h.ctrl.DeletePost(req.Context(), tweetID)
+	err = h.ctrl.DeletePost(req.Context(), userId, tweetID)
+	if err != nil && errors.Is(err, controller.ErrNotAuthor) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("there is no data in db: %s", err), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Could not delete post: %s", err), http.StatusInternalServerError)
 		log.Printf("Failed to delete post: %v\n", err)
 	}
 }
 
+// helper function to read optional tweet id from multipart form
+func formTweetId(req *http.Request, key string) (*types.TweetId, error) {
+	value := req.FormValue(key)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("bad %s: %s", key, value)
+	}
+	tweetId := types.TweetId(id)
+	return &tweetId, nil
+}
+
+// helper function to read optional string from multipart form
+func formString(req *http.Request, key string) *string {
+	if _, ok := req.MultipartForm.Value[key]; !ok {
+		return nil
+	}
+	value := req.FormValue(key)
+	return &value
+}
+
+// read tweet metadata from multipart form fields
+func parseTweetForm(req *http.Request) (model.Tweet, error) {
+	var (
+		tweet model.Tweet
+		err   error
+	)
+
+	tweet.Content = req.FormValue("content")
+	tweet.ReplyPolicy = model.ReplyPolicy(req.FormValue("reply_policy"))
+	tweet.ContentWarning = formString(req, "content_warning")
+	tweet.MediaAltText = formString(req, "alt_text")
+
+	if sensitive := req.FormValue("sensitive"); sensitive != "" {
+		tweet.Sensitive, err = strconv.ParseBool(sensitive)
+		if err != nil {
+			return tweet, fmt.Errorf("bad sensitive: %s", sensitive)
+		}
+	}
+	if tweet.RetweetId, err = formTweetId(req, "retweet_id"); err != nil {
+		return tweet, err
+	}
+	if tweet.ReplyId, err = formTweetId(req, "reply_id"); err != nil {
+		return tweet, err
+	}
+	return tweet, nil
+}
+
 // Post tweet
 //
-//	@description	Post tweet
-//	@Param			user_id		body		int		true	"User ID"
+//	@description	Post tweet of the authenticated user either as json body or as multipart form with media, email of the user should be verified
+//	@Security		BearerAuth
 //	@Param			content		body		string	true	"Content"
 //	@Param			retweet_id	body		int		false	"Retweet ID"
+//	@Param			reply_id	body		int		false	"Reply ID"
+//	@Param			reply_policy	body		string	false	"Reply policy: everyone, following or mentioned"
+//	@Param			sensitive	body		bool	false	"Sensitive media"
+//	@Param			content_warning	body		string	false	"Content warning, implies sensitive"
 //	@Param			media		formData	file	false	"Media"
+//	@Param			alt_text	formData	string	false	"Media description for screen readers"
 //	@Success		200			{object}	int
 //	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		403			{object}	int
 //	@Failure		404			{object}	int
 //	@Failure		405			{object}	int
 //	@Failure		500			{object}	int
@@ -158,52 +235,370 @@ func (h *Handler) Post(w http.ResponseWriter, req *http.Request) {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
 	requestData := model.Tweet{}
-	var retweetId *types.TweetId = nil
+
 	// 1 << 16 is the maximum size you can read from the request
-	req.ParseMultipartForm(1 << 16)
+	if err := req.ParseMultipartForm(1 << 16); err == nil {
+		requestData, err = parseTweetForm(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		bodyBytes, err := ioutil.ReadAll(req.Body)
+		defer req.Body.Close()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		err = json.Unmarshal(bodyBytes, &requestData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// tweet is always posted by the authenticated user
+	requestData.UserId = userId
+	if requestData.Content == "" {
+		http.Error(w, "content is empty", http.StatusBadRequest)
+		return
+	}
+
+	file, handler, err := req.FormFile("media")
+	if handler != nil {
+		defer file.Close()
+	}
+
+	tweetId, err := h.ctrl.PostNewTweet(
+		req.Context(),
+		file,
+		handler,
+		requestData,
+	)
+
+	if err != nil && errors.Is(err, controller.ErrReplyNotAllowed) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("replied tweet is not found: %s", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to post tweet: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(tweetId); err != nil {
+		http.Error(w, "response encode error", http.StatusInternalServerError)
+		log.Printf("Response encode error: %v\n", err)
+	}
+}
+
+// MarkSensitive set sensitive flag and content warning
+//
+//	@description	Set sensitive flag and content warning after posting, only author or moderator can edit tweet
+//	@Security		BearerAuth
+//	@Param			tweet_id		body		int		true	"Tweet ID"
+//	@Param			sensitive		body		bool	true	"Sensitive media"
+//	@Param			content_warning	body		string	false	"Content warning, implies sensitive"
+//	@Success		200				{object}	int
+//	@Failure		400				{object}	int
+//	@Failure		401				{object}	int
+//	@Failure		403				{object}	int
+//	@Failure		404				{object}	int
+//	@Failure		405				{object}	int
+//	@Failure		500				{object}	int
+//	@Router			/mark_sensitive [put]
+func (h *Handler) MarkSensitive(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
 
+	var requestData struct {
+		TweetId        types.TweetId `json:"tweet_id"`
+		Sensitive      bool          `json:"sensitive"`
+		ContentWarning *string       `json:"content_warning"`
+	}
 	bodyBytes, err := ioutil.ReadAll(req.Body)
 	defer req.Body.Close()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if requestData.TweetId == 0 {
+		http.Error(w, "tweet_id is empty", http.StatusBadRequest)
+		return
+	}
 
+	err = h.ctrl.SetSensitive(
+		req.Context(), userId, requestData.TweetId, requestData.Sensitive, requestData.ContentWarning,
+	)
+	if err != nil && errors.Is(err, controller.ErrNotAuthor) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("there is no data in db: %s", err), http.StatusNotFound)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrContentWarningTooLong) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// UpdateAltText edit media description
+//
+//	@description	Edit media description after posting, only author can edit tweet
+//	@Security		BearerAuth
+//	@Param			tweet_id	body		int		true	"Tweet ID"
+//	@Param			alt_text	body		string	true	"Media description for screen readers"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		403			{object}	int
+//	@Failure		404			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/update_alt_text [put]
+func (h *Handler) UpdateAltText(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	var requestData struct {
+		TweetId types.TweetId `json:"tweet_id"`
+		AltText *string       `json:"alt_text"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	err = json.Unmarshal(bodyBytes, &requestData)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if requestData.TweetId == 0 {
+		http.Error(w, "tweet_id is empty", http.StatusBadRequest)
+		return
+	}
 
-	if requestData.UserId == 0 && requestData.Content == "" {
-		http.Error(w, "user_id and content are empty", http.StatusBadRequest)
+	err = h.ctrl.SetAltText(req.Context(), userId, requestData.TweetId, requestData.AltText)
+	if err != nil && errors.Is(err, controller.ErrNotAuthor) {
+		http.Error(w, err.Error(), http.StatusForbidden)
 		return
 	}
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("there is no data in db: %s", err), http.StatusNotFound)
+		return
+	}
+	if err != nil && (errors.Is(err, controller.ErrAltTextTooLong) || errors.Is(err, controller.ErrNoMedia)) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
 
-	if requestData.RetweetId != nil {
-		retweetId = requestData.RetweetId
+// ReportTweet ask moderators to review the tweet
+//
+//	@description	Report tweet to moderators, reporting the same tweet again replaces the reason
+//	@Security		BearerAuth
+//	@Param			tweet_id	body		int		true	"Tweet ID"
+//	@Param			reason		body		string	false	"Reason of the report"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		404			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/report_tweet [post]
+func (h *Handler) ReportTweet(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
 	}
 
-	file, handler, err := req.FormFile("media")
-	if handler != nil {
-		defer file.Close()
+	var requestData struct {
+		TweetId types.TweetId `json:"tweet_id"`
+		Reason  string        `json:"reason"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if requestData.TweetId == 0 {
+		http.Error(w, "tweet_id is empty", http.StatusBadRequest)
+		return
 	}
 
-	tweetId, err := h.ctrl.PostNewTweet(
-		req.Context(),
-		file,
-		handler,
-		requestData.UserId,
-		requestData.Content,
-		retweetId,
-	)
+	err = h.ctrl.ReportTweet(req.Context(), userId, requestData.TweetId, requestData.Reason)
+	if err != nil && (errors.Is(err, mysql.ErrNotFound) || errors.Is(err, controller.ErrTweetHidden)) {
+		http.Error(w, "tweet is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrReportReasonTooLong) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// ReportedTweets list tweets waiting for review
+//
+//	@description	List tweets with open reports, most reported first, requires moderator role
+//	@Security		BearerAuth
+//	@Success		200		{object}	[]model.ReportedTweet
+//	@Failure		401		{object}	int
+//	@Failure		403		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/admin/reports [get]
+func (h *Handler) ReportedTweets(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
 
+	reported, err := h.ctrl.GetReportedTweets(req.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to post tweet: %s", err), http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
-	if err := json.NewEncoder(w).Encode(tweetId); err != nil {
-		http.Error(w, "response encode error", http.StatusInternalServerError)
-		log.Printf("Response encode error: %v\n", err)
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(reported); err != nil {
+		http.Error(w, "failed to encode reports", http.StatusInternalServerError)
+	}
+}
+
+// ResolveReports review reported tweet
+//
+//	@description	Close open reports of the tweet, the tweet is kept, marked sensitive or deleted. Requires moderator role
+//	@Security		BearerAuth
+//	@Param			tweet_id	body		int		true	"Tweet ID"
+//	@Param			resolution	body		string	true	"dismiss, sensitive or delete"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		403			{object}	int
+//	@Failure		404			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/admin/resolve_report [post]
+func (h *Handler) ResolveReports(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		TweetId    types.TweetId    `json:"tweet_id"`
+		Resolution model.Resolution `json:"resolution"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.ctrl.ResolveReports(req.Context(), requestData.TweetId, requestData.Resolution)
+	if err != nil && errors.Is(err, controller.ErrInvalidResolution) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "tweet has no open reports", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// ForceDelete delete tweet of any user
+//
+//	@description	Delete tweet of any user, requires moderator role
+//	@Security		BearerAuth
+//	@Param			tweet_id	query		int		true	"Tweet ID"
+//	@Param			reason		query		string	false	"Reason that is kept in audit history"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		403			{object}	int
+//	@Failure		404			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/admin/delete_tweet [delete]
+func (h *Handler) ForceDelete(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tweet, err := strconv.Atoi(req.FormValue("tweet_id"))
+	if err != nil {
+		http.Error(w, "Bad tweet_id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.ctrl.ForceDelete(req.Context(), types.TweetId(tweet), req.FormValue("reason"))
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, fmt.Sprintf("there is no data in db: %s", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Could not delete post: %s", err), http.StatusInternalServerError)
+		log.Printf("Failed to delete post: %v\n", err)
 	}
 }
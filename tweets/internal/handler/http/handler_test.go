@@ -6,25 +6,44 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	followmodel "github.com/alexvishnevskiy/twitter-clone/follow/pkg/model"
+	usersgen "github.com/alexvishnevskiy/twitter-clone/gen/api/users"
 	mockCache "github.com/alexvishnevskiy/twitter-clone/gen/cache"
 	mockcontroller "github.com/alexvishnevskiy/twitter-clone/gen/controller/tweets"
 	mockStorage "github.com/alexvishnevskiy/twitter-clone/gen/storage"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	auditfile "github.com/alexvishnevskiy/twitter-clone/internal/audit/file"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
 	localcache "github.com/alexvishnevskiy/twitter-clone/internal/cache/local"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/lang"
 	"github.com/alexvishnevskiy/twitter-clone/internal/storage/local"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/internal/controller"
+	usersgateway "github.com/alexvishnevskiy/twitter-clone/tweets/internal/gateway/users/grpc"
+	"github.com/alexvishnevskiy/twitter-clone/tweets/internal/repository/mysql"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	usersmodel "github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
 	"log"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
 
+// context of the authenticated user
+func userContext(userId types.UserId) context.Context {
+	return auth.NewContext(context.Background(), &auth.Principal{UserId: userId})
+}
+
 func TestHandler_Delete(t *testing.T) {
-	ctx := context.Background()
+	ctx := userContext(1)
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
@@ -43,13 +62,17 @@ func TestHandler_Delete(t *testing.T) {
 	mockTweetRepo.EXPECT().GetByTweet(ctx, types.TweetId(2)).Return(
 		[]model.Tweet{
 			{
-				UserId:    types.UserId(2),
+				UserId:    types.UserId(1),
 				TweetId:   types.TweetId(2),
 				Content:   "",
 				CreatedAt: time.Now(),
 			},
 		}, nil,
 	)
+	// tweet of another user is not deleted
+	mockTweetRepo.EXPECT().GetByTweet(ctx, types.TweetId(3)).Return(
+		[]model.Tweet{{UserId: types.UserId(2), TweetId: types.TweetId(3)}}, nil,
+	)
 	mockTweetRepo.EXPECT().DeletePost(ctx, types.TweetId(1)).Return(nil)
 	mockTweetRepo.EXPECT().DeletePost(ctx, types.TweetId(2)).Return(errors.New(""))
 
@@ -60,7 +83,7 @@ func TestHandler_Delete(t *testing.T) {
 	mockcache.EXPECT().Remove("tweet_id_1").Return(nil)
 
 	// tweet controller
-	tweetCtrl := controller.New(mockTweetRepo, mockstorage, mockcache)
+	tweetCtrl := controller.New(mockTweetRepo, mockstorage, mockcache, nil, nil, nil)
 	tweetHandler := New(tweetCtrl)
 
 	testCases := []struct {
@@ -78,6 +101,11 @@ func TestHandler_Delete(t *testing.T) {
 			tweetId: 2,
 			method:  "DELETE",
 		},
+		{
+			name:    "DELETE3",
+			tweetId: 3,
+			method:  "DELETE",
+		},
 		{
 			name:    "PUT1",
 			tweetId: 1,
@@ -89,7 +117,7 @@ func TestHandler_Delete(t *testing.T) {
 		t.Run(
 			tc.name, func(t *testing.T) {
 				// Create a request to pass to our handler.
-				req, err := http.NewRequest(tc.method, fmt.Sprintf("/delete_tweet?tweet_id=%d", tc.tweetId), nil)
+				req, err := http.NewRequestWithContext(ctx, tc.method, fmt.Sprintf("/delete_tweet?tweet_id=%d", tc.tweetId), nil)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -114,12 +142,18 @@ func TestHandler_Delete(t *testing.T) {
 							status, http.StatusOK,
 						)
 					}
-					if status := rr.Code; tc.tweetId != 1 && tc.method == "DELETE" && status != http.StatusInternalServerError {
+					if status := rr.Code; tc.tweetId == 2 && tc.method == "DELETE" && status != http.StatusInternalServerError {
 						t.Errorf(
 							"handler returned wrong status code: got %v want %v",
 							status, http.StatusInternalServerError,
 						)
 					}
+					if status := rr.Code; tc.tweetId == 3 && tc.method == "DELETE" && status != http.StatusForbidden {
+						t.Errorf(
+							"handler returned wrong status code: got %v want %v",
+							status, http.StatusForbidden,
+						)
+					}
 				}
 			},
 		)
@@ -127,34 +161,34 @@ func TestHandler_Delete(t *testing.T) {
 }
 
 func TestHandler_Post(t *testing.T) {
-	ctx := context.Background()
+	ctx := userContext(1)
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	// mock tweet controller
 	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
-	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), localcache.New(10))
+	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), localcache.New(10), nil, nil, nil)
 	tweetHandler := New(tweetCtrl)
 
 	want := types.TweetId(1)
 	// mock tweet controller
 	mockTweetRepo.EXPECT().
-		Put(ctx, types.UserId(1), "content", nil, nil).
+		Put(ctx, model.Tweet{UserId: 1, Content: "content", ReplyPolicy: model.ReplyEveryone, Lang: lang.Undetermined}).
 		Return(want, time.Now(), nil)
 
-	// make json for body request
+	// make json for body request, user_id of the body is ignored
 	payloadBytes, err := json.Marshal(
 		struct {
 			UserId  types.UserId `json:"user_id"`
 			Content string       `json:"content"`
-		}{1, "content"},
+		}{2, "content"},
 	)
 	if err != nil {
 		log.Fatalf("Failed to marshal payload: %v", err)
 	}
 	body := bytes.NewReader(payloadBytes)
 
-	req, err := http.NewRequest("POST", "/post_tweet", body)
+	req, err := http.NewRequestWithContext(ctx, "POST", "/post_tweet", body)
 	req.Header.Set("Content-Type", "application/json")
 	if err != nil {
 		t.Fatal(err)
@@ -182,6 +216,93 @@ func TestHandler_Post(t *testing.T) {
 	}
 }
 
+func TestHandler_PostReply(t *testing.T) {
+	ctx1, ctx3 := userContext(1), userContext(3)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// mock tweet repo and follow service
+	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
+	mockFollow := mockcontroller.NewMockfollowGateway(mockCtrl)
+	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), nil, mockFollow, nil, nil)
+	tweetHandler := New(tweetCtrl)
+
+	following := types.TweetId(1)
+	mentioned := types.TweetId(2)
+	for userId, ctx := range map[types.UserId]context.Context{1: ctx1, 3: ctx3} {
+		// users did not block the author
+		mockFollow.EXPECT().GetRelationships(ctx, userId, types.UserId(2)).Return(
+			map[types.UserId]followmodel.Relationship{}, nil,
+		).Times(2)
+		mockTweetRepo.EXPECT().GetByTweet(ctx, following).Return(
+			[]model.Tweet{{UserId: 2, TweetId: following, ReplyPolicy: model.ReplyFollowing}}, nil,
+		)
+		mockTweetRepo.EXPECT().GetByTweet(ctx, mentioned).Return(
+			[]model.Tweet{{UserId: 2, TweetId: mentioned, ReplyPolicy: model.ReplyMentioned}}, nil,
+		)
+		mockFollow.EXPECT().GetUsers(ctx, types.UserId(2)).Return([]types.UserId{3}, nil)
+		mockTweetRepo.EXPECT().GetMentions(ctx, mentioned).Return([]types.UserId{1}, nil)
+	}
+	mockTweetRepo.EXPECT().
+		Put(
+			ctx3, model.Tweet{
+				UserId: 3, Content: "content", ReplyId: &following,
+				ReplyPolicy: model.ReplyEveryone, Lang: lang.Undetermined,
+			},
+		).
+		Return(types.TweetId(3), time.Now(), nil)
+	mockTweetRepo.EXPECT().
+		Put(
+			ctx1, model.Tweet{
+				UserId: 1, Content: "content", ReplyId: &mentioned,
+				ReplyPolicy: model.ReplyEveryone, Lang: lang.Undetermined,
+			},
+		).
+		Return(types.TweetId(4), time.Now(), nil)
+
+	testCases := []struct {
+		name    string
+		userId  types.UserId
+		replyId types.TweetId
+		want    int
+	}{
+		{name: "followingAllowed", userId: 3, replyId: following, want: http.StatusOK},
+		{name: "followingRejected", userId: 1, replyId: following, want: http.StatusForbidden},
+		{name: "mentionedAllowed", userId: 1, replyId: mentioned, want: http.StatusOK},
+		{name: "mentionedRejected", userId: 3, replyId: mentioned, want: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				payloadBytes, err := json.Marshal(
+					struct {
+						Content string        `json:"content"`
+						ReplyId types.TweetId `json:"reply_id"`
+					}{"content", tc.replyId},
+				)
+				if err != nil {
+					log.Fatalf("Failed to marshal payload: %v", err)
+				}
+
+				req, err := http.NewRequestWithContext(
+					userContext(tc.userId), "POST", "/post_tweet", bytes.NewReader(payloadBytes),
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				handler := http.HandlerFunc(tweetHandler.Post)
+				handler.ServeHTTP(rr, req)
+
+				if status := rr.Code; status != tc.want {
+					t.Errorf("handler returned wrong status code: got %v want %v", status, tc.want)
+				}
+			},
+		)
+	}
+}
+
 func TestHandler_Retrieve(t *testing.T) {
 	ctx := context.Background()
 	mockCtrl := gomock.NewController(t)
@@ -189,7 +310,7 @@ func TestHandler_Retrieve(t *testing.T) {
 
 	// mock tweet controller
 	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
-	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), localcache.New(10))
+	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), localcache.New(10), nil, nil, nil)
 	tweetHandler := New(tweetCtrl)
 
 	// expected output
@@ -210,11 +331,13 @@ func TestHandler_Retrieve(t *testing.T) {
 	}
 	wantHandler := []model.Media{
 		{
+			TweetId:   types.TweetId(1),
 			Media:     "",
 			Content:   "content",
 			CreatedAt: timeNow,
 		},
 		{
+			TweetId:   types.TweetId(2),
 			Media:     "",
 			Content:   "content",
 			CreatedAt: timeNow,
@@ -280,3 +403,479 @@ func TestHandler_Retrieve(t *testing.T) {
 		)
 	}
 }
+
+func TestHandler_RetrieveLang(t *testing.T) {
+	ctx := context.Background()
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
+	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), nil, nil, nil, nil)
+	tweetHandler := New(tweetCtrl)
+
+	timeNow := time.Now()
+	mockTweetRepo.EXPECT().GetByUser(ctx, types.UserId(1)).Return(
+		[]model.Tweet{
+			{UserId: 1, TweetId: 1, Content: "hello", CreatedAt: timeNow, Lang: "en"},
+			{UserId: 1, TweetId: 2, Content: "bonjour", CreatedAt: timeNow, Lang: "fr"},
+			{UserId: 1, TweetId: 3, Content: "ok", CreatedAt: timeNow, Lang: lang.Undetermined},
+		}, nil,
+	)
+	want := []model.Media{
+		{TweetId: 1, Content: "hello", CreatedAt: timeNow, Lang: "en"},
+		{TweetId: 3, Content: "ok", CreatedAt: timeNow, Lang: lang.Undetermined},
+	}
+
+	req, err := http.NewRequest("GET", "/retrieve_tweet?user_id=1&lang=en", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(tweetHandler.Retrieve)
+	handler.ServeHTTP(rr, req)
+
+	var res []model.Media
+	if err = json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Errorf("failed to unmarshal result request")
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// users service returning public profiles
+type usersServer struct {
+	usersgen.UnimplementedUsersServiceServer
+}
+
+func (s *usersServer) GetUsers(_ context.Context, req *usersgen.GetUsersRequest) (*usersgen.GetUsersResponse, error) {
+	response := &usersgen.GetUsersResponse{}
+	for _, id := range req.UserId {
+		response.Users = append(response.Users, &usersgen.Profile{UserId: id, Nickname: fmt.Sprintf("user%d", id)})
+	}
+	return response, nil
+}
+
+// start users grpc server with auth interceptors and return its address
+func startUsersServer(t *testing.T, public ...string) string {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(auth.ServerOptions(public...)...)
+	usersgen.RegisterUsersServiceServer(srv, &usersServer{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestHandler_RetrieveAnonymous(t *testing.T) {
+	timeNow := time.Now()
+	testCases := []struct {
+		name   string
+		public []string
+		status int
+	}{
+		{
+			name:   "public profiles",
+			public: []string{usersgen.UsersService_GetUsers_FullMethodName},
+			status: http.StatusOK,
+		},
+		{
+			name:   "authenticated profiles",
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				mockCtrl := gomock.NewController(t)
+				defer mockCtrl.Finish()
+
+				mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
+				users := usersgateway.New(startUsersServer(t, tc.public...))
+				tweetCtrl := controller.New(mockTweetRepo, local.New("./"), nil, nil, users, nil)
+				tweetHandler := New(tweetCtrl)
+
+				mockTweetRepo.EXPECT().GetByTweet(gomock.Any(), types.TweetId(1)).Return(
+					[]model.Tweet{{UserId: 1, TweetId: 1, Content: "content", CreatedAt: timeNow}}, nil,
+				)
+
+				// anonymous reader, no token is forwarded to users service
+				req, err := http.NewRequest("GET", "/retrieve_tweet?tweet_id=1", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				handler := auth.AllowScope(types.ScopeRead, auth.OptionalMiddleware(http.HandlerFunc(tweetHandler.Retrieve)))
+				handler.ServeHTTP(rr, req)
+
+				if rr.Code != tc.status {
+					t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tc.status)
+				}
+				if tc.status != http.StatusOK {
+					return
+				}
+				var res []model.Media
+				if err = json.NewDecoder(rr.Body).Decode(&res); err != nil {
+					t.Errorf("failed to unmarshal result request")
+				}
+				want := []model.Media{{TweetId: 1, Content: "content", CreatedAt: timeNow}}
+				if diff := cmp.Diff(want, res); diff != "" {
+					t.Errorf("mismatch (-want +got):\n%s", diff)
+				}
+			},
+		)
+	}
+}
+
+func TestHandler_RetrieveProtected(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 4})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
+	mockFollow := mockcontroller.NewMockfollowGateway(mockCtrl)
+	mockUsers := mockcontroller.NewMockusersGateway(mockCtrl)
+	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), nil, mockFollow, mockUsers, nil)
+	tweetHandler := New(tweetCtrl)
+
+	// user 2 and 3 are protected, viewer follows user 1 and 2
+	timeNow := time.Now()
+	mockTweetRepo.EXPECT().GetByUser(gomock.Any(), types.UserId(1), types.UserId(2), types.UserId(3)).Return(
+		[]model.Tweet{
+			{UserId: 1, TweetId: 1, Content: "public", CreatedAt: timeNow},
+			{UserId: 2, TweetId: 2, Content: "followed", CreatedAt: timeNow},
+			{UserId: 3, TweetId: 3, Content: "hidden", CreatedAt: timeNow},
+		}, nil,
+	).Times(4)
+	profiles := map[types.UserId]usersmodel.Profile{
+		1: {UserId: 1}, 2: {UserId: 2, Protected: true}, 3: {UserId: 3, Protected: true},
+	}
+	mockUsers.EXPECT().GetProfiles(gomock.Any(), types.UserId(1), types.UserId(2), types.UserId(3)).Return(
+		profiles, nil,
+	).Times(3)
+	mockFollow.EXPECT().GetRelationships(gomock.Any(), types.UserId(4), types.UserId(1), types.UserId(2), types.UserId(3)).Return(
+		map[types.UserId]followmodel.Relationship{1: {UserId: 1, Following: true}, 2: {UserId: 2, Following: true}}, nil,
+	)
+
+	retrieve := func(ctx context.Context) []model.Media {
+		req, err := http.NewRequestWithContext(ctx, "GET", "/retrieve_tweet?user_id=1&user_id=2&user_id=3", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(tweetHandler.Retrieve).ServeHTTP(rr, req)
+
+		var res []model.Media
+		if err = json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Errorf("failed to unmarshal result request")
+		}
+		return res
+	}
+
+	want := []model.Media{
+		{TweetId: 1, Content: "public", CreatedAt: timeNow},
+		{TweetId: 2, Content: "followed", CreatedAt: timeNow},
+	}
+	if diff := cmp.Diff(want, retrieve(ctx)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	// anonymous viewer sees only public accounts
+	if diff := cmp.Diff(want[:1], retrieve(context.Background())); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// blocked users do not see each other's tweets
+	mockFollow.EXPECT().GetRelationships(gomock.Any(), types.UserId(4), types.UserId(1), types.UserId(2), types.UserId(3)).Return(
+		map[types.UserId]followmodel.Relationship{1: {UserId: 1, Blocking: true}, 2: {UserId: 2, Following: true}}, nil,
+	)
+	if diff := cmp.Diff(want[1:], retrieve(ctx)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// tweets of deactivated users are hidden, users service does not return their profiles
+	mockUsers.EXPECT().GetProfiles(gomock.Any(), types.UserId(1), types.UserId(2), types.UserId(3)).Return(
+		map[types.UserId]usersmodel.Profile{2: profiles[2], 3: profiles[3]}, nil,
+	)
+	if res := retrieve(context.Background()); len(res) != 0 {
+		t.Errorf("tweets of deactivated user should be hidden, got %+v", res)
+	}
+}
+
+func TestHandler_MarkSensitive(t *testing.T) {
+	ctx := userContext(1)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
+	cache := localcache.New(10)
+	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), cache, nil, nil, nil)
+	tweetHandler := New(tweetCtrl)
+
+	warning := "spoiler"
+	mockTweetRepo.EXPECT().GetByTweet(ctx, types.TweetId(1)).Return(
+		[]model.Tweet{{UserId: 1, TweetId: 1, Content: "content"}}, nil,
+	)
+	mockTweetRepo.EXPECT().GetByTweet(ctx, types.TweetId(2)).Return(
+		[]model.Tweet{{UserId: 2, TweetId: 2, Content: "content"}}, nil,
+	)
+	mockTweetRepo.EXPECT().UpdateSensitive(ctx, types.TweetId(1), true, &warning).Return(nil)
+
+	// moderator marks tweet of another user
+	moderator := auth.NewContext(
+		context.Background(), &auth.Principal{UserId: 9, Claims: &jwt.Claims{UserId: 9, Role: types.RoleModerator}},
+	)
+	mockTweetRepo.EXPECT().GetByTweet(moderator, types.TweetId(2)).Return(
+		[]model.Tweet{{UserId: 2, TweetId: 2, Content: "content"}}, nil,
+	)
+	mockTweetRepo.EXPECT().UpdateSensitive(moderator, types.TweetId(2), true, &warning).Return(nil)
+
+	testCases := []struct {
+		name    string
+		ctx     context.Context
+		tweetId types.TweetId
+		warning string
+		want    int
+	}{
+		{name: "marked", ctx: ctx, tweetId: 1, warning: warning, want: http.StatusOK},
+		{name: "notAuthor", ctx: ctx, tweetId: 2, warning: warning, want: http.StatusForbidden},
+		{name: "moderator", ctx: moderator, tweetId: 2, warning: warning, want: http.StatusOK},
+		{name: "tooLong", ctx: ctx, tweetId: 1, warning: strings.Repeat("a", model.MaxContentWarningLength+1), want: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				payloadBytes, err := json.Marshal(
+					struct {
+						TweetId        types.TweetId `json:"tweet_id"`
+						ContentWarning string        `json:"content_warning"`
+					}{tc.tweetId, tc.warning},
+				)
+				if err != nil {
+					log.Fatalf("Failed to marshal payload: %v", err)
+				}
+
+				req, err := http.NewRequestWithContext(tc.ctx, "PUT", "/mark_sensitive", bytes.NewReader(payloadBytes))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				handler := http.HandlerFunc(tweetHandler.MarkSensitive)
+				handler.ServeHTTP(rr, req)
+
+				if status := rr.Code; status != tc.want {
+					t.Errorf("handler returned wrong status code: got %v want %v", status, tc.want)
+				}
+			},
+		)
+	}
+
+	// cached tweet is marked as well
+	media, err := tweetCtrl.RetrieveByTweetID(ctx, types.TweetId(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !media[0].Sensitive || media[0].ContentWarning != warning {
+		t.Errorf("cached tweet is not marked as sensitive: %+v", media[0])
+	}
+}
+
+func TestHandler_UpdateAltText(t *testing.T) {
+	ctx := userContext(1)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
+	cache := localcache.New(10)
+	tweetCtrl := controller.New(mockTweetRepo, local.New("./"), cache, nil, nil, nil)
+	tweetHandler := New(tweetCtrl)
+
+	altText := "a cat sitting on a keyboard"
+	mediaUrl := "cat.png"
+	mockTweetRepo.EXPECT().GetByTweet(ctx, types.TweetId(1)).Return(
+		[]model.Tweet{{UserId: 1, TweetId: 1, Content: "content", MediaUrl: &mediaUrl}}, nil,
+	)
+	mockTweetRepo.EXPECT().GetByTweet(ctx, types.TweetId(2)).Return(
+		[]model.Tweet{{UserId: 1, TweetId: 2, Content: "content"}}, nil,
+	)
+	mockTweetRepo.EXPECT().UpdateAltText(ctx, types.TweetId(1), &altText).Return(nil)
+
+	testCases := []struct {
+		name    string
+		tweetId types.TweetId
+		altText string
+		want    int
+	}{
+		{name: "updated", tweetId: 1, altText: altText, want: http.StatusOK},
+		{name: "noMedia", tweetId: 2, altText: altText, want: http.StatusBadRequest},
+		{name: "tooLong", tweetId: 1, altText: strings.Repeat("a", model.MaxAltTextLength+1), want: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				payloadBytes, err := json.Marshal(
+					struct {
+						TweetId types.TweetId `json:"tweet_id"`
+						AltText string        `json:"alt_text"`
+					}{tc.tweetId, tc.altText},
+				)
+				if err != nil {
+					log.Fatalf("Failed to marshal payload: %v", err)
+				}
+
+				req, err := http.NewRequestWithContext(ctx, "PUT", "/update_alt_text", bytes.NewReader(payloadBytes))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				handler := http.HandlerFunc(tweetHandler.UpdateAltText)
+				handler.ServeHTTP(rr, req)
+
+				if status := rr.Code; status != tc.want {
+					t.Errorf("handler returned wrong status code: got %v want %v", status, tc.want)
+				}
+			},
+		)
+	}
+
+	// cached tweet returns new description
+	media, err := tweetCtrl.RetrieveByTweetID(ctx, types.TweetId(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media[0].AltText != altText {
+		t.Errorf("cached tweet has wrong alt text: %+v", media[0])
+	}
+}
+
+func TestHandler_PostAltText(t *testing.T) {
+	ctx := userContext(1)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
+	mockstorage := mockStorage.NewMockStorage(mockCtrl)
+	tweetCtrl := controller.New(mockTweetRepo, mockstorage, nil, nil, nil, nil)
+	tweetHandler := New(tweetCtrl)
+
+	mediaUrl, altText := "cat.png", "a cat sitting on a keyboard"
+	mockstorage.EXPECT().SaveImageFromRequest(gomock.Any(), gomock.Any()).Return(mediaUrl, nil)
+	mockTweetRepo.EXPECT().
+		Put(
+			ctx, model.Tweet{
+				UserId: 1, Content: "content", MediaUrl: &mediaUrl, MediaAltText: &altText,
+				ReplyPolicy: model.ReplyEveryone, Lang: lang.Undetermined,
+			},
+		).
+		Return(types.TweetId(1), time.Now(), nil)
+
+	testCases := []struct {
+		name    string
+		media   bool
+		altText string
+		want    int
+	}{
+		{name: "withMedia", media: true, altText: altText, want: http.StatusOK},
+		{name: "withoutMedia", media: false, altText: altText, want: http.StatusBadRequest},
+		{name: "tooLong", media: true, altText: strings.Repeat("a", model.MaxAltTextLength+1), want: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				body := &bytes.Buffer{}
+				form := multipart.NewWriter(body)
+				form.WriteField("content", "content")
+				form.WriteField("alt_text", tc.altText)
+				if tc.media {
+					part, err := form.CreateFormFile("media", "cat.png")
+					if err != nil {
+						t.Fatal(err)
+					}
+					part.Write([]byte("image"))
+				}
+				form.Close()
+
+				req, err := http.NewRequestWithContext(ctx, "POST", "/post_tweet", body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Content-Type", form.FormDataContentType())
+				rr := httptest.NewRecorder()
+				handler := http.HandlerFunc(tweetHandler.Post)
+				handler.ServeHTTP(rr, req)
+
+				if status := rr.Code; status != tc.want {
+					t.Errorf("handler returned wrong status code: got %v want %v", status, tc.want)
+				}
+			},
+		)
+	}
+}
+
+func TestHandler_ResolveReports(t *testing.T) {
+	// moderator resolves reports of tweet of another user
+	ctx := userContext(9)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var events bytes.Buffer
+	mockTweetRepo := mockcontroller.NewMocktweetsRepository(mockCtrl)
+	mockstorage := mockStorage.NewMockStorage(mockCtrl)
+	tweetCtrl := controller.New(mockTweetRepo, mockstorage, nil, nil, nil, auditfile.NewWriter(&events))
+	tweetHandler := New(tweetCtrl)
+
+	mediaUrl := "cat.png"
+	tweet := model.Tweet{UserId: 1, TweetId: 1, Content: "spam", MediaUrl: &mediaUrl}
+	mockTweetRepo.EXPECT().GetByTweet(ctx, types.TweetId(1)).Return([]model.Tweet{tweet}, nil).Times(2)
+	mockTweetRepo.EXPECT().
+		ResolveReports(ctx, types.TweetId(1), types.UserId(9), model.ResolutionDelete, gomock.Any()).
+		Return(nil)
+	mockTweetRepo.EXPECT().DeletePost(ctx, types.TweetId(1)).Return(nil)
+	mockstorage.EXPECT().Delete(mediaUrl).Return(nil)
+	// already resolved
+	mockTweetRepo.EXPECT().
+		ResolveReports(ctx, types.TweetId(1), types.UserId(9), model.ResolutionDismiss, gomock.Any()).
+		Return(mysql.ErrNotFound)
+
+	testCases := []struct {
+		name       string
+		resolution model.Resolution
+		want       int
+	}{
+		{name: "delete", resolution: model.ResolutionDelete, want: http.StatusOK},
+		{name: "resolved", resolution: model.ResolutionDismiss, want: http.StatusNotFound},
+		{name: "invalid", resolution: "ban", want: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				body, _ := json.Marshal(map[string]interface{}{"tweet_id": 1, "resolution": tc.resolution})
+				req, err := http.NewRequestWithContext(ctx, "POST", "/admin/resolve_report", bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				rr := httptest.NewRecorder()
+				handler := http.HandlerFunc(tweetHandler.ResolveReports)
+				handler.ServeHTTP(rr, req)
+
+				if status := rr.Code; status != tc.want {
+					t.Errorf("handler returned wrong status code: got %v want %v", status, tc.want)
+				}
+			},
+		)
+	}
+
+	var event audit.Event
+	if err := json.Unmarshal(events.Bytes(), &event); err != nil {
+		t.Fatalf("resolution should be recorded: %s", err)
+	}
+	if event.Type != audit.TweetReportResolved || event.UserId != 1 || event.ActorId != 9 {
+		t.Errorf("wrong event: %+v", event)
+	}
+}
@@ -33,26 +33,36 @@ func New(driverName string, dataSourceName string) (*Repository, error) {
 	return &Repository{db}, nil
 }
 
-// Put new tweet to database
-func (r *Repository) Put(
-	ctx context.Context,
-	userId types.UserId,
-	content string,
-	mediaUrl *string,
-	retweetId *types.TweetId,
-) (types.TweetId, time.Time, error) {
+// Put new tweet to database, users mentioned in tweet are saved in the same transaction
+func (r *Repository) Put(ctx context.Context, tweet model.Tweet) (types.TweetId, time.Time, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return types.TweetId(0), time.Time{}, err
+	}
+	defer tx.Rollback()
+
 	createdAt := time.Now()
-	row, err := r.db.ExecContext(
+	row, err := tx.ExecContext(
 		ctx,
-		"INSERT INTO Tweets (user_id, retweet_id, content, media_url, created_at) VALUES (?, ?, ?, ?, ?)",
-		userId, retweetId, content, mediaUrl, createdAt.Format(layout),
+		"INSERT INTO Tweets (user_id, retweet_id, content, media_url, created_at, reply_id, reply_policy, lang, sensitive, content_warning, media_alt_text, entities) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		tweet.UserId, tweet.RetweetId, tweet.Content, tweet.MediaUrl, createdAt.Format(layout),
+		tweet.ReplyId, tweet.ReplyPolicy, tweet.Lang, tweet.Sensitive, tweet.ContentWarning,
+		tweet.MediaAltText, tweet.Entities,
 	)
 	if err != nil {
 		return types.TweetId(0), time.Time{}, err
 	}
 	id, err := row.LastInsertId()
+	if err != nil {
+		return types.TweetId(0), time.Time{}, err
+	}
 	tweetId := types.TweetId(id)
-	return tweetId, createdAt, err
+
+	// mentions are saved to check reply policy later
+	if err = putMentions(ctx, tx, tweetId, tweet.Entities.Mentions()...); err != nil {
+		return types.TweetId(0), time.Time{}, err
+	}
+	return tweetId, createdAt, tx.Commit()
 }
 
 // helper function to retrieve tweets from database
@@ -75,6 +85,9 @@ func get(ctx context.Context, r *Repository, idName string, ids []interface{}) (
 			&tweet.TweetId, &tweet.UserId,
 			&tweet.RetweetId, &tweet.Content,
 			&tweet.MediaUrl, &createdAtStr,
+			&tweet.ReplyId, &tweet.ReplyPolicy,
+			&tweet.Lang, &tweet.Sensitive, &tweet.ContentWarning,
+			&tweet.MediaAltText, &tweet.Entities,
 		); err != nil {
 			return nil, err
 		}
@@ -87,7 +100,7 @@ func get(ctx context.Context, r *Repository, idName string, ids []interface{}) (
 		res = append(res, tweet)
 	}
 	if len(res) == 0 {
-		return nil, fmt.Errorf("there are no tweets for your request")
+		return nil, ErrNotFound
 	}
 	return res, nil
 }
@@ -117,3 +130,125 @@ func (r *Repository) DeletePost(ctx context.Context, postId types.TweetId) error
 	_, err := r.db.ExecContext(ctx, "DELETE FROM Tweets WHERE tweet_id = ?", postId)
 	return err
 }
+
+// UpdateSensitive set sensitive flag and content warning of the tweet
+func (r *Repository) UpdateSensitive(
+	ctx context.Context,
+	tweetId types.TweetId,
+	sensitive bool,
+	contentWarning *string,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"UPDATE Tweets SET sensitive = ?, content_warning = ? WHERE tweet_id = ?",
+		sensitive, contentWarning, tweetId,
+	)
+	return err
+}
+
+// UpdateAltText set media description of the tweet
+func (r *Repository) UpdateAltText(ctx context.Context, tweetId types.TweetId, altText *string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE Tweets SET media_alt_text = ? WHERE tweet_id = ?", altText, tweetId)
+	return err
+}
+
+// save users mentioned in tweet, nicknames are resolved to user ids
+func putMentions(ctx context.Context, tx *sql.Tx, tweetId types.TweetId, nicknames ...string) error {
+	if len(nicknames) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(nicknames)+1)
+	args = append(args, tweetId)
+	for _, nickname := range nicknames {
+		args = append(args, nickname)
+	}
+	placeholder := strings.TrimSuffix(strings.Repeat("?,", len(nicknames)), ",")
+	query := fmt.Sprintf(
+		"INSERT IGNORE INTO Mentions (tweet_id, user_id) SELECT ?, user_id FROM User WHERE nickname IN (%s)",
+		placeholder,
+	)
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetMentions retrieve users mentioned in tweet
+func (r *Repository) GetMentions(ctx context.Context, tweetId types.TweetId) ([]types.UserId, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT user_id FROM Mentions WHERE tweet_id = ?", tweetId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []types.UserId
+	for rows.Next() {
+		var id types.UserId
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		res = append(res, id)
+	}
+	return res, rows.Err()
+}
+
+// PutReport save report of the tweet, report of the same user is reopened with the new reason
+func (r *Repository) PutReport(ctx context.Context, report model.Report) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"INSERT INTO TweetReports (tweet_id, user_id, reason, created_at) VALUES (?, ?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE reason = VALUES(reason), created_at = VALUES(created_at), "+
+			"resolved_at = NULL, resolved_by = NULL, resolution = NULL",
+		report.TweetId, report.UserId, report.Reason, report.CreatedAt.UTC().Format(layout),
+	)
+	return err
+}
+
+// GetOpenReports retrieve reports that are not resolved yet, oldest first
+func (r *Repository) GetOpenReports(ctx context.Context) ([]model.Report, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		"SELECT tweet_id, user_id, reason, created_at FROM TweetReports WHERE resolved_at IS NULL ORDER BY created_at",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []model.Report
+	for rows.Next() {
+		var (
+			report       model.Report
+			createdAtStr string
+		)
+		if err := rows.Scan(&report.TweetId, &report.UserId, &report.Reason, &createdAtStr); err != nil {
+			return nil, err
+		}
+		if report.CreatedAt, err = time.Parse(layout, createdAtStr); err != nil {
+			return nil, err
+		}
+		res = append(res, report)
+	}
+	return res, rows.Err()
+}
+
+// ResolveReports close open reports of the tweet, ErrNotFound is returned if there are none
+func (r *Repository) ResolveReports(
+	ctx context.Context,
+	tweetId types.TweetId,
+	resolvedBy types.UserId,
+	resolution model.Resolution,
+	resolvedAt time.Time,
+) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		"UPDATE TweetReports SET resolved_at = ?, resolved_by = ?, resolution = ? WHERE tweet_id = ? AND resolved_at IS NULL",
+		resolvedAt.UTC().Format(layout), resolvedBy, resolution, tweetId,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"context"
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
@@ -21,11 +22,22 @@ func TestRepository_Put(t *testing.T) {
 	repo := Repository{db}
 	ctx := context.Background()
 
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO Tweets").
-		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "some content", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(
+			sqlmock.AnyArg(), sqlmock.AnyArg(), "some content", sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), model.ReplyEveryone, "en", true, "spoiler", "a cat",
+			sqlmock.AnyArg(),
+		).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	_, _, err = repo.Put(ctx, types.UserId(1), "some content", nil, nil)
+	warning, altText := "spoiler", "a cat"
+	tweet := model.Tweet{
+		UserId: 1, Content: "some content", ReplyPolicy: model.ReplyEveryone, Lang: "en",
+		Sensitive: true, ContentWarning: &warning, MediaAltText: &altText,
+	}
+	_, _, err = repo.Put(ctx, tweet)
 	if err != nil {
 		t.Errorf("error was not expected while inserting tweet: %s", err)
 	}
@@ -74,12 +86,15 @@ func TestRepository_Get(t *testing.T) {
 	mediaUrl := "url"
 	retweetId := types.TweetId(2)
 	want := model.Tweet{
-		TweetId:   types.TweetId(1),
-		UserId:    types.UserId(1),
-		RetweetId: &retweetId,
-		MediaUrl:  &mediaUrl,
-		Content:   "content",
-		CreatedAt: curTime,
+		TweetId:     types.TweetId(1),
+		UserId:      types.UserId(1),
+		RetweetId:   &retweetId,
+		MediaUrl:    &mediaUrl,
+		Content:     "content",
+		CreatedAt:   curTime,
+		ReplyPolicy: model.ReplyEveryone,
+		Lang:        "en",
+		Entities:    model.Entities{{Type: model.EntityHashtag, Start: 0, End: 4, Text: "tag"}},
 	}
 
 	testCases := []struct {
@@ -99,8 +114,14 @@ func TestRepository_Get(t *testing.T) {
 		t.Run(
 			tc.name, func(t *testing.T) {
 				// Create rows to return
-				rows := sqlmock.NewRows([]string{"user_id", "tweet_id", "retweet_id", "content", "media_url", "created_at"}).
-					AddRow(1, 1, 2, "content", "url", curTime.Format(layout))
+				rows := sqlmock.NewRows(
+					[]string{
+						"user_id", "tweet_id", "retweet_id", "content", "media_url", "created_at",
+						"reply_id", "reply_policy", "lang", "sensitive", "content_warning", "media_alt_text", "entities",
+					},
+				).
+					AddRow(1, 1, 2, "content", "url", curTime.Format(layout), nil, "everyone", "en", false, nil, nil,
+						`[{"type":"hashtag","start":0,"end":4,"text":"tag"}]`)
 				// Set expectation
 				mock.ExpectQuery(tc.query).
 					WithArgs(1).
@@ -133,3 +154,149 @@ func TestRepository_Get(t *testing.T) {
 		)
 	}
 }
+
+func TestRepository_UpdateSensitive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+
+	warning := "spoiler"
+	mock.ExpectExec("UPDATE Tweets SET sensitive = \\?, content_warning = \\? WHERE tweet_id = \\?").
+		WithArgs(true, warning, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err = repo.UpdateSensitive(ctx, types.TweetId(1), true, &warning); err != nil {
+		t.Errorf("error was not expected while updating tweet: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_UpdateAltText(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+
+	altText := "a cat sitting on a keyboard"
+	mock.ExpectExec("UPDATE Tweets SET media_alt_text = \\? WHERE tweet_id = \\?").
+		WithArgs(altText, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err = repo.UpdateAltText(ctx, types.TweetId(1), &altText); err != nil {
+		t.Errorf("error was not expected while updating tweet: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_Mentions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+
+	// mentions are saved with the tweet
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO Tweets").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT IGNORE INTO Mentions").
+		WithArgs(1, "alex", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT user_id FROM Mentions WHERE tweet_id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2).AddRow(3))
+	// tweet is not saved without its mentions
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO Tweets").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT IGNORE INTO Mentions").
+		WithArgs(2, "alex").
+		WillReturnError(errors.New("connection lost"))
+	mock.ExpectRollback()
+
+	content := "hi @alex and @bob"
+	tweet := model.Tweet{UserId: 1, Content: content, Entities: model.ParseEntities(content)}
+	if _, _, err = repo.Put(ctx, tweet); err != nil {
+		t.Errorf("error was not expected while inserting tweet with mentions: %s", err)
+	}
+	users, err := repo.GetMentions(ctx, types.TweetId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting mentions: %s", err)
+	}
+	if diff := cmp.Diff([]types.UserId{2, 3}, users); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	content = "hi @alex"
+	tweet = model.Tweet{UserId: 1, Content: content, Entities: model.ParseEntities(content)}
+	if _, _, err = repo.Put(ctx, tweet); err == nil {
+		t.Errorf("error was expected when mentions are not saved")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_Reports(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	report := model.Report{TweetId: 1, UserId: 2, Reason: "spam", CreatedAt: createdAt}
+
+	mock.ExpectExec("INSERT INTO TweetReports .* ON DUPLICATE KEY UPDATE").
+		WithArgs(1, 2, "spam", "2023-01-02 03:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT tweet_id, user_id, reason, created_at FROM TweetReports WHERE resolved_at IS NULL").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"tweet_id", "user_id", "reason", "created_at"}).
+				AddRow(1, 2, "spam", "2023-01-02 03:04:05"),
+		)
+	mock.ExpectExec("UPDATE TweetReports SET resolved_at = \\?, resolved_by = \\?, resolution = \\? WHERE tweet_id = \\? AND resolved_at IS NULL").
+		WithArgs("2023-01-02 03:04:05", 3, model.ResolutionDismiss, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// already resolved
+	mock.ExpectExec("UPDATE TweetReports SET resolved_at").
+		WithArgs("2023-01-02 03:04:05", 3, model.ResolutionDismiss, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err = repo.PutReport(ctx, report); err != nil {
+		t.Errorf("error was not expected while reporting tweet: %s", err)
+	}
+	reports, err := repo.GetOpenReports(ctx)
+	if err != nil {
+		t.Errorf("error was not expected while retrieving reports: %s", err)
+	}
+	if diff := cmp.Diff([]model.Report{report}, reports); diff != "" {
+		t.Errorf("wrong reports (-want +got):\n%s", diff)
+	}
+	if err = repo.ResolveReports(ctx, 1, 3, model.ResolutionDismiss, createdAt); err != nil {
+		t.Errorf("error was not expected while resolving reports: %s", err)
+	}
+	if err = repo.ResolveReports(ctx, 1, 3, model.ResolutionDismiss, createdAt); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// kind of rich text entity
+type EntityType string
+
+const (
+	EntityUrl     EntityType = "url"
+	EntityMention EntityType = "mention"
+	EntityHashtag EntityType = "hashtag"
+	EntityCashtag EntityType = "cashtag"
+)
+
+// Entity is a part of tweet content, start and end are unicode code point offsets,
+// end is exclusive. Text is the entity without leading @, # or $
+type Entity struct {
+	Type  EntityType `json:"type"`
+	Start int        `json:"start"`
+	End   int        `json:"end"`
+	Text  string     `json:"text"`
+}
+
+// Entities is stored as json column
+type Entities []Entity
+
+// Value implements driver.Valuer, empty entities are stored as NULL
+func (e Entities) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (e *Entities) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for entities")
+	}
+	return json.Unmarshal(data, e)
+}
+
+var (
+	urlRegexp = regexp.MustCompile(`https?://[^\s]+`)
+	// mentions are @nickname, nickname is limited by User table
+	mentionRegexp = regexp.MustCompile(`(?:^|[^\w@])(@([A-Za-z0-9_]{1,15}))\b`)
+	hashtagRegexp = regexp.MustCompile(`(?:^|[^\w#&])(#([\p{L}\p{N}_]*\p{L}[\p{L}\p{N}_]*))`)
+	cashtagRegexp = regexp.MustCompile(`(?:^|[^\w$])(\$([A-Za-z]{1,6}(?:[._][A-Za-z]{1,2})?))\b`)
+)
+
+// characters that usually end a sentence rather than a link
+const urlTrailing = ".,:;!?'\")]}"
+
+// ParseEntities finds links, mentions, hashtags and cashtags in tweet content
+func ParseEntities(content string) Entities {
+	var (
+		entities Entities
+		// byte ranges already taken by links
+		links [][]int
+	)
+
+	// byte offset -> code point offset
+	toRunes := func(i int) int {
+		return utf8.RuneCountInString(content[:i])
+	}
+
+	for _, loc := range urlRegexp.FindAllStringIndex(content, -1) {
+		url := strings.TrimRight(content[loc[0]:loc[1]], urlTrailing)
+		end := loc[0] + len(url)
+		links = append(links, []int{loc[0], end})
+		entities = append(
+			entities, Entity{Type: EntityUrl, Start: toRunes(loc[0]), End: toRunes(end), Text: url},
+		)
+	}
+
+	insideLink := func(i int) bool {
+		for _, link := range links {
+			if i >= link[0] && i < link[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, tag := range []struct {
+		t      EntityType
+		regexp *regexp.Regexp
+	}{
+		{EntityMention, mentionRegexp},
+		{EntityHashtag, hashtagRegexp},
+		{EntityCashtag, cashtagRegexp},
+	} {
+		// submatch 1 is the entity with prefix, submatch 2 is the entity text
+		for _, loc := range tag.regexp.FindAllStringSubmatchIndex(content, -1) {
+			if insideLink(loc[2]) {
+				continue
+			}
+			entities = append(
+				entities, Entity{
+					Type:  tag.t,
+					Start: toRunes(loc[2]),
+					End:   toRunes(loc[3]),
+					Text:  content[loc[4]:loc[5]],
+				},
+			)
+		}
+	}
+
+	sort.Slice(
+		entities, func(i, j int) bool {
+			return entities[i].Start < entities[j].Start
+		},
+	)
+	return entities
+}
+
+// Mentions returns unique mentioned nicknames
+func (e Entities) Mentions() []string {
+	var (
+		nicknames []string
+		seen      = make(map[string]bool)
+	)
+	for _, entity := range e {
+		if entity.Type == EntityMention && !seen[entity.Text] {
+			seen[entity.Text] = true
+			nicknames = append(nicknames, entity.Text)
+		}
+	}
+	return nicknames
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestParseEntities(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		want    Entities
+	}{
+		{
+			name:    "all",
+			content: "hi @alex, see https://example.com/a?b=1. #go $TSLA",
+			want: Entities{
+				{Type: EntityMention, Start: 3, End: 8, Text: "alex"},
+				{Type: EntityUrl, Start: 14, End: 39, Text: "https://example.com/a?b=1"},
+				{Type: EntityHashtag, Start: 41, End: 44, Text: "go"},
+				{Type: EntityCashtag, Start: 45, End: 50, Text: "TSLA"},
+			},
+		},
+		{
+			name:    "codePoints",
+			content: "привет 👋 #мир @bob",
+			want: Entities{
+				{Type: EntityHashtag, Start: 9, End: 13, Text: "мир"},
+				{Type: EntityMention, Start: 14, End: 18, Text: "bob"},
+			},
+		},
+		{
+			name:    "notEntities",
+			content: "mail me@example.com, #123, $100 and https://t.co/#tag",
+			want: Entities{
+				{Type: EntityUrl, Start: 36, End: 53, Text: "https://t.co/#tag"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				if diff := cmp.Diff(tc.want, ParseEntities(tc.content)); diff != "" {
+					t.Errorf("mismatch (-want +got):\n%s", diff)
+				}
+			},
+		)
+	}
+}
+
+func TestEntities_Mentions(t *testing.T) {
+	entities := ParseEntities("@alex @bob @alex #alex")
+	if diff := cmp.Diff([]string{"alex", "bob"}, entities.Mentions()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
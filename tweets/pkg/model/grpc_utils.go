@@ -3,6 +3,7 @@ package model
 import (
 	"fmt"
 	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/tweets"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -15,9 +16,17 @@ func MediaToProto(m *Media) *gen.Media {
 	}
 
 	return &gen.Media{
-		Media:     m.Media,
-		Content:   m.Content,
-		CreatedAt: protoTimestamp,
+		TweetId:        int32(m.TweetId),
+		ReplyId:        tweetIdToProto(m.ReplyId),
+		RetweetId:      tweetIdToProto(m.RetweetId),
+		Media:          m.Media,
+		Content:        m.Content,
+		CreatedAt:      protoTimestamp,
+		Lang:           m.Lang,
+		Sensitive:      m.Sensitive,
+		ContentWarning: m.ContentWarning,
+		AltText:        m.AltText,
+		Entities:       EntitiesToProto(m.Entities),
 	}
 }
 
@@ -25,8 +34,66 @@ func MediaToProto(m *Media) *gen.Media {
 // media counterpart.
 func MediaFromProto(m *gen.Media) *Media {
 	return &Media{
-		Media:     m.Media,
-		Content:   m.Content,
-		CreatedAt: m.CreatedAt.AsTime(),
+		TweetId:        types.TweetId(m.TweetId),
+		ReplyId:        tweetIdFromProto(m.ReplyId),
+		RetweetId:      tweetIdFromProto(m.RetweetId),
+		Media:          m.Media,
+		Content:        m.Content,
+		CreatedAt:      m.CreatedAt.AsTime(),
+		Lang:           m.Lang,
+		Sensitive:      m.Sensitive,
+		ContentWarning: m.ContentWarning,
+		AltText:        m.AltText,
+		Entities:       EntitiesFromProto(m.Entities),
 	}
 }
+
+// optional tweet id is zero in proto
+func tweetIdToProto(id *types.TweetId) int32 {
+	if id == nil {
+		return 0
+	}
+	return int32(*id)
+}
+
+func tweetIdFromProto(id int32) *types.TweetId {
+	if id == 0 {
+		return nil
+	}
+	tweetId := types.TweetId(id)
+	return &tweetId
+}
+
+// EntitiesToProto converts entities into
+// generated proto counterpart.
+func EntitiesToProto(entities Entities) []*gen.Entity {
+	protoEntities := make([]*gen.Entity, 0, len(entities))
+	for _, e := range entities {
+		protoEntities = append(
+			protoEntities, &gen.Entity{
+				Type:  string(e.Type),
+				Start: int32(e.Start),
+				End:   int32(e.End),
+				Text:  e.Text,
+			},
+		)
+	}
+	return protoEntities
+}
+
+// EntitiesFromProto converts proto entities into
+// model counterpart.
+func EntitiesFromProto(protoEntities []*gen.Entity) Entities {
+	var entities Entities
+	for _, e := range protoEntities {
+		entities = append(
+			entities, Entity{
+				Type:  EntityType(e.Type),
+				Start: int(e.Start),
+				End:   int(e.End),
+				Text:  e.Text,
+			},
+		)
+	}
+	return entities
+}
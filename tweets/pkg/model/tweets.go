@@ -1,21 +1,119 @@
 package model
 
 import "time"
+import "github.com/alexvishnevskiy/twitter-clone/internal/lang"
 import "github.com/alexvishnevskiy/twitter-clone/internal/types"
 
+// who is allowed to reply to a tweet
+type ReplyPolicy string
+
+const (
+	ReplyEveryone  ReplyPolicy = "everyone"
+	ReplyFollowing ReplyPolicy = "following"
+	ReplyMentioned ReplyPolicy = "mentioned"
+)
+
+// check that reply policy is one of the known values
+func (p ReplyPolicy) Valid() bool {
+	switch p {
+	case ReplyEveryone, ReplyFollowing, ReplyMentioned:
+		return true
+	}
+	return false
+}
+
 // tweets data types
 type Tweet struct {
-	UserId    types.UserId   `json:"user_id"`
-	TweetId   types.TweetId  `json:"tweet_id"`
-	RetweetId *types.TweetId `json:"retweet_id"`
-	Content   string         `json:"content"`
-	MediaUrl  *string        `json:"media_url"`
-	CreatedAt time.Time      `json:"created_at"`
+	UserId      types.UserId   `json:"user_id"`
+	TweetId     types.TweetId  `json:"tweet_id"`
+	RetweetId   *types.TweetId `json:"retweet_id"`
+	Content     string         `json:"content"`
+	MediaUrl    *string        `json:"media_url"`
+	CreatedAt   time.Time      `json:"created_at"`
+	ReplyId     *types.TweetId `json:"reply_id"`
+	ReplyPolicy ReplyPolicy    `json:"reply_policy"`
+	Lang        string         `json:"lang"`
+	// sensitive media is hidden behind content warning
+	Sensitive      bool    `json:"sensitive"`
+	ContentWarning *string `json:"content_warning"`
+	// description of media for screen readers
+	MediaAltText *string `json:"media_alt_text"`
+	// links, mentions, hashtags and cashtags parsed from content
+	Entities Entities `json:"entities"`
 }
 
 // struct for media
 type Media struct {
-	Media     string    `json:"media"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	TweetId types.TweetId `json:"tweet_id"`
+	// set for replies and retweets
+	ReplyId   *types.TweetId `json:"reply_id,omitempty"`
+	RetweetId *types.TweetId `json:"retweet_id,omitempty"`
+	Media     string         `json:"media"`
+	Content   string         `json:"content"`
+	CreatedAt time.Time      `json:"created_at"`
+	Lang      string         `json:"lang"`
+	// set by author or moderator, blurred is set by timeline according to user preferences
+	Sensitive      bool     `json:"sensitive"`
+	ContentWarning string   `json:"content_warning"`
+	Blurred        bool     `json:"blurred,omitempty"`
+	AltText        string   `json:"alt_text"`
+	Entities       Entities `json:"entities"`
+}
+
+// maximum length of content warning
+const MaxContentWarningLength = 100
+
+// maximum length of media description
+const MaxAltTextLength = 1000
+
+// maximum length of reason of the report
+const MaxReportReasonLength = 200
+
+// Report of the tweet by user, waiting for review by moderator
+type Report struct {
+	TweetId   types.TweetId `json:"tweet_id"`
+	UserId    types.UserId  `json:"user_id"`
+	Reason    string        `json:"reason"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// ReportedTweet is a tweet with its open reports
+type ReportedTweet struct {
+	Tweet   Tweet    `json:"tweet"`
+	Reports []Report `json:"reports"`
+}
+
+// decision of moderator on reported tweet
+type Resolution string
+
+const (
+	// tweet is fine, reports are closed
+	ResolutionDismiss Resolution = "dismiss"
+	// media of the tweet is hidden behind content warning
+	ResolutionSensitive Resolution = "sensitive"
+	// tweet is deleted
+	ResolutionDelete Resolution = "delete"
+)
+
+// check that resolution is one of the known values
+func (r Resolution) Valid() bool {
+	switch r {
+	case ResolutionDismiss, ResolutionSensitive, ResolutionDelete:
+		return true
+	}
+	return false
+}
+
+// FilterByLang keeps media written in one of the languages, empty langs keep everything
+func FilterByLang(media []Media, langs []string) []Media {
+	if len(langs) == 0 {
+		return media
+	}
+	filtered := make([]Media, 0, len(media))
+	for _, m := range media {
+		if lang.Match(m.Lang, langs) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
 }
@@ -1,25 +1,92 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/users"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	auditfile "github.com/alexvishnevskiy/twitter-clone/internal/audit/file"
+	auditmysql "github.com/alexvishnevskiy/twitter-clone/internal/audit/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	authmysql "github.com/alexvishnevskiy/twitter-clone/internal/auth/mysql"
 	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/mailer"
+	"github.com/alexvishnevskiy/twitter-clone/internal/mailer/file"
+	"github.com/alexvishnevskiy/twitter-clone/internal/mailer/smtp"
+	"github.com/alexvishnevskiy/twitter-clone/internal/ratelimit"
+	"github.com/alexvishnevskiy/twitter-clone/internal/storage/local"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	_ "github.com/alexvishnevskiy/twitter-clone/users/docs"
 	"github.com/alexvishnevskiy/twitter-clone/users/internal/controller"
+	followGateway "github.com/alexvishnevskiy/twitter-clone/users/internal/gateway/follow/grpc"
+	likesGateway "github.com/alexvishnevskiy/twitter-clone/users/internal/gateway/likes/http"
+	tweetsGateway "github.com/alexvishnevskiy/twitter-clone/users/internal/gateway/tweets/grpc"
+	grpchandler "github.com/alexvishnevskiy/twitter-clone/users/internal/handler/grpc"
 	httphandler "github.com/alexvishnevskiy/twitter-clone/users/internal/handler/http"
+	"github.com/alexvishnevskiy/twitter-clone/users/internal/names"
 	"github.com/alexvishnevskiy/twitter-clone/users/internal/repository/mysql"
+	"github.com/soheilhy/cmux"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"time"
 )
 
-//	@title			Users API documentation
-//	@version		1.0.0
-//	@host			localhost:8084
-//	@description	This is API for users service
+// @title			Users API documentation
+// @version		1.0.0
+// @host			localhost:8084
+// @description	This is API for users service
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
 func main() {
-	var port int
+	var (
+		port          int
+		storagePath   string
+		smtpHost      string
+		smtpPort      int
+		smtpUser      string
+		smtpPassword  string
+		mailFrom      string
+		mailPath      string
+		verifyUrl     string
+		resetUrl      string
+		jwtKeyFile    string
+		jwtPrevFiles  string
+		auditPath     string
+		followPort    int
+		tweetsPort    int
+		likesPort     int
+		exportUrl     string
+		purgeInterval time.Duration
+		reservedPath  string
+		blockedPath   string
+	)
 	flag.IntVar(&port, "port", 8084, "API handler port")
+	flag.StringVar(&storagePath, "storage_path", getStoragePath(), "storage path")
+	flag.StringVar(&smtpHost, "smtp_host", "", "SMTP host, emails are written to mail_path if empty")
+	flag.IntVar(&smtpPort, "smtp_port", 587, "SMTP port")
+	flag.StringVar(&smtpUser, "smtp_user", "", "SMTP username")
+	flag.StringVar(&smtpPassword, "smtp_password", "", "SMTP password")
+	flag.StringVar(&mailFrom, "mail_from", "no-reply@localhost", "sender of emails")
+	flag.StringVar(&mailPath, "mail_path", "", "file to write emails to, stderr if empty")
+	flag.StringVar(&verifyUrl, "verify_url", "", "url of verify_email endpoint sent in emails")
+	flag.StringVar(&resetUrl, "reset_url", "", "url of password reset form sent in emails")
+	flag.StringVar(&jwtKeyFile, "jwt_private_key", "", "PEM file with RSA or Ed25519 key that signs tokens")
+	flag.StringVar(&jwtPrevFiles, "jwt_previous_keys", "", "comma separated PEM files with public keys accepted during rotation")
+	flag.StringVar(&auditPath, "audit_path", "", "file to write audit events to, stderr if empty")
+	flag.IntVar(&followPort, "follow_port", 8082, "follow API handler port")
+	flag.IntVar(&tweetsPort, "tweets_port", 8080, "tweets API handler port")
+	flag.IntVar(&likesPort, "likes_port", 8081, "likes API handler port")
+	flag.StringVar(&exportUrl, "export_url", "", "url of data export download endpoint sent in emails")
+	flag.DurationVar(&purgeInterval, "purge_interval", time.Hour, "how often deactivated accounts, expired exports and nickname history are purged")
+	flag.StringVar(&reservedPath, "reserved_nicknames", "", "file with reserved nicknames, one per line, built-in list if empty")
+	flag.StringVar(&blockedPath, "blocked_nicknames", "", "file with words that nicknames can't contain, one per line")
 	flag.Parse()
 	log.Printf("Starting users service on port %d", port)
 
@@ -28,20 +95,268 @@ func main() {
 		log.Printf("Error: %v\n", err)
 	}
 
-	ctrl := controller.New(repo)
+	if verifyUrl == "" {
+		verifyUrl = fmt.Sprintf("http://localhost:%d/verify_email", port)
+	}
+	if resetUrl == "" {
+		resetUrl = fmt.Sprintf("http://localhost:%d/reset_password", port)
+	}
+	if exportUrl == "" {
+		exportUrl = fmt.Sprintf("http://localhost:%d/export/download", port)
+	}
+
+	// send emails with smtp or write them locally
+	var mail mailer.Mailer = file.NewWriter(os.Stderr)
+	if smtpHost != "" {
+		mail = smtp.New(smtpHost, smtpPort, smtpUser, smtpPassword, mailFrom)
+	} else if mailPath != "" {
+		mail, err = file.New(mailPath)
+		if err != nil {
+			log.Fatalf("failed to open mail file: %v", err)
+		}
+	}
+
+	keys, err := loadKeySet(jwtKeyFile, jwtPrevFiles)
+	if err != nil {
+		log.Fatalf("failed to load jwt keys: %v", err)
+	}
+	jwt.SetKeySet(keys)
+
+	// personal access tokens can't be used with users service, they are looked up
+	// to tell them from invalid tokens
+	tokenStore, err := authmysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
+	if err != nil {
+		log.Fatalf("failed to open access tokens: %v", err)
+	}
+	auth.SetTokenStore(tokenStore)
+	// revoked access tokens and sessions are shared through the database
+	auth.SetSessionStore(tokenStore)
+	jwt.SetDenylist(tokenStore)
+
+	// write audit events locally
+	var recorder audit.Recorder = auditfile.NewWriter(os.Stderr)
+	if auditPath != "" {
+		recorder, err = auditfile.New(auditPath)
+		if err != nil {
+			log.Fatalf("failed to open audit file: %v", err)
+		}
+	}
+	// history of every service is kept in the database for admins
+	auditLog, err := auditmysql.New("mysql", "root:root@tcp(localhost:3306)/twitter")
+	if err != nil {
+		log.Fatalf("failed to open audit log: %v", err)
+	}
+	recorder = audit.Multi(recorder, auditLog)
+
+	// nicknames that look like staff or contain blocked words are rejected
+	reserved, blocked := names.DefaultReserved, []string(nil)
+	if reservedPath != "" {
+		if reserved, err = names.LoadList(reservedPath); err != nil {
+			log.Fatalf("failed to load reserved nicknames: %v", err)
+		}
+	}
+	if blockedPath != "" {
+		if blocked, err = names.LoadList(blockedPath); err != nil {
+			log.Fatalf("failed to load blocked nicknames: %v", err)
+		}
+	}
+
+	storage := local.New(storagePath)
+	followService := followGateway.New(fmt.Sprintf("localhost:%d", followPort))
+	tweetsService := tweetsGateway.New(fmt.Sprintf("localhost:%d", tweetsPort))
+	likesService := likesGateway.New(fmt.Sprintf("http://localhost:%d", likesPort))
+	ctrl := controller.New(
+		repo, storage, mail, recorder, followService, tweetsService, likesService, names.NewPolicy(reserved, blocked),
+		verifyUrl, resetUrl, exportUrl,
+	)
+	if err = ctrl.LoadSearchIndex(context.Background()); err != nil {
+		log.Printf("failed to load search index: %v", err)
+	}
+	// search results are ranked by follower counts from follow service
+	go refreshFollowerCounts(ctrl, time.Minute)
+	// accounts deactivated longer than grace period are deleted with their media,
+	// expired data exports are deleted with their archives, old nicknames are freed
+	go purge(ctrl, purgeInterval)
+	// activity of sessions is kept in memory and saved in batches
+	go flushLastSeen(ctrl, time.Minute)
+
+	// setup the main listener
+	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+
+	// Create a new cmux instance.
+	m := cmux.New(lis)
+	// Match connections in order: first gRPC, then HTTP.
+	grpcL := m.MatchWithWriters(cmux.HTTP2MatchHeaderFieldSendSettings("content-type", "application/grpc"))
+	httpL := m.Match(cmux.HTTP1Fast())
+
+	// grpc and http server
+	// profiles are public, anonymous readers of tweets need them too
+	srv := grpc.NewServer(auth.ServerOptions(
+		gen.UsersService_GetUser_FullMethodName,
+		gen.UsersService_GetUsers_FullMethodName,
+		gen.UsersService_SearchUsers_FullMethodName,
+	)...)
+	reflection.Register(srv)
+	httpS := &http.Server{}
+
+	// Use the servers in goroutines.
+	go srv.Serve(grpcL)
+	go httpS.Serve(httpL)
+
+	// grpc handler
+	grpch := grpchandler.New(ctrl)
+	gen.RegisterUsersServiceServer(srv, grpch)
+	// http handler
 	h := httphandler.New(ctrl)
+	// validate token and remember activity of the session
+	protected := func(handler http.HandlerFunc) http.Handler {
+		return auth.Middleware(h.SessionMiddleware(handler))
+	}
+	// same as protected, but also requires the role
+	privileged := func(role types.Role, handler http.HandlerFunc) http.Handler {
+		return auth.Middleware(h.SessionMiddleware(auth.RequireRole(role, handler)))
+	}
+	// limit password reset attempts per client
+	forgotPasswordHandler := ratelimit.Middleware(ratelimit.New(5, time.Hour), http.HandlerFunc(h.ForgotPassword))
+	resetPasswordHandler := ratelimit.Middleware(ratelimit.New(10, 15*time.Minute), http.HandlerFunc(h.ResetPassword))
 
-	updateHandler := jwt.ValidateMiddleware(http.HandlerFunc(h.Update))
-	deleteHandler := jwt.ValidateMiddleware(http.HandlerFunc(h.Delete))
-	loginHandler := http.HandlerFunc(httphandler.JwtHandler(h.Login))
-	registerHandler := http.HandlerFunc(httphandler.JwtHandler(h.Register))
+	updateHandler := protected(h.Update)
+	accountHandler := protected(h.GetAccount)
+	deleteHandler := protected(h.Delete)
+	languagesHandler := protected(h.GetPreferredLanguages)
+	updateLanguagesHandler := protected(h.UpdatePreferredLanguages)
+	sensitiveHandler := protected(h.GetSensitiveMedia)
+	updateSensitiveHandler := protected(h.UpdateSensitiveMedia)
+	updateProtectedHandler := protected(h.UpdateProtected)
+	updateProfileHandler := protected(h.UpdateProfile)
+	updateAvatarHandler := protected(h.UpdateAvatar)
+	updateHeaderHandler := protected(h.UpdateHeader)
+	resendVerificationHandler := protected(h.ResendVerification)
+	logoutHandler := protected(h.Logout)
+	logoutAllHandler := protected(h.LogoutAll)
+	searchHandler := protected(h.SearchUsers)
+	enrollTOTPHandler := protected(h.EnrollTOTP)
+	confirmTOTPHandler := protected(h.ConfirmTOTP)
+	disableTOTPHandler := protected(h.DisableTOTP)
+	recoveryCodesHandler := protected(h.RegenerateRecoveryCodes)
+	sessionsHandler := protected(h.GetSessions)
+	revokeSessionHandler := protected(h.RevokeSession)
+	requestExportHandler := protected(h.RequestExport)
+	exportsHandler := protected(h.GetExports)
+	accessTokensHandler := protected(h.GetAccessTokens)
+	createAccessTokenHandler := protected(h.CreateAccessToken)
+	revokeAccessTokenHandler := protected(h.RevokeAccessToken)
+	suspendHandler := privileged(types.RoleModerator, h.Suspend)
+	unsuspendHandler := privileged(types.RoleModerator, h.Unsuspend)
+	setRoleHandler := privileged(types.RoleAdmin, h.SetRole)
+	auditHandler := privileged(types.RoleAdmin, audit.HistoryHandler(auditLog).ServeHTTP)
+	lockoutsHandler := privileged(types.RoleAdmin, h.Lockouts)
+	unlockHandler := privileged(types.RoleAdmin, h.ClearLockout)
+	loginHandler := http.HandlerFunc(h.JwtHandler(h.Login))
+	verifyLoginHandler := http.HandlerFunc(h.JwtHandler(h.VerifyLogin))
+	registerHandler := http.HandlerFunc(h.JwtHandler(h.Register))
 
 	http.Handle("/login", loginHandler)
+	http.Handle("/login/2fa", verifyLoginHandler)
 	http.Handle("/register", registerHandler)
 	http.Handle("/update", updateHandler)
+	http.Handle("/account", accountHandler)
 	http.Handle("/delete", deleteHandler)
+	http.Handle("/preferred_languages", languagesHandler)
+	http.Handle("/update_preferred_languages", updateLanguagesHandler)
+	http.Handle("/sensitive_media", sensitiveHandler)
+	http.Handle("/update_sensitive_media", updateSensitiveHandler)
+	http.Handle("/update_protected", updateProtectedHandler)
+	http.Handle("/user", http.HandlerFunc(h.GetUser))
+	http.Handle("/users", http.HandlerFunc(h.GetUsers))
+	http.Handle("/users/search", searchHandler)
+	http.Handle("/update_profile", updateProfileHandler)
+	http.Handle("/update_avatar", updateAvatarHandler)
+	http.Handle("/update_header", updateHeaderHandler)
+	http.Handle("/verify_email", http.HandlerFunc(h.VerifyEmail))
+	http.Handle("/resend_verification", resendVerificationHandler)
+	http.Handle("/forgot_password", forgotPasswordHandler)
+	http.Handle("/reset_password", resetPasswordHandler)
+	http.Handle("/refresh", http.HandlerFunc(h.Refresh))
+	http.Handle("/logout", logoutHandler)
+	http.Handle("/logout_all", logoutAllHandler)
+	http.Handle("/2fa/enroll", enrollTOTPHandler)
+	http.Handle("/2fa/confirm", confirmTOTPHandler)
+	http.Handle("/2fa/disable", disableTOTPHandler)
+	http.Handle("/2fa/recovery_codes", recoveryCodesHandler)
+	http.Handle("/sessions", sessionsHandler)
+	http.Handle("/sessions/revoke", revokeSessionHandler)
+	http.Handle("/export", requestExportHandler)
+	http.Handle("/exports", exportsHandler)
+	http.Handle("/export/download", http.HandlerFunc(h.DownloadExport))
+	http.Handle("/tokens", accessTokensHandler)
+	http.Handle("/tokens/create", createAccessTokenHandler)
+	http.Handle("/tokens/revoke", revokeAccessTokenHandler)
+	http.Handle("/admin/suspend", suspendHandler)
+	http.Handle("/admin/unsuspend", unsuspendHandler)
+	http.Handle("/admin/role", setRoleHandler)
+	http.Handle("/admin/audit", auditHandler)
+	http.Handle("/admin/lockouts", lockoutsHandler)
+	http.Handle("/admin/unlock", unlockHandler)
+	http.Handle(jwt.JWKSPath, jwt.JWKSHandler(keys))
 	http.HandleFunc("/swagger/", httpSwagger.WrapHandler)
-	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil); err != nil {
-		panic(err)
+	// Start serving!
+	log.Fatal(m.Serve())
+}
+
+// purge deactivated accounts, expired data exports and nickname history every interval
+func purge(ctrl *controller.Controller, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		purged, err := ctrl.PurgeDeactivated(context.Background())
+		if err != nil {
+			log.Printf("failed to purge deactivated accounts: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("purged %d deactivated accounts", purged)
+		}
+
+		purged, err = ctrl.PurgeExpiredExports(context.Background())
+		if err != nil {
+			log.Printf("failed to purge expired exports: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("purged %d expired exports", purged)
+		}
+
+		if err = ctrl.PurgeNicknameHistory(context.Background()); err != nil {
+			log.Printf("failed to purge nickname history: %v", err)
+		}
+	}
+}
+
+// refresh follower counts that rank search results every interval, first right after start
+func refreshFollowerCounts(ctrl *controller.Controller, interval time.Duration) {
+	refresh := func() {
+		if err := ctrl.RefreshFollowerCounts(context.Background()); err != nil {
+			log.Printf("failed to refresh follower counts: %v", err)
+		}
+	}
+	refresh()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		refresh()
+	}
+}
+
+// save activity of sessions every interval
+func flushLastSeen(ctrl *controller.Controller, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := ctrl.FlushLastSeen(context.Background()); err != nil {
+			log.Printf("failed to save last seen of sessions: %v", err)
+		}
 	}
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func getStoragePath() string {
+	// Get the current directory
+	currentDir, _ := os.Getwd()
+	// Traverse two levels up
+	twoLevelsUp := filepath.Join(currentDir, "..", "..")
+	// Get the absolute path
+	absTwoLevelsUp, _ := filepath.Abs(twoLevelsUp)
+	// Append storage to the path
+	storagePath := filepath.Join(absTwoLevelsUp, "storage")
+	return storagePath
+}
+
+// load signing key from JWT_PRIVATE_KEY or file, previous public keys from
+// JWT_PREVIOUS_KEYS or files are accepted until tokens signed with them expire. Without configured key
+// tokens are signed with generated key that changes on every restart
+func loadKeySet(privateKeyPath string, previousKeyPaths string) (*jwt.KeySet, error) {
+	data, err := jwt.ReadKey("JWT_PRIVATE_KEY", privateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		log.Println("Warning: jwt private key is not configured, using generated key")
+		return jwt.GenerateKeySet()
+	}
+	signer, err := jwt.ParsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := jwt.ParsePublicKeys([]byte(os.Getenv("JWT_PREVIOUS_KEYS")))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range strings.Split(previousKeyPaths, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwt.ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		previous = append(previous, key)
+	}
+	return jwt.NewKeySet(signer, previous...)
+}
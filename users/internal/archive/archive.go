@@ -0,0 +1,156 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	tweetsmodel "github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"io"
+	"net/http"
+	"path"
+	"time"
+)
+
+// Account is the profile of the user with private settings
+type Account struct {
+	model.Profile
+	Email              string               `json:"email"`
+	EmailVerified      bool                 `json:"email_verified"`
+	PreferredLanguages []string             `json:"preferred_languages"`
+	SensitiveMedia     model.SensitiveMedia `json:"sensitive_media"`
+	TwoFactorEnabled   bool                 `json:"two_factor_enabled"`
+}
+
+// Data is everything that is kept about the user
+type Data struct {
+	Account Account
+	// profile images, nil if they are not set
+	Avatar []byte
+	Header []byte
+	// media of tweets is base64 encoded as returned by tweets service
+	Tweets    []tweetsmodel.Media
+	Likes     []types.TweetId
+	Followers []types.UserId
+	Following []types.UserId
+	Sessions  []model.Session
+	CreatedAt time.Time
+}
+
+// tweet in the archive refers to its media file instead of embedding it
+type tweet struct {
+	tweetsmodel.Media
+	// path of the media file, it hides base64 content of the tweet
+	File string `json:"media,omitempty"`
+}
+
+const readme = `Personal data export
+
+This archive contains everything the service keeps about your account, created at %s.
+
+profile.json    profile, email and settings of the account
+tweets.json     your tweets with ids of replied and retweeted tweets,
+                "media" is the path of attached file in the media folder
+likes.json      ids of tweets you liked
+followers.json  ids of users that follow you
+following.json  ids of users you follow
+sessions.json   devices where you are logged in
+media/          avatar, header and media of your tweets
+`
+
+// Write writes zip archive with data as JSON files, media files and README
+func Write(w io.Writer, data Data) error {
+	zw := zip.NewWriter(w)
+
+	account := data.Account
+	if data.Avatar != nil {
+		name := mediaName("avatar", data.Avatar)
+		if err := writeFile(zw, name, data.Avatar); err != nil {
+			return err
+		}
+		account.AvatarUrl = &name
+	}
+	if data.Header != nil {
+		name := mediaName("header", data.Header)
+		if err := writeFile(zw, name, data.Header); err != nil {
+			return err
+		}
+		account.HeaderUrl = &name
+	}
+
+	tweets := make([]tweet, len(data.Tweets))
+	for i, media := range data.Tweets {
+		tweets[i].Media = media
+		if media.Media == "" {
+			continue
+		}
+		content, err := base64.StdEncoding.DecodeString(media.Media)
+		if err != nil {
+			return fmt.Errorf("media of tweet %d: %w", i+1, err)
+		}
+		name := mediaName(fmt.Sprintf("tweet_%d", i+1), content)
+		if err = writeFile(zw, name, content); err != nil {
+			return err
+		}
+		tweets[i].File = name
+	}
+
+	for _, file := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"profile.json", account},
+		{"tweets.json", tweets},
+		{"likes.json", nonNil(data.Likes)},
+		{"followers.json", nonNil(data.Followers)},
+		{"following.json", nonNil(data.Following)},
+		{"sessions.json", nonNil(data.Sessions)},
+	} {
+		content, err := json.MarshalIndent(file.value, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err = writeFile(zw, file.name, content); err != nil {
+			return err
+		}
+	}
+
+	content := fmt.Sprintf(readme, data.CreatedAt.UTC().Format(time.RFC1123))
+	if err := writeFile(zw, "README.txt", []byte(content)); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeFile(zw *zip.Writer, name string, content []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+// empty lists are written as [] instead of null
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+	return values
+}
+
+// path of media file in the archive, extension is taken from the content
+func mediaName(name string, content []byte) string {
+	ext := ".jpg"
+	switch http.DetectContentType(content) {
+	case "image/png":
+		ext = ".png"
+	case "image/gif":
+		ext = ".gif"
+	case "image/webp":
+		ext = ".webp"
+	}
+	return path.Join("media", name+ext)
+}
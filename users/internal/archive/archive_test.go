@@ -0,0 +1,95 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	tweetsmodel "github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"github.com/google/go-cmp/cmp"
+	"io"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nimage")
+	avatarUrl := "storage/avatar.jpg"
+	replyId := types.TweetId(7)
+	data := Data{
+		Account: Account{
+			Profile: model.Profile{UserId: 1, Nickname: "alex", AvatarUrl: &avatarUrl},
+			Email:   "alex@mail.com",
+		},
+		Avatar: []byte("jpeg"),
+		Tweets: []tweetsmodel.Media{
+			{TweetId: 7, Content: "with image", Media: base64.StdEncoding.EncodeToString(png)},
+			{TweetId: 8, ReplyId: &replyId, Content: "text"},
+		},
+		Likes:     []types.TweetId{5},
+		CreatedAt: time.Now(),
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	var names []string
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = content
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+
+	want := []string{
+		"README.txt", "followers.json", "following.json", "likes.json", "media/avatar.jpg",
+		"media/tweet_1.png", "profile.json", "sessions.json", "tweets.json",
+	}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if !bytes.Equal(files["media/tweet_1.png"], png) {
+		t.Errorf("media of the tweet should be decoded")
+	}
+
+	// storage paths are replaced with files in the archive
+	var account Account
+	if err = json.Unmarshal(files["profile.json"], &account); err != nil {
+		t.Fatal(err)
+	}
+	if account.Email != "alex@mail.com" || account.AvatarUrl == nil || *account.AvatarUrl != "media/avatar.jpg" {
+		t.Errorf("wrong profile: %s", files["profile.json"])
+	}
+	var tweets []map[string]interface{}
+	if err = json.Unmarshal(files["tweets.json"], &tweets); err != nil {
+		t.Fatal(err)
+	}
+	if len(tweets) != 2 || tweets[0]["media"] != "media/tweet_1.png" || tweets[1]["media"] != nil {
+		t.Errorf("wrong tweets: %s", files["tweets.json"])
+	}
+	// tweets keep their ids and ids of replied tweets
+	if tweets[0]["tweet_id"] != 7.0 || tweets[0]["reply_id"] != nil ||
+		tweets[1]["tweet_id"] != 8.0 || tweets[1]["reply_id"] != 7.0 {
+		t.Errorf("wrong tweet ids: %s", files["tweets.json"])
+	}
+	if string(files["followers.json"]) != "[]" {
+		t.Errorf("empty list should be written as [], got %s", files["followers.json"])
+	}
+}
@@ -2,16 +2,47 @@ package controller
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/imaging"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/lang"
+	"github.com/alexvishnevskiy/twitter-clone/internal/mailer"
+	"github.com/alexvishnevskiy/twitter-clone/internal/ratelimit"
+	"github.com/alexvishnevskiy/twitter-clone/internal/storage"
+	totputil "github.com/alexvishnevskiy/twitter-clone/internal/totp"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	tweetsmodel "github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	"github.com/alexvishnevskiy/twitter-clone/users/internal/archive"
+	"github.com/alexvishnevskiy/twitter-clone/users/internal/names"
+	"github.com/alexvishnevskiy/twitter-clone/users/internal/search"
 	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
 	"golang.org/x/crypto/bcrypt"
+	"io"
+	"log"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 type usersRepository interface {
 	Register(
 		ctx context.Context,
 		nickname string,
+		canonical string,
 		firstname string,
 		lastname string,
 		email string,
@@ -20,22 +51,421 @@ type usersRepository interface {
 		ctx context.Context,
 		email string,
 	) (types.UserId, string, error)
-	Delete(
+	Deactivate(
 		ctx context.Context,
 		userid types.UserId,
+		deactivatedAt time.Time,
 	) error
+	GetDeactivatedAt(
+		ctx context.Context,
+		userid types.UserId,
+	) (*time.Time, error)
+	Reactivate(
+		ctx context.Context,
+		userid types.UserId,
+	) error
+	GetDeactivatedBefore(
+		ctx context.Context,
+		before time.Time,
+	) ([]types.UserId, error)
+	GetMediaUrls(
+		ctx context.Context,
+		userid types.UserId,
+	) ([]string, error)
+	Purge(
+		ctx context.Context,
+		userid types.UserId,
+		before time.Time,
+	) error
+	GetRole(
+		ctx context.Context,
+		userid types.UserId,
+	) (types.Role, error)
+	SetRole(
+		ctx context.Context,
+		userid types.UserId,
+		role types.Role,
+	) error
+	Suspend(
+		ctx context.Context,
+		userid types.UserId,
+		suspendedAt time.Time,
+	) error
+	Unsuspend(
+		ctx context.Context,
+		userid types.UserId,
+	) error
+	IsSuspended(
+		ctx context.Context,
+		userid types.UserId,
+	) (bool, error)
 	Update(
 		ctx context.Context,
-		userData model.User,
+		userid types.UserId,
+		update model.UserUpdate,
+	) (int, error)
+	GetAccount(
+		ctx context.Context,
+		userid types.UserId,
+	) (model.Account, error)
+	GetNicknameOwner(
+		ctx context.Context,
+		canonical string,
+		since time.Time,
+	) (types.UserId, error)
+	CountNicknameChanges(
+		ctx context.Context,
+		userid types.UserId,
+		since time.Time,
+	) (int, error)
+	PurgeNicknameHistory(
+		ctx context.Context,
+		before time.Time,
+	) error
+	SetPreferredLanguages(
+		ctx context.Context,
+		userid types.UserId,
+		langs []string,
+	) error
+	GetPreferredLanguages(
+		ctx context.Context,
+		userid types.UserId,
+	) ([]string, error)
+	SetSensitiveMedia(
+		ctx context.Context,
+		userid types.UserId,
+		preference model.SensitiveMedia,
+	) error
+	GetSensitiveMedia(
+		ctx context.Context,
+		userid types.UserId,
+	) (model.SensitiveMedia, error)
+	SetProtected(
+		ctx context.Context,
+		userid types.UserId,
+		protected bool,
+	) error
+	GetUsers(
+		ctx context.Context,
+		userIds ...types.UserId,
+	) ([]model.Profile, error)
+	GetAllUsers(
+		ctx context.Context,
+	) ([]model.Profile, error)
+	GetById(
+		ctx context.Context,
+		userid types.UserId,
+	) (model.Profile, error)
+	GetByNickname(
+		ctx context.Context,
+		canonical string,
+	) (model.Profile, error)
+	UpdateProfile(
+		ctx context.Context,
+		userid types.UserId,
+		profile model.ProfileUpdate,
+	) error
+	UpdateImage(
+		ctx context.Context,
+		userid types.UserId,
+		kind model.ImageKind,
+		url string,
+	) error
+	GetEmail(
+		ctx context.Context,
+		userid types.UserId,
+	) (string, bool, error)
+	SetEmailVerified(
+		ctx context.Context,
+		userid types.UserId,
+		verified bool,
+	) error
+	GetIdByEmail(
+		ctx context.Context,
+		email string,
+	) (types.UserId, error)
+	PutPasswordReset(
+		ctx context.Context,
+		userid types.UserId,
+		tokenHash string,
+		expiresAt time.Time,
+	) error
+	ConsumePasswordReset(
+		ctx context.Context,
+		tokenHash string,
+	) (types.UserId, time.Time, error)
+	ResetPassword(
+		ctx context.Context,
+		userid types.UserId,
+		password string,
+		revokedAt time.Time,
+	) error
+	PutRefreshToken(
+		ctx context.Context,
+		userid types.UserId,
+		tokenHash string,
+		family string,
+		expiresAt time.Time,
+	) error
+	GetRefreshToken(
+		ctx context.Context,
+		tokenHash string,
+	) (model.RefreshToken, error)
+	UseRefreshToken(
+		ctx context.Context,
+		tokenHash string,
+	) (bool, error)
+	DeleteRefreshFamily(
+		ctx context.Context,
+		family string,
+	) error
+	RevokeSessions(
+		ctx context.Context,
+		userid types.UserId,
+		revokedAt time.Time,
+	) error
+	CreateSession(
+		ctx context.Context,
+		session model.Session,
+		tokenHash string,
+		expiresAt time.Time,
+	) error
+	GetSession(
+		ctx context.Context,
+		sessionId string,
+	) (model.Session, error)
+	GetSessions(
+		ctx context.Context,
+		userid types.UserId,
+	) ([]model.Session, error)
+	SetLastSeen(
+		ctx context.Context,
+		lastSeen map[string]time.Time,
+	) error
+	DeleteSession(
+		ctx context.Context,
+		userid types.UserId,
+		sessionId string,
+	) error
+	CreateExport(
+		ctx context.Context,
+		userid types.UserId,
+		createdAt time.Time,
+	) (int, error)
+	CompleteExport(
+		ctx context.Context,
+		exportId int,
+		tokenHash string,
+		storagePath string,
+		expiresAt time.Time,
+	) error
+	FailExport(
+		ctx context.Context,
+		exportId int,
+	) error
+	GetExports(
+		ctx context.Context,
+		userid types.UserId,
+	) ([]model.Export, error)
+	GetExportByToken(
+		ctx context.Context,
+		tokenHash string,
+	) (model.Export, error)
+	GetExpiredExports(
+		ctx context.Context,
+		before time.Time,
+	) ([]model.Export, error)
+	DeleteExport(
+		ctx context.Context,
+		exportId int,
+	) error
+	GetTOTP(
+		ctx context.Context,
+		userid types.UserId,
+	) (model.TOTP, error)
+	SetTOTPSecret(
+		ctx context.Context,
+		userid types.UserId,
+		secret string,
+	) error
+	EnableTOTP(
+		ctx context.Context,
+		userid types.UserId,
+		step int64,
+		codeHashes []string,
+	) error
+	DisableTOTP(
+		ctx context.Context,
+		userid types.UserId,
 	) error
+	UseTOTPStep(
+		ctx context.Context,
+		userid types.UserId,
+		step int64,
+	) error
+	ReplaceRecoveryCodes(
+		ctx context.Context,
+		userid types.UserId,
+		codeHashes []string,
+	) error
+	UseRecoveryCode(
+		ctx context.Context,
+		userid types.UserId,
+		codeHash string,
+	) error
+	CreateAccessToken(
+		ctx context.Context,
+		token model.AccessToken,
+		tokenHash string,
+	) (int, error)
+	GetAccessTokens(
+		ctx context.Context,
+		userid types.UserId,
+	) ([]model.AccessToken, error)
+	DeleteAccessToken(
+		ctx context.Context,
+		userid types.UserId,
+		tokenId int,
+	) error
+}
+
+// follow service is used to rank search results and export followers
+type followGateway interface {
+	GetFollowerCounts(ctx context.Context, userIds ...types.UserId) (map[types.UserId]int, error)
+	GetFollowers(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+	GetFollowing(ctx context.Context, userId types.UserId) ([]types.UserId, error)
+}
+
+// tweets and likes services provide content of data export
+type tweetsGateway interface {
+	GetTweets(ctx context.Context, userId types.UserId) ([]tweetsmodel.Media, error)
+}
+
+type likesGateway interface {
+	GetLikedTweets(ctx context.Context, userId types.UserId) ([]types.TweetId, error)
 }
 
+const (
+	// how long verification link is valid
+	verificationTTL = 24 * time.Hour
+	// how long password reset link is valid
+	passwordResetTTL = time.Hour
+	// how long background lookup of the email and sending of reset link may take
+	passwordResetTimeout = time.Minute
+	// minimal length of new password
+	minPasswordLength = 8
+	// how long login lasts without activity
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// longer user agents are truncated
+	maxUserAgentLength = 255
+	// how long user has to enter second factor after password
+	ChallengeTTL = 5 * time.Minute
+	// deactivated account can be restored by login during this period, then it is purged
+	DeactivationPeriod = 30 * 24 * time.Hour
+	// how long archive of personal data can be downloaded
+	exportTTL = 7 * 24 * time.Hour
+	// pending export older than this is considered failed
+	exportTimeout = time.Hour
+	// number of one-time recovery codes
+	recoveryCodeCount = 10
+	// issuer shown in authenticator apps
+	totpIssuer = "Twitter clone"
+	// number of search results by default and at most
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	// follower counts of indexed users are requested in batches
+	followerCountsBatch = 500
+	// prefixes of lockout keys
+	emailLockPrefix = "email:"
+	ipLockPrefix    = "ip:"
+	// expiration of personal access token by default and at most
+	defaultAccessTokenTTL = 30 * 24 * time.Hour
+	maxAccessTokenTTL     = 365 * 24 * time.Hour
+	// limits of personal access tokens of the user
+	maxAccessTokens          = 20
+	maxAccessTokenNameLength = 50
+	// old nickname can't be taken by others and redirects to the account during this period
+	NicknameReservation = 30 * 24 * time.Hour
+	// user can change nickname this many times during the window, the window
+	// shouldn't be longer than reservation that history is kept for
+	maxNicknameChanges   = 3
+	nicknameChangeWindow = 30 * 24 * time.Hour
+)
+
+// compared with entered password when email is unknown,
+// so that response time doesn't reveal registered emails
+var dummyPassword = encodePassword("dummy password")
+
 type Controller struct {
-	repo usersRepository
+	repo    usersRepository
+	storage storage.Storage
+	mailer  mailer.Mailer
+	// url of /verify_email endpoint that is sent to users
+	verifyUrl string
+	// url of password reset form that is sent to users
+	resetUrl string
+	// url of /export/download endpoint that is sent to users
+	exportUrl string
+	// limits reset emails sent to one address
+	resetLimiter *ratelimit.Limiter
+	// limits second factor attempts of one user
+	codeLimiter *ratelimit.Limiter
+	// limits data exports of one user
+	exportLimiter *ratelimit.Limiter
+	// backoff of failed logins per email and per client ip
+	emailBackoff *ratelimit.Backoff
+	ipBackoff    *ratelimit.Backoff
+	audit        audit.Recorder
+	follow       followGateway
+	tweets       tweetsGateway
+	likes        likesGateway
+	// reserved and blocked nicknames
+	nicknamePolicy *names.Policy
+	// users by prefix of nickname and name
+	index *search.Index
+	// last activity of sessions that is not saved yet, see FlushLastSeen
+	seenMu   sync.Mutex
+	lastSeen map[string]time.Time
 }
 
-func New(repo usersRepository) *Controller {
-	return &Controller{repo}
+func New(
+	repo usersRepository,
+	storage storage.Storage,
+	mailer mailer.Mailer,
+	audit audit.Recorder,
+	follow followGateway,
+	tweets tweetsGateway,
+	likes likesGateway,
+	nicknamePolicy *names.Policy,
+	verifyUrl string,
+	resetUrl string,
+	exportUrl string,
+) *Controller {
+	// only default names are reserved without policy
+	if nicknamePolicy == nil {
+		nicknamePolicy = names.NewPolicy(names.DefaultReserved, nil)
+	}
+	return &Controller{
+		repo:           repo,
+		storage:        storage,
+		mailer:         mailer,
+		audit:          audit,
+		follow:         follow,
+		tweets:         tweets,
+		likes:          likes,
+		index:          search.New(),
+		nicknamePolicy: nicknamePolicy,
+		lastSeen:       make(map[string]time.Time),
+		verifyUrl:      verifyUrl,
+		resetUrl:       resetUrl,
+		exportUrl:      exportUrl,
+		resetLimiter:   ratelimit.New(3, time.Hour),
+		codeLimiter:    ratelimit.New(5, 15*time.Minute),
+		exportLimiter:  ratelimit.New(3, 24*time.Hour),
+		emailBackoff:   ratelimit.NewBackoff(5, 30*time.Second, 15*time.Minute),
+		// clients behind NAT share ip, so it tolerates more failures
+		ipBackoff: ratelimit.NewBackoff(20, 30*time.Second, 15*time.Minute),
+	}
 }
 
 // hash password
@@ -50,44 +480,1543 @@ func checkPassword(enteredPassword string, databasePassword string) bool {
 	return err == nil
 }
 
+// Register saves new user, fields are checked the same way as by Update
 func (ctrl *Controller) Register(
 	ctx context.Context,
 	userData model.User,
 ) (types.UserId, error) {
+	fields := model.UserUpdate{
+		Nickname:  &userData.Nickname,
+		FirstName: &userData.FirstName,
+		LastName:  &userData.LastName,
+		Email:     &userData.Email,
+		Password:  &userData.Password,
+	}
+	if err := validateFields(&fields); err != nil {
+		return 0, err
+	}
+	canonical, err := ctrl.canonicalNickname(userData.Nickname)
+	if err != nil {
+		return 0, err
+	}
+	if err := ctrl.checkNicknameFree(ctx, 0, canonical); err != nil {
+		return 0, err
+	}
 	// register: insert new row
 	decodedPassword := encodePassword(userData.Password)
 	id, err := ctrl.repo.Register(
-		ctx, userData.Nickname, userData.FirstName, userData.LastName, userData.Email, decodedPassword,
+		ctx, userData.Nickname, canonical, userData.FirstName, userData.LastName, userData.Email, decodedPassword,
+	)
+	if err != nil {
+		return id, err
+	}
+	ctrl.index.Put(
+		model.Profile{UserId: id, Nickname: userData.Nickname, FirstName: userData.FirstName, LastName: userData.LastName},
+	)
+
+	// account is usable right away, link can be sent again with /resend_verification
+	if err := ctrl.sendVerification(id, userData.Email); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", id, err)
+	}
+	return id, nil
+}
+
+// send signed link to confirm the email
+func (ctrl *Controller) sendVerification(userid types.UserId, email string) error {
+	token, err := jwt.GenerateEmailToken(userid, email, verificationTTL)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s?token=%s", ctrl.verifyUrl, url.QueryEscape(token))
+	body := fmt.Sprintf(
+		"Confirm your email by following the link below. It expires in %d hours.\n\n%s",
+		int(verificationTTL.Hours()), link,
 	)
-	return id, err
+	return ctrl.mailer.Send(email, "Confirm your email", body)
+}
+
+// send verification link again
+func (ctrl *Controller) ResendVerification(ctx context.Context, userid types.UserId) error {
+	email, verified, err := ctrl.repo.GetEmail(ctx, userid)
+	if err != nil {
+		return err
+	}
+	if verified {
+		return nil
+	}
+	return ctrl.sendVerification(userid, email)
+}
+
+// verify email with token from the link, link is outdated if email was changed
+func (ctrl *Controller) VerifyEmail(ctx context.Context, token string) error {
+	userid, tokenEmail, err := jwt.ParseEmailToken(token)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidVerificationToken, err)
+	}
+	email, verified, err := ctrl.repo.GetEmail(ctx, userid)
+	if err != nil {
+		return err
+	}
+	if email != tokenEmail {
+		return ErrInvalidVerificationToken
+	}
+	if verified {
+		return nil
+	}
+	return ctrl.repo.SetEmailVerified(ctx, userid, true)
+}
+
+// EmailVerified reports whether email of the user is verified, it is put into access tokens
+func (ctrl *Controller) EmailVerified(ctx context.Context, userid types.UserId) (bool, error) {
+	_, verified, err := ctrl.repo.GetEmail(ctx, userid)
+	return verified, err
+}
+
+// features visible to other users require verified email
+func (ctrl *Controller) requireVerified(ctx context.Context, userid types.UserId) error {
+	_, verified, err := ctrl.repo.GetEmail(ctx, userid)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// record audit event, failure to record doesn't fail the request
+func (ctrl *Controller) record(ctx context.Context, event audit.Event) {
+	if err := ctrl.audit.Record(ctx, event); err != nil {
+		log.Printf("failed to record audit event %s: %v", event.Type, err)
+	}
 }
 
-func (ctrl *Controller) Login(ctx context.Context, email string, password string) (types.UserId, error) {
-	// retrieve password for specific email
+// keys of email and ip in login backoff
+func lockKeys(email string, ip string) (string, string) {
+	return emailLockPrefix + strings.ToLower(strings.TrimSpace(email)), ipLockPrefix + ip
+}
+
+// count failed login of email and ip, both are locked after too many failures
+func (ctrl *Controller) loginFailed(ctx context.Context, userid types.UserId, email string, ip string) {
+	emailKey, ipKey := lockKeys(email, ip)
+	ctrl.record(ctx, audit.Event{Type: audit.LoginFailed, UserId: userid, IP: ip})
+	if lock := ctrl.emailBackoff.Fail(emailKey); lock > 0 {
+		ctrl.record(ctx, audit.Event{Type: audit.LoginLocked, UserId: userid, Detail: fmt.Sprintf("%s for %s", emailKey, lock)})
+	}
+	if lock := ctrl.ipBackoff.Fail(ipKey); lock > 0 {
+		ctrl.record(ctx, audit.Event{Type: audit.LoginLocked, Detail: fmt.Sprintf("%s for %s", ipKey, lock)})
+	}
+}
+
+// Login checks password of the user. If two-factor authentication is enabled
+// session is not started, challenge token is returned for VerifyLogin instead.
+// Unknown email, wrong password and locked email or ip return ErrInvalidCredentials
+// after the same password check, so neither response nor its time reveal them
+func (ctrl *Controller) Login(
+	ctx context.Context,
+	email string,
+	password string,
+	ip string,
+) (types.UserId, string, error) {
+	emailKey, ipKey := lockKeys(email, ip)
+	if ctrl.emailBackoff.Check(emailKey) > 0 || ctrl.ipBackoff.Check(ipKey) > 0 {
+		checkPassword(password, dummyPassword)
+		ctrl.record(ctx, audit.Event{Type: audit.LoginBlocked, IP: ip, Detail: emailKey})
+		return types.UserId(0), "", ErrInvalidCredentials
+	}
+
+	// retrieve password for specific email, zero user means unknown email
 	userId, databasePassword, err := ctrl.repo.RetrievePassword(ctx, email)
 	if err != nil {
-		return types.UserId(0), err
+		return types.UserId(0), "", err
+	}
+	if userId == 0 {
+		databasePassword = dummyPassword
 	}
 
 	// check password
 	check := checkPassword(password, databasePassword)
-	if check {
-		return userId, nil
+	if !check || userId == 0 {
+		ctrl.loginFailed(ctx, userId, email, ip)
+		return types.UserId(0), "", ErrInvalidCredentials
+	}
+	deactivated, err := ctrl.checkDeactivated(ctx, userId)
+	if err != nil {
+		return types.UserId(0), "", err
+	}
+	suspended, err := ctrl.repo.IsSuspended(ctx, userId)
+	if err != nil {
+		return types.UserId(0), "", err
+	}
+	if suspended {
+		ctrl.record(ctx, audit.Event{Type: audit.LoginBlocked, UserId: userId, IP: ip, Detail: "suspended"})
+		return types.UserId(0), "", ErrAccountSuspended
+	}
+	// failures of the ip are kept, attacker could reset them with own account
+	ctrl.emailBackoff.Reset(emailKey)
+
+	totp, err := ctrl.repo.GetTOTP(ctx, userId)
+	if err != nil {
+		return types.UserId(0), "", err
+	}
+	if !totp.Enabled {
+		if deactivated {
+			if err = ctrl.reactivate(ctx, userId); err != nil {
+				return types.UserId(0), "", err
+			}
+		}
+		ctrl.record(ctx, audit.Event{Type: audit.LoginSucceeded, UserId: userId, IP: ip})
+		return userId, "", nil
+	}
+	challenge, err := jwt.GenerateChallengeToken(userId, ChallengeTTL)
+	if err != nil {
+		return types.UserId(0), "", err
 	}
-	return types.UserId(0), fmt.Errorf("password is incorrect")
+	return userId, challenge, nil
 }
 
-// delete user
-func (ctrl *Controller) Delete(ctx context.Context, userid types.UserId) error {
-	err := ctrl.repo.Delete(ctx, userid)
-	return err
+// Lockouts returns emails and ips that are locked after failed logins
+func (ctrl *Controller) Lockouts() []ratelimit.Lockout {
+	return append(ctrl.emailBackoff.Lockouts(), ctrl.ipBackoff.Lockouts()...)
+}
+
+// ClearLockout forgets failed logins of the key returned by Lockouts, admin is taken from the context
+func (ctrl *Controller) ClearLockout(ctx context.Context, key string) {
+	ctrl.emailBackoff.Reset(key)
+	ctrl.ipBackoff.Reset(key)
+	actor, _ := auth.UserId(ctx)
+	ctrl.record(ctx, audit.Event{Type: audit.LockoutCleared, ActorId: actor, Detail: key})
 }
 
-// update info
-func (ctrl *Controller) Update(ctx context.Context, userData model.User) error {
-	if userData.Password != "" {
-		userData.Password = encodePassword(userData.Password)
+// Deactivate hides profile and content of the user and logs out every session,
+// login within DeactivationPeriod restores the account, later it is purged
+func (ctrl *Controller) Deactivate(ctx context.Context, userid types.UserId) error {
+	if err := ctrl.repo.Deactivate(ctx, userid, time.Now()); err != nil {
+		return err
 	}
-	err := ctrl.repo.Update(ctx, userData)
-	return err
+	ctrl.index.Remove(userid)
+	ctrl.record(ctx, audit.Event{Type: audit.AccountDeactivated, UserId: userid})
+	return ctrl.repo.RevokeSessions(ctx, userid, revocationTime())
+}
+
+// GetRole outputs role of the user, it is put into access tokens
+func (ctrl *Controller) GetRole(ctx context.Context, userid types.UserId) (types.Role, error) {
+	role, err := ctrl.repo.GetRole(ctx, userid)
+	if err != nil {
+		return role, err
+	}
+	if !role.Valid() {
+		role = types.RoleUser
+	}
+	return role, nil
+}
+
+// check that the authenticated moderator can act on the account,
+// only admins moderate other moderators and admins
+func (ctrl *Controller) checkModerator(ctx context.Context, userid types.UserId) error {
+	if actor, _ := auth.UserId(ctx); actor == userid {
+		return ErrInsufficientRole
+	}
+	role, err := ctrl.GetRole(ctx, userid)
+	if err != nil {
+		return err
+	}
+	if role != types.RoleUser && !auth.HasRole(ctx, types.RoleAdmin) {
+		return ErrInsufficientRole
+	}
+	return nil
+}
+
+// Suspend hides profile of the user, logs out every session and blocks login until Unsuspend,
+// moderator is taken from the context
+func (ctrl *Controller) Suspend(ctx context.Context, userid types.UserId, reason string) error {
+	if err := ctrl.checkModerator(ctx, userid); err != nil {
+		return err
+	}
+	if err := ctrl.repo.Suspend(ctx, userid, time.Now()); err != nil {
+		return err
+	}
+	ctrl.index.Remove(userid)
+	actor, _ := auth.UserId(ctx)
+	ctrl.record(ctx, audit.Event{Type: audit.AccountSuspended, UserId: userid, ActorId: actor, Detail: reason})
+	return ctrl.repo.RevokeSessions(ctx, userid, revocationTime())
+}
+
+// Unsuspend restores suspended account, moderator is taken from the context
+func (ctrl *Controller) Unsuspend(ctx context.Context, userid types.UserId) error {
+	if err := ctrl.checkModerator(ctx, userid); err != nil {
+		return err
+	}
+	if err := ctrl.repo.Unsuspend(ctx, userid); err != nil {
+		return err
+	}
+	if profile, err := ctrl.repo.GetById(ctx, userid); err == nil {
+		ctrl.index.Put(profile)
+	}
+	actor, _ := auth.UserId(ctx)
+	ctrl.record(ctx, audit.Event{Type: audit.AccountUnsuspended, UserId: userid, ActorId: actor})
+	return nil
+}
+
+// SetRole changes role of the user and logs out every session, so that
+// tokens with the old role are not used. Admin is taken from the context
+func (ctrl *Controller) SetRole(ctx context.Context, userid types.UserId, role types.Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	actor, _ := auth.UserId(ctx)
+	if actor == userid {
+		return ErrInsufficientRole
+	}
+	previous, err := ctrl.GetRole(ctx, userid)
+	if err != nil {
+		return err
+	}
+	if previous == role {
+		return nil
+	}
+	if err = ctrl.repo.SetRole(ctx, userid, role); err != nil {
+		return err
+	}
+	ctrl.record(
+		ctx, audit.Event{
+			Type: audit.RoleChanged, UserId: userid, ActorId: actor, Detail: fmt.Sprintf("%s -> %s", previous, role),
+		},
+	)
+	return ctrl.repo.RevokeSessions(ctx, userid, revocationTime())
+}
+
+// check that account can log in, deactivated accounts past grace period are waiting for purge
+func (ctrl *Controller) checkDeactivated(ctx context.Context, userid types.UserId) (bool, error) {
+	deactivatedAt, err := ctrl.repo.GetDeactivatedAt(ctx, userid)
+	if err != nil || deactivatedAt == nil {
+		return false, err
+	}
+	if time.Since(*deactivatedAt) >= DeactivationPeriod {
+		return true, ErrInvalidCredentials
+	}
+	return true, nil
+}
+
+// reactivate account of the user after successful login
+func (ctrl *Controller) reactivate(ctx context.Context, userid types.UserId) error {
+	if err := ctrl.repo.Reactivate(ctx, userid); err != nil {
+		return err
+	}
+	if profile, err := ctrl.repo.GetById(ctx, userid); err == nil {
+		ctrl.index.Put(profile)
+	}
+	ctrl.record(ctx, audit.Event{Type: audit.AccountReactivated, UserId: userid})
+	return nil
+}
+
+// PurgeDeactivated deletes users deactivated more than DeactivationPeriod ago with
+// all their rows and media, it outputs number of purged users
+func (ctrl *Controller) PurgeDeactivated(ctx context.Context) (int, error) {
+	before := time.Now().Add(-DeactivationPeriod)
+	userIds, err := ctrl.repo.GetDeactivatedBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		purged   int
+		firstErr error
+	)
+	for _, userid := range userIds {
+		// urls are read first, rows of tweets are gone after purge
+		urls, err := ctrl.repo.GetMediaUrls(ctx, userid)
+		if err == nil {
+			// user could log in after the query, then nothing is deleted
+			err = ctrl.repo.Purge(ctx, userid, before)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, url := range urls {
+			if err := ctrl.storage.Delete(url); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		purged++
+		ctrl.record(ctx, audit.Event{Type: audit.AccountPurged, UserId: userid})
+	}
+	return purged, firstErr
+}
+
+// nicknames are latin letters, digits and underscores
+var nicknamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// check fields of account update, names and email are trimmed
+func validateAccount(update *model.UserUpdate) error {
+	if update.Version == nil {
+		return fmt.Errorf("%w: version is required", ErrInvalidAccount)
+	}
+	if update.Nickname == nil && update.FirstName == nil && update.LastName == nil &&
+		update.Email == nil && update.Password == nil {
+		return fmt.Errorf("%w: nothing to update", ErrInvalidAccount)
+	}
+	return validateFields(update)
+}
+
+// check account fields that are set, names and email are trimmed
+func validateFields(update *model.UserUpdate) error {
+	for _, field := range []struct {
+		name   string
+		value  *string
+		length int
+	}{
+		{"nickname", update.Nickname, model.MaxNicknameLength},
+		{"first_name", update.FirstName, model.MaxFirstNameLength},
+		{"last_name", update.LastName, model.MaxLastNameLength},
+		{"email", update.Email, model.MaxEmailLength},
+	} {
+		if field.value == nil {
+			continue
+		}
+		*field.value = strings.TrimSpace(*field.value)
+		length := utf8.RuneCountInString(*field.value)
+		if length == 0 || length > field.length {
+			return fmt.Errorf("%w: %s should be 1 to %d characters", ErrInvalidAccount, field.name, field.length)
+		}
+	}
+
+	if update.Nickname != nil && !nicknamePattern.MatchString(*update.Nickname) {
+		return fmt.Errorf("%w: nickname should contain only letters, digits and underscores", ErrInvalidAccount)
+	}
+	if update.Email != nil {
+		address, err := mail.ParseAddress(*update.Email)
+		if err != nil || address.Address != *update.Email || address.Name != "" {
+			return fmt.Errorf("%w: email should be an address like name@example.com", ErrInvalidAccount)
+		}
+	}
+	if update.Password != nil && len(*update.Password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	return nil
+}
+
+// Update changes account fields that are set in update, it outputs the new version of the account.
+// Update that is based on an outdated version is rejected, so concurrent changes are not lost
+func (ctrl *Controller) Update(ctx context.Context, userid types.UserId, update model.UserUpdate) (int, error) {
+	if err := validateAccount(&update); err != nil {
+		return 0, err
+	}
+	if update.Nickname != nil {
+		canonical, err := ctrl.canonicalNickname(*update.Nickname)
+		if err != nil {
+			return 0, err
+		}
+		update.NicknameCanonical = &canonical
+
+		account, err := ctrl.repo.GetAccount(ctx, userid)
+		if err != nil {
+			return 0, err
+		}
+		// change of case keeps the nickname
+		if names.Canonical(account.Nickname) != canonical {
+			if err = ctrl.checkNicknameChange(ctx, userid, canonical); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if update.Password != nil {
+		password := encodePassword(*update.Password)
+		update.Password = &password
+	}
+	version, err := ctrl.repo.Update(ctx, userid, update)
+	if err != nil {
+		return 0, err
+	}
+	// names could change, so search index is updated from the database
+	if profile, err := ctrl.repo.GetById(ctx, userid); err == nil {
+		ctrl.index.Put(profile)
+	} else {
+		log.Printf("failed to update search index for user %d: %v", userid, err)
+	}
+
+	// new email is marked unverified by the update
+	if update.Email != nil {
+		if err := ctrl.sendVerification(userid, *update.Email); err != nil {
+			log.Printf("failed to send verification email to user %d: %v", userid, err)
+		}
+	}
+	return version, nil
+}
+
+// canonical form of the new nickname, names of the reserved and blocked lists are rejected
+func (ctrl *Controller) canonicalNickname(nickname string) (string, error) {
+	if err := ctrl.nicknamePolicy.Check(nickname); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidAccount, err)
+	}
+	return names.Canonical(nickname), nil
+}
+
+// check that canonical nickname isn't reserved by another user, zero userid means new user
+func (ctrl *Controller) checkNicknameFree(ctx context.Context, userid types.UserId, canonical string) error {
+	owner, err := ctrl.repo.GetNicknameOwner(ctx, canonical, time.Now().Add(-NicknameReservation))
+	if err != nil {
+		return err
+	}
+	// user can take own old nickname back
+	if owner != 0 && owner != userid {
+		return ErrNicknameReserved
+	}
+	return nil
+}
+
+// check that user can change nickname to the new canonical one
+func (ctrl *Controller) checkNicknameChange(ctx context.Context, userid types.UserId, canonical string) error {
+	changes, err := ctrl.repo.CountNicknameChanges(ctx, userid, time.Now().Add(-nicknameChangeWindow))
+	if err != nil {
+		return err
+	}
+	if changes >= maxNicknameChanges {
+		return ErrTooManyNicknameChanges
+	}
+	return ctrl.checkNicknameFree(ctx, userid, canonical)
+}
+
+// GetAccount outputs private account of the user with its current version
+func (ctrl *Controller) GetAccount(ctx context.Context, userid types.UserId) (model.Account, error) {
+	return ctrl.repo.GetAccount(ctx, userid)
+}
+
+// set languages that are shown in the timeline
+func (ctrl *Controller) SetPreferredLanguages(ctx context.Context, userid types.UserId, langs []string) error {
+	var (
+		normalized []string
+		seen       = make(map[string]bool)
+	)
+	for _, code := range langs {
+		code = lang.Normalize(code)
+		if len(code) < 2 || len(code) > 3 {
+			return fmt.Errorf("%w: %q", ErrInvalidLanguage, code)
+		}
+		if !seen[code] {
+			seen[code] = true
+			normalized = append(normalized, code)
+		}
+	}
+	return ctrl.repo.SetPreferredLanguages(ctx, userid, normalized)
+}
+
+// get languages that are shown in the timeline
+func (ctrl *Controller) GetPreferredLanguages(ctx context.Context, userid types.UserId) ([]string, error) {
+	return ctrl.repo.GetPreferredLanguages(ctx, userid)
+}
+
+// set how sensitive media is shown in the timeline
+func (ctrl *Controller) SetSensitiveMedia(ctx context.Context, userid types.UserId, preference model.SensitiveMedia) error {
+	if !preference.Valid() {
+		return ErrInvalidSensitiveMedia
+	}
+	return ctrl.repo.SetSensitiveMedia(ctx, userid, preference)
+}
+
+// get how sensitive media is shown in the timeline
+func (ctrl *Controller) GetSensitiveMedia(ctx context.Context, userid types.UserId) (model.SensitiveMedia, error) {
+	return ctrl.repo.GetSensitiveMedia(ctx, userid)
+}
+
+// protect tweets of the user, new followers have to be approved
+func (ctrl *Controller) SetProtected(ctx context.Context, userid types.UserId, protected bool) error {
+	return ctrl.repo.SetProtected(ctx, userid, protected)
+}
+
+// get public profile of the user
+func (ctrl *Controller) GetUser(ctx context.Context, userid types.UserId) (model.Profile, error) {
+	return ctrl.repo.GetById(ctx, userid)
+}
+
+// get public profile of the user by nickname regardless of case and look-alike characters,
+// recently changed nickname resolves to the current profile with RedirectedFrom set
+func (ctrl *Controller) GetUserByNickname(ctx context.Context, nickname string) (model.Profile, error) {
+	canonical := names.Canonical(nickname)
+	// reserved nickname can't belong to another user
+	owner, err := ctrl.repo.GetNicknameOwner(ctx, canonical, time.Now().Add(-NicknameReservation))
+	if err != nil {
+		return model.Profile{}, err
+	}
+	if owner == 0 {
+		return ctrl.repo.GetByNickname(ctx, canonical)
+	}
+
+	profile, err := ctrl.repo.GetById(ctx, owner)
+	if err != nil {
+		return model.Profile{}, err
+	}
+	// owner could take the nickname back
+	if names.Canonical(profile.Nickname) != canonical {
+		profile.RedirectedFrom = nickname
+	}
+	return profile, nil
+}
+
+// get public profiles of the users, missing users are skipped
+func (ctrl *Controller) GetUsers(ctx context.Context, userIds ...types.UserId) ([]model.Profile, error) {
+	profiles, err := ctrl.repo.GetUsers(ctx, userIds...)
+	if profiles == nil {
+		profiles = []model.Profile{}
+	}
+	return profiles, err
+}
+
+// LoadSearchIndex indexes all users, it is called on start
+func (ctrl *Controller) LoadSearchIndex(ctx context.Context) error {
+	profiles, err := ctrl.repo.GetAllUsers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, profile := range profiles {
+		ctrl.index.Put(profile)
+	}
+	return nil
+}
+
+// RefreshFollowerCounts updates follower counts that rank search results,
+// shorter names go first while counts are unknown
+func (ctrl *Controller) RefreshFollowerCounts(ctx context.Context) error {
+	if ctrl.follow == nil {
+		return nil
+	}
+	userIds := ctrl.index.Users()
+	for start := 0; start < len(userIds); start += followerCountsBatch {
+		end := start + followerCountsBatch
+		if end > len(userIds) {
+			end = len(userIds)
+		}
+		counts, err := ctrl.follow.GetFollowerCounts(ctx, userIds[start:end]...)
+		if err != nil {
+			return err
+		}
+		ctrl.index.SetFollowers(counts)
+	}
+	return nil
+}
+
+// SearchUsers returns users whose nickname or name starts with prefix,
+// users with more followers go first
+func (ctrl *Controller) SearchUsers(ctx context.Context, prefix string, limit int) ([]model.Profile, error) {
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	userIds := ctrl.index.Search(prefix, limit)
+	if len(userIds) == 0 {
+		return []model.Profile{}, nil
+	}
+
+	profiles, err := ctrl.repo.GetUsers(ctx, userIds...)
+	if err != nil {
+		return nil, err
+	}
+	// database returns profiles in its own order
+	rank := make(map[types.UserId]int, len(userIds))
+	for i, id := range userIds {
+		rank[id] = i
+	}
+	sort.Slice(profiles, func(i, j int) bool { return rank[profiles[i].UserId] < rank[profiles[j].UserId] })
+	if profiles == nil {
+		profiles = []model.Profile{}
+	}
+	return profiles, nil
+}
+
+// check length and format of profile fields
+func validateProfile(profile *model.ProfileUpdate) error {
+	for _, field := range []struct {
+		name   string
+		value  *string
+		length int
+	}{
+		{"bio", profile.Bio, model.MaxBioLength},
+		{"location", profile.Location, model.MaxLocationLength},
+		{"website", profile.Website, model.MaxWebsiteLength},
+	} {
+		if field.value == nil {
+			continue
+		}
+		*field.value = strings.TrimSpace(*field.value)
+		if utf8.RuneCountInString(*field.value) > field.length {
+			return fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidProfile, field.name, field.length)
+		}
+	}
+
+	if profile.Website != nil && *profile.Website != "" {
+		u, err := url.Parse(*profile.Website)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: website should be http or https url", ErrInvalidProfile)
+		}
+	}
+	if profile.Birthday != nil && *profile.Birthday != "" {
+		birthday, err := time.Parse(model.BirthdayLayout, *profile.Birthday)
+		if err != nil || birthday.After(time.Now()) {
+			return fmt.Errorf("%w: birthday should be a past date in YYYY-MM-DD format", ErrInvalidProfile)
+		}
+	}
+	return nil
+}
+
+// update bio, location, website and birthday
+func (ctrl *Controller) UpdateProfile(ctx context.Context, userid types.UserId, profile model.ProfileUpdate) error {
+	if err := validateProfile(&profile); err != nil {
+		return err
+	}
+	if err := ctrl.requireVerified(ctx, userid); err != nil {
+		return err
+	}
+	return ctrl.repo.UpdateProfile(ctx, userid, profile)
+}
+
+// crop and resize image, save it to temporary file
+func saveImage(image io.Reader, userid types.UserId, kind model.ImageKind) (string, error) {
+	width, height := kind.Size()
+	img, err := imaging.Fill(image, width, height)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("%s_%d_%d.jpg", kind, userid, time.Now().UnixNano()))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err = imaging.EncodeJPEG(file, img); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// upload avatar or header image, replaced image is removed from storage
+func (ctrl *Controller) UpdateImage(
+	ctx context.Context,
+	userid types.UserId,
+	kind model.ImageKind,
+	image io.Reader,
+) (string, error) {
+	if err := ctrl.requireVerified(ctx, userid); err != nil {
+		return "", err
+	}
+	profile, err := ctrl.repo.GetById(ctx, userid)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := saveImage(image, userid, kind)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path)
+
+	imageUrl, err := ctrl.storage.Upload(path)
+	if err != nil {
+		return "", err
+	}
+	if err = ctrl.repo.UpdateImage(ctx, userid, kind, imageUrl); err != nil {
+		ctrl.storage.Delete(imageUrl)
+		return "", err
+	}
+
+	// clean up replaced image, failure doesn't affect the update
+	previous := profile.AvatarUrl
+	if kind == model.ImageHeader {
+		previous = profile.HeaderUrl
+	}
+	if previous != nil && *previous != "" && *previous != imageUrl {
+		if err := ctrl.storage.Delete(*previous); err != nil {
+			log.Printf("failed to delete replaced %s %s: %v", kind, *previous, err)
+		}
+	}
+	return imageUrl, nil
+}
+
+// random url safe token
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// only hashes of tokens are stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ForgotPassword sends password reset link in background, so response
+// and its time are the same whether email is registered or not
+func (ctrl *Controller) ForgotPassword(email string) {
+	email = strings.TrimSpace(email)
+	// silently drop requests over the limit, client sees the same response
+	if !ctrl.resetLimiter.Allow(strings.ToLower(email)) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+		defer cancel()
+		if err := ctrl.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("password reset link is not sent: %v", err)
+		}
+	}()
+}
+
+// create reset token of the user with the email and send the link to it
+func (ctrl *Controller) sendPasswordReset(ctx context.Context, email string) error {
+	userId, err := ctrl.repo.GetIdByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+	err = ctrl.repo.PutPasswordReset(ctx, userId, hashToken(token), time.Now().Add(passwordResetTTL))
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s?token=%s", ctrl.resetUrl, token)
+	body := fmt.Sprintf(
+		"Reset your password by following the link below. It expires in %d minutes.\n"+
+			"If you didn't request it, ignore this email.\n\n%s",
+		int(passwordResetTTL.Minutes()), link,
+	)
+	if err := ctrl.mailer.Send(email, "Reset your password", body); err != nil {
+		return fmt.Errorf("user %d: %w", userId, err)
+	}
+	return nil
+}
+
+// set new password with reset token, token can be used once
+// and all existing sessions are revoked
+func (ctrl *Controller) ResetPassword(ctx context.Context, token string, password string) error {
+	if utf8.RuneCountInString(password) < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	userId, expiresAt, err := ctrl.repo.ConsumePasswordReset(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if time.Now().After(expiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	return ctrl.repo.ResetPassword(ctx, userId, encodePassword(password), revocationTime())
+}
+
+// tokens have second precision, so every token issued up to this second is revoked
+func revocationTime() time.Time {
+	return time.Now().Truncate(time.Second).Add(time.Second)
+}
+
+// SeenSession remembers activity of the session, access tokens of revoked
+// sessions are rejected by auth.SessionStore before
+func (ctrl *Controller) SeenSession(sessionId string) {
+	if sessionId != "" {
+		ctrl.seen(sessionId)
+	}
+}
+
+// remember activity of the session, it is saved by FlushLastSeen
+// so requests don't write to the database
+func (ctrl *Controller) seen(sessionId string) {
+	ctrl.seenMu.Lock()
+	defer ctrl.seenMu.Unlock()
+	ctrl.lastSeen[sessionId] = time.Now()
+}
+
+// FlushLastSeen saves activity of sessions remembered since the previous call
+func (ctrl *Controller) FlushLastSeen(ctx context.Context) error {
+	ctrl.seenMu.Lock()
+	lastSeen := ctrl.lastSeen
+	ctrl.lastSeen = make(map[string]time.Time)
+	ctrl.seenMu.Unlock()
+	if len(lastSeen) == 0 {
+		return nil
+	}
+
+	err := ctrl.repo.SetLastSeen(ctx, lastSeen)
+	if err != nil {
+		// keep activity for the next attempt unless there is a newer one
+		ctrl.seenMu.Lock()
+		for sessionId, at := range lastSeen {
+			if _, ok := ctrl.lastSeen[sessionId]; !ok {
+				ctrl.lastSeen[sessionId] = at
+			}
+		}
+		ctrl.seenMu.Unlock()
+	}
+	return err
+}
+
+// issue refresh token of the family
+func (ctrl *Controller) putRefreshToken(ctx context.Context, userid types.UserId, family string) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	err = ctrl.repo.PutRefreshToken(ctx, userid, hashToken(token), family, time.Now().Add(RefreshTokenTTL))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CreateSession records login from the device and starts its family of refresh tokens,
+// it outputs id of the session and refresh token
+func (ctrl *Controller) CreateSession(
+	ctx context.Context,
+	userid types.UserId,
+	userAgent string,
+	ip string,
+) (string, string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token, err := generateToken()
+	if err != nil {
+		return "", "", err
+	}
+	if utf8.RuneCountInString(userAgent) > maxUserAgentLength {
+		userAgent = string([]rune(userAgent)[:maxUserAgentLength])
+	}
+
+	now := time.Now()
+	session := model.Session{
+		SessionId:  hex.EncodeToString(b),
+		UserId:     userid,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	err = ctrl.repo.CreateSession(ctx, session, hashToken(token), now.Add(RefreshTokenTTL))
+	if err != nil {
+		return "", "", err
+	}
+	return session.SessionId, token, nil
+}
+
+// Refresh exchanges refresh token for a new one, every token can be used once.
+// Reuse of the token means it was stolen, so the whole family is revoked.
+// It outputs user, id of the session and new refresh token
+func (ctrl *Controller) Refresh(ctx context.Context, token string) (types.UserId, string, string, error) {
+	tokenHash := hashToken(token)
+	refreshToken, err := ctrl.repo.GetRefreshToken(ctx, tokenHash)
+	if err != nil {
+		return 0, "", "", err
+	}
+	if time.Now().After(refreshToken.ExpiresAt) {
+		return 0, "", "", ErrInvalidRefreshToken
+	}
+
+	used := false
+	if !refreshToken.Used {
+		// token could be used concurrently after it was read
+		used, err = ctrl.repo.UseRefreshToken(ctx, tokenHash)
+		if err != nil {
+			return 0, "", "", err
+		}
+	}
+	if !used {
+		if err = ctrl.repo.DeleteRefreshFamily(ctx, refreshToken.Family); err != nil {
+			return 0, "", "", err
+		}
+		return 0, "", "", ErrInvalidRefreshToken
+	}
+
+	newToken, err := ctrl.putRefreshToken(ctx, refreshToken.UserId, refreshToken.Family)
+	if err != nil {
+		return 0, "", "", err
+	}
+	ctrl.seen(refreshToken.Family)
+	return refreshToken.UserId, refreshToken.Family, newToken, nil
+}
+
+// GetSessions outputs sessions of the user, current is the session of the request
+func (ctrl *Controller) GetSessions(
+	ctx context.Context,
+	userid types.UserId,
+	current string,
+) ([]model.Session, error) {
+	sessions, err := ctrl.repo.GetSessions(ctx, userid)
+	if err != nil {
+		return nil, err
+	}
+
+	// activity that is not saved yet
+	ctrl.seenMu.Lock()
+	for i := range sessions {
+		if at, ok := ctrl.lastSeen[sessions[i].SessionId]; ok && at.After(sessions[i].LastSeenAt) {
+			sessions[i].LastSeenAt = at
+		}
+		sessions[i].Current = sessions[i].SessionId == current
+	}
+	ctrl.seenMu.Unlock()
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions, nil
+}
+
+// RevokeSession logs out the device, its access tokens are rejected by auth.SessionStore
+// and refresh tokens are deleted
+func (ctrl *Controller) RevokeSession(ctx context.Context, userid types.UserId, sessionId string) error {
+	if err := ctrl.repo.DeleteSession(ctx, userid, sessionId); err != nil {
+		return err
+	}
+	ctrl.seenMu.Lock()
+	delete(ctrl.lastSeen, sessionId)
+	ctrl.seenMu.Unlock()
+	ctrl.record(ctx, audit.Event{Type: audit.SessionRevoked, UserId: userid, Detail: sessionId})
+	return nil
+}
+
+// Logout revokes current access token, its session and refresh tokens of the same login,
+// refresh token from the cookie is revoked too if it belongs to another login of the user
+func (ctrl *Controller) Logout(ctx context.Context, claims *jwt.Claims, refreshToken string) error {
+	if err := jwt.Revoke(ctx, claims); err != nil {
+		return err
+	}
+	if claims.SessionId != "" {
+		if err := ctrl.repo.DeleteSession(ctx, claims.UserId, claims.SessionId); err != nil {
+			return err
+		}
+		ctrl.seenMu.Lock()
+		delete(ctrl.lastSeen, claims.SessionId)
+		ctrl.seenMu.Unlock()
+	}
+	if refreshToken == "" {
+		return nil
+	}
+
+	token, err := ctrl.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil || token.UserId != claims.UserId || token.Family == claims.SessionId {
+		// nothing else to revoke
+		return nil
+	}
+	return ctrl.repo.DeleteRefreshFamily(ctx, token.Family)
+}
+
+// LogoutAll revokes every access and refresh token of the user
+func (ctrl *Controller) LogoutAll(ctx context.Context, userid types.UserId) error {
+	return ctrl.repo.RevokeSessions(ctx, userid, revocationTime())
+}
+
+// random recovery codes like "abcde-fghij" and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// recovery codes are hashed without formatting so users may type them differently
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(code)
+}
+
+// check code from authenticator or unused recovery code, both can be used once
+func (ctrl *Controller) verifySecondFactor(ctx context.Context, userid types.UserId, totp model.TOTP, code string) error {
+	if !ctrl.codeLimiter.Allow(fmt.Sprint(userid)) {
+		return ErrTooManyAttempts
+	}
+	if step, ok := totputil.Validate(totp.Secret, code, time.Now()); ok {
+		// step is updated only if it is later than the last one, so replayed code fails
+		if step <= totp.LastStep || ctrl.repo.UseTOTPStep(ctx, userid, step) != nil {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+
+	// recovery code is deleted on use
+	if ctrl.repo.UseRecoveryCode(ctx, userid, hashRecoveryCode(code)) != nil {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// EnrollTOTP starts two-factor enrollment and returns otpauth uri for authenticator app,
+// enrollment is finished with ConfirmTOTP
+func (ctrl *Controller) EnrollTOTP(ctx context.Context, userid types.UserId) (string, error) {
+	totp, err := ctrl.repo.GetTOTP(ctx, userid)
+	if err != nil {
+		return "", err
+	}
+	if totp.Enabled {
+		return "", ErrTOTPEnabled
+	}
+	email, _, err := ctrl.repo.GetEmail(ctx, userid)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := totputil.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	if err = ctrl.repo.SetTOTPSecret(ctx, userid, secret); err != nil {
+		return "", err
+	}
+	return totputil.URI(totpIssuer, email, secret), nil
+}
+
+// ConfirmTOTP enables two-factor authentication with the first code from authenticator
+// and returns recovery codes, they are shown to user only once
+func (ctrl *Controller) ConfirmTOTP(ctx context.Context, userid types.UserId, code string) ([]string, error) {
+	totp, err := ctrl.repo.GetTOTP(ctx, userid)
+	if err != nil {
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, ErrTOTPEnabled
+	}
+	if totp.Secret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if !ctrl.codeLimiter.Allow(fmt.Sprint(userid)) {
+		return nil, ErrTooManyAttempts
+	}
+	step, ok := totputil.Validate(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = ctrl.repo.EnableTOTP(ctx, userid, step, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns off two-factor authentication, current code or recovery code is required
+func (ctrl *Controller) DisableTOTP(ctx context.Context, userid types.UserId, code string) error {
+	totp, err := ctrl.repo.GetTOTP(ctx, userid)
+	if err != nil {
+		return err
+	}
+	if !totp.Enabled {
+		return ErrTOTPNotEnabled
+	}
+	if err = ctrl.verifySecondFactor(ctx, userid, totp, code); err != nil {
+		return err
+	}
+	return ctrl.repo.DisableTOTP(ctx, userid)
+}
+
+// RegenerateRecoveryCodes replaces recovery codes with new ones, current code is required
+func (ctrl *Controller) RegenerateRecoveryCodes(ctx context.Context, userid types.UserId, code string) ([]string, error) {
+	totp, err := ctrl.repo.GetTOTP(ctx, userid)
+	if err != nil {
+		return nil, err
+	}
+	if !totp.Enabled {
+		return nil, ErrTOTPNotEnabled
+	}
+	if err = ctrl.verifySecondFactor(ctx, userid, totp, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = ctrl.repo.ReplaceRecoveryCodes(ctx, userid, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyLogin finishes login started by Login with code from authenticator or recovery code
+func (ctrl *Controller) VerifyLogin(ctx context.Context, challenge string, code string) (types.UserId, error) {
+	userid, err := jwt.ParseChallengeToken(challenge)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidChallenge, err)
+	}
+	totp, err := ctrl.repo.GetTOTP(ctx, userid)
+	if err != nil {
+		return 0, err
+	}
+	if !totp.Enabled {
+		// disabled after challenge was issued
+		return 0, ErrInvalidChallenge
+	}
+	if err = ctrl.verifySecondFactor(ctx, userid, totp, code); err != nil {
+		ctrl.record(ctx, audit.Event{Type: audit.LoginFailed, UserId: userid, Detail: "second factor"})
+		return 0, err
+	}
+	// account is reactivated only after the second factor
+	deactivated, err := ctrl.checkDeactivated(ctx, userid)
+	if err != nil {
+		return 0, err
+	}
+	if deactivated {
+		if err = ctrl.reactivate(ctx, userid); err != nil {
+			return 0, err
+		}
+	}
+	ctrl.record(ctx, audit.Event{Type: audit.LoginSucceeded, UserId: userid, Detail: "second factor"})
+	return userid, nil
+}
+
+// export started long ago is considered failed, e.g. service was restarted
+func exportPending(export model.Export) bool {
+	return export.Status == model.ExportPending && time.Since(export.CreatedAt) < exportTimeout
+}
+
+// RequestExport collects personal data and prepares its archive in background,
+// download link is sent to email of the user when it is ready
+func (ctrl *Controller) RequestExport(ctx context.Context, userid types.UserId) (model.Export, error) {
+	exports, err := ctrl.repo.GetExports(ctx, userid)
+	if err != nil {
+		return model.Export{}, err
+	}
+	if len(exports) > 0 && exportPending(exports[0]) {
+		return exports[0], ErrExportInProgress
+	}
+	if !ctrl.exportLimiter.Allow(fmt.Sprint(userid)) {
+		return model.Export{}, ErrTooManyExports
+	}
+	email, _, err := ctrl.repo.GetEmail(ctx, userid)
+	if err != nil {
+		return model.Export{}, err
+	}
+	// other services are called with token of the request, it may expire
+	// or be revoked before the archive is written
+	data, err := ctrl.gatherExport(ctx, userid)
+	if err != nil {
+		return model.Export{}, err
+	}
+
+	export := model.Export{UserId: userid, Status: model.ExportPending, CreatedAt: time.Now()}
+	if export.ExportId, err = ctrl.repo.CreateExport(ctx, userid, export.CreatedAt); err != nil {
+		return model.Export{}, err
+	}
+	ctrl.record(ctx, audit.Event{Type: audit.DataExportRequested, UserId: userid})
+	go ctrl.buildExport(export, email, data)
+	return export, nil
+}
+
+// prepare archive and notify the user
+func (ctrl *Controller) buildExport(export model.Export, email string, data archive.Data) {
+	// export outlives the request
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	subject := "Your data export failed"
+	body := "We couldn't prepare archive with your data. Please request it again later."
+	link, err := ctrl.writeExport(ctx, export, data)
+	if err == nil {
+		subject = "Your data is ready"
+		body = fmt.Sprintf(
+			"Archive with your data is ready. Download it by following the link below, it expires in %d days.\n\n%s",
+			int(exportTTL.Hours()/24), link,
+		)
+	} else {
+		log.Printf("failed to export data of user %d: %v", export.UserId, err)
+		// deadline of ctx may be exceeded already
+		if err := ctrl.repo.FailExport(context.Background(), export.ExportId); err != nil {
+			log.Printf("failed to mark export %d as failed: %v", export.ExportId, err)
+		}
+	}
+	if err := ctrl.mailer.Send(email, subject, body); err != nil {
+		log.Printf("failed to send data export email to user %d: %v", export.UserId, err)
+	}
+}
+
+// write archive, upload it to storage and output download link
+func (ctrl *Controller) writeExport(ctx context.Context, export model.Export, data archive.Data) (string, error) {
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("export_%d_%d.zip", export.UserId, export.ExportId))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path)
+	err = archive.Write(file, data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	storagePath, err := ctrl.storage.Upload(path)
+	if err != nil {
+		return "", err
+	}
+	token, err := generateToken()
+	if err == nil {
+		err = ctrl.repo.CompleteExport(ctx, export.ExportId, hashToken(token), storagePath, time.Now().Add(exportTTL))
+	}
+	if err != nil {
+		ctrl.storage.Delete(storagePath)
+		return "", err
+	}
+	return fmt.Sprintf("%s?token=%s", ctrl.exportUrl, token), nil
+}
+
+// collect data of the user from the database and other services
+func (ctrl *Controller) gatherExport(ctx context.Context, userid types.UserId) (archive.Data, error) {
+	data := archive.Data{CreatedAt: time.Now()}
+	account := &data.Account
+
+	var err error
+	if account.Profile, err = ctrl.repo.GetById(ctx, userid); err != nil {
+		return data, err
+	}
+	if account.Email, account.EmailVerified, err = ctrl.repo.GetEmail(ctx, userid); err != nil {
+		return data, err
+	}
+	if account.PreferredLanguages, err = ctrl.repo.GetPreferredLanguages(ctx, userid); err != nil {
+		return data, err
+	}
+	if account.SensitiveMedia, err = ctrl.repo.GetSensitiveMedia(ctx, userid); err != nil {
+		return data, err
+	}
+	totp, err := ctrl.repo.GetTOTP(ctx, userid)
+	if err != nil {
+		return data, err
+	}
+	account.TwoFactorEnabled = totp.Enabled
+	if data.Sessions, err = ctrl.repo.GetSessions(ctx, userid); err != nil {
+		return data, err
+	}
+	if data.Avatar, err = ctrl.readImage(account.AvatarUrl); err != nil {
+		return data, err
+	}
+	if data.Header, err = ctrl.readImage(account.HeaderUrl); err != nil {
+		return data, err
+	}
+
+	// archive has to be complete, so failure of any service fails the export
+	if ctrl.tweets != nil {
+		if data.Tweets, err = ctrl.tweets.GetTweets(ctx, userid); err != nil {
+			return data, fmt.Errorf("tweets: %w", err)
+		}
+	}
+	if ctrl.likes != nil {
+		if data.Likes, err = ctrl.likes.GetLikedTweets(ctx, userid); err != nil {
+			return data, fmt.Errorf("likes: %w", err)
+		}
+	}
+	if ctrl.follow != nil {
+		if data.Followers, err = ctrl.follow.GetFollowers(ctx, userid); err != nil {
+			return data, fmt.Errorf("followers: %w", err)
+		}
+		if data.Following, err = ctrl.follow.GetFollowing(ctx, userid); err != nil {
+			return data, fmt.Errorf("following: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// read profile image from storage, nil if it is not set
+func (ctrl *Controller) readImage(storagePath *string) ([]byte, error) {
+	if storagePath == nil || *storagePath == "" {
+		return nil, nil
+	}
+	encoded, err := ctrl.storage.ConvertImageFromStorage(*storagePath)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// GetExports outputs data exports of the user, the latest first
+func (ctrl *Controller) GetExports(ctx context.Context, userid types.UserId) ([]model.Export, error) {
+	exports, err := ctrl.repo.GetExports(ctx, userid)
+	if err != nil {
+		return nil, err
+	}
+	for i := range exports {
+		if exports[i].Status == model.ExportPending && !exportPending(exports[i]) {
+			exports[i].Status = model.ExportFailed
+		}
+	}
+	return exports, nil
+}
+
+// archive copied from storage, the copy is removed on close
+type exportFile struct {
+	*os.File
+	dir string
+}
+
+func (f *exportFile) Close() error {
+	err := f.File.Close()
+	os.RemoveAll(f.dir)
+	return err
+}
+
+// DownloadExport opens archive by token from the link, the caller has to close it
+func (ctrl *Controller) DownloadExport(ctx context.Context, token string) (io.ReadCloser, model.Export, error) {
+	export, err := ctrl.repo.GetExportByToken(ctx, hashToken(token))
+	if err != nil {
+		return nil, export, err
+	}
+	if export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		return nil, export, ErrExportExpired
+	}
+
+	dir, err := os.MkdirTemp("", "export")
+	if err != nil {
+		return nil, export, err
+	}
+	path, err := ctrl.storage.Download(export.StoragePath, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, export, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, export, err
+	}
+	ctrl.record(ctx, audit.Event{Type: audit.DataExportDownloaded, UserId: export.UserId})
+	return &exportFile{file, dir}, export, nil
+}
+
+// PurgeNicknameHistory frees nicknames whose reservation is over
+func (ctrl *Controller) PurgeNicknameHistory(ctx context.Context) error {
+	return ctrl.repo.PurgeNicknameHistory(ctx, time.Now().Add(-NicknameReservation))
+}
+
+// PurgeExpiredExports deletes archives that can't be downloaded anymore,
+// it outputs number of deleted archives
+func (ctrl *Controller) PurgeExpiredExports(ctx context.Context) (int, error) {
+	exports, err := ctrl.repo.GetExpiredExports(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		purged   int
+		firstErr error
+	)
+	for _, export := range exports {
+		err := ctrl.storage.Delete(export.StoragePath)
+		if err != nil && !os.IsNotExist(err) {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if err = ctrl.repo.DeleteExport(ctx, export.ExportId); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		purged++
+	}
+	return purged, firstErr
+}
+
+// CreateAccessToken issues personal access token with the scopes, the token is returned
+// only once and only its hash is stored. Zero expiresIn means default expiration.
+// Tokens can create content, so they are issued only to users with verified email
+func (ctrl *Controller) CreateAccessToken(
+	ctx context.Context,
+	userid types.UserId,
+	name string,
+	scopes []types.Scope,
+	expiresIn time.Duration,
+) (model.AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxAccessTokenNameLength {
+		return model.AccessToken{}, "", fmt.Errorf(
+			"%w: name is required and should be at most %d characters", ErrInvalidAccessToken, maxAccessTokenNameLength,
+		)
+	}
+	if len(scopes) == 0 {
+		return model.AccessToken{}, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidAccessToken)
+	}
+	seen := make(map[types.Scope]bool)
+	unique := []types.Scope{}
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return model.AccessToken{}, "", fmt.Errorf("%w: unknown scope %q", ErrInvalidAccessToken, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	if expiresIn == 0 {
+		expiresIn = defaultAccessTokenTTL
+	}
+	if expiresIn < 0 || expiresIn > maxAccessTokenTTL {
+		return model.AccessToken{}, "", fmt.Errorf(
+			"%w: expiration should be at most %d days", ErrInvalidAccessToken, int(maxAccessTokenTTL.Hours()/24),
+		)
+	}
+	if err := ctrl.requireVerified(ctx, userid); err != nil {
+		return model.AccessToken{}, "", err
+	}
+
+	tokens, err := ctrl.repo.GetAccessTokens(ctx, userid)
+	if err != nil {
+		return model.AccessToken{}, "", err
+	}
+	if len(tokens) >= maxAccessTokens {
+		return model.AccessToken{}, "", ErrTooManyAccessTokens
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return model.AccessToken{}, "", err
+	}
+	secret = auth.PersonalTokenPrefix + secret
+	// stored without fractional seconds like every timestamp
+	now := time.Now().UTC().Truncate(time.Second)
+	token := model.AccessToken{
+		UserId:    userid,
+		Name:      name,
+		Scopes:    unique,
+		CreatedAt: now,
+		ExpiresAt: now.Add(expiresIn),
+	}
+	if token.TokenId, err = ctrl.repo.CreateAccessToken(ctx, token, auth.HashPersonalToken(secret)); err != nil {
+		return model.AccessToken{}, "", err
+	}
+	ctrl.record(ctx, audit.Event{Type: audit.AccessTokenCreated, UserId: userid, Detail: fmt.Sprintf("%d %s", token.TokenId, name)})
+	return token, secret, nil
+}
+
+// GetAccessTokens outputs personal access tokens of the user without secrets
+func (ctrl *Controller) GetAccessTokens(ctx context.Context, userid types.UserId) ([]model.AccessToken, error) {
+	return ctrl.repo.GetAccessTokens(ctx, userid)
+}
+
+// RevokeAccessToken deletes personal access token of the user, it is rejected right away
+func (ctrl *Controller) RevokeAccessToken(ctx context.Context, userid types.UserId, tokenId int) error {
+	if err := ctrl.repo.DeleteAccessToken(ctx, userid, tokenId); err != nil {
+		return err
+	}
+	ctrl.record(ctx, audit.Event{Type: audit.AccessTokenRevoked, UserId: userid, Detail: fmt.Sprint(tokenId)})
+	return nil
 }
@@ -0,0 +1,762 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	mock_controller "github.com/alexvishnevskiy/twitter-clone/gen/controller/users"
+	mock_storage "github.com/alexvishnevskiy/twitter-clone/gen/storage"
+	"github.com/alexvishnevskiy/twitter-clone/internal/audit"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/ratelimit"
+	totputil "github.com/alexvishnevskiy/twitter-clone/internal/totp"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	tweetsmodel "github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	"github.com/alexvishnevskiy/twitter-clone/users/internal/names"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	ks, err := jwt.GenerateKeySet()
+	if err != nil {
+		panic(err)
+	}
+	jwt.SetKeySet(ks)
+	os.Exit(m.Run())
+}
+
+// mailer that passes subjects of sent emails to the test
+type mailbox chan string
+
+func (m mailbox) Send(_ string, subject string, _ string) error {
+	m <- subject
+	return nil
+}
+
+// controller without other services, every repository call has to be expected
+func newTestController(t *testing.T) (*Controller, *mock_controller.MockusersRepository) {
+	mockCtrl := gomock.NewController(t)
+	t.Cleanup(mockCtrl.Finish)
+	repo := mock_controller.NewMockusersRepository(mockCtrl)
+	return New(repo, nil, make(mailbox, 10), audit.Multi(), nil, nil, nil, nil, "", "", ""), repo
+}
+
+// wait for email sent in background
+func receive(t *testing.T, mail mailbox) string {
+	t.Helper()
+	select {
+	case subject := <-mail:
+		return subject
+	case <-time.After(5 * time.Second):
+		t.Fatal("email was not sent")
+		return ""
+	}
+}
+
+func TestController_RequestExportRevokedToken(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Principal{UserId: 1, Token: "token"})
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// services reject the token of the user once it is revoked
+	var revoked atomic.Bool
+	checkToken := func() error {
+		if revoked.Load() {
+			return jwt.ErrTokenRevoked
+		}
+		return nil
+	}
+	mockTweets := mock_controller.NewMocktweetsGateway(mockCtrl)
+	mockTweets.EXPECT().GetTweets(ctx, types.UserId(1)).DoAndReturn(
+		func(context.Context, types.UserId) ([]tweetsmodel.Media, error) {
+			return []tweetsmodel.Media{{TweetId: 5, Content: "tweet"}}, checkToken()
+		},
+	)
+	mockLikes := mock_controller.NewMocklikesGateway(mockCtrl)
+	mockLikes.EXPECT().GetLikedTweets(ctx, types.UserId(1)).DoAndReturn(
+		func(context.Context, types.UserId) ([]types.TweetId, error) {
+			return []types.TweetId{7}, checkToken()
+		},
+	)
+	mockFollow := mock_controller.NewMockfollowGateway(mockCtrl)
+	mockFollow.EXPECT().GetFollowers(ctx, types.UserId(1)).DoAndReturn(
+		func(context.Context, types.UserId) ([]types.UserId, error) {
+			return []types.UserId{2}, checkToken()
+		},
+	)
+	mockFollow.EXPECT().GetFollowing(ctx, types.UserId(1)).DoAndReturn(
+		func(context.Context, types.UserId) ([]types.UserId, error) {
+			return []types.UserId{3}, checkToken()
+		},
+	)
+
+	mockRepo := mock_controller.NewMockusersRepository(mockCtrl)
+	mockRepo.EXPECT().GetExports(ctx, types.UserId(1)).Return(nil, nil)
+	mockRepo.EXPECT().GetEmail(ctx, types.UserId(1)).Return("user@example.com", true, nil).Times(2)
+	mockRepo.EXPECT().GetById(ctx, types.UserId(1)).Return(model.Profile{UserId: 1, Nickname: "user"}, nil)
+	mockRepo.EXPECT().GetPreferredLanguages(ctx, types.UserId(1)).Return([]string{"en"}, nil)
+	mockRepo.EXPECT().GetSensitiveMedia(ctx, types.UserId(1)).Return(model.SensitiveBlur, nil)
+	mockRepo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(model.TOTP{}, nil)
+	mockRepo.EXPECT().GetSessions(ctx, types.UserId(1)).Return(nil, nil)
+	mockRepo.EXPECT().CreateExport(ctx, types.UserId(1), gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().CompleteExport(gomock.Any(), 1, gomock.Any(), "exports/1.zip", gomock.Any()).Return(nil)
+	mockStorage := mock_storage.NewMockStorage(mockCtrl)
+	mockStorage.EXPECT().Upload(gomock.Any()).Return("exports/1.zip", nil)
+
+	mail := make(mailbox, 1)
+	ctrl := New(mockRepo, mockStorage, mail, audit.Multi(), mockFollow, mockTweets, mockLikes, nil, "", "", "")
+	export, err := ctrl.RequestExport(ctx, types.UserId(1))
+	if err != nil {
+		t.Fatalf("error was not expected while requesting export: %s", err)
+	}
+	if export.ExportId != 1 || export.Status != model.ExportPending {
+		t.Errorf("wrong export: %+v", export)
+	}
+
+	// user logs out right after the request, archive is written anyway
+	revoked.Store(true)
+	if subject := receive(t, mail); subject != "Your data is ready" {
+		t.Errorf("export should succeed after token is revoked, got email %q", subject)
+	}
+}
+
+func TestController_TOTPEnrollment(t *testing.T) {
+	ctx := context.Background()
+	ctrl, repo := newTestController(t)
+
+	var secret string
+	repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(model.TOTP{}, nil)
+	repo.EXPECT().GetEmail(ctx, types.UserId(1)).Return("user@example.com", true, nil)
+	repo.EXPECT().SetTOTPSecret(ctx, types.UserId(1), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ types.UserId, s string) error {
+			secret = s
+			return nil
+		},
+	)
+	uri, err := ctrl.EnrollTOTP(ctx, types.UserId(1))
+	if err != nil {
+		t.Fatalf("error was not expected while enrolling: %s", err)
+	}
+	if secret == "" || !strings.Contains(uri, secret) {
+		t.Errorf("uri %q should contain the saved secret", uri)
+	}
+
+	// wrong code doesn't enable two-factor authentication
+	repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(model.TOTP{Secret: secret}, nil)
+	if _, err = ctrl.ConfirmTOTP(ctx, types.UserId(1), "12345"); err != ErrInvalidCode {
+		t.Errorf("expected ErrInvalidCode for wrong code, got: %v", err)
+	}
+
+	step := totputil.Step(time.Now())
+	code, err := totputil.Code(secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hashes []string
+	repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(model.TOTP{Secret: secret}, nil)
+	repo.EXPECT().EnableTOTP(ctx, types.UserId(1), step, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ types.UserId, _ int64, h []string) error {
+			hashes = h
+			return nil
+		},
+	)
+	codes, err := ctrl.ConfirmTOTP(ctx, types.UserId(1), code)
+	if err != nil {
+		t.Fatalf("error was not expected while confirming: %s", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d codes and %d hashes", recoveryCodeCount, len(codes), len(hashes))
+	}
+	// only hashes of recovery codes are stored
+	for i := range codes {
+		if hashes[i] != hashRecoveryCode(codes[i]) {
+			t.Errorf("hash of recovery code %d doesn't match", i)
+		}
+	}
+
+	repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(model.TOTP{Secret: secret, Enabled: true}, nil).Times(2)
+	if _, err = ctrl.EnrollTOTP(ctx, types.UserId(1)); err != ErrTOTPEnabled {
+		t.Errorf("expected ErrTOTPEnabled for enrolled user, got: %v", err)
+	}
+	if _, err = ctrl.ConfirmTOTP(ctx, types.UserId(1), code); err != ErrTOTPEnabled {
+		t.Errorf("expected ErrTOTPEnabled for enrolled user, got: %v", err)
+	}
+	repo.EXPECT().GetTOTP(ctx, types.UserId(2)).Return(model.TOTP{}, nil)
+	if _, err = ctrl.ConfirmTOTP(ctx, types.UserId(2), code); err != ErrTOTPNotEnrolled {
+		t.Errorf("expected ErrTOTPNotEnrolled without enrollment, got: %v", err)
+	}
+}
+
+func TestController_VerifyLogin(t *testing.T) {
+	ctx := context.Background()
+	secret, err := totputil.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := totputil.Step(time.Now())
+	code, err := totputil.Code(secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := jwt.GenerateChallengeToken(types.UserId(1), ChallengeTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := jwt.GenerateJWT(types.UserId(1), "session", types.RoleUser, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		challenge string
+		code      string
+		expect    func(repo *mock_controller.MockusersRepository)
+		err       error
+	}{
+		{
+			name:      "code from authenticator",
+			challenge: challenge,
+			code:      code,
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(model.TOTP{Secret: secret, Enabled: true}, nil)
+				repo.EXPECT().UseTOTPStep(ctx, types.UserId(1), step).Return(nil)
+				repo.EXPECT().GetDeactivatedAt(ctx, types.UserId(1)).Return(nil, nil)
+			},
+		},
+		{
+			name:      "replayed code",
+			challenge: challenge,
+			code:      code,
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(
+					model.TOTP{Secret: secret, Enabled: true, LastStep: step}, nil,
+				)
+			},
+			err: ErrInvalidCode,
+		},
+		{
+			name:      "malformed challenge",
+			challenge: "challenge",
+			code:      code,
+			expect:    func(*mock_controller.MockusersRepository) {},
+			err:       ErrInvalidChallenge,
+		},
+		{
+			name:      "session token is not a challenge",
+			challenge: session,
+			code:      code,
+			expect:    func(*mock_controller.MockusersRepository) {},
+			err:       ErrInvalidChallenge,
+		},
+		{
+			name:      "disabled after challenge",
+			challenge: challenge,
+			code:      code,
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(model.TOTP{}, nil)
+			},
+			err: ErrInvalidChallenge,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, repo := newTestController(t)
+			tc.expect(repo)
+			userId, err := ctrl.VerifyLogin(ctx, tc.challenge, tc.code)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got: %v", tc.err, err)
+			}
+			if err == nil && userId != 1 {
+				t.Errorf("wrong user %d", userId)
+			}
+		})
+	}
+}
+
+func TestController_RecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	ctrl, repo := newTestController(t)
+	challenge, err := jwt.GenerateChallengeToken(types.UserId(1), ChallengeTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	totp := model.TOTP{Secret: "JBSWY3DPEHPK3PXP", Enabled: true}
+
+	// code is accepted however it is typed and is deleted on use
+	repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(totp, nil).Times(6)
+	repo.EXPECT().UseRecoveryCode(ctx, types.UserId(1), hashRecoveryCode("abcde-fghij")).Return(nil)
+	repo.EXPECT().GetDeactivatedAt(ctx, types.UserId(1)).Return(nil, nil)
+	if _, err = ctrl.VerifyLogin(ctx, challenge, "ABCDE FGHIJ"); err != nil {
+		t.Fatalf("error was not expected while using recovery code: %s", err)
+	}
+	repo.EXPECT().UseRecoveryCode(ctx, types.UserId(1), hashRecoveryCode("abcde-fghij")).Return(errors.New("not found"))
+	if _, err = ctrl.VerifyLogin(ctx, challenge, "abcde-fghij"); err != ErrInvalidCode {
+		t.Errorf("expected ErrInvalidCode for used recovery code, got: %v", err)
+	}
+
+	// attempts of the user are limited
+	repo.EXPECT().UseRecoveryCode(ctx, types.UserId(1), gomock.Any()).Return(errors.New("not found")).Times(3)
+	for i := 0; i < 3; i++ {
+		if _, err = ctrl.VerifyLogin(ctx, challenge, "wrong-code"); err != ErrInvalidCode {
+			t.Errorf("expected ErrInvalidCode for unknown recovery code, got: %v", err)
+		}
+	}
+	if _, err = ctrl.VerifyLogin(ctx, challenge, "abcde-fghij"); err != ErrTooManyAttempts {
+		t.Errorf("expected ErrTooManyAttempts, got: %v", err)
+	}
+}
+
+func TestController_Refresh(t *testing.T) {
+	ctx := context.Background()
+	tokenHash := hashToken("token")
+	valid := model.RefreshToken{UserId: 1, Family: "family", ExpiresAt: time.Now().Add(time.Hour)}
+	used := valid
+	used.Used = true
+	expired := valid
+	expired.ExpiresAt = time.Now().Add(-time.Hour)
+	dbErr := errors.New("connection lost")
+
+	tests := []struct {
+		name   string
+		expect func(repo *mock_controller.MockusersRepository)
+		err    error
+	}{
+		{
+			name: "rotation",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetRefreshToken(ctx, tokenHash).Return(valid, nil)
+				repo.EXPECT().UseRefreshToken(ctx, tokenHash).Return(true, nil)
+				repo.EXPECT().PutRefreshToken(ctx, types.UserId(1), gomock.Not(tokenHash), "family", gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name: "reused token revokes the family",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetRefreshToken(ctx, tokenHash).Return(used, nil)
+				repo.EXPECT().DeleteRefreshFamily(ctx, "family").Return(nil)
+			},
+			err: ErrInvalidRefreshToken,
+		},
+		{
+			name: "token used concurrently revokes the family",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetRefreshToken(ctx, tokenHash).Return(valid, nil)
+				repo.EXPECT().UseRefreshToken(ctx, tokenHash).Return(false, nil)
+				repo.EXPECT().DeleteRefreshFamily(ctx, "family").Return(nil)
+			},
+			err: ErrInvalidRefreshToken,
+		},
+		{
+			name: "database error keeps the family",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetRefreshToken(ctx, tokenHash).Return(valid, nil)
+				repo.EXPECT().UseRefreshToken(ctx, tokenHash).Return(false, dbErr)
+			},
+			err: dbErr,
+		},
+		{
+			name: "expired token",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetRefreshToken(ctx, tokenHash).Return(expired, nil)
+			},
+			err: ErrInvalidRefreshToken,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, repo := newTestController(t)
+			tc.expect(repo)
+			userId, family, token, err := ctrl.Refresh(ctx, "token")
+			if err != tc.err {
+				t.Fatalf("expected error %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if userId != 1 || family != "family" || token == "" || token == "token" {
+				t.Errorf("wrong refresh result: %d %q %q", userId, family, token)
+			}
+			if _, ok := ctrl.lastSeen["family"]; !ok {
+				t.Error("refresh should be recorded as activity of the session")
+			}
+		})
+	}
+}
+
+func TestController_Sessions(t *testing.T) {
+	ctx := context.Background()
+	ctrl, repo := newTestController(t)
+	ctrl.SeenSession("first")
+	ctrl.SeenSession("second")
+
+	// activity of revoked session is not saved
+	repo.EXPECT().DeleteSession(ctx, types.UserId(1), "first").Return(nil)
+	if err := ctrl.RevokeSession(ctx, types.UserId(1), "first"); err != nil {
+		t.Fatalf("error was not expected while revoking session: %s", err)
+	}
+	repo.EXPECT().DeleteSession(ctx, types.UserId(1), "other").Return(errors.New("not found"))
+	if err := ctrl.RevokeSession(ctx, types.UserId(1), "other"); err == nil {
+		t.Error("session of another user should not be revoked")
+	}
+
+	// activity is kept for the next flush if saving fails
+	var saved []string
+	saveKeys := func(_ context.Context, lastSeen map[string]time.Time) error {
+		saved = saved[:0]
+		for sessionId := range lastSeen {
+			saved = append(saved, sessionId)
+		}
+		return nil
+	}
+	repo.EXPECT().SetLastSeen(ctx, gomock.Any()).Return(errors.New("connection lost"))
+	if err := ctrl.FlushLastSeen(ctx); err == nil {
+		t.Error("error of the repository should be returned")
+	}
+	repo.EXPECT().SetLastSeen(ctx, gomock.Any()).DoAndReturn(saveKeys)
+	if err := ctrl.FlushLastSeen(ctx); err != nil {
+		t.Fatalf("error was not expected while saving activity: %s", err)
+	}
+	if diff := cmp.Diff([]string{"second"}, saved); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	// nothing is written without new activity
+	if err := ctrl.FlushLastSeen(ctx); err != nil {
+		t.Errorf("error was not expected without activity: %s", err)
+	}
+}
+
+func TestController_Logout(t *testing.T) {
+	ctx := context.Background()
+	claims := &jwt.Claims{UserId: 1, SessionId: "session"}
+	claims.Id = "jti"
+	claims.ExpiresAt = time.Now().Add(time.Minute).Unix()
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		expect       func(repo *mock_controller.MockusersRepository)
+	}{
+		{
+			name: "without cookie",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().DeleteSession(ctx, types.UserId(1), "session").Return(nil)
+			},
+		},
+		{
+			name:         "cookie of the same session",
+			refreshToken: "token",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().DeleteSession(ctx, types.UserId(1), "session").Return(nil)
+				repo.EXPECT().GetRefreshToken(ctx, hashToken("token")).Return(
+					model.RefreshToken{UserId: 1, Family: "session"}, nil,
+				)
+			},
+		},
+		{
+			name:         "cookie of another login",
+			refreshToken: "token",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().DeleteSession(ctx, types.UserId(1), "session").Return(nil)
+				repo.EXPECT().GetRefreshToken(ctx, hashToken("token")).Return(
+					model.RefreshToken{UserId: 1, Family: "other"}, nil,
+				)
+				repo.EXPECT().DeleteRefreshFamily(ctx, "other").Return(nil)
+			},
+		},
+		{
+			name:         "cookie of another user",
+			refreshToken: "token",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().DeleteSession(ctx, types.UserId(1), "session").Return(nil)
+				repo.EXPECT().GetRefreshToken(ctx, hashToken("token")).Return(
+					model.RefreshToken{UserId: 2, Family: "other"}, nil,
+				)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, repo := newTestController(t)
+			tc.expect(repo)
+			ctrl.SeenSession("session")
+			if err := ctrl.Logout(ctx, claims, tc.refreshToken); err != nil {
+				t.Fatalf("error was not expected while logging out: %s", err)
+			}
+			if _, ok := ctrl.lastSeen["session"]; ok {
+				t.Error("activity of the session should be forgotten")
+			}
+		})
+	}
+}
+
+func TestController_CreateAccessToken(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		scopes    []types.Scope
+		expiresIn time.Duration
+		expect    func(repo *mock_controller.MockusersRepository)
+		err       error
+	}{
+		{
+			name:   "duplicate scopes are dropped",
+			scopes: []types.Scope{types.ScopeRead, types.ScopeTweetsWrite, types.ScopeRead},
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetEmail(ctx, types.UserId(1)).Return("user@example.com", true, nil)
+				repo.EXPECT().GetAccessTokens(ctx, types.UserId(1)).Return(nil, nil)
+				repo.EXPECT().CreateAccessToken(ctx, gomock.Any(), gomock.Any()).Return(3, nil)
+			},
+		},
+		{
+			name:   "unknown scope",
+			scopes: []types.Scope{types.ScopeRead, "admin"},
+			expect: func(*mock_controller.MockusersRepository) {},
+			err:    ErrInvalidAccessToken,
+		},
+		{
+			name:   "no scopes",
+			expect: func(*mock_controller.MockusersRepository) {},
+			err:    ErrInvalidAccessToken,
+		},
+		{
+			name:      "too long expiration",
+			scopes:    []types.Scope{types.ScopeRead},
+			expiresIn: maxAccessTokenTTL + time.Hour,
+			expect:    func(*mock_controller.MockusersRepository) {},
+			err:       ErrInvalidAccessToken,
+		},
+		{
+			name:   "unverified email",
+			scopes: []types.Scope{types.ScopeRead},
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetEmail(ctx, types.UserId(1)).Return("user@example.com", false, nil)
+			},
+			err: ErrEmailNotVerified,
+		},
+		{
+			name:   "too many tokens",
+			scopes: []types.Scope{types.ScopeRead},
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetEmail(ctx, types.UserId(1)).Return("user@example.com", true, nil)
+				repo.EXPECT().GetAccessTokens(ctx, types.UserId(1)).Return(make([]model.AccessToken, maxAccessTokens), nil)
+			},
+			err: ErrTooManyAccessTokens,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, repo := newTestController(t)
+			tc.expect(repo)
+			token, secret, err := ctrl.CreateAccessToken(ctx, types.UserId(1), " bot ", tc.scopes, tc.expiresIn)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if !auth.IsPersonalToken(secret) || token.TokenId != 3 || token.Name != "bot" {
+				t.Errorf("wrong token %+v with secret %q", token, secret)
+			}
+			if diff := cmp.Diff([]types.Scope{types.ScopeRead, types.ScopeTweetsWrite}, token.Scopes); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			if token.ExpiresAt.Sub(token.CreatedAt) != defaultAccessTokenTTL {
+				t.Errorf("token should expire after %s by default", defaultAccessTokenTTL)
+			}
+		})
+	}
+}
+
+func TestController_UpdateNickname(t *testing.T) {
+	ctx := context.Background()
+	version := 1
+	account := model.Account{UserId: 1, Nickname: "alex"}
+
+	tests := []struct {
+		name     string
+		nickname string
+		expect   func(repo *mock_controller.MockusersRepository)
+		err      error
+	}{
+		{
+			name:     "new nickname",
+			nickname: "bob",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetAccount(ctx, types.UserId(1)).Return(account, nil)
+				repo.EXPECT().CountNicknameChanges(ctx, types.UserId(1), gomock.Any()).Return(2, nil)
+				repo.EXPECT().GetNicknameOwner(ctx, names.Canonical("bob"), gomock.Any()).Return(types.UserId(0), nil)
+				repo.EXPECT().Update(ctx, types.UserId(1), gomock.Any()).Return(2, nil)
+				repo.EXPECT().GetById(ctx, types.UserId(1)).Return(model.Profile{UserId: 1, Nickname: "bob"}, nil)
+			},
+		},
+		{
+			name:     "change of case is not counted",
+			nickname: "Alex",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetAccount(ctx, types.UserId(1)).Return(account, nil)
+				repo.EXPECT().Update(ctx, types.UserId(1), gomock.Any()).Return(2, nil)
+				repo.EXPECT().GetById(ctx, types.UserId(1)).Return(model.Profile{UserId: 1, Nickname: "Alex"}, nil)
+			},
+		},
+		{
+			name:     "own old nickname",
+			nickname: "alex_old",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetAccount(ctx, types.UserId(1)).Return(account, nil)
+				repo.EXPECT().CountNicknameChanges(ctx, types.UserId(1), gomock.Any()).Return(0, nil)
+				repo.EXPECT().GetNicknameOwner(ctx, names.Canonical("alex_old"), gomock.Any()).Return(types.UserId(1), nil)
+				repo.EXPECT().Update(ctx, types.UserId(1), gomock.Any()).Return(2, nil)
+				repo.EXPECT().GetById(ctx, types.UserId(1)).Return(model.Profile{UserId: 1, Nickname: "alex_old"}, nil)
+			},
+		},
+		{
+			name:     "too many changes",
+			nickname: "bob",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetAccount(ctx, types.UserId(1)).Return(account, nil)
+				repo.EXPECT().CountNicknameChanges(ctx, types.UserId(1), gomock.Any()).Return(maxNicknameChanges, nil)
+			},
+			err: ErrTooManyNicknameChanges,
+		},
+		{
+			name:     "reserved by another user",
+			nickname: "bob",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().GetAccount(ctx, types.UserId(1)).Return(account, nil)
+				repo.EXPECT().CountNicknameChanges(ctx, types.UserId(1), gomock.Any()).Return(0, nil)
+				repo.EXPECT().GetNicknameOwner(ctx, names.Canonical("bob"), gomock.Any()).Return(types.UserId(2), nil)
+			},
+			err: ErrNicknameReserved,
+		},
+		{
+			name:     "look-alike of reserved name",
+			nickname: "Adm1n",
+			expect:   func(*mock_controller.MockusersRepository) {},
+			err:      ErrInvalidAccount,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, repo := newTestController(t)
+			tc.expect(repo)
+			nickname := tc.nickname
+			newVersion, err := ctrl.Update(ctx, types.UserId(1), model.UserUpdate{Nickname: &nickname, Version: &version})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got: %v", tc.err, err)
+			}
+			if err == nil && newVersion != 2 {
+				t.Errorf("wrong version %d", newVersion)
+			}
+		})
+	}
+}
+
+func TestController_LoginFailures(t *testing.T) {
+	ctx := context.Background()
+	password := encodePassword("password")
+	// the fastest of a few password checks
+	var check time.Duration
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		checkPassword("password", dummyPassword)
+		if elapsed := time.Since(start); i == 0 || elapsed < check {
+			check = elapsed
+		}
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		locked   bool
+		expect   func(repo *mock_controller.MockusersRepository)
+	}{
+		{
+			name:     "unknown email",
+			email:    "unknown@example.com",
+			password: "password",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().RetrievePassword(ctx, "unknown@example.com").Return(types.UserId(0), "", nil)
+			},
+		},
+		{
+			name:     "wrong password",
+			email:    "user@example.com",
+			password: "wrong password",
+			expect: func(repo *mock_controller.MockusersRepository) {
+				repo.EXPECT().RetrievePassword(ctx, "user@example.com").Return(types.UserId(1), password, nil)
+			},
+		},
+		{
+			name:     "locked email",
+			email:    "user@example.com",
+			password: "password",
+			locked:   true,
+			expect:   func(*mock_controller.MockusersRepository) {},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl, repo := newTestController(t)
+			tc.expect(repo)
+			if tc.locked {
+				emailKey, _ := lockKeys(tc.email, "")
+				for ctrl.emailBackoff.Fail(emailKey) == 0 {
+				}
+			}
+
+			start := time.Now()
+			_, _, err := ctrl.Login(ctx, tc.email, tc.password, "10.0.0.1")
+			elapsed := time.Since(start)
+			if err != ErrInvalidCredentials {
+				t.Fatalf("expected ErrInvalidCredentials, got: %v", err)
+			}
+			// every failure checks a password, so none of them is noticeably faster
+			if elapsed < check/2 {
+				t.Errorf("login took %s, password check takes %s", elapsed, check)
+			}
+		})
+	}
+}
+
+func TestController_LoginIPLimit(t *testing.T) {
+	ctx := context.Background()
+	ctrl, repo := newTestController(t)
+	ctrl.ipBackoff = ratelimit.NewBackoff(2, time.Minute, time.Hour)
+
+	// client tries a different email every time, so only its ip is locked
+	emails := []string{"a@example.com", "b@example.com", "c@example.com"}
+	for _, email := range emails {
+		repo.EXPECT().RetrievePassword(ctx, email).Return(types.UserId(0), "", nil)
+		if _, _, err := ctrl.Login(ctx, email, "password", "10.0.0.1"); err != ErrInvalidCredentials {
+			t.Fatalf("expected ErrInvalidCredentials, got: %v", err)
+		}
+	}
+	lockouts := ctrl.Lockouts()
+	if len(lockouts) != 1 || lockouts[0].Key != ipLockPrefix+"10.0.0.1" {
+		t.Fatalf("only ip should be locked, got %+v", lockouts)
+	}
+
+	// correct password doesn't pass from the locked ip, repository isn't queried
+	if _, _, err := ctrl.Login(ctx, "user@example.com", "password", "10.0.0.1"); err != ErrInvalidCredentials {
+		t.Errorf("expected ErrInvalidCredentials from locked ip, got: %v", err)
+	}
+
+	// the same user logs in from another ip
+	repo.EXPECT().RetrievePassword(ctx, "user@example.com").Return(types.UserId(1), encodePassword("password"), nil)
+	repo.EXPECT().GetDeactivatedAt(ctx, types.UserId(1)).Return(nil, nil)
+	repo.EXPECT().IsSuspended(ctx, types.UserId(1)).Return(false, nil)
+	repo.EXPECT().GetTOTP(ctx, types.UserId(1)).Return(model.TOTP{}, nil)
+	userId, challenge, err := ctrl.Login(ctx, "user@example.com", "password", "10.0.0.2")
+	if err != nil || userId != 1 || challenge != "" {
+		t.Errorf("login from another ip should succeed, got %d %q %v", userId, challenge, err)
+	}
+}
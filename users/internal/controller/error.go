@@ -0,0 +1,81 @@
+package controller
+
+import "errors"
+
+// ErrInvalidLanguage is returned when language code is malformed.
+var ErrInvalidLanguage = errors.New("invalid language code")
+
+// ErrInvalidSensitiveMedia is returned when sensitive media preference is unknown.
+var ErrInvalidSensitiveMedia = errors.New("sensitive media preference should be show, blur or hide")
+
+// ErrInvalidProfile is returned when profile fields are too long or malformed.
+var ErrInvalidProfile = errors.New("invalid profile")
+
+// ErrInvalidAccount is returned when account fields are too long or malformed.
+var ErrInvalidAccount = errors.New("invalid account")
+
+// ErrEmailNotVerified is returned when unverified account uses restricted feature.
+var ErrEmailNotVerified = errors.New("email is not verified")
+
+// ErrInvalidVerificationToken is returned when verification link is malformed, expired or outdated.
+var ErrInvalidVerificationToken = errors.New("invalid or expired verification link")
+
+// ErrInvalidResetToken is returned when password reset token is unknown, used or expired.
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// ErrPasswordTooShort is returned when new password is too short.
+var ErrPasswordTooShort = errors.New("password should be at least 8 characters")
+
+// ErrInvalidRefreshToken is returned when refresh token is expired or reused.
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// ErrTOTPEnabled is returned when two-factor authentication is enrolled again.
+var ErrTOTPEnabled = errors.New("two-factor authentication is already enabled")
+
+// ErrTOTPNotEnrolled is returned when two-factor authentication is confirmed before enrollment.
+var ErrTOTPNotEnrolled = errors.New("two-factor authentication is not enrolled")
+
+// ErrTOTPNotEnabled is returned when two-factor authentication is changed while it is disabled.
+var ErrTOTPNotEnabled = errors.New("two-factor authentication is not enabled")
+
+// ErrInvalidCode is returned when authentication code is wrong, used or outdated.
+var ErrInvalidCode = errors.New("invalid authentication code")
+
+// ErrInvalidChallenge is returned when login challenge is malformed or expired.
+var ErrInvalidChallenge = errors.New("invalid or expired login challenge")
+
+// ErrInvalidCredentials is returned when password doesn't match the email.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// ErrTooManyAttempts is returned when user enters too many codes.
+var ErrTooManyAttempts = errors.New("too many attempts, try again later")
+
+// ErrExportInProgress is returned when data export is requested while previous one is being prepared.
+var ErrExportInProgress = errors.New("data export is already in progress")
+
+// ErrTooManyExports is returned when user requests data export too often.
+var ErrTooManyExports = errors.New("too many data exports, try again later")
+
+// ErrExportExpired is returned when download link of data export has expired.
+var ErrExportExpired = errors.New("data export has expired, request a new one")
+
+// ErrAccountSuspended is returned when suspended user logs in.
+var ErrAccountSuspended = errors.New("account is suspended")
+
+// ErrInvalidRole is returned when role is unknown.
+var ErrInvalidRole = errors.New("role should be user, moderator or admin")
+
+// ErrInsufficientRole is returned when moderator acts on own account or on account with the same or higher role.
+var ErrInsufficientRole = errors.New("not allowed to moderate this account")
+
+// ErrInvalidAccessToken is returned when personal access token has no name, unknown scopes or too long expiration.
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// ErrTooManyAccessTokens is returned when user has too many personal access tokens.
+var ErrTooManyAccessTokens = errors.New("too many access tokens, revoke unused ones")
+
+// ErrNicknameReserved is returned when nickname was recently changed by another user.
+var ErrNicknameReserved = errors.New("nickname was recently used by another account")
+
+// ErrTooManyNicknameChanges is returned when user changes nickname too often.
+var ErrTooManyNicknameChanges = errors.New("too many nickname changes, try again later")
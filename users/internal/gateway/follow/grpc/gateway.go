@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/follow"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// get number of followers of the users from follow service
+func (g *Gateway) GetFollowerCounts(ctx context.Context, userIds ...types.UserId) (map[types.UserId]int, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	request := &gen.GetFollowerCountsRequest{}
+	for _, id := range userIds {
+		request.UserId = append(request.UserId, int32(id))
+	}
+	client := gen.NewFollowServiceClient(conn)
+	response, err := client.GetFollowerCounts(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[types.UserId]int)
+	for _, count := range response.Counts {
+		counts[types.UserId(count.GetUserId())] = int(count.GetCount())
+	}
+	return counts, nil
+}
+
+// get users that follow the user from follow service
+func (g *Gateway) GetFollowers(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	return g.getUsers(ctx, userId, true)
+}
+
+// get users followed by the user from follow service
+func (g *Gateway) GetFollowing(ctx context.Context, userId types.UserId) ([]types.UserId, error) {
+	return g.getUsers(ctx, userId, false)
+}
+
+// get followers or followed users, user without relations has empty list
+func (g *Gateway) getUsers(ctx context.Context, userId types.UserId, followers bool) ([]types.UserId, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var (
+		client   = gen.NewFollowServiceClient(conn)
+		request  = &gen.UserId{UserId: int32(userId)}
+		response *gen.GetResponse
+	)
+	// GetFollowingUser outputs followers of the user, GetUserFollowers outputs followed users
+	if followers {
+		response, err = client.GetFollowingUser(ctx, request)
+	} else {
+		response, err = client.GetUserFollowers(ctx, request)
+	}
+	if status.Code(err) == codes.NotFound {
+		return []types.UserId{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]types.UserId, 0, len(response.UserId))
+	for _, protoUser := range response.UserId {
+		users = append(users, types.UserId(protoUser.GetUserId()))
+	}
+	return users, nil
+}
@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// get tweets liked by the user from likes service, user without likes has empty list
+func (g *Gateway) GetLikedTweets(ctx context.Context, userId types.UserId) ([]types.TweetId, error) {
+	base, err := url.Parse(g.Url)
+	if err != nil {
+		return nil, err
+	}
+	base.Path = path.Join(base.Path, "/tweets_user")
+	values := base.Query()
+	values.Set("user_id", strconv.Itoa(int(userId)))
+	base.RawQuery = values.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	auth.ForwardToken(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return []types.TweetId{}, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("non-2xx response: %v", resp)
+	}
+	var tweets []types.TweetId
+	if err := json.NewDecoder(resp.Body).Decode(&tweets); err != nil {
+		return nil, err
+	}
+	return tweets, nil
+}
@@ -0,0 +1,45 @@
+package grpc
+
+import (
+	"context"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/tweets"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/alexvishnevskiy/twitter-clone/tweets/pkg/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Gateway struct {
+	Url string
+}
+
+func New(url string) *Gateway {
+	return &Gateway{url}
+}
+
+// get tweets of the user with media from tweets service, user without tweets has empty list
+func (g *Gateway) GetTweets(ctx context.Context, userId types.UserId) ([]model.Media, error) {
+	// Set up the connection to the gRPC server, token of the user is forwarded
+	conn, err := grpc.Dial(g.Url, append(auth.DialOptions(), grpc.WithInsecure())...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	client := gen.NewTweetsServiceClient(conn)
+	response, err := client.Retrieve(ctx, &gen.RetrieveRequest{UserId: []int32{int32(userId)}})
+	if status.Code(err) == codes.NotFound {
+		return []model.Media{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tweets := make([]model.Media, 0, len(response.MediaContent))
+	for _, media := range response.MediaContent {
+		tweets = append(tweets, *model.MediaFromProto(media))
+	}
+	return tweets, nil
+}
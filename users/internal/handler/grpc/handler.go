@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/users"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/alexvishnevskiy/twitter-clone/users/internal/controller"
+	"github.com/alexvishnevskiy/twitter-clone/users/internal/repository/mysql"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Handler struct {
+	gen.UnimplementedUsersServiceServer
+	ctrl *controller.Controller
+}
+
+func New(ctrl *controller.Controller) *Handler {
+	return &Handler{ctrl: ctrl}
+}
+
+// GetUser retrieve profile either by user_id or nickname
+func (h *Handler) GetUser(ctx context.Context, req *gen.GetUserRequest) (*gen.Profile, error) {
+	if req == nil || (req.UserId == 0 && req.Nickname == "") {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+
+	var (
+		profile model.Profile
+		err     error
+	)
+	if req.Nickname != "" {
+		profile, err = h.ctrl.GetUserByNickname(ctx, req.Nickname)
+	} else {
+		profile, err = h.ctrl.GetUser(ctx, types.UserId(req.UserId))
+	}
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return model.ProfileToProto(&profile), nil
+}
+
+// GetUsers retrieve profiles of the users, missing users are skipped
+func (h *Handler) GetUsers(ctx context.Context, req *gen.GetUsersRequest) (*gen.GetUsersResponse, error) {
+	if req == nil || len(req.UserId) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty id")
+	}
+
+	userIds := make([]types.UserId, len(req.UserId))
+	for i, id := range req.UserId {
+		userIds[i] = types.UserId(id)
+	}
+	profiles, err := h.ctrl.GetUsers(ctx, userIds...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	return profilesToProto(profiles), nil
+}
+
+// SearchUsers retrieve profiles by prefix of nickname or name, popular users go first
+func (h *Handler) SearchUsers(ctx context.Context, req *gen.SearchUsersRequest) (*gen.GetUsersResponse, error) {
+	if req == nil || req.Prefix == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "nil req or empty prefix")
+	}
+
+	profiles, err := h.ctrl.SearchUsers(ctx, req.Prefix, int(req.Limit))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return profilesToProto(profiles), nil
+}
+
+// GetPreferences retrieve timeline settings of the user from forwarded token
+func (h *Handler) GetPreferences(ctx context.Context, req *gen.GetPreferencesRequest) (*gen.Preferences, error) {
+	userId, ok := auth.UserId(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "token of the user is required")
+	}
+
+	langs, err := h.ctrl.GetPreferredLanguages(ctx, userId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	preference, err := h.ctrl.GetSensitiveMedia(ctx, userId)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+	return &gen.Preferences{Languages: langs, SensitiveMedia: string(preference)}, nil
+}
+
+func profilesToProto(profiles []model.Profile) *gen.GetUsersResponse {
+	var protoUsers []*gen.Profile
+	for i := range profiles {
+		protoUsers = append(protoUsers, model.ProfileToProto(&profiles[i]))
+	}
+	return &gen.GetUsersResponse{
+		Users: protoUsers,
+	}
+}
@@ -3,14 +3,23 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/auth"
+	"github.com/alexvishnevskiy/twitter-clone/internal/imaging"
 	"github.com/alexvishnevskiy/twitter-clone/internal/jwt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/ratelimit"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/users/internal/controller"
+	"github.com/alexvishnevskiy/twitter-clone/users/internal/repository/mysql"
 	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"io"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Handler struct {
@@ -29,45 +38,115 @@ func New(ctrl *controller.Controller) *Handler {
 	return &Handler{ctrl}
 }
 
-// handler to generate jwt token
-func JwtHandler(h handlerMethod) func(http.ResponseWriter, *http.Request) {
+// name of cookie with refresh token
+const refreshCookieName = "refresh_token"
+
+// response with access token for clients that use Authorization header
+type tokenResponse struct {
+	UserId      types.UserId `json:"user_id"`
+	AccessToken string       `json:"access_token"`
+	ExpiresIn   int          `json:"expires_in"`
+}
+
+// set tokens as HttpOnly cookies so scripts can't read them
+func setTokenCookies(w http.ResponseWriter, accessToken string, refreshToken string) {
+	http.SetCookie(
+		w, &http.Cookie{
+			Name:     jwt.CookieName,
+			Value:    accessToken,
+			Path:     "/",
+			MaxAge:   int(jwt.AccessTokenTTL.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		},
+	)
+	http.SetCookie(
+		w, &http.Cookie{
+			Name:     refreshCookieName,
+			Value:    refreshToken,
+			Path:     "/",
+			MaxAge:   int(controller.RefreshTokenTTL.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		},
+	)
+}
+
+// remove token cookies from the browser
+func clearTokenCookies(w http.ResponseWriter) {
+	for _, name := range []string{jwt.CookieName, refreshCookieName} {
+		http.SetCookie(
+			w, &http.Cookie{Name: name, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteStrictMode},
+		)
+	}
+}
+
+// issue access token of the session with current role and email status of the user
+// and write both tokens to the response
+func (h *Handler) writeTokens(
+	w http.ResponseWriter,
+	req *http.Request,
+	userId types.UserId,
+	sessionId string,
+	refreshToken string,
+) {
+	role, err := h.ctrl.GetRole(req.Context(), userId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get role: %s", err), http.StatusInternalServerError)
+		return
+	}
+	verified, err := h.ctrl.EmailVerified(req.Context(), userId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get email status: %s", err), http.StatusInternalServerError)
+		return
+	}
+	token, err := jwt.GenerateJWT(userId, sessionId, role, verified)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to generate jwt: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	setTokenCookies(w, token, refreshToken)
+	response := tokenResponse{UserId: userId, AccessToken: token, ExpiresIn: int(jwt.AccessTokenTTL.Seconds())}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "failed to encode token", http.StatusInternalServerError)
+	}
+}
+
+// handler to generate access and refresh tokens
+func (h *Handler) JwtHandler(next handlerMethod) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
-		h(w, req)
+		next(w, req)
 
 		ctx := req.Context()
 		userId, ok := ctx.Value(idCtxKey).(types.UserId)
 		if !ok {
-			http.Error(w, "failed to get id", http.StatusInternalServerError)
+			// session was not started, response is already written
 			return
 		}
 
-		token, err := jwt.GenerateJWT(userId)
+		sessionId, refreshToken, err := h.ctrl.CreateSession(ctx, userId, req.UserAgent(), ratelimit.ClientIP(req))
 		if err != nil {
-			http.Error(w, fmt.Sprintf("failed to generate jwt: %s", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("failed to generate refresh token: %s", err), http.StatusInternalServerError)
 			return
 		}
-
-		http.SetCookie(
-			w, &http.Cookie{
-				Name:  "token",
-				Value: token,
-			},
-		)
+		h.writeTokens(w, req, userId, sessionId, refreshToken)
 	}
 }
 
 // Register handle register method
 //
-//	@description	Register new user
+//	@description	Register new user, reserved nicknames and nicknames with blocked words are rejected
 //	@Param			password	body		string	true	"Password"
 //	@Param			email		body		string	true	"Email"
-//	@Param			nickname	body		string	true	"Nickname"
+//	@Param			nickname	body		string	true	"Nickname, letters, digits and underscores, unique regardless of case and look-alike characters"
 //	@Param			first_name	body		string	false	"First name"
 //	@Param			last_name	body		string	false	"Last name"
 //	@Success		200			{object}	int
 //	@Failure		400			{object}	int
 //	@Failure		404			{object}	int
 //	@Failure		405			{object}	int
+//	@Failure		409			{object}	int
 //	@Failure		500			{object}	int
 //	@Router			/register       [post]
 func (h *Handler) Register(w http.ResponseWriter, req *http.Request) {
@@ -99,8 +178,19 @@ func (h *Handler) Register(w http.ResponseWriter, req *http.Request) {
 
 	// register user
 	id, err := h.ctrl.Register(req.Context(), requestData)
-	if err != nil {
+	switch {
+	case errors.Is(err, controller.ErrInvalidAccount) || errors.Is(err, controller.ErrPasswordTooShort):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, controller.ErrNicknameReserved):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, mysql.ErrDuplicate):
+		http.Error(w, "nickname or email is already taken", http.StatusConflict)
+		return
+	case err != nil:
 		http.Error(w, fmt.Sprintf("failed to register user: %s", err), http.StatusInternalServerError)
+		return
 	}
 
 	// Set id in request context.
@@ -108,14 +198,21 @@ func (h *Handler) Register(w http.ResponseWriter, req *http.Request) {
 	*req = *req.WithContext(ctx)
 }
 
+// response with challenge token when login requires second factor
+type challengeResponse struct {
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int    `json:"expires_in"`
+}
+
 // Login handle login method
 //
-//	@description	Login for user
+//	@description	Login for user, challenge token is returned instead of session if two-factor authentication is enabled
 //	@Param			password	body		string	true	"Password"
 //	@Param			email		body		string	true	"Email"
-//	@Success		200			{object}	int
+//	@Success		200			{object}	tokenResponse
+//	@Success		202			{object}	challengeResponse
 //	@Failure		400			{object}	int
-//	@Failure		404			{object}	int
+//	@Failure		403			{object}	int
 //	@Failure		405			{object}	int
 //	@Failure		500			{object}	int
 //	@Router			/login       [post]
@@ -143,9 +240,29 @@ func (h *Handler) Login(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	userId, err := h.ctrl.Login(req.Context(), requestData.Email, requestData.Password)
+	userId, challenge, err := h.ctrl.Login(
+		req.Context(), requestData.Email, requestData.Password, ratelimit.ClientIP(req),
+	)
+	// response doesn't reveal whether email is registered or locked
+	if err != nil && errors.Is(err, controller.ErrInvalidCredentials) {
+		http.Error(w, "Invalid email or password", http.StatusForbidden)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrAccountSuspended) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Invalid email or password: %s", err), http.StatusForbidden)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// session is started by /login/2fa
+	if challenge != "" {
+		w.WriteHeader(http.StatusAccepted)
+		response := challengeResponse{ChallengeToken: challenge, ExpiresIn: int(controller.ChallengeTTL.Seconds())}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "failed to encode challenge", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -154,12 +271,19 @@ func (h *Handler) Login(w http.ResponseWriter, req *http.Request) {
 	*req = *req.WithContext(ctx)
 }
 
+// response of deactivation
+type deactivationResponse struct {
+	// account is deleted with all content if user does not log in before this moment
+	PurgeAt time.Time `json:"purge_at"`
+}
+
 // Delete handle delete method
 //
-//	@description	Delete user
-//	@Param			user_id	query		string	true	"User id"
-//	@Success		200		{object}	int
-//	@Failure		400		{object}	int
+//	@description	Deactivate user, profile and tweets are hidden at once and every session is logged out.
+//	@description	Login within 30 days restores the account, after that it is deleted with all content
+//	@Security		BearerAuth
+//	@Success		200		{object}	deactivationResponse
+//	@Failure		401		{object}	int
 //	@Failure		404		{object}	int
 //	@Failure		405		{object}	int
 //	@Failure		500		{object}	int
@@ -170,57 +294,1646 @@ func (h *Handler) Delete(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	userId, err := strconv.Atoi(req.FormValue("user_id"))
-	if err != nil {
-		http.Error(w, fmt.Sprintf("invalid user_id :%s", err), http.StatusBadRequest)
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
 		return
 	}
 
-	err = h.ctrl.Delete(req.Context(), types.UserId(userId))
+	err := h.ctrl.Deactivate(req.Context(), userId)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "user is not found or already deactivated", http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to delete: %s", err), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("failed to deactivate: %s", err), http.StatusInternalServerError)
+		return
 	}
+	clearTokenCookies(w)
+	response := deactivationResponse{PurgeAt: time.Now().Add(controller.DeactivationPeriod).UTC()}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// response with version of the account after update
+type versionResponse struct {
+	Version int `json:"version"`
 }
 
 // Update handle update method
 //
-//	@description	Update user data
-//	@Param			user_id		body		int		true	"User id"
-//	@Param			nickname	body		string	false	"Nickname"
+//	@description	Update fields of the account that are present, version from /account is required.
+//	@description	Update is rejected with 409 if the account was changed since that version.
+//	@description	Old nickname is reserved for 30 days, nickname can be changed 3 times in 30 days
+//	@Security		BearerAuth
+//	@Param			nickname	body		string	false	"Nickname, letters, digits and underscores, unique regardless of case and look-alike characters"
 //	@Param			first_name	body		string	false	"First name"
 //	@Param			last_name	body		string	false	"Last name"
 //	@Param			email		body		string	false	"Email"
 //	@Param			password	body		string	false	"Password"
-//	@Success		200			{object}	int
+//	@Param			version		body		int		true	"Version of the account"
+//	@Success		200			{object}	versionResponse
 //	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
 //	@Failure		404			{object}	int
 //	@Failure		405			{object}	int
+//	@Failure		409			{object}	int
+//	@Failure		429			{object}	int
 //	@Failure		500			{object}	int
+//	@Router			/update       [patch]
 //	@Router			/update       [put]
 func (h *Handler) Update(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPatch && req.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	var requestData model.UserUpdate
+
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// user can update only own account
+	version, err := h.ctrl.Update(req.Context(), userId, requestData)
+	switch {
+	case errors.Is(err, controller.ErrInvalidAccount) || errors.Is(err, controller.ErrPasswordTooShort):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, mysql.ErrNotFound):
+		http.Error(w, "user is not found", http.StatusNotFound)
+		return
+	case errors.Is(err, mysql.ErrVersionConflict):
+		http.Error(w, "account was changed, get the new version from /account and retry", http.StatusConflict)
+		return
+	case errors.Is(err, mysql.ErrDuplicate):
+		http.Error(w, "nickname or email is already taken", http.StatusConflict)
+		return
+	case errors.Is(err, controller.ErrNicknameReserved):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, controller.ErrTooManyNicknameChanges):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = json.NewEncoder(w).Encode(versionResponse{Version: version}); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// GetAccount handle account retrieval
+//
+//	@description	Retrieve private account of the authenticated user with its version for /update
+//	@Security		BearerAuth
+//	@Success		200		{object}	model.Account
+//	@Failure		401		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/account       [get]
+func (h *Handler) GetAccount(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	account, err := h.ctrl.GetAccount(req.Context(), userId)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "user is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = json.NewEncoder(w).Encode(account); err != nil {
+		http.Error(w, "failed to encode account", http.StatusInternalServerError)
+	}
+}
+
+// GetPreferredLanguages handle preferred languages retrieval
+//
+//	@description	Retrieve languages authenticated user wants to see in the timeline
+//	@Security		BearerAuth
+//	@Success		200	{object}	[]string
+//	@Failure		401	{object}	int
+//	@Failure		405	{object}	int
+//	@Failure		500	{object}	int
+//	@Router			/preferred_languages       [get]
+func (h *Handler) GetPreferredLanguages(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	langs, err := h.ctrl.GetPreferredLanguages(req.Context(), userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(langs); err != nil {
+		http.Error(w, "failed to encode languages", http.StatusInternalServerError)
+	}
+}
+
+// UpdatePreferredLanguages handle preferred languages update
+//
+//	@description	Replace languages user wants to see in the timeline
+//	@Security		BearerAuth
+//	@Param			languages	body		[]string	true	"ISO 639-1 language codes"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/update_preferred_languages       [put]
+func (h *Handler) UpdatePreferredLanguages(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPut {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var requestData model.User
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
 
+	var requestData struct {
+		Languages []string `json:"languages"`
+	}
 	bodyBytes, err := ioutil.ReadAll(req.Body)
 	defer req.Body.Close()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.ctrl.SetPreferredLanguages(req.Context(), userId, requestData.Languages)
+	if err != nil && errors.Is(err, controller.ErrInvalidLanguage) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetSensitiveMedia handle sensitive media preference retrieval
+//
+//	@description	Retrieve how sensitive media is shown in the timeline of authenticated user
+//	@Security		BearerAuth
+//	@Success		200	{object}	string
+//	@Failure		401	{object}	int
+//	@Failure		404	{object}	int
+//	@Failure		405	{object}	int
+//	@Failure		500	{object}	int
+//	@Router			/sensitive_media       [get]
+func (h *Handler) GetSensitiveMedia(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	preference, err := h.ctrl.GetSensitiveMedia(req.Context(), userId)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "failed to find user by this user_id", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(preference); err != nil {
+		http.Error(w, "failed to encode preference", http.StatusInternalServerError)
+	}
+}
+
+// UpdateSensitiveMedia handle sensitive media preference update
+//
+//	@description	Set how sensitive media is shown in the timeline
+//	@Security		BearerAuth
+//	@Param			sensitive_media	body		string	true	"show, blur or hide"
+//	@Success		200				{object}	int
+//	@Failure		400				{object}	int
+//	@Failure		401				{object}	int
+//	@Failure		405				{object}	int
+//	@Failure		500				{object}	int
+//	@Router			/update_sensitive_media       [put]
+func (h *Handler) UpdateSensitiveMedia(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
 
+	var requestData struct {
+		SensitiveMedia model.SensitiveMedia `json:"sensitive_media"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	err = json.Unmarshal(bodyBytes, &requestData)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	err = h.ctrl.Update(req.Context(), requestData)
+	err = h.ctrl.SetSensitiveMedia(req.Context(), userId, requestData.SensitiveMedia)
+	if err != nil && errors.Is(err, controller.ErrInvalidSensitiveMedia) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// UpdateProtected handle protected account update
+//
+//	@description	Protect tweets of the user, new followers have to be approved
+//	@Security		BearerAuth
+//	@Param			protected	body		bool	true	"Whether tweets are visible only to approved followers"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/update_protected       [put]
+func (h *Handler) UpdateProtected(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	var requestData struct {
+		Protected *bool `json:"protected"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil || requestData.Protected == nil {
+		http.Error(w, "protected should be present", http.StatusBadRequest)
+		return
+	}
+
+	if err = h.ctrl.SetProtected(req.Context(), userId, *requestData.Protected); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// GetUser handle profile retrieval
+//
+//	@description	Retrieve public profile of the user either by user_id or nickname.
+//	@description	Recently changed nickname resolves to the current profile with redirected_from set
+//	@Param			user_id		query		int		false	"User id"
+//	@Param			nickname	query		string	false	"Nickname"
+//	@Success		200			{object}	model.Profile
+//	@Failure		400			{object}	int
+//	@Failure		404			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/user       [get]
+func (h *Handler) GetUser(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		profile model.Profile
+		err     error
+	)
+	if nickname := req.FormValue("nickname"); nickname != "" {
+		profile, err = h.ctrl.GetUserByNickname(req.Context(), nickname)
+	} else {
+		userId, convErr := strconv.Atoi(req.FormValue("user_id"))
+		if convErr != nil {
+			http.Error(w, fmt.Sprintf("invalid user_id :%s", convErr), http.StatusBadRequest)
+			return
+		}
+		profile, err = h.ctrl.GetUser(req.Context(), types.UserId(userId))
+	}
+
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "failed to find user", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := json.NewEncoder(w).Encode(profile); err != nil {
+		http.Error(w, "failed to encode user", http.StatusInternalServerError)
+	}
+}
+
+// GetUsers handle batch profile retrieval
+//
+//	@description	Retrieve public profiles of the users, missing users are skipped
+//	@Param			user_id	query		[]int	true	"User ids"
+//	@Success		200		{object}	[]model.Profile
+//	@Failure		400		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/users       [get]
+func (h *Handler) GetUsers(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := req.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var userIds []types.UserId
+	for _, value := range req.Form["user_id"] {
+		userId, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid user_id :%s", err), http.StatusBadRequest)
+			return
+		}
+		userIds = append(userIds, types.UserId(userId))
+	}
+	if len(userIds) == 0 {
+		http.Error(w, "user_id is empty", http.StatusBadRequest)
+		return
+	}
+
+	profiles, err := h.ctrl.GetUsers(req.Context(), userIds...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(profiles); err != nil {
+		http.Error(w, "failed to encode users", http.StatusInternalServerError)
+	}
+}
+
+// SearchUsers handle nickname typeahead
+//
+//	@description	Retrieve users whose nickname or name starts with prefix, case-insensitively. Users with more followers go first
+//	@Security		BearerAuth
+//	@Param			prefix	query		string	true	"Prefix of nickname, first name, last name or full name"
+//	@Param			limit	query		int		false	"Maximum number of users, 10 by default and 50 at most"
+//	@Success		200		{object}	[]model.Profile
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/users/search       [get]
+func (h *Handler) SearchUsers(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefix := req.FormValue("prefix")
+	if strings.TrimSpace(prefix) == "" {
+		http.Error(w, "prefix is empty", http.StatusBadRequest)
+		return
+	}
+	var limit int
+	if value := req.FormValue("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit :%s", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	profiles, err := h.ctrl.SearchUsers(req.Context(), prefix, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(profiles); err != nil {
+		http.Error(w, "failed to encode users", http.StatusInternalServerError)
+	}
+}
+
+// UpdateProfile handle profile update
+//
+//	@description	Update bio, location, website and birthday, omitted fields are left unchanged
+//	@Security		BearerAuth
+//	@Param			bio			body		string	false	"Bio"
+//	@Param			location	body		string	false	"Location"
+//	@Param			website		body		string	false	"Website"
+//	@Param			birthday	body		string	false	"Birthday in YYYY-MM-DD format, empty removes it"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		403			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/update_profile       [put]
+func (h *Handler) UpdateProfile(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	var requestData model.ProfileUpdate
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.ctrl.UpdateProfile(req.Context(), userId, requestData)
+	if err != nil && errors.Is(err, controller.ErrInvalidProfile) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrEmailNotVerified) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// helper function to upload profile image of given kind
+func (h *Handler) updateImage(w http.ResponseWriter, req *http.Request, kind model.ImageKind) {
+	if req.Method != http.MethodPut {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	// 10 << 20 is the maximum size of image kept in memory
+	if err := req.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, _, err := req.FormFile("image")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read image: %s", err), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	url, err := h.ctrl.UpdateImage(req.Context(), userId, kind, file)
+	if err != nil && errors.Is(err, imaging.ErrInvalidImage) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrEmailNotVerified) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "failed to find user by this user_id", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(url); err != nil {
+		http.Error(w, "failed to encode url", http.StatusInternalServerError)
+	}
+}
+
+// UpdateAvatar handle avatar upload
+//
+//	@description	Upload or replace avatar, image is cropped to 400x400
+//	@Security		BearerAuth
+//	@Param			image	formData	file	true	"Image"
+//	@Success		200		{object}	string
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		403		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/update_avatar       [put]
+func (h *Handler) UpdateAvatar(w http.ResponseWriter, req *http.Request) {
+	h.updateImage(w, req, model.ImageAvatar)
+}
+
+// UpdateHeader handle header image upload
+//
+//	@description	Upload or replace header image, image is cropped to 1500x500
+//	@Security		BearerAuth
+//	@Param			image	formData	file	true	"Image"
+//	@Success		200		{object}	string
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		403		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/update_header       [put]
+func (h *Handler) UpdateHeader(w http.ResponseWriter, req *http.Request) {
+	h.updateImage(w, req, model.ImageHeader)
+}
+
+// VerifyEmail handle verification link
+//
+//	@description	Confirm email with the token sent after registration, access tokens issued after it can create content
+//	@Param			token	query		string	true	"Verification token"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/verify_email       [get]
+func (h *Handler) VerifyEmail(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := req.FormValue("token")
+	if token == "" {
+		http.Error(w, "token is empty", http.StatusBadRequest)
+		return
+	}
+
+	err := h.ctrl.VerifyEmail(req.Context(), token)
+	if err != nil && errors.Is(err, controller.ErrInvalidVerificationToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "failed to find user", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// ResendVerification handle verification email resend
+//
+//	@description	Send verification link again, does nothing if email is already verified
+//	@Security		BearerAuth
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/resend_verification       [post]
+func (h *Handler) ResendVerification(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	err := h.ctrl.ResendVerification(req.Context(), userId)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "failed to find user by this user_id", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// SessionMiddleware remembers activity of the session shown in the list of sessions,
+// it should be wrapped by auth.Middleware which rejects tokens of revoked sessions
+func (h *Handler) SessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if principal, ok := auth.FromContext(req.Context()); ok && principal.Claims != nil {
+				h.ctrl.SeenSession(principal.Claims.SessionId)
+			}
+			next.ServeHTTP(w, req)
+		},
+	)
+}
+
+// ForgotPassword handle password reset request
+//
+//	@description	Send password reset link, response is the same whether email is registered or not
+//	@Param			email	body		string	true	"Email"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		429		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/forgot_password       [post]
+func (h *Handler) ForgotPassword(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		Email string `json:"email"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil || requestData.Email == "" {
+		http.Error(w, "email is empty", http.StatusBadRequest)
+		return
+	}
+
+	// unknown email is not reported to prevent enumeration
+	h.ctrl.ForgotPassword(requestData.Email)
+}
+
+// ResetPassword handle password reset
+//
+//	@description	Set new password with token from reset link, all sessions are revoked
+//	@Param			token		body		string	true	"Reset token"
+//	@Param			password	body		string	true	"New password"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		429			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/reset_password       [post]
+func (h *Handler) ResetPassword(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil || requestData.Token == "" {
+		http.Error(w, "token is empty", http.StatusBadRequest)
+		return
+	}
+
+	err = h.ctrl.ResetPassword(req.Context(), requestData.Token, requestData.Password)
+	if err != nil && (errors.Is(err, mysql.ErrNotFound) || errors.Is(err, controller.ErrInvalidResetToken)) {
+		http.Error(w, controller.ErrInvalidResetToken.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrPasswordTooShort) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// Refresh handle token refresh
+//
+//	@description	Exchange refresh token from cookie or body for new access and refresh tokens
+//	@Param			refresh_token	body		string	false	"Refresh token, read from cookie if empty"
+//	@Success		200				{object}	tokenResponse
+//	@Failure		401				{object}	int
+//	@Failure		405				{object}	int
+//	@Failure		500				{object}	int
+//	@Router			/refresh       [post]
+func (h *Handler) Refresh(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(bodyBytes) > 0 {
+		json.Unmarshal(bodyBytes, &requestData)
+	}
+	if cookie, err := req.Cookie(refreshCookieName); requestData.RefreshToken == "" && err == nil {
+		requestData.RefreshToken = cookie.Value
+	}
+	if requestData.RefreshToken == "" {
+		http.Error(w, "refresh token is missing", http.StatusUnauthorized)
+		return
+	}
+
+	userId, sessionId, refreshToken, err := h.ctrl.Refresh(req.Context(), requestData.RefreshToken)
+	if err != nil && (errors.Is(err, mysql.ErrNotFound) || errors.Is(err, controller.ErrInvalidRefreshToken)) {
+		clearTokenCookies(w)
+		http.Error(w, controller.ErrInvalidRefreshToken.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeTokens(w, req, userId, sessionId, refreshToken)
+}
+
+// Logout handle logout
+//
+//	@description	Revoke current access token and its session with refresh tokens of this login
+//	@Security		BearerAuth
+//	@Success		200		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/logout       [post]
+func (h *Handler) Logout(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	principal, ok := auth.FromContext(req.Context())
+	if !ok {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+
+	var refreshToken string
+	if cookie, err := req.Cookie(refreshCookieName); err == nil {
+		refreshToken = cookie.Value
+	}
+	if err := h.ctrl.Logout(req.Context(), principal.Claims, refreshToken); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	clearTokenCookies(w)
+}
+
+// LogoutAll handle logout from all devices
+//
+//	@description	Revoke all access and refresh tokens of the user
+//	@Security		BearerAuth
+//	@Success		200		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/logout_all       [post]
+func (h *Handler) LogoutAll(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	if err := h.ctrl.LogoutAll(req.Context(), userId); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	clearTokenCookies(w)
+}
+
+// GetSessions handle list of sessions
+//
+//	@description	List devices where the user is logged in, recently used first
+//	@Security		BearerAuth
+//	@Success		200		{object}	[]model.Session
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/sessions       [get]
+func (h *Handler) GetSessions(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	principal, ok := auth.FromContext(req.Context())
+	if !ok {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.ctrl.GetSessions(req.Context(), principal.UserId, principal.Claims.SessionId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(sessions); err != nil {
+		http.Error(w, "failed to encode sessions", http.StatusInternalServerError)
+	}
+}
+
+// RevokeSession handle logout of one device
+//
+//	@description	Revoke session from /sessions, its access and refresh tokens stop working
+//	@Security		BearerAuth
+//	@Param			session_id	body		string	true	"Session ID"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		404			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/sessions/revoke       [post]
+func (h *Handler) RevokeSession(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	principal, ok := auth.FromContext(req.Context())
+	if !ok {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+
+	var requestData struct {
+		SessionId string `json:"session_id"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil || requestData.SessionId == "" {
+		http.Error(w, "session_id is empty", http.StatusBadRequest)
+		return
+	}
+
+	err = h.ctrl.RevokeSession(req.Context(), principal.UserId, requestData.SessionId)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "session is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if requestData.SessionId == principal.Claims.SessionId {
+		clearTokenCookies(w)
+	}
+}
+
+// RequestExport handle personal data export
+//
+//	@description	Start preparing archive with profile, tweets, likes, followers and sessions of the user.
+//	@description	Download link is sent to email when archive is ready
+//	@Security		BearerAuth
+//	@Success		202		{object}	model.Export
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		409		{object}	model.Export
+//	@Failure		429		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/export       [post]
+func (h *Handler) RequestExport(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	export, err := h.ctrl.RequestExport(req.Context(), userId)
+	switch {
+	case errors.Is(err, controller.ErrExportInProgress):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, controller.ErrTooManyExports):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case err != nil:
+		http.Error(w, fmt.Sprintf("failed to request export: %s", err), http.StatusInternalServerError)
+		return
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		http.Error(w, "failed to encode export", http.StatusInternalServerError)
+	}
+}
+
+// GetExports handle list of personal data exports
+//
+//	@description	List data exports of the user, the latest first
+//	@Security		BearerAuth
+//	@Success		200		{object}	[]model.Export
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/exports       [get]
+func (h *Handler) GetExports(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	exports, err := h.ctrl.GetExports(req.Context(), userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(exports); err != nil {
+		http.Error(w, "failed to encode exports", http.StatusInternalServerError)
+	}
+}
+
+// DownloadExport handle download of personal data archive
+//
+//	@description	Download ZIP archive by token from the email link
+//	@Param			token	query		string	true	"Token from the link"
+//	@Success		200		{file}		file
+//	@Failure		400		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		410		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/export/download       [get]
+func (h *Handler) DownloadExport(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	token := req.FormValue("token")
+	if token == "" {
+		http.Error(w, "token is empty", http.StatusBadRequest)
+		return
+	}
+
+	file, export, err := h.ctrl.DownloadExport(req.Context(), token)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "export is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrExportExpired) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to download export: %s", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="data_%d_%s.zip"`, export.UserId, export.CreatedAt.Format("20060102")),
+	)
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("failed to send export %d: %v", export.ExportId, err)
+	}
+}
+
+// request with code from authenticator app or recovery code
+type codeRequest struct {
+	Code string `json:"code"`
+}
+
+// read code from request body
+func readCode(w http.ResponseWriter, req *http.Request) (string, bool) {
+	var requestData codeRequest
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil || requestData.Code == "" {
+		http.Error(w, "code is empty", http.StatusBadRequest)
+		return "", false
+	}
+	return requestData.Code, true
+}
+
+// write error of two-factor authentication
+func writeTOTPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, controller.ErrInvalidCode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, controller.ErrTOTPEnabled), errors.Is(err, controller.ErrTOTPNotEnrolled),
+		errors.Is(err, controller.ErrTOTPNotEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, controller.ErrTooManyAttempts):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, mysql.ErrNotFound):
+		http.Error(w, "failed to find user by this user_id", http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// response with recovery codes, they are shown only once
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func writeRecoveryCodes(w http.ResponseWriter, codes []string) {
+	if err := json.NewEncoder(w).Encode(recoveryCodesResponse{codes}); err != nil {
+		http.Error(w, "failed to encode recovery codes", http.StatusInternalServerError)
+	}
+}
+
+// EnrollTOTP handle two-factor enrollment
+//
+//	@description	Start two-factor enrollment, returns otpauth uri for authenticator app
+//	@Security		BearerAuth
+//	@Success		200		{object}	string
+//	@Failure		401		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		409		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/2fa/enroll       [post]
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	uri, err := h.ctrl.EnrollTOTP(req.Context(), userId)
+	if err != nil {
+		writeTOTPError(w, err)
+		return
+	}
+	response := struct {
+		URI string `json:"uri"`
+	}{uri}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "failed to encode uri", http.StatusInternalServerError)
+	}
+}
+
+// ConfirmTOTP handle two-factor confirmation
+//
+//	@description	Enable two-factor authentication with the first code from authenticator app, returns recovery codes
+//	@Security		BearerAuth
+//	@Param			code	body		string	true	"Code from authenticator app"
+//	@Success		200		{object}	recoveryCodesResponse
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		409		{object}	int
+//	@Failure		429		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/2fa/confirm       [post]
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+	code, ok := readCode(w, req)
+	if !ok {
+		return
+	}
+
+	codes, err := h.ctrl.ConfirmTOTP(req.Context(), userId, code)
+	if err != nil {
+		writeTOTPError(w, err)
+		return
+	}
+	writeRecoveryCodes(w, codes)
+}
+
+// DisableTOTP handle disabling of two-factor authentication
+//
+//	@description	Disable two-factor authentication, code from authenticator app or recovery code is required
+//	@Security		BearerAuth
+//	@Param			code	body		string	true	"Code from authenticator app or recovery code"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		409		{object}	int
+//	@Failure		429		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/2fa/disable       [post]
+func (h *Handler) DisableTOTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+	code, ok := readCode(w, req)
+	if !ok {
+		return
+	}
+
+	if err := h.ctrl.DisableTOTP(req.Context(), userId, code); err != nil {
+		writeTOTPError(w, err)
+		return
+	}
+}
+
+// RegenerateRecoveryCodes handle recovery codes regeneration
+//
+//	@description	Replace recovery codes with new ones, code from authenticator app or recovery code is required
+//	@Security		BearerAuth
+//	@Param			code	body		string	true	"Code from authenticator app or recovery code"
+//	@Success		200		{object}	recoveryCodesResponse
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		409		{object}	int
+//	@Failure		429		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/2fa/recovery_codes       [post]
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+	code, ok := readCode(w, req)
+	if !ok {
+		return
+	}
+
+	codes, err := h.ctrl.RegenerateRecoveryCodes(req.Context(), userId, code)
+	if err != nil {
+		writeTOTPError(w, err)
+		return
+	}
+	writeRecoveryCodes(w, codes)
+}
+
+// VerifyLogin handle second step of the login
+//
+//	@description	Finish login with challenge token and code from authenticator app or recovery code
+//	@Param			challenge_token	body		string	true	"Challenge token returned by /login"
+//	@Param			code			body		string	true	"Code from authenticator app or recovery code"
+//	@Success		200				{object}	tokenResponse
+//	@Failure		400				{object}	int
+//	@Failure		401				{object}	int
+//	@Failure		405				{object}	int
+//	@Failure		429				{object}	int
+//	@Failure		500				{object}	int
+//	@Router			/login/2fa       [post]
+func (h *Handler) VerifyLogin(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil || requestData.ChallengeToken == "" || requestData.Code == "" {
+		http.Error(w, "challenge_token and code should be present", http.StatusBadRequest)
+		return
+	}
+
+	userId, err := h.ctrl.VerifyLogin(req.Context(), requestData.ChallengeToken, requestData.Code)
+	if err != nil && (errors.Is(err, controller.ErrInvalidChallenge) || errors.Is(err, controller.ErrInvalidCode)) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil && errors.Is(err, controller.ErrTooManyAttempts) {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Set id in request context.
+	ctx := context.WithValue(req.Context(), idCtxKey, userId)
+	*req = *req.WithContext(ctx)
+}
+
+// Lockouts handle list of login lockouts
+//
+//	@description	List emails and ips locked after failed logins, requires admin role
+//	@Security		BearerAuth
+//	@Success		200		{object}	[]ratelimit.Lockout
+//	@Failure		401		{object}	int
+//	@Failure		403		{object}	int
+//	@Failure		405		{object}	int
+//	@Router			/admin/lockouts       [get]
+func (h *Handler) Lockouts(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(h.ctrl.Lockouts()); err != nil {
+		http.Error(w, "failed to encode lockouts", http.StatusInternalServerError)
+	}
+}
+
+// ClearLockout handle login unlock
+//
+//	@description	Forget failed logins of email or ip, requires admin role
+//	@Security		BearerAuth
+//	@Param			key		body		string	true	"Key from /admin/lockouts, e.g. email:alex@mail.com"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		403		{object}	int
+//	@Failure		405		{object}	int
+//	@Router			/admin/unlock       [post]
+func (h *Handler) ClearLockout(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		Key string `json:"key"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil || requestData.Key == "" {
+		http.Error(w, "key is empty", http.StatusBadRequest)
+		return
+	}
+	h.ctrl.ClearLockout(req.Context(), requestData.Key)
+}
+
+// read user_id of the moderated account from the body
+func readModeration(w http.ResponseWriter, req *http.Request, requestData interface{}) bool {
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if err = json.Unmarshal(bodyBytes, requestData); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// write error of moderation action
+func writeModerationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, mysql.ErrNotFound):
+		http.Error(w, "user is not found or is already in this state", http.StatusNotFound)
+	case errors.Is(err, controller.ErrInsufficientRole):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, controller.ErrInvalidRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Suspend handle account suspension
+//
+//	@description	Hide profile of the user, log out every session and block login, requires moderator role.
+//	@description	Only admins suspend moderators and admins
+//	@Security		BearerAuth
+//	@Param			user_id	body		int		true	"User id"
+//	@Param			reason	body		string	false	"Reason that is kept in audit history"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		403		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/admin/suspend       [post]
+func (h *Handler) Suspend(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		UserId types.UserId `json:"user_id"`
+		Reason string       `json:"reason"`
+	}
+	if !readModeration(w, req, &requestData) {
+		return
+	}
+	if err := h.ctrl.Suspend(req.Context(), requestData.UserId, requestData.Reason); err != nil {
+		writeModerationError(w, err)
+	}
+}
+
+// Unsuspend handle lifting of suspension
+//
+//	@description	Restore suspended account, requires moderator role
+//	@Security		BearerAuth
+//	@Param			user_id	body		int		true	"User id"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		403		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/admin/unsuspend       [post]
+func (h *Handler) Unsuspend(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		UserId types.UserId `json:"user_id"`
+	}
+	if !readModeration(w, req, &requestData) {
+		return
+	}
+	if err := h.ctrl.Unsuspend(req.Context(), requestData.UserId); err != nil {
+		writeModerationError(w, err)
+	}
+}
+
+// SetRole handle role change
+//
+//	@description	Change role of the user and log out every session, requires admin role
+//	@Security		BearerAuth
+//	@Param			user_id	body		int		true	"User id"
+//	@Param			role	body		string	true	"user, moderator or admin"
+//	@Success		200		{object}	int
+//	@Failure		400		{object}	int
+//	@Failure		401		{object}	int
+//	@Failure		403		{object}	int
+//	@Failure		404		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/admin/role       [post]
+func (h *Handler) SetRole(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData struct {
+		UserId types.UserId `json:"user_id"`
+		Role   types.Role   `json:"role"`
+	}
+	if !readModeration(w, req, &requestData) {
+		return
+	}
+	if err := h.ctrl.SetRole(req.Context(), requestData.UserId, requestData.Role); err != nil {
+		writeModerationError(w, err)
+	}
+}
+
+type accessTokenResponse struct {
+	model.AccessToken
+	// secret is shown only once
+	Token string `json:"token"`
+}
+
+// CreateAccessToken handle creation of personal access token
+//
+//	@description	Create token for bots and scripts, it is sent as Bearer token and is shown only once.
+//	@description	Email of the user should be verified.
+//	@description	Scopes are read, tweets:write, likes:write and follow:write
+//	@Security		BearerAuth
+//	@Param			name			body		string		true	"Name of the token"
+//	@Param			scopes			body		[]string	true	"Scopes of the token"
+//	@Param			expires_in_days	body		int			false	"Expiration in days, 30 by default and 365 at most"
+//	@Success		201				{object}	accessTokenResponse
+//	@Failure		400				{object}	int
+//	@Failure		401				{object}	int
+//	@Failure		403				{object}	int
+//	@Failure		405				{object}	int
+//	@Failure		429				{object}	int
+//	@Failure		500				{object}	int
+//	@Router			/tokens/create       [post]
+func (h *Handler) CreateAccessToken(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	var requestData struct {
+		Name          string        `json:"name"`
+		Scopes        []types.Scope `json:"scopes"`
+		ExpiresInDays int           `json:"expires_in_days"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err = json.Unmarshal(bodyBytes, &requestData); err != nil {
+		http.Error(w, "failed to parse request body", http.StatusBadRequest)
+		return
+	}
+
+	token, secret, err := h.ctrl.CreateAccessToken(
+		req.Context(), userId, requestData.Name, requestData.Scopes,
+		time.Duration(requestData.ExpiresInDays)*24*time.Hour,
+	)
+	switch {
+	case errors.Is(err, controller.ErrInvalidAccessToken):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, controller.ErrTooManyAccessTokens):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	case errors.Is(err, controller.ErrEmailNotVerified):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, fmt.Sprintf("failed to create access token: %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(accessTokenResponse{token, secret}); err != nil {
+		http.Error(w, "failed to encode access token", http.StatusInternalServerError)
+	}
+}
+
+// GetAccessTokens handle list of personal access tokens
+//
+//	@description	List personal access tokens of the user without secrets, the latest first
+//	@Security		BearerAuth
+//	@Success		200		{object}	[]model.AccessToken
+//	@Failure		401		{object}	int
+//	@Failure		405		{object}	int
+//	@Failure		500		{object}	int
+//	@Router			/tokens       [get]
+func (h *Handler) GetAccessTokens(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.ctrl.GetAccessTokens(req.Context(), userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		http.Error(w, "failed to encode access tokens", http.StatusInternalServerError)
+	}
+}
+
+// RevokeAccessToken handle revocation of personal access token
+//
+//	@description	Revoke token from /tokens, it stops working right away
+//	@Security		BearerAuth
+//	@Param			token_id	body		int		true	"Token ID"
+//	@Success		200			{object}	int
+//	@Failure		400			{object}	int
+//	@Failure		401			{object}	int
+//	@Failure		404			{object}	int
+//	@Failure		405			{object}	int
+//	@Failure		500			{object}	int
+//	@Router			/tokens/revoke       [post]
+func (h *Handler) RevokeAccessToken(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+	userId, ok := auth.RequireUser(w, req)
+	if !ok {
+		return
+	}
+
+	var requestData struct {
+		TokenId int `json:"token_id"`
+	}
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	defer req.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = json.Unmarshal(bodyBytes, &requestData)
+	if err != nil || requestData.TokenId == 0 {
+		http.Error(w, "token_id is empty", http.StatusBadRequest)
+		return
+	}
+
+	err = h.ctrl.RevokeAccessToken(req.Context(), userId, requestData.TokenId)
+	if err != nil && errors.Is(err, mysql.ErrNotFound) {
+		http.Error(w, "access token is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
@@ -0,0 +1,121 @@
+package names
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ErrReserved is returned when nickname looks like a reserved name.
+var ErrReserved = errors.New("nickname is reserved")
+
+// ErrBlocked is returned when nickname contains a blocked word.
+var ErrBlocked = errors.New("nickname contains a blocked word")
+
+// letters of other scripts that look like latin ones
+var homoglyphs = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ӏ': 'l',
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// latin letters and digits that look alike, every group is mapped to one letter
+var confusables = strings.NewReplacer(
+	"rn", "m",
+	"vv", "w",
+	"0", "o",
+	"1", "l",
+	"i", "l",
+	"5", "s",
+)
+
+// Canonical outputs the form nicknames are compared in: case is folded, fullwidth
+// forms and look-alike letters of other scripts are replaced with latin ones and
+// confusable latin letters and digits are mapped to one of them, so "Adm1n" and
+// "admin" have the same canonical form
+func Canonical(nickname string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(nickname) {
+		// fullwidth forms of ascii
+		if r >= '！' && r <= '～' {
+			r = r - '！' + '!'
+		}
+		r = unicode.ToLower(r)
+		if latin, ok := homoglyphs[r]; ok {
+			r = latin
+		}
+		b.WriteRune(r)
+	}
+	return confusables.Replace(b.String())
+}
+
+// Policy rejects nicknames that look like reserved names or contain blocked words
+type Policy struct {
+	reserved map[string]bool
+	blocked  []string
+}
+
+// DefaultReserved are names of the service and its staff that users can't take
+var DefaultReserved = []string{
+	"admin", "administrator", "root", "system", "support", "help", "helpdesk", "security",
+	"moderator", "mod", "staff", "official", "team", "twitter", "api", "www", "mail",
+	"noreply", "no_reply", "settings", "login", "logout", "register", "signup", "home",
+	"explore", "search", "notifications", "messages", "account", "about", "null", "undefined",
+}
+
+// NewPolicy compares reserved names and blocked words in canonical form
+func NewPolicy(reserved []string, blocked []string) *Policy {
+	p := &Policy{reserved: make(map[string]bool)}
+	for _, name := range reserved {
+		if name = Canonical(name); name != "" {
+			p.reserved[name] = true
+		}
+	}
+	for _, word := range blocked {
+		if word = Canonical(word); word != "" {
+			p.blocked = append(p.blocked, word)
+		}
+	}
+	return p
+}
+
+// Check returns ErrReserved if nickname looks like a reserved name,
+// ErrBlocked if it contains a blocked word
+func (p *Policy) Check(nickname string) error {
+	canonical := Canonical(nickname)
+	if p.reserved[canonical] {
+		return fmt.Errorf("%w: %q", ErrReserved, nickname)
+	}
+	for _, word := range p.blocked {
+		if strings.Contains(canonical, word) {
+			return fmt.Errorf("%w: %q", ErrBlocked, nickname)
+		}
+	}
+	return nil
+}
+
+// LoadList reads names from file, one per line. Empty lines and lines starting with # are skipped
+func LoadList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var list []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	return list, scanner.Err()
+}
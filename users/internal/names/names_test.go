@@ -0,0 +1,72 @@
+package names
+
+import (
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		nickname string
+		want     string
+	}{
+		{"Alex", "alex"},
+		{"ALEX", "alex"},
+		{"Adm1n", "admln"},
+		{"admin", "admln"},
+		{"ADMIN", "admln"},
+		{"adrnin", "admln"},
+		{"g00gle", "google"},
+		{"vvhat", "what"},
+		// cyrillic а and о
+		{"аdmin", "admln"},
+		{"bоb", "bob"},
+		// fullwidth
+		{"ＡＤＭＩＮ", "admln"},
+		{" alex ", "alex"},
+	}
+	for _, tt := range tests {
+		if got := Canonical(tt.nickname); got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.nickname, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := NewPolicy([]string{"admin", "support"}, []string{"spam"})
+	tests := []struct {
+		nickname string
+		want     error
+	}{
+		{"alex", nil},
+		{"Admin", ErrReserved},
+		{"adm1n", ErrReserved},
+		{"SUPP0RT", ErrReserved},
+		// reserved names can be a part of nickname
+		{"admin_alex", nil},
+		{"spammer", ErrBlocked},
+		{"no_5pam", ErrBlocked},
+	}
+	for _, tt := range tests {
+		if err := policy.Check(tt.nickname); !errors.Is(err, tt.want) {
+			t.Errorf("Check(%q) = %v, want %v", tt.nickname, err, tt.want)
+		}
+	}
+}
+
+func TestLoadList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reserved.txt")
+	if err := os.WriteFile(path, []byte("# staff\nadmin\n\n  support \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadList(path)
+	if err != nil {
+		t.Fatalf("error was not expected while loading list: %s", err)
+	}
+	if diff := cmp.Diff([]string{"admin", "support"}, list); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
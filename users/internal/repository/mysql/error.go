@@ -3,3 +3,9 @@ package mysql
 import "errors"
 
 var ErrNotFound = errors.New("not found")
+
+// ErrVersionConflict is returned when a record was changed since it was read.
+var ErrVersionConflict = errors.New("record was changed by another request")
+
+// ErrDuplicate is returned when a unique value is already taken.
+var ErrDuplicate = errors.New("already taken")
@@ -7,11 +7,17 @@ import (
 	"fmt"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
-	_ "github.com/go-sql-driver/mysql"
-	"reflect"
+	"github.com/go-sql-driver/mysql"
 	"strings"
+	"time"
 )
 
+// time layout
+const layout = "2006-01-02 15:04:05"
+
+// error number of unique key violation
+const duplicateEntry = 1062
+
 type Repository struct {
 	db *sql.DB
 }
@@ -29,9 +35,11 @@ func New(driverName string, dataSourceName string) (*Repository, error) {
 	return &Repository{db}, nil
 }
 
+// save new user, ErrDuplicate is returned if nickname or email is taken
 func (r *Repository) Register(
 	ctx context.Context,
 	nickname string,
+	canonical string,
 	firstname string,
 	lastname string,
 	email string,
@@ -39,15 +47,22 @@ func (r *Repository) Register(
 ) (types.UserId, error) {
 	row, err := r.db.ExecContext(
 		ctx,
-		"INSERT INTO User (nickname, first_name, last_name, email, password) VALUES (?, ?, ?, ?, ?)",
-		nickname, firstname, lastname, email, password,
+		"INSERT INTO User (nickname, nickname_canonical, first_name, last_name, email, password) VALUES (?, ?, ?, ?, ?, ?)",
+		nickname, canonical, firstname, lastname, email, password,
 	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntry {
+		return 0, ErrDuplicate
+	}
+	if err != nil {
+		return 0, err
+	}
 	id, err := row.LastInsertId()
 	userId := types.UserId(id)
 	return userId, err
 }
 
-// outputs password for email address
+// outputs password for email address, zero user is returned for unknown email
 func (r *Repository) RetrievePassword(
 	ctx context.Context,
 	email string,
@@ -70,37 +85,1409 @@ func (r *Repository) RetrievePassword(
 		}
 		return userId, password, nil
 	} else {
-		return userId, "", errors.New("No user found with given email")
+		return 0, "", nil
+	}
+}
+
+// mark user as deactivated, profile and content are hidden until reactivation or purge
+func (r *Repository) Deactivate(
+	ctx context.Context,
+	userid types.UserId,
+	deactivatedAt time.Time,
+) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		"UPDATE User SET deactivated_at = ? WHERE user_id = ? AND deactivated_at IS NULL",
+		deactivatedAt.UTC().Format(layout), userid,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// outputs moment when user was deactivated, nil if user is active
+func (r *Repository) GetDeactivatedAt(
+	ctx context.Context,
+	userid types.UserId,
+) (*time.Time, error) {
+	var deactivatedAtStr sql.NullString
+
+	row := r.db.QueryRowContext(ctx, "SELECT deactivated_at FROM User WHERE user_id = ?", userid)
+	err := row.Scan(&deactivatedAtStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil || !deactivatedAtStr.Valid {
+		return nil, err
+	}
+
+	deactivatedAt, err := time.Parse(layout, deactivatedAtStr.String)
+	if err != nil {
+		return nil, err
+	}
+	return &deactivatedAt, nil
+}
+
+func (r *Repository) Reactivate(
+	ctx context.Context,
+	userid types.UserId,
+) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE User SET deactivated_at = NULL WHERE user_id = ?", userid)
+	return err
+}
+
+// outputs users deactivated before the moment
+func (r *Repository) GetDeactivatedBefore(
+	ctx context.Context,
+	before time.Time,
+) ([]types.UserId, error) {
+	rows, err := r.db.QueryContext(
+		ctx, "SELECT user_id FROM User WHERE deactivated_at < ?", before.UTC().Format(layout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIds []types.UserId
+	for rows.Next() {
+		var id types.UserId
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIds = append(userIds, id)
+	}
+	return userIds, rows.Err()
+}
+
+// outputs storage paths of profile images, tweet media and data exports of the user
+func (r *Repository) GetMediaUrls(
+	ctx context.Context,
+	userid types.UserId,
+) ([]string, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		"SELECT avatar_url FROM User WHERE user_id = ? AND avatar_url IS NOT NULL "+
+			"UNION ALL SELECT header_url FROM User WHERE user_id = ? AND header_url IS NOT NULL "+
+			"UNION ALL SELECT media_url FROM Tweets WHERE user_id = ? AND media_url IS NOT NULL "+
+			"UNION ALL SELECT storage_path FROM DataExports WHERE user_id = ? AND storage_path IS NOT NULL",
+		userid, userid, userid, userid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
+
+// delete user deactivated before the moment, rows of other tables are removed by cascade.
+// ErrNotFound is returned if user was reactivated in the meantime
+func (r *Repository) Purge(
+	ctx context.Context,
+	userid types.UserId,
+	before time.Time,
+) error {
+	res, err := r.db.ExecContext(
+		ctx, "DELETE FROM User WHERE user_id = ? AND deactivated_at < ?", userid, before.UTC().Format(layout),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// outputs role of the user
+func (r *Repository) GetRole(
+	ctx context.Context,
+	userid types.UserId,
+) (types.Role, error) {
+	var role types.Role
+
+	row := r.db.QueryRowContext(ctx, "SELECT role FROM User WHERE user_id = ?", userid)
+	err := row.Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return role, ErrNotFound
 	}
+	return role, err
 }
 
-func (r *Repository) Delete(
+func (r *Repository) SetRole(
 	ctx context.Context,
 	userid types.UserId,
+	role types.Role,
 ) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM User WHERE user_id = ?", userid)
+	_, err := r.db.ExecContext(ctx, "UPDATE User SET role = ? WHERE user_id = ?", role, userid)
 	return err
 }
 
+// mark user as suspended, ErrNotFound is returned if user is missing or already suspended
+func (r *Repository) Suspend(
+	ctx context.Context,
+	userid types.UserId,
+	suspendedAt time.Time,
+) error {
+	res, err := r.db.ExecContext(
+		ctx,
+		"UPDATE User SET suspended_at = ? WHERE user_id = ? AND suspended_at IS NULL",
+		suspendedAt.UTC().Format(layout), userid,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// lift suspension, ErrNotFound is returned if user is missing or not suspended
+func (r *Repository) Unsuspend(
+	ctx context.Context,
+	userid types.UserId,
+) error {
+	res, err := r.db.ExecContext(
+		ctx, "UPDATE User SET suspended_at = NULL WHERE user_id = ? AND suspended_at IS NOT NULL", userid,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *Repository) IsSuspended(
+	ctx context.Context,
+	userid types.UserId,
+) (bool, error) {
+	var suspended bool
+
+	row := r.db.QueryRowContext(ctx, "SELECT suspended_at IS NOT NULL FROM User WHERE user_id = ?", userid)
+	err := row.Scan(&suspended)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	return suspended, err
+}
+
+// column of User table with its new value, nil values are left unchanged
+type updateColumn struct {
+	name  string
+	value *string
+}
+
+// whitelist of columns that are changed by Update
+func updateColumns(update model.UserUpdate) []updateColumn {
+	return []updateColumn{
+		{"nickname", update.Nickname},
+		{"nickname_canonical", update.NicknameCanonical},
+		{"first_name", update.FirstName},
+		{"last_name", update.LastName},
+		{"email", update.Email},
+		{"password", update.Password},
+	}
+}
+
+// change fields of the account that are set in update, it outputs the new version.
+// Old nickname is kept in history when its canonical form changes. ErrVersionConflict is returned
+// if the account was changed after update.Version, ErrDuplicate if nickname or email is taken
 func (r *Repository) Update(
 	ctx context.Context,
-	userData model.User,
+	userid types.UserId,
+	update model.UserUpdate,
+) (int, error) {
+	if update.Version == nil {
+		return 0, ErrVersionConflict
+	}
+	var (
+		assignments []string
+		args        []interface{}
+	)
+	for _, column := range updateColumns(update) {
+		if column.value != nil {
+			assignments = append(assignments, column.name+" = ?")
+			args = append(args, *column.value)
+		}
+	}
+	// new email has to be verified again
+	if update.Email != nil {
+		assignments = append(assignments, "email_verified = FALSE")
+	}
+	version := *update.Version
+	assignments = append(assignments, "version = version + 1")
+	args = append(args, userid, version)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var previous string
+	if update.NicknameCanonical != nil {
+		err = tx.QueryRowContext(
+			ctx, "SELECT nickname_canonical FROM User WHERE user_id = ? FOR UPDATE", userid,
+		).Scan(&previous)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	query := fmt.Sprintf("UPDATE User SET %s WHERE user_id = ? AND version = ?", strings.Join(assignments, ", "))
+	res, err := tx.ExecContext(ctx, query, args...)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntry {
+		return 0, ErrDuplicate
+	}
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		// tell missing user from the changed one
+		var current int
+		err = tx.QueryRowContext(ctx, "SELECT version FROM User WHERE user_id = ?", userid).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		return 0, ErrVersionConflict
+	}
+
+	if update.NicknameCanonical != nil && previous != *update.NicknameCanonical {
+		_, err = tx.ExecContext(
+			ctx, "INSERT INTO NicknameHistory (user_id, nickname_canonical, changed_at) VALUES (?, ?, ?)",
+			userid, previous, time.Now().UTC().Format(layout),
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return version + 1, tx.Commit()
+}
+
+// outputs the user that most recently changed nickname from the given canonical form
+// after since, zero if nickname wasn't changed from during that time
+func (r *Repository) GetNicknameOwner(
+	ctx context.Context,
+	canonical string,
+	since time.Time,
+) (types.UserId, error) {
+	var userId types.UserId
+
+	row := r.db.QueryRowContext(
+		ctx,
+		"SELECT user_id FROM NicknameHistory WHERE nickname_canonical = ? AND changed_at > ? "+
+			"ORDER BY history_id DESC LIMIT 1",
+		canonical, since.UTC().Format(layout),
+	)
+	err := row.Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return userId, err
+}
+
+// outputs number of nickname changes of the user after since
+func (r *Repository) CountNicknameChanges(
+	ctx context.Context,
+	userid types.UserId,
+	since time.Time,
+) (int, error) {
+	var count int
+
+	row := r.db.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM NicknameHistory WHERE user_id = ? AND changed_at > ?",
+		userid, since.UTC().Format(layout),
+	)
+	err := row.Scan(&count)
+	return count, err
+}
+
+// delete nickname history older than the moment
+func (r *Repository) PurgeNicknameHistory(
+	ctx context.Context,
+	before time.Time,
+) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM NicknameHistory WHERE changed_at < ?", before.UTC().Format(layout))
+	return err
+}
+
+// outputs private account of the user
+func (r *Repository) GetAccount(
+	ctx context.Context,
+	userid types.UserId,
+) (model.Account, error) {
+	var account model.Account
+
+	row := r.db.QueryRowContext(
+		ctx,
+		"SELECT user_id, nickname, first_name, last_name, email, email_verified, version FROM User WHERE user_id = ?",
+		userid,
+	)
+	err := row.Scan(
+		&account.UserId, &account.Nickname, &account.FirstName, &account.LastName,
+		&account.Email, &account.EmailVerified, &account.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return account, ErrNotFound
+	}
+	return account, err
+}
+
+// replace preferred languages of the user
+func (r *Repository) SetPreferredLanguages(
+	ctx context.Context,
+	userid types.UserId,
+	langs []string,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM UserLanguages WHERE user_id = ?", userid); err != nil {
+		return err
+	}
+	for _, lang := range langs {
+		_, err = tx.ExecContext(ctx, "INSERT INTO UserLanguages (user_id, lang) VALUES (?, ?)", userid, lang)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// outputs preferred languages of the user
+func (r *Repository) GetPreferredLanguages(
+	ctx context.Context,
+	userid types.UserId,
+) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT lang FROM UserLanguages WHERE user_id = ? ORDER BY lang", userid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	langs := []string{}
+	for rows.Next() {
+		var lang string
+		if err := rows.Scan(&lang); err != nil {
+			return nil, err
+		}
+		langs = append(langs, lang)
+	}
+	return langs, rows.Err()
+}
+
+// set how sensitive media is shown to the user
+func (r *Repository) SetSensitiveMedia(
+	ctx context.Context,
+	userid types.UserId,
+	preference model.SensitiveMedia,
+) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE User SET sensitive_media = ? WHERE user_id = ?", preference, userid)
+	return err
+}
+
+// set whether tweets of the user are visible only to approved followers
+func (r *Repository) SetProtected(
+	ctx context.Context,
+	userid types.UserId,
+	protected bool,
 ) error {
-	var conditions []string
+	_, err := r.db.ExecContext(ctx, "UPDATE User SET protected = ? WHERE user_id = ?", protected, userid)
+	return err
+}
+
+// outputs how sensitive media is shown to the user
+func (r *Repository) GetSensitiveMedia(
+	ctx context.Context,
+	userid types.UserId,
+) (model.SensitiveMedia, error) {
+	var preference model.SensitiveMedia
+
+	row := r.db.QueryRowContext(ctx, "SELECT sensitive_media FROM User WHERE user_id = ?", userid)
+	err := row.Scan(&preference)
+	if errors.Is(err, sql.ErrNoRows) {
+		return preference, ErrNotFound
+	}
+	return preference, err
+}
+
+// columns of the public profile, password and email are never selected
+const profileColumns = "user_id, nickname, first_name, last_name, bio, location, website, birthday, avatar_url, header_url, protected"
+
+// profiles of deactivated and suspended users are hidden
+const activeCondition = "deactivated_at IS NULL AND suspended_at IS NULL"
+
+// columns of profile images
+var imageColumns = map[model.ImageKind]string{
+	model.ImageAvatar: "avatar_url",
+	model.ImageHeader: "header_url",
+}
+
+// helper function to scan profiles
+func scanProfiles(rows *sql.Rows) ([]model.Profile, error) {
+	var profiles []model.Profile
+	for rows.Next() {
+		var profile model.Profile
+		err := rows.Scan(
+			&profile.UserId, &profile.Nickname, &profile.FirstName, &profile.LastName,
+			&profile.Bio, &profile.Location, &profile.Website, &profile.Birthday,
+			&profile.AvatarUrl, &profile.HeaderUrl, &profile.Protected,
+		)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+// outputs public profiles of the users, missing and deactivated users are skipped
+func (r *Repository) GetUsers(
+	ctx context.Context,
+	userIds ...types.UserId,
+) ([]model.Profile, error) {
+	if len(userIds) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(userIds))
+	for i, id := range userIds {
+		args[i] = id
+	}
+	query := fmt.Sprintf(
+		"SELECT %s FROM User WHERE user_id IN (?%s) AND %s",
+		profileColumns, strings.Repeat(", ?", len(userIds)-1), activeCondition,
+	)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanProfiles(rows)
+}
+
+// outputs public profiles of all users
+func (r *Repository) GetAllUsers(ctx context.Context) ([]model.Profile, error) {
+	query := fmt.Sprintf("SELECT %s FROM User WHERE %s", profileColumns, activeCondition)
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanProfiles(rows)
+}
+
+// helper function to get single profile
+func (r *Repository) getProfile(ctx context.Context, condition string, arg interface{}) (model.Profile, error) {
+	query := fmt.Sprintf("SELECT %s FROM User WHERE %s = ? AND %s", profileColumns, condition, activeCondition)
+	rows, err := r.db.QueryContext(ctx, query, arg)
+	if err != nil {
+		return model.Profile{}, err
+	}
+	defer rows.Close()
+
+	profiles, err := scanProfiles(rows)
+	if err != nil {
+		return model.Profile{}, err
+	}
+	if len(profiles) == 0 {
+		return model.Profile{}, ErrNotFound
+	}
+	return profiles[0], nil
+}
+
+// outputs public profile of the user
+func (r *Repository) GetById(
+	ctx context.Context,
+	userid types.UserId,
+) (model.Profile, error) {
+	return r.getProfile(ctx, "user_id", userid)
+}
+
+// outputs public profile of the user with canonical form of nickname
+func (r *Repository) GetByNickname(
+	ctx context.Context,
+	canonical string,
+) (model.Profile, error) {
+	return r.getProfile(ctx, "nickname_canonical", canonical)
+}
 
-	// iterate over fields
-	v := reflect.ValueOf(userData)
-	for i := 0; i < v.NumField(); i++ {
-		length := len(v.Type().Field(i).Tag)
-		fieldName := string(v.Type().Field(i).Tag[6 : length-1])
-		fieldValue := v.Field(i).Interface()
-		if fieldValue != "" && fieldName != "user_id" {
-			conditions = append(conditions, fmt.Sprintf("%s = '%s'", fieldName, fieldValue))
+// update editable profile fields, nil fields are left unchanged
+func (r *Repository) UpdateProfile(
+	ctx context.Context,
+	userid types.UserId,
+	profile model.ProfileUpdate,
+) error {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"bio", profile.Bio},
+		{"location", profile.Location},
+		{"website", profile.Website},
+		{"birthday", profile.Birthday},
+	} {
+		if field.value == nil {
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = ?", field.column))
+		// empty birthday removes it
+		if field.column == "birthday" && *field.value == "" {
+			args = append(args, nil)
+		} else {
+			args = append(args, *field.value)
 		}
 	}
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	args = append(args, userid)
+	query := fmt.Sprintf("UPDATE User SET %s WHERE user_id = ?", strings.Join(conditions, ", "))
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
 
-	setStatement := strings.Join(conditions, ", ")
-	execStatement := fmt.Sprintf("UPDATE User SET %s WHERE user_id = ?", setStatement)
-	_, err := r.db.ExecContext(ctx, execStatement, userData.UserId)
+// set url of profile image
+func (r *Repository) UpdateImage(
+	ctx context.Context,
+	userid types.UserId,
+	kind model.ImageKind,
+	url string,
+) error {
+	column, ok := imageColumns[kind]
+	if !ok {
+		return fmt.Errorf("unknown image kind: %s", kind)
+	}
+	query := fmt.Sprintf("UPDATE User SET %s = ? WHERE user_id = ?", column)
+	_, err := r.db.ExecContext(ctx, query, url, userid)
 	return err
 }
+
+// outputs email of the user and whether it is verified
+func (r *Repository) GetEmail(
+	ctx context.Context,
+	userid types.UserId,
+) (string, bool, error) {
+	var (
+		email    string
+		verified bool
+	)
+
+	row := r.db.QueryRowContext(ctx, "SELECT email, email_verified FROM User WHERE user_id = ?", userid)
+	err := row.Scan(&email, &verified)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, ErrNotFound
+	}
+	return email, verified, err
+}
+
+// mark email of the user as verified or not
+func (r *Repository) SetEmailVerified(
+	ctx context.Context,
+	userid types.UserId,
+	verified bool,
+) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE User SET email_verified = ? WHERE user_id = ?", verified, userid)
+	return err
+}
+
+// outputs id of the user with email
+func (r *Repository) GetIdByEmail(
+	ctx context.Context,
+	email string,
+) (types.UserId, error) {
+	var userId types.UserId
+
+	row := r.db.QueryRowContext(ctx, "SELECT user_id FROM User WHERE email = ?", email)
+	err := row.Scan(&userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userId, ErrNotFound
+	}
+	return userId, err
+}
+
+// save hash of password reset token
+func (r *Repository) PutPasswordReset(
+	ctx context.Context,
+	userid types.UserId,
+	tokenHash string,
+	expiresAt time.Time,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"INSERT INTO PasswordResets (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		tokenHash, userid, expiresAt.UTC().Format(layout),
+	)
+	return err
+}
+
+// delete password reset token and output its user and expiration time
+func (r *Repository) ConsumePasswordReset(
+	ctx context.Context,
+	tokenHash string,
+) (types.UserId, time.Time, error) {
+	var (
+		userId       types.UserId
+		expiresAtStr string
+	)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return userId, time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx, "SELECT user_id, expires_at FROM PasswordResets WHERE token_hash = ? FOR UPDATE", tokenHash,
+	)
+	err = row.Scan(&userId, &expiresAtStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userId, time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return userId, time.Time{}, err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM PasswordResets WHERE token_hash = ?", tokenHash); err != nil {
+		return userId, time.Time{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return userId, time.Time{}, err
+	}
+
+	expiresAt, err := time.Parse(layout, expiresAtStr)
+	return userId, expiresAt, err
+}
+
+// set new password, revoke sessions issued before revokedAt and remaining reset tokens
+func (r *Repository) ResetPassword(
+	ctx context.Context,
+	userid types.UserId,
+	password string,
+	revokedAt time.Time,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx, "UPDATE User SET password = ?, sessions_revoked_at = ? WHERE user_id = ?",
+		password, revokedAt.UTC().Format(layout), userid,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM PasswordResets WHERE user_id = ?", userid); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM RefreshTokens WHERE user_id = ?", userid); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM Sessions WHERE user_id = ?", userid); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// outputs moment before which sessions of the user are revoked, nil if there is none
+func (r *Repository) GetSessionsRevokedAt(
+	ctx context.Context,
+	userid types.UserId,
+) (*time.Time, error) {
+	var revokedAtStr sql.NullString
+
+	row := r.db.QueryRowContext(ctx, "SELECT sessions_revoked_at FROM User WHERE user_id = ?", userid)
+	err := row.Scan(&revokedAtStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil || !revokedAtStr.Valid {
+		return nil, err
+	}
+
+	revokedAt, err := time.Parse(layout, revokedAtStr.String)
+	if err != nil {
+		return nil, err
+	}
+	return &revokedAt, nil
+}
+
+// save hash of refresh token, tokens rotated from the same login share family
+func (r *Repository) PutRefreshToken(
+	ctx context.Context,
+	userid types.UserId,
+	tokenHash string,
+	family string,
+	expiresAt time.Time,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"INSERT INTO RefreshTokens (token_hash, user_id, family, expires_at) VALUES (?, ?, ?, ?)",
+		tokenHash, userid, family, expiresAt.UTC().Format(layout),
+	)
+	return err
+}
+
+// outputs refresh token by its hash
+func (r *Repository) GetRefreshToken(
+	ctx context.Context,
+	tokenHash string,
+) (model.RefreshToken, error) {
+	var (
+		token        model.RefreshToken
+		expiresAtStr string
+	)
+
+	row := r.db.QueryRowContext(
+		ctx, "SELECT user_id, family, used, expires_at FROM RefreshTokens WHERE token_hash = ?", tokenHash,
+	)
+	err := row.Scan(&token.UserId, &token.Family, &token.Used, &expiresAtStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return token, ErrNotFound
+	}
+	if err != nil {
+		return token, err
+	}
+	token.ExpiresAt, err = time.Parse(layout, expiresAtStr)
+	return token, err
+}
+
+// mark refresh token as used, false is returned if it was already used
+func (r *Repository) UseRefreshToken(
+	ctx context.Context,
+	tokenHash string,
+) (bool, error) {
+	res, err := r.db.ExecContext(
+		ctx, "UPDATE RefreshTokens SET used = TRUE WHERE token_hash = ? AND used = FALSE", tokenHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// delete all refresh tokens of the login and its session
+func (r *Repository) DeleteRefreshFamily(
+	ctx context.Context,
+	family string,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM RefreshTokens WHERE family = ?", family); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM Sessions WHERE session_id = ?", family); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// delete all sessions and refresh tokens of the user and revoke access tokens issued before revokedAt
+func (r *Repository) RevokeSessions(
+	ctx context.Context,
+	userid types.UserId,
+	revokedAt time.Time,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx, "UPDATE User SET sessions_revoked_at = ? WHERE user_id = ?", revokedAt.UTC().Format(layout), userid,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM RefreshTokens WHERE user_id = ?", userid); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM Sessions WHERE user_id = ?", userid); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// columns of Sessions in the order of scanSessions
+const sessionColumns = "session_id, user_id, user_agent, ip, created_at, last_seen_at"
+
+func scanSessions(rows *sql.Rows) ([]model.Session, error) {
+	sessions := []model.Session{}
+	for rows.Next() {
+		var (
+			session       model.Session
+			createdAtStr  string
+			lastSeenAtStr string
+		)
+		err := rows.Scan(
+			&session.SessionId, &session.UserId, &session.UserAgent, &session.IP, &createdAtStr, &lastSeenAtStr,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if session.CreatedAt, err = time.Parse(layout, createdAtStr); err != nil {
+			return nil, err
+		}
+		if session.LastSeenAt, err = time.Parse(layout, lastSeenAtStr); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// save new login and its first refresh token
+func (r *Repository) CreateSession(
+	ctx context.Context,
+	session model.Session,
+	tokenHash string,
+	expiresAt time.Time,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO Sessions (session_id, user_id, user_agent, ip, created_at, last_seen_at) VALUES (?, ?, ?, ?, ?, ?)",
+		session.SessionId, session.UserId, session.UserAgent, session.IP,
+		session.CreatedAt.UTC().Format(layout), session.LastSeenAt.UTC().Format(layout),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO RefreshTokens (token_hash, user_id, family, expires_at) VALUES (?, ?, ?, ?)",
+		tokenHash, session.UserId, session.SessionId, expiresAt.UTC().Format(layout),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// outputs session by id, ErrNotFound is returned if it was revoked
+func (r *Repository) GetSession(
+	ctx context.Context,
+	sessionId string,
+) (model.Session, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+sessionColumns+" FROM Sessions WHERE session_id = ?", sessionId)
+	if err != nil {
+		return model.Session{}, err
+	}
+	defer rows.Close()
+
+	sessions, err := scanSessions(rows)
+	if err != nil {
+		return model.Session{}, err
+	}
+	if len(sessions) == 0 {
+		return model.Session{}, ErrNotFound
+	}
+	return sessions[0], nil
+}
+
+// outputs sessions of the user, recently used first
+func (r *Repository) GetSessions(
+	ctx context.Context,
+	userid types.UserId,
+) ([]model.Session, error) {
+	rows, err := r.db.QueryContext(
+		ctx, "SELECT "+sessionColumns+" FROM Sessions WHERE user_id = ? ORDER BY last_seen_at DESC", userid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSessions(rows)
+}
+
+// save last activity of sessions in one transaction, revoked sessions are skipped
+func (r *Repository) SetLastSeen(
+	ctx context.Context,
+	lastSeen map[string]time.Time,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "UPDATE Sessions SET last_seen_at = ? WHERE session_id = ? AND last_seen_at < ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for sessionId, at := range lastSeen {
+		at := at.UTC().Format(layout)
+		if _, err = stmt.ExecContext(ctx, at, sessionId, at); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// delete session of the user with its refresh tokens, ErrNotFound is returned
+// if there is no such session
+func (r *Repository) DeleteSession(
+	ctx context.Context,
+	userid types.UserId,
+	sessionId string,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM Sessions WHERE session_id = ? AND user_id = ?", sessionId, userid)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM RefreshTokens WHERE family = ?", sessionId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// columns of DataExports in the order of scanExports
+const exportColumns = "export_id, user_id, status, storage_path, created_at, expires_at"
+
+func scanExports(rows *sql.Rows) ([]model.Export, error) {
+	exports := []model.Export{}
+	for rows.Next() {
+		var (
+			export       model.Export
+			storagePath  sql.NullString
+			createdAtStr string
+			expiresAtStr sql.NullString
+		)
+		err := rows.Scan(&export.ExportId, &export.UserId, &export.Status, &storagePath, &createdAtStr, &expiresAtStr)
+		if err != nil {
+			return nil, err
+		}
+		export.StoragePath = storagePath.String
+		if export.CreatedAt, err = time.Parse(layout, createdAtStr); err != nil {
+			return nil, err
+		}
+		if expiresAtStr.Valid {
+			expiresAt, err := time.Parse(layout, expiresAtStr.String)
+			if err != nil {
+				return nil, err
+			}
+			export.ExpiresAt = &expiresAt
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
+
+// save pending data export of the user and output its id
+func (r *Repository) CreateExport(
+	ctx context.Context,
+	userid types.UserId,
+	createdAt time.Time,
+) (int, error) {
+	res, err := r.db.ExecContext(
+		ctx, "INSERT INTO DataExports (user_id, status, created_at) VALUES (?, ?, ?)",
+		userid, model.ExportPending, createdAt.UTC().Format(layout),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// mark data export as ready, archive is downloaded with the token until expiresAt
+func (r *Repository) CompleteExport(
+	ctx context.Context,
+	exportId int,
+	tokenHash string,
+	storagePath string,
+	expiresAt time.Time,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		"UPDATE DataExports SET status = ?, token_hash = ?, storage_path = ?, expires_at = ? WHERE export_id = ?",
+		model.ExportReady, tokenHash, storagePath, expiresAt.UTC().Format(layout), exportId,
+	)
+	return err
+}
+
+// mark data export as failed
+func (r *Repository) FailExport(
+	ctx context.Context,
+	exportId int,
+) error {
+	_, err := r.db.ExecContext(
+		ctx, "UPDATE DataExports SET status = ? WHERE export_id = ?", model.ExportFailed, exportId,
+	)
+	return err
+}
+
+// outputs data exports of the user, the latest first
+func (r *Repository) GetExports(
+	ctx context.Context,
+	userid types.UserId,
+) ([]model.Export, error) {
+	rows, err := r.db.QueryContext(
+		ctx, "SELECT "+exportColumns+" FROM DataExports WHERE user_id = ? ORDER BY export_id DESC", userid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanExports(rows)
+}
+
+// outputs ready data export by hash of its download token
+func (r *Repository) GetExportByToken(
+	ctx context.Context,
+	tokenHash string,
+) (model.Export, error) {
+	rows, err := r.db.QueryContext(
+		ctx, "SELECT "+exportColumns+" FROM DataExports WHERE token_hash = ?", tokenHash,
+	)
+	if err != nil {
+		return model.Export{}, err
+	}
+	defer rows.Close()
+
+	exports, err := scanExports(rows)
+	if err != nil {
+		return model.Export{}, err
+	}
+	if len(exports) == 0 {
+		return model.Export{}, ErrNotFound
+	}
+	return exports[0], nil
+}
+
+// outputs data exports that expired before the moment
+func (r *Repository) GetExpiredExports(
+	ctx context.Context,
+	before time.Time,
+) ([]model.Export, error) {
+	rows, err := r.db.QueryContext(
+		ctx, "SELECT "+exportColumns+" FROM DataExports WHERE expires_at < ?", before.UTC().Format(layout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanExports(rows)
+}
+
+// delete data export
+func (r *Repository) DeleteExport(
+	ctx context.Context,
+	exportId int,
+) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM DataExports WHERE export_id = ?", exportId)
+	return err
+}
+
+// columns of AccessTokens in the order of scanAccessTokens
+const accessTokenColumns = "token_id, user_id, name, scopes, created_at, expires_at, last_used_at"
+
+func scanAccessTokens(rows *sql.Rows) ([]model.AccessToken, error) {
+	tokens := []model.AccessToken{}
+	for rows.Next() {
+		var (
+			token         model.AccessToken
+			scopes        string
+			createdAtStr  string
+			expiresAtStr  string
+			lastUsedAtStr sql.NullString
+		)
+		err := rows.Scan(
+			&token.TokenId, &token.UserId, &token.Name, &scopes, &createdAtStr, &expiresAtStr, &lastUsedAtStr,
+		)
+		if err != nil {
+			return nil, err
+		}
+		token.Scopes = []types.Scope{}
+		for _, scope := range strings.Fields(scopes) {
+			token.Scopes = append(token.Scopes, types.Scope(scope))
+		}
+		if token.CreatedAt, err = time.Parse(layout, createdAtStr); err != nil {
+			return nil, err
+		}
+		if token.ExpiresAt, err = time.Parse(layout, expiresAtStr); err != nil {
+			return nil, err
+		}
+		if lastUsedAtStr.Valid {
+			lastUsedAt, err := time.Parse(layout, lastUsedAtStr.String)
+			if err != nil {
+				return nil, err
+			}
+			token.LastUsedAt = &lastUsedAt
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// save personal access token by its hash and output its id
+func (r *Repository) CreateAccessToken(
+	ctx context.Context,
+	token model.AccessToken,
+	tokenHash string,
+) (int, error) {
+	scopes := make([]string, len(token.Scopes))
+	for i, scope := range token.Scopes {
+		scopes[i] = string(scope)
+	}
+	res, err := r.db.ExecContext(
+		ctx,
+		"INSERT INTO AccessTokens (user_id, name, token_hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+		token.UserId, token.Name, tokenHash, strings.Join(scopes, " "),
+		token.CreatedAt.UTC().Format(layout), token.ExpiresAt.UTC().Format(layout),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// outputs personal access tokens of the user, the latest first
+func (r *Repository) GetAccessTokens(
+	ctx context.Context,
+	userid types.UserId,
+) ([]model.AccessToken, error) {
+	rows, err := r.db.QueryContext(
+		ctx, "SELECT "+accessTokenColumns+" FROM AccessTokens WHERE user_id = ? ORDER BY token_id DESC", userid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanAccessTokens(rows)
+}
+
+// delete personal access token of the user, ErrNotFound is returned if there is no such token
+func (r *Repository) DeleteAccessToken(
+	ctx context.Context,
+	userid types.UserId,
+	tokenId int,
+) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM AccessTokens WHERE token_id = ? AND user_id = ?", tokenId, userid)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// outputs two-factor authentication state of the user
+func (r *Repository) GetTOTP(
+	ctx context.Context,
+	userid types.UserId,
+) (model.TOTP, error) {
+	var (
+		totp   model.TOTP
+		secret sql.NullString
+	)
+
+	row := r.db.QueryRowContext(
+		ctx, "SELECT totp_secret, totp_enabled, totp_last_step FROM User WHERE user_id = ?", userid,
+	)
+	err := row.Scan(&secret, &totp.Enabled, &totp.LastStep)
+	if errors.Is(err, sql.ErrNoRows) {
+		return totp, ErrNotFound
+	}
+	totp.Secret = secret.String
+	return totp, err
+}
+
+// save secret of not yet confirmed enrollment
+func (r *Repository) SetTOTPSecret(
+	ctx context.Context,
+	userid types.UserId,
+	secret string,
+) error {
+	_, err := r.db.ExecContext(
+		ctx, "UPDATE User SET totp_secret = ? WHERE user_id = ? AND totp_enabled = FALSE", secret, userid,
+	)
+	return err
+}
+
+// replace recovery codes of the user inside transaction
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userid types.UserId, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM RecoveryCodes WHERE user_id = ?", userid); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		_, err := tx.ExecContext(
+			ctx, "INSERT INTO RecoveryCodes (user_id, code_hash) VALUES (?, ?)", userid, codeHash,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enable two-factor authentication with first accepted step and hashes of recovery codes
+func (r *Repository) EnableTOTP(
+	ctx context.Context,
+	userid types.UserId,
+	step int64,
+	codeHashes []string,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx, "UPDATE User SET totp_enabled = TRUE, totp_last_step = ? WHERE user_id = ?", step, userid,
+	)
+	if err != nil {
+		return err
+	}
+	if err = replaceRecoveryCodes(ctx, tx, userid, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// disable two-factor authentication and delete its secret and recovery codes
+func (r *Repository) DisableTOTP(
+	ctx context.Context,
+	userid types.UserId,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE User SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0 WHERE user_id = ?",
+		userid,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM RecoveryCodes WHERE user_id = ?", userid); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// save accepted step, ErrNotFound is returned if the same or later step was already used
+func (r *Repository) UseTOTPStep(
+	ctx context.Context,
+	userid types.UserId,
+	step int64,
+) error {
+	res, err := r.db.ExecContext(
+		ctx, "UPDATE User SET totp_last_step = ? WHERE user_id = ? AND totp_last_step < ?", step, userid, step,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// replace recovery codes of the user with new ones
+func (r *Repository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userid types.UserId,
+	codeHashes []string,
+) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = replaceRecoveryCodes(ctx, tx, userid, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// delete recovery code, ErrNotFound is returned if it is unknown or was already used
+func (r *Repository) UseRecoveryCode(
+	ctx context.Context,
+	userid types.UserId,
+	codeHash string,
+) error {
+	res, err := r.db.ExecContext(
+		ctx, "DELETE FROM RecoveryCodes WHERE user_id = ? AND code_hash = ?", userid, codeHash,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -5,8 +5,10 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/alexvishnevskiy/twitter-clone/internal/types"
 	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/go-cmp/cmp"
 	"testing"
+	"time"
 )
 
 func TestRepository_Register(t *testing.T) {
@@ -20,28 +22,36 @@ func TestRepository_Register(t *testing.T) {
 	ctx := context.Background()
 
 	user := model.User{
-		1,
-		"user",
-		"alex",
-		"ivanov",
-		"email",
-		"password",
+		UserId:    1,
+		Nickname:  "user",
+		FirstName: "alex",
+		LastName:  "ivanov",
+		Email:     "email",
+		Password:  "password",
 	}
 
 	mock.ExpectExec("INSERT INTO User").
-		WithArgs(user.Nickname, user.FirstName, user.LastName, user.Email, user.Password).
+		WithArgs(user.Nickname, "user", user.FirstName, user.LastName, user.Email, user.Password).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	// nickname is taken in canonical form
+	mock.ExpectExec("INSERT INTO User").
+		WithArgs("USER", "user", user.FirstName, user.LastName, user.Email, user.Password).
+		WillReturnError(&mysql.MySQLError{Number: duplicateEntry, Message: "Duplicate entry"})
 
-	_, err = repo.Register(ctx, user.Nickname, user.FirstName, user.LastName, user.Email, user.Password)
+	_, err = repo.Register(ctx, user.Nickname, "user", user.FirstName, user.LastName, user.Email, user.Password)
 	if err != nil {
 		t.Errorf("Error was not expecting while register: %s", err)
 	}
+	_, err = repo.Register(ctx, "USER", "user", user.FirstName, user.LastName, user.Email, user.Password)
+	if err != ErrDuplicate {
+		t.Errorf("expected ErrDuplicate, got: %v", err)
+	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
 
-func TestRepository_Delete(t *testing.T) {
+func TestRepository_Deactivation(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
@@ -50,13 +60,70 @@ func TestRepository_Delete(t *testing.T) {
 
 	repo := Repository{db}
 	ctx := context.Background()
+	deactivatedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	before := deactivatedAt.Add(time.Hour)
 
-	mock.ExpectExec("DELETE FROM User WHERE user_id = ?").
+	mock.ExpectExec("UPDATE User SET deactivated_at = \\? WHERE user_id = \\? AND deactivated_at IS NULL").
+		WithArgs("2023-01-02 03:04:05", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// already deactivated
+	mock.ExpectExec("UPDATE User SET deactivated_at").
+		WithArgs("2023-01-02 03:04:05", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT deactivated_at FROM User WHERE user_id = \\?").
 		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+		WillReturnRows(sqlmock.NewRows([]string{"deactivated_at"}).AddRow("2023-01-02 03:04:05"))
+	mock.ExpectExec("UPDATE User SET deactivated_at = NULL WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT user_id FROM User WHERE deactivated_at < \\?").
+		WithArgs("2023-01-02 04:04:05").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery("SELECT avatar_url FROM User .* UNION ALL SELECT header_url .* UNION ALL SELECT media_url FROM Tweets "+
+		".* UNION ALL SELECT storage_path FROM DataExports").
+		WithArgs(1, 1, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"url"}).AddRow("avatar.jpg").AddRow("tweet.jpg"))
+	mock.ExpectExec("DELETE FROM User WHERE user_id = \\? AND deactivated_at < \\?").
+		WithArgs(1, "2023-01-02 04:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// reactivated before purge
+	mock.ExpectExec("DELETE FROM User WHERE user_id = \\? AND deactivated_at < \\?").
+		WithArgs(2, "2023-01-02 04:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	if err = repo.Delete(ctx, types.UserId(1)); err != nil {
-		t.Errorf("error was not expected while deleting: %s", err)
+	if err = repo.Deactivate(ctx, types.UserId(1), deactivatedAt); err != nil {
+		t.Errorf("error was not expected while deactivating: %s", err)
+	}
+	if err = repo.Deactivate(ctx, types.UserId(1), deactivatedAt); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	got, err := repo.GetDeactivatedAt(ctx, types.UserId(1))
+	if err != nil || got == nil || !got.Equal(deactivatedAt) {
+		t.Errorf("wrong deactivation time %v, err: %v", got, err)
+	}
+	if err = repo.Reactivate(ctx, types.UserId(1)); err != nil {
+		t.Errorf("error was not expected while reactivating: %s", err)
+	}
+
+	userIds, err := repo.GetDeactivatedBefore(ctx, before)
+	if err != nil {
+		t.Errorf("error was not expected while getting deactivated users: %s", err)
+	}
+	if diff := cmp.Diff([]types.UserId{1}, userIds); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	urls, err := repo.GetMediaUrls(ctx, types.UserId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting media: %s", err)
+	}
+	if diff := cmp.Diff([]string{"avatar.jpg", "tweet.jpg"}, urls); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err = repo.Purge(ctx, types.UserId(1), before); err != nil {
+		t.Errorf("error was not expected while purging: %s", err)
+	}
+	if err = repo.Purge(ctx, types.UserId(2), before); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
 	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -90,6 +157,15 @@ func TestRepository_RetrievePassword(t *testing.T) {
 	if diff := cmp.Diff(password, retrievedPassword); diff != "" {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
+
+	// unknown email is reported with zero user
+	mock.ExpectQuery("SELECT user_id, password FROM User WHERE email = ?").
+		WithArgs("unknown@mail.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "password"}))
+	userId, _, err := repo.RetrievePassword(ctx, "unknown@mail.com")
+	if err != nil || userId != 0 {
+		t.Errorf("expected zero user for unknown email, got %d, %v", userId, err)
+	}
 }
 
 func TestRepository_Update(t *testing.T) {
@@ -102,15 +178,847 @@ func TestRepository_Update(t *testing.T) {
 	repo := Repository{db}
 	ctx := context.Background()
 
-	userData := model.User{UserId: 1, Email: "somemail@gmail.com"}
+	// values are parameters, so quotes can't break the query
+	nickname, canonical, email, version := "o'brien", "o'brlen", "somemail@gmail.com", 3
+	update := model.UserUpdate{Nickname: &nickname, NicknameCanonical: &canonical, Email: &email, Version: &version}
 
-	mock.ExpectExec("UPDATE User SET email = 'somemail@gmail.com' WHERE user_id = ?").
-		WithArgs(userData.UserId).
+	selectNickname := "SELECT nickname_canonical FROM User WHERE user_id = \\? FOR UPDATE"
+	query := "UPDATE User SET nickname = \\?, nickname_canonical = \\?, email = \\?, email_verified = FALSE, " +
+		"version = version \\+ 1 WHERE user_id = \\? AND version = \\?"
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectNickname).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"nickname_canonical"}).AddRow("alex"))
+	mock.ExpectExec(query).
+		WithArgs(nickname, canonical, email, 1, version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// old nickname is reserved
+	mock.ExpectExec("INSERT INTO NicknameHistory \\(user_id, nickname_canonical, changed_at\\) VALUES \\(\\?, \\?, \\?\\)").
+		WithArgs(1, "alex", sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	// changed by another request
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectNickname).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"nickname_canonical"}).AddRow(canonical))
+	mock.ExpectExec(query).
+		WithArgs(nickname, canonical, email, 1, version).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version FROM User WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
+	mock.ExpectRollback()
+	// unknown user
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectNickname).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"nickname_canonical"}))
+	mock.ExpectRollback()
+	// nickname is taken
+	mock.ExpectBegin()
+	mock.ExpectQuery(selectNickname).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"nickname_canonical"}).AddRow("alex"))
+	mock.ExpectExec(query).
+		WithArgs(nickname, canonical, email, 1, version).
+		WillReturnError(&mysql.MySQLError{Number: duplicateEntry, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+	// nickname isn't locked if it doesn't change
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE User SET email = \\?, email_verified = FALSE, version = version \\+ 1 WHERE user_id = \\? AND version = \\?").
+		WithArgs(email, 1, version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	got, err := repo.Update(ctx, types.UserId(1), update)
+	if err != nil || got != version+1 {
+		t.Errorf("wrong version %d, err: %v", got, err)
+	}
+	if _, err = repo.Update(ctx, types.UserId(1), update); err != ErrVersionConflict {
+		t.Errorf("expected ErrVersionConflict, got: %v", err)
+	}
+	if _, err = repo.Update(ctx, types.UserId(2), update); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err = repo.Update(ctx, types.UserId(1), update); err != ErrDuplicate {
+		t.Errorf("expected ErrDuplicate, got: %v", err)
+	}
+	if _, err = repo.Update(ctx, types.UserId(1), model.UserUpdate{Email: &email, Version: &version}); err != nil {
+		t.Errorf("error was not expected while updating email: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_NicknameHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+	since := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	owner := "SELECT user_id FROM NicknameHistory WHERE nickname_canonical = \\? AND changed_at > \\? " +
+		"ORDER BY history_id DESC LIMIT 1"
+	mock.ExpectQuery(owner).
+		WithArgs("alex", "2023-01-02 03:04:05").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+	mock.ExpectQuery(owner).
+		WithArgs("free", "2023-01-02 03:04:05").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM NicknameHistory WHERE user_id = \\? AND changed_at > \\?").
+		WithArgs(1, "2023-01-02 03:04:05").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec("DELETE FROM NicknameHistory WHERE changed_at < \\?").
+		WithArgs("2023-01-02 03:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	if userId, err := repo.GetNicknameOwner(ctx, "alex", since); err != nil || userId != 1 {
+		t.Errorf("wrong owner %d, err: %v", userId, err)
+	}
+	if userId, err := repo.GetNicknameOwner(ctx, "free", since); err != nil || userId != 0 {
+		t.Errorf("nickname should be free, got owner %d, err: %v", userId, err)
+	}
+	if count, err := repo.CountNicknameChanges(ctx, types.UserId(1), since); err != nil || count != 2 {
+		t.Errorf("wrong count %d, err: %v", count, err)
+	}
+	if err = repo.PurgeNicknameHistory(ctx, since); err != nil {
+		t.Errorf("error was not expected while purging history: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_GetAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+
+	columns := []string{"user_id", "nickname", "first_name", "last_name", "email", "email_verified", "version"}
+	mock.ExpectQuery("SELECT user_id, nickname, first_name, last_name, email, email_verified, version FROM User WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "alex", "Alex", "V", "alex@mail.com", true, 2))
+	mock.ExpectQuery("SELECT user_id, nickname").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	account, err := repo.GetAccount(ctx, types.UserId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting account: %s", err)
+	}
+	want := model.Account{
+		UserId: 1, Nickname: "alex", FirstName: "Alex", LastName: "V", Email: "alex@mail.com", EmailVerified: true, Version: 2,
+	}
+	if diff := cmp.Diff(want, account); diff != "" {
+		t.Errorf("wrong account (-want +got):\n%s", diff)
+	}
+	if _, err = repo.GetAccount(ctx, types.UserId(2)); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_Moderation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+	suspendedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectExec("UPDATE User SET role = \\? WHERE user_id = \\?").
+		WithArgs(types.RoleModerator, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT role FROM User WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("moderator"))
+	mock.ExpectQuery("SELECT role FROM User WHERE user_id = \\?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}))
+	mock.ExpectExec("UPDATE User SET suspended_at = \\? WHERE user_id = \\? AND suspended_at IS NULL").
+		WithArgs("2023-01-02 03:04:05", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// already suspended
+	mock.ExpectExec("UPDATE User SET suspended_at = \\?").
+		WithArgs("2023-01-02 03:04:05", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT suspended_at IS NOT NULL FROM User WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"suspended"}).AddRow(true))
+	mock.ExpectExec("UPDATE User SET suspended_at = NULL WHERE user_id = \\? AND suspended_at IS NOT NULL").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err = repo.SetRole(ctx, types.UserId(1), types.RoleModerator); err != nil {
+		t.Errorf("error was not expected while setting role: %s", err)
+	}
+	if role, err := repo.GetRole(ctx, types.UserId(1)); err != nil || role != types.RoleModerator {
+		t.Errorf("wrong role %q, err: %v", role, err)
+	}
+	if _, err = repo.GetRole(ctx, types.UserId(2)); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err = repo.Suspend(ctx, types.UserId(1), suspendedAt); err != nil {
+		t.Errorf("error was not expected while suspending: %s", err)
+	}
+	if err = repo.Suspend(ctx, types.UserId(1), suspendedAt); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if suspended, err := repo.IsSuspended(ctx, types.UserId(1)); err != nil || !suspended {
+		t.Errorf("user should be suspended, err: %v", err)
+	}
+	if err = repo.Unsuspend(ctx, types.UserId(1)); err != nil {
+		t.Errorf("error was not expected while unsuspending: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_PreferredLanguages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM UserLanguages WHERE user_id = ?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO UserLanguages").
+		WithArgs(1, "en").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO UserLanguages").
+		WithArgs(1, "fr").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT lang FROM UserLanguages WHERE user_id = ?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"lang"}).AddRow("en").AddRow("fr"))
+
+	langs := []string{"en", "fr"}
+	if err = repo.SetPreferredLanguages(ctx, types.UserId(1), langs); err != nil {
+		t.Errorf("error was not expected while setting languages: %s", err)
+	}
+	retrieved, err := repo.GetPreferredLanguages(ctx, types.UserId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting languages: %s", err)
+	}
+	if diff := cmp.Diff(langs, retrieved); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_GetUsers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
 
-	err = repo.Update(ctx, userData)
+	columns := []string{
+		"user_id", "nickname", "first_name", "last_name", "bio", "location", "website", "birthday",
+		"avatar_url", "header_url", "protected",
+	}
+	selectQuery := "^SELECT user_id, nickname, first_name, last_name, bio, location, website, birthday, " +
+		"avatar_url, header_url, protected FROM User WHERE "
+	mock.ExpectQuery(selectQuery+"user_id IN \\(\\?, \\?\\) AND deactivated_at IS NULL AND suspended_at IS NULL$").
+		WithArgs(1, 2).
+		WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow(1, "alex", "Alex", "V", "hi", "Moscow", "", "2000-01-02", "avatar.jpg", nil, false).
+				AddRow(2, "bob", "Bob", "B", "", "", "", nil, nil, nil, true),
+		)
+	mock.ExpectQuery(selectQuery + "nickname_canonical = \\? AND deactivated_at IS NULL AND suspended_at IS NULL$").
+		WithArgs("alex").
+		WillReturnRows(
+			sqlmock.NewRows(columns).AddRow(1, "alex", "Alex", "V", "hi", "Moscow", "", "2000-01-02", "avatar.jpg", nil, false),
+		)
+	mock.ExpectQuery(selectQuery + "user_id = \\? AND deactivated_at IS NULL AND suspended_at IS NULL$").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery("^SELECT user_id, nickname, first_name, last_name, bio, location, website, birthday, " +
+		"avatar_url, header_url, protected FROM User WHERE deactivated_at IS NULL AND suspended_at IS NULL$").
+		WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow(1, "alex", "Alex", "V", "hi", "Moscow", "", "2000-01-02", "avatar.jpg", nil, false).
+				AddRow(2, "bob", "Bob", "B", "", "", "", nil, nil, nil, true),
+		)
+
+	birthday, avatar := "2000-01-02", "avatar.jpg"
+	want := []model.Profile{
+		{
+			UserId: 1, Nickname: "alex", FirstName: "Alex", LastName: "V", Bio: "hi", Location: "Moscow",
+			Birthday: &birthday, AvatarUrl: &avatar,
+		},
+		{UserId: 2, Nickname: "bob", FirstName: "Bob", LastName: "B", Protected: true},
+	}
+	profiles, err := repo.GetUsers(ctx, types.UserId(1), types.UserId(2))
+	if err != nil {
+		t.Errorf("error was not expected while getting users: %s", err)
+	}
+	if diff := cmp.Diff(want, profiles); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	profile, err := repo.GetByNickname(ctx, "alex")
+	if err != nil {
+		t.Errorf("error was not expected while getting user: %s", err)
+	}
+	if diff := cmp.Diff(want[0], profile); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err = repo.GetById(ctx, types.UserId(3)); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+
+	all, err := repo.GetAllUsers(ctx)
+	if err != nil {
+		t.Errorf("error was not expected while getting all users: %s", err)
+	}
+	if diff := cmp.Diff(want, all); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_UpdateProfile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+
+	mock.ExpectExec("^UPDATE User SET bio = \\?, birthday = \\? WHERE user_id = \\?$").
+		WithArgs("it's me", nil, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("^UPDATE User SET header_url = \\? WHERE user_id = \\?$").
+		WithArgs("header.jpg", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	bio, birthday := "it's me", ""
+	err = repo.UpdateProfile(ctx, types.UserId(1), model.ProfileUpdate{Bio: &bio, Birthday: &birthday})
+	if err != nil {
+		t.Errorf("error was not expected while updating profile: %s", err)
+	}
+	if err = repo.UpdateImage(ctx, types.UserId(1), model.ImageHeader, "header.jpg"); err != nil {
+		t.Errorf("error was not expected while updating image: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_EmailVerified(t *testing.T) {
+	db, mock, err := sqlmock.New()
 	if err != nil {
-		t.Errorf("Error updating data table: %s", err)
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+
+	mock.ExpectExec("^UPDATE User SET email_verified = \\? WHERE user_id = \\?$").
+		WithArgs(true, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("^SELECT email, email_verified FROM User WHERE user_id = \\?$").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "email_verified"}).AddRow("alex@mail.com", true))
+	mock.ExpectQuery("^SELECT email, email_verified FROM User WHERE user_id = \\?$").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "email_verified"}))
+
+	if err = repo.SetEmailVerified(ctx, types.UserId(1), true); err != nil {
+		t.Errorf("error was not expected while verifying email: %s", err)
+	}
+	email, verified, err := repo.GetEmail(ctx, types.UserId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting email: %s", err)
+	}
+	if email != "alex@mail.com" || !verified {
+		t.Errorf("wrong email: got %s %v", email, verified)
+	}
+	if _, _, err = repo.GetEmail(ctx, types.UserId(2)); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_PasswordReset(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+	expiresAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO PasswordResets").
+		WithArgs("hash", 1, "2023-01-02 03:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// token is deleted when it is used
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT user_id, expires_at FROM PasswordResets WHERE token_hash = \\? FOR UPDATE").
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "expires_at"}).AddRow(1, "2023-01-02 03:04:05"))
+	mock.ExpectExec("DELETE FROM PasswordResets WHERE token_hash = \\?").
+		WithArgs("hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT user_id, expires_at FROM PasswordResets WHERE token_hash = \\? FOR UPDATE").
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "expires_at"}))
+	mock.ExpectRollback()
+	// new password revokes sessions and other tokens
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE User SET password = \\?, sessions_revoked_at = \\? WHERE user_id = \\?").
+		WithArgs("password", "2023-01-02 03:04:05", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM PasswordResets WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM RefreshTokens WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM Sessions WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT sessions_revoked_at FROM User WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"sessions_revoked_at"}).AddRow("2023-01-02 03:04:05"))
+
+	if err = repo.PutPasswordReset(ctx, types.UserId(1), "hash", expiresAt); err != nil {
+		t.Errorf("error was not expected while saving token: %s", err)
+	}
+	userId, expires, err := repo.ConsumePasswordReset(ctx, "hash")
+	if err != nil {
+		t.Errorf("error was not expected while using token: %s", err)
+	}
+	if userId != 1 || !expires.Equal(expiresAt) {
+		t.Errorf("wrong token: got %d %v", userId, expires)
+	}
+	if _, _, err = repo.ConsumePasswordReset(ctx, "hash"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for used token, got: %v", err)
+	}
+	if err = repo.ResetPassword(ctx, types.UserId(1), "password", expiresAt); err != nil {
+		t.Errorf("error was not expected while resetting password: %s", err)
+	}
+	revokedAt, err := repo.GetSessionsRevokedAt(ctx, types.UserId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting revocation time: %s", err)
+	}
+	if revokedAt == nil || !revokedAt.Equal(expiresAt) {
+		t.Errorf("wrong revocation time: got %v", revokedAt)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_RefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+	expiresAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectExec("INSERT INTO RefreshTokens").
+		WithArgs("hash", 1, "family", "2023-01-02 03:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT user_id, family, used, expires_at FROM RefreshTokens WHERE token_hash = \\?").
+		WithArgs("hash").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"user_id", "family", "used", "expires_at"}).
+				AddRow(1, "family", false, "2023-01-02 03:04:05"),
+		)
+	// second use of the token doesn't update anything
+	mock.ExpectExec("UPDATE RefreshTokens SET used = TRUE WHERE token_hash = \\? AND used = FALSE").
+		WithArgs("hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE RefreshTokens SET used = TRUE WHERE token_hash = \\? AND used = FALSE").
+		WithArgs("hash").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM RefreshTokens WHERE family = \\?").
+		WithArgs("family").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM Sessions WHERE session_id = \\?").
+		WithArgs("family").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err = repo.PutRefreshToken(ctx, types.UserId(1), "hash", "family", expiresAt); err != nil {
+		t.Errorf("error was not expected while saving token: %s", err)
+	}
+	token, err := repo.GetRefreshToken(ctx, "hash")
+	if err != nil {
+		t.Errorf("error was not expected while getting token: %s", err)
+	}
+	want := model.RefreshToken{UserId: 1, Family: "family", ExpiresAt: expiresAt}
+	if diff := cmp.Diff(want, token); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	used, err := repo.UseRefreshToken(ctx, "hash")
+	if err != nil || !used {
+		t.Errorf("token should be used, got: %v, %v", used, err)
+	}
+	used, err = repo.UseRefreshToken(ctx, "hash")
+	if err != nil || used {
+		t.Errorf("used token should not be used again, got: %v, %v", used, err)
+	}
+	if err = repo.DeleteRefreshFamily(ctx, "family"); err != nil {
+		t.Errorf("error was not expected while deleting tokens: %s", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_Sessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	session := model.Session{
+		SessionId: "session", UserId: 1, UserAgent: "curl", IP: "127.0.0.1", CreatedAt: createdAt, LastSeenAt: createdAt,
+	}
+	columns := []string{"session_id", "user_id", "user_agent", "ip", "created_at", "last_seen_at"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO Sessions").
+		WithArgs("session", 1, "curl", "127.0.0.1", "2023-01-02 03:04:05", "2023-01-02 03:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO RefreshTokens").
+		WithArgs("hash", 1, "session", "2023-02-01 03:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT .* FROM Sessions WHERE session_id = \\?").
+		WithArgs("session").
+		WillReturnRows(
+			sqlmock.NewRows(columns).AddRow("session", 1, "curl", "127.0.0.1", "2023-01-02 03:04:05", "2023-01-02 03:04:05"),
+		)
+	mock.ExpectQuery("SELECT .* FROM Sessions WHERE session_id = \\?").
+		WithArgs("revoked").
+		WillReturnRows(sqlmock.NewRows(columns))
+	// older activity doesn't overwrite newer one
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE Sessions SET last_seen_at = \\? WHERE session_id = \\? AND last_seen_at < \\?").
+		ExpectExec().
+		WithArgs("2023-01-02 04:04:05", "session", "2023-01-02 04:04:05").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM Sessions WHERE session_id = \\? AND user_id = \\?").
+		WithArgs("session", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM RefreshTokens WHERE family = \\?").
+		WithArgs("session").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// session of another user
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM Sessions WHERE session_id = \\? AND user_id = \\?").
+		WithArgs("session", 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if err = repo.CreateSession(ctx, session, "hash", createdAt.Add(30*24*time.Hour)); err != nil {
+		t.Errorf("error was not expected while creating session: %s", err)
+	}
+	got, err := repo.GetSession(ctx, "session")
+	if err != nil {
+		t.Errorf("error was not expected while getting session: %s", err)
+	}
+	if diff := cmp.Diff(session, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if _, err = repo.GetSession(ctx, "revoked"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err = repo.SetLastSeen(ctx, map[string]time.Time{"session": createdAt.Add(time.Hour)}); err != nil {
+		t.Errorf("error was not expected while saving last seen: %s", err)
+	}
+	if err = repo.DeleteSession(ctx, types.UserId(1), "session"); err != nil {
+		t.Errorf("error was not expected while deleting session: %s", err)
+	}
+	if err = repo.DeleteSession(ctx, types.UserId(2), "session"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_Exports(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	expiresAt := createdAt.Add(7 * 24 * time.Hour)
+	columns := []string{"export_id", "user_id", "status", "storage_path", "created_at", "expires_at"}
+
+	mock.ExpectExec("INSERT INTO DataExports").
+		WithArgs(1, model.ExportPending, "2023-01-02 03:04:05").
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec("UPDATE DataExports SET status = \\?, token_hash = \\?, storage_path = \\?, expires_at = \\? WHERE export_id = \\?").
+		WithArgs(model.ExportReady, "hash", "export.zip", "2023-01-09 03:04:05", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT .* FROM DataExports WHERE token_hash = \\?").
+		WithArgs("hash").
+		WillReturnRows(
+			sqlmock.NewRows(columns).AddRow(7, 1, "ready", "export.zip", "2023-01-02 03:04:05", "2023-01-09 03:04:05"),
+		)
+	mock.ExpectQuery("SELECT .* FROM DataExports WHERE token_hash = \\?").
+		WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery("SELECT .* FROM DataExports WHERE user_id = \\? ORDER BY export_id DESC").
+		WithArgs(1).
+		WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow(8, 1, "pending", nil, "2023-01-03 03:04:05", nil).
+				AddRow(7, 1, "ready", "export.zip", "2023-01-02 03:04:05", "2023-01-09 03:04:05"),
+		)
+
+	id, err := repo.CreateExport(ctx, types.UserId(1), createdAt)
+	if err != nil || id != 7 {
+		t.Errorf("wrong export id %d, err: %v", id, err)
+	}
+	if err = repo.CompleteExport(ctx, id, "hash", "export.zip", expiresAt); err != nil {
+		t.Errorf("error was not expected while completing export: %s", err)
+	}
+	export, err := repo.GetExportByToken(ctx, "hash")
+	if err != nil {
+		t.Errorf("error was not expected while getting export: %s", err)
+	}
+	ready := model.Export{
+		ExportId: 7, UserId: 1, Status: model.ExportReady, CreatedAt: createdAt, ExpiresAt: &expiresAt, StoragePath: "export.zip",
+	}
+	if diff := cmp.Diff(ready, export); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if _, err = repo.GetExportByToken(ctx, "unknown"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	exports, err := repo.GetExports(ctx, types.UserId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting exports: %s", err)
+	}
+	want := []model.Export{
+		{ExportId: 8, UserId: 1, Status: model.ExportPending, CreatedAt: createdAt.Add(24 * time.Hour)},
+		ready,
+	}
+	if diff := cmp.Diff(want, exports); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_AccessTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	expiresAt := createdAt.Add(30 * 24 * time.Hour)
+	lastUsedAt := createdAt.Add(time.Hour)
+	columns := []string{"token_id", "user_id", "name", "scopes", "created_at", "expires_at", "last_used_at"}
+
+	mock.ExpectExec("INSERT INTO AccessTokens").
+		WithArgs(1, "bot", "hash", "read tweets:write", "2023-01-02 03:04:05", "2023-02-01 03:04:05").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectQuery("SELECT .* FROM AccessTokens WHERE user_id = \\? ORDER BY token_id DESC").
+		WithArgs(1).
+		WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow(3, 1, "bot", "read tweets:write", "2023-01-02 03:04:05", "2023-02-01 03:04:05", "2023-01-02 04:04:05").
+				AddRow(2, 1, "script", "likes:write", "2023-01-02 03:04:05", "2023-02-01 03:04:05", nil),
+		)
+	mock.ExpectExec("DELETE FROM AccessTokens WHERE token_id = \\? AND user_id = \\?").
+		WithArgs(3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM AccessTokens WHERE token_id = \\? AND user_id = \\?").
+		WithArgs(3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	bot := model.AccessToken{
+		UserId:    1,
+		Name:      "bot",
+		Scopes:    []types.Scope{types.ScopeRead, types.ScopeTweetsWrite},
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	}
+	id, err := repo.CreateAccessToken(ctx, bot, "hash")
+	if err != nil || id != 3 {
+		t.Errorf("wrong token id %d, err: %v", id, err)
+	}
+	tokens, err := repo.GetAccessTokens(ctx, types.UserId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting tokens: %s", err)
+	}
+	bot.TokenId, bot.LastUsedAt = 3, &lastUsedAt
+	want := []model.AccessToken{
+		bot,
+		{
+			TokenId: 2, UserId: 1, Name: "script", Scopes: []types.Scope{types.ScopeLikesWrite},
+			CreatedAt: createdAt, ExpiresAt: expiresAt,
+		},
+	}
+	if diff := cmp.Diff(want, tokens); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err = repo.DeleteAccessToken(ctx, types.UserId(1), 3); err != nil {
+		t.Errorf("error was not expected while deleting token: %s", err)
+	}
+	// token of another user
+	if err = repo.DeleteAccessToken(ctx, types.UserId(2), 3); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRepository_TOTP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := Repository{db}
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT totp_secret, totp_enabled, totp_last_step FROM User WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"totp_secret", "totp_enabled", "totp_last_step"}).AddRow(nil, false, 0),
+		)
+	mock.ExpectExec("UPDATE User SET totp_secret = \\? WHERE user_id = \\? AND totp_enabled = FALSE").
+		WithArgs("SECRET", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE User SET totp_enabled = TRUE, totp_last_step = \\? WHERE user_id = \\?").
+		WithArgs(100, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM RecoveryCodes WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO RecoveryCodes").
+		WithArgs(1, "hash1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO RecoveryCodes").
+		WithArgs(1, "hash2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	// code of the same step can't be used twice
+	mock.ExpectExec("UPDATE User SET totp_last_step = \\? WHERE user_id = \\? AND totp_last_step < \\?").
+		WithArgs(101, 1, 101).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE User SET totp_last_step = \\? WHERE user_id = \\? AND totp_last_step < \\?").
+		WithArgs(101, 1, 101).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// recovery code can be used once
+	mock.ExpectExec("DELETE FROM RecoveryCodes WHERE user_id = \\? AND code_hash = \\?").
+		WithArgs(1, "hash1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM RecoveryCodes WHERE user_id = \\? AND code_hash = \\?").
+		WithArgs(1, "hash1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE User SET totp_secret = NULL, totp_enabled = FALSE, totp_last_step = 0 WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM RecoveryCodes WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	totp, err := repo.GetTOTP(ctx, types.UserId(1))
+	if err != nil {
+		t.Errorf("error was not expected while getting totp: %s", err)
+	}
+	if diff := cmp.Diff(model.TOTP{}, totp); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if err = repo.SetTOTPSecret(ctx, types.UserId(1), "SECRET"); err != nil {
+		t.Errorf("error was not expected while setting secret: %s", err)
+	}
+	if err = repo.EnableTOTP(ctx, types.UserId(1), 100, []string{"hash1", "hash2"}); err != nil {
+		t.Errorf("error was not expected while enabling totp: %s", err)
+	}
+	if err = repo.UseTOTPStep(ctx, types.UserId(1), 101); err != nil {
+		t.Errorf("error was not expected while using step: %s", err)
+	}
+	if err = repo.UseTOTPStep(ctx, types.UserId(1), 101); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for used step, got: %v", err)
+	}
+	if err = repo.UseRecoveryCode(ctx, types.UserId(1), "hash1"); err != nil {
+		t.Errorf("error was not expected while using recovery code: %s", err)
+	}
+	if err = repo.UseRecoveryCode(ctx, types.UserId(1), "hash1"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for used recovery code, got: %v", err)
+	}
+	if err = repo.DisableTOTP(ctx, types.UserId(1)); err != nil {
+		t.Errorf("error was not expected while disabling totp: %s", err)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("there were unfulfilled expectations: %s", err)
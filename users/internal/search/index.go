@@ -0,0 +1,134 @@
+package search
+
+import (
+	"github.com/alexvishnevskiy/twitter-clone/internal/cache/local"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Index finds users by prefix of nickname or name, case-insensitively
+type Index struct {
+	mu   sync.RWMutex
+	trie *local.Trie[types.UserId]
+	// indexed keys of every user, used to remove them
+	keys map[types.UserId][]string
+	// follower counts rank matches, they are refreshed by SetFollowers
+	followers map[types.UserId]int
+}
+
+func New() *Index {
+	return &Index{
+		trie:      local.NewTrie[types.UserId](),
+		keys:      make(map[types.UserId][]string),
+		followers: make(map[types.UserId]int),
+	}
+}
+
+// keys of the profile, full name lets users type "first last"
+func profileKeys(profile model.Profile) []string {
+	names := []string{
+		profile.Nickname,
+		profile.FirstName,
+		profile.LastName,
+		profile.FirstName + " " + profile.LastName,
+	}
+	var keys []string
+	for _, name := range names {
+		if key := strings.ToLower(strings.TrimSpace(name)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Put indexes the user, previous names of the user are replaced
+func (idx *Index) Put(profile model.Profile) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// follower count is kept when names change
+	followers, ok := idx.followers[profile.UserId]
+	idx.remove(profile.UserId)
+	keys := profileKeys(profile)
+	for _, key := range keys {
+		idx.trie.Insert(key, profile.UserId)
+	}
+	idx.keys[profile.UserId] = keys
+	if ok {
+		idx.followers[profile.UserId] = followers
+	}
+}
+
+// Remove deletes the user from index
+func (idx *Index) Remove(userid types.UserId) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(userid)
+}
+
+func (idx *Index) remove(userid types.UserId) {
+	for _, key := range idx.keys[userid] {
+		idx.trie.Delete(key, userid)
+	}
+	delete(idx.keys, userid)
+	delete(idx.followers, userid)
+}
+
+// Users returns ids of every indexed user
+func (idx *Index) Users() []types.UserId {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	userIds := make([]types.UserId, 0, len(idx.keys))
+	for userid := range idx.keys {
+		userIds = append(userIds, userid)
+	}
+	return userIds
+}
+
+// SetFollowers updates follower counts of indexed users
+func (idx *Index) SetFollowers(counts map[types.UserId]int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for userid, count := range counts {
+		if _, ok := idx.keys[userid]; ok {
+			idx.followers[userid] = count
+		}
+	}
+}
+
+// Search returns up to limit users whose nickname or name starts with prefix, every match
+// is ranked, users with more followers go first, then users with shorter matching names
+func (idx *Index) Search(prefix string, limit int) []types.UserId {
+	// trailing space is kept, "first " matches only full names
+	prefix = strings.ToLower(strings.TrimLeft(prefix, " "))
+	if strings.TrimSpace(prefix) == "" || limit <= 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var userIds []types.UserId
+	seen := make(map[types.UserId]bool)
+	idx.trie.Walk(
+		prefix, func(_ string, userid types.UserId) bool {
+			if !seen[userid] {
+				seen[userid] = true
+				userIds = append(userIds, userid)
+			}
+			return true
+		},
+	)
+	sort.SliceStable(
+		userIds, func(i, j int) bool { return idx.followers[userIds[i]] > idx.followers[userIds[j]] },
+	)
+	if len(userIds) > limit {
+		userIds = userIds[:limit]
+	}
+	return userIds
+}
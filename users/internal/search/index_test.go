@@ -0,0 +1,75 @@
+package search
+
+import (
+	"fmt"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"github.com/alexvishnevskiy/twitter-clone/users/pkg/model"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	idx := New()
+	idx.Put(model.Profile{UserId: 1, Nickname: "alex", FirstName: "Alexander", LastName: "Smith"})
+	idx.Put(model.Profile{UserId: 2, Nickname: "Alexis", FirstName: "Bob", LastName: "Alexeev"})
+	idx.Put(model.Profile{UserId: 3, Nickname: "bob", FirstName: "Bob", LastName: "Brown"})
+
+	tests := []struct {
+		prefix string
+		limit  int
+		want   []types.UserId
+	}{
+		{"ALE", 10, []types.UserId{1, 2}},
+		{"ale", 1, []types.UserId{1}},
+		{"bob b", 10, []types.UserId{3}},
+		{"bob a", 10, []types.UserId{2}},
+		{"smith", 10, []types.UserId{1}},
+		{"", 10, nil},
+		{"carl", 10, nil},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, idx.Search(tt.prefix, tt.limit)); diff != "" {
+			t.Errorf("search %q mismatch (-want +got):\n%s", tt.prefix, diff)
+		}
+	}
+
+	// old names are not found after update or delete
+	idx.Put(model.Profile{UserId: 1, Nickname: "sasha", FirstName: "Sasha", LastName: "Smith"})
+	if diff := cmp.Diff([]types.UserId{2}, idx.Search("alex", 10)); diff != "" {
+		t.Errorf("old nickname should be removed (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]types.UserId{1}, idx.Search("sash", 10)); diff != "" {
+		t.Errorf("new nickname should be found (-want +got):\n%s", diff)
+	}
+	idx.Remove(2)
+	if got := idx.Search("alex", 10); len(got) != 0 {
+		t.Errorf("deleted user should be removed, got %v", got)
+	}
+}
+
+func TestIndex_Followers(t *testing.T) {
+	idx := New()
+	// popular user has the longest name and is walked last
+	for i := 1; i <= 300; i++ {
+		idx.Put(model.Profile{UserId: types.UserId(i), Nickname: fmt.Sprintf("al%03d", i)})
+	}
+	idx.Put(model.Profile{UserId: 1000, Nickname: "alzzzzzzzzzz"})
+	idx.SetFollowers(map[types.UserId]int{1000: 500, 2: 10, 4000: 1})
+
+	if diff := cmp.Diff([]types.UserId{1000, 2, 1}, idx.Search("al", 3)); diff != "" {
+		t.Errorf("popular users should go first (-want +got):\n%s", diff)
+	}
+	if got := len(idx.Users()); got != 301 {
+		t.Errorf("expected 301 indexed users, got %d", got)
+	}
+	// count is kept when names change, but forgotten for removed users
+	idx.Put(model.Profile{UserId: 1000, Nickname: "alyyyyyyyyyy"})
+	if diff := cmp.Diff([]types.UserId{1000}, idx.Search("al", 1)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	idx.Remove(1000)
+	idx.Put(model.Profile{UserId: 1000, Nickname: "alzzzzzzzzzz"})
+	if diff := cmp.Diff([]types.UserId{2, 1}, idx.Search("al", 2)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
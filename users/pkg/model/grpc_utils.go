@@ -0,0 +1,60 @@
+package model
+
+import (
+	gen "github.com/alexvishnevskiy/twitter-clone/gen/api/users"
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+)
+
+// ProfileToProto converts a Profile struct into a
+// generated proto counterpart.
+func ProfileToProto(p *Profile) *gen.Profile {
+	return &gen.Profile{
+		UserId:         int32(p.UserId),
+		Nickname:       p.Nickname,
+		FirstName:      p.FirstName,
+		LastName:       p.LastName,
+		Bio:            p.Bio,
+		Location:       p.Location,
+		Website:        p.Website,
+		Birthday:       stringValue(p.Birthday),
+		AvatarUrl:      stringValue(p.AvatarUrl),
+		HeaderUrl:      stringValue(p.HeaderUrl),
+		Protected:      p.Protected,
+		RedirectedFrom: p.RedirectedFrom,
+	}
+}
+
+// ProfileFromProto converts a proto struct into a
+// profile counterpart.
+func ProfileFromProto(p *gen.Profile) *Profile {
+	return &Profile{
+		UserId:         types.UserId(p.UserId),
+		Nickname:       p.Nickname,
+		FirstName:      p.FirstName,
+		LastName:       p.LastName,
+		Bio:            p.Bio,
+		Location:       p.Location,
+		Website:        p.Website,
+		Birthday:       stringPointer(p.Birthday),
+		AvatarUrl:      stringPointer(p.AvatarUrl),
+		HeaderUrl:      stringPointer(p.HeaderUrl),
+		Protected:      p.Protected,
+		RedirectedFrom: p.RedirectedFrom,
+	}
+}
+
+// helper function for optional proto strings
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// helper function for optional proto strings
+func stringPointer(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -1,6 +1,9 @@
 package model
 
-import "github.com/alexvishnevskiy/twitter-clone/internal/types"
+import (
+	"github.com/alexvishnevskiy/twitter-clone/internal/types"
+	"time"
+)
 
 type User struct {
 	UserId    types.UserId `json:"user_id"`
@@ -9,4 +12,171 @@ type User struct {
 	LastName  string       `json:"last_name"`
 	Email     string       `json:"email"`
 	Password  string       `json:"password"`
+	// changed only by admins, see SetRole
+	Role types.Role `json:"role"`
+}
+
+// editable account fields, nil fields are left unchanged. Version is the version
+// of the account the update is based on, update of a newer account is rejected
+type UserUpdate struct {
+	Nickname  *string `json:"nickname"`
+	FirstName *string `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Email     *string `json:"email"`
+	Password  *string `json:"password"`
+	Version   *int    `json:"version"`
+	// canonical form of the new nickname, set by controller
+	NicknameCanonical *string `json:"-"`
+}
+
+// private account of the user, version changes with every update
+type Account struct {
+	UserId        types.UserId `json:"user_id"`
+	Nickname      string       `json:"nickname"`
+	FirstName     string       `json:"first_name"`
+	LastName      string       `json:"last_name"`
+	Email         string       `json:"email"`
+	EmailVerified bool         `json:"email_verified"`
+	Version       int          `json:"version"`
+}
+
+// limits of account fields, same as columns of User table
+const (
+	MaxNicknameLength  = 15
+	MaxFirstNameLength = 10
+	MaxLastNameLength  = 15
+	MaxEmailLength     = 20
+)
+
+// public part of the user, never contains email or password
+type Profile struct {
+	UserId    types.UserId `json:"user_id"`
+	Nickname  string       `json:"nickname"`
+	FirstName string       `json:"first_name"`
+	LastName  string       `json:"last_name"`
+	Bio       string       `json:"bio"`
+	Location  string       `json:"location"`
+	Website   string       `json:"website"`
+	// birthday in YYYY-MM-DD format
+	Birthday  *string `json:"birthday"`
+	AvatarUrl *string `json:"avatar_url"`
+	HeaderUrl *string `json:"header_url"`
+	// tweets are visible only to approved followers
+	Protected bool `json:"protected"`
+	// old nickname the profile was looked up by, clients should use the current one
+	RedirectedFrom string `json:"redirected_from,omitempty"`
+}
+
+// editable profile fields, nil fields are left unchanged
+type ProfileUpdate struct {
+	Bio      *string `json:"bio"`
+	Location *string `json:"location"`
+	Website  *string `json:"website"`
+	Birthday *string `json:"birthday"`
+}
+
+// limits of profile fields
+const (
+	MaxBioLength      = 160
+	MaxLocationLength = 30
+	MaxWebsiteLength  = 100
+	// layout of birthday
+	BirthdayLayout = "2006-01-02"
+)
+
+// kind of profile image
+type ImageKind string
+
+const (
+	ImageAvatar ImageKind = "avatar"
+	ImageHeader ImageKind = "header"
+)
+
+// Size returns width and height profile image is cropped to
+func (k ImageKind) Size() (int, int) {
+	switch k {
+	case ImageHeader:
+		return 1500, 500
+	default:
+		return 400, 400
+	}
+}
+
+// how sensitive media is shown in the timeline
+type SensitiveMedia string
+
+const (
+	SensitiveShow SensitiveMedia = "show"
+	SensitiveBlur SensitiveMedia = "blur"
+	SensitiveHide SensitiveMedia = "hide"
+)
+
+// check that preference is one of the known values
+func (s SensitiveMedia) Valid() bool {
+	switch s {
+	case SensitiveShow, SensitiveBlur, SensitiveHide:
+		return true
+	}
+	return false
+}
+
+// server side state of refresh token
+type RefreshToken struct {
+	UserId types.UserId
+	// tokens rotated from the same login
+	Family    string
+	Used      bool
+	ExpiresAt time.Time
+}
+
+// login of the user on a device, id of the session is the family of its refresh tokens
+type Session struct {
+	SessionId  string       `json:"session_id"`
+	UserId     types.UserId `json:"-"`
+	UserAgent  string       `json:"user_agent"`
+	IP         string       `json:"ip"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastSeenAt time.Time    `json:"last_seen_at"`
+	// session of the request that lists sessions
+	Current bool `json:"current,omitempty"`
+}
+
+// state of personal data export
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+)
+
+// archive with personal data requested by the user
+type Export struct {
+	ExportId  int          `json:"export_id"`
+	UserId    types.UserId `json:"user_id"`
+	Status    ExportStatus `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+	// archive can be downloaded until expiration, set when it is ready
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	StoragePath string     `json:"-"`
+}
+
+// personal access token of the user for bots and scripts, only its hash is stored
+type AccessToken struct {
+	TokenId    int           `json:"token_id"`
+	UserId     types.UserId  `json:"-"`
+	Name       string        `json:"name"`
+	Scopes     []types.Scope `json:"scopes"`
+	CreatedAt  time.Time     `json:"created_at"`
+	ExpiresAt  time.Time     `json:"expires_at"`
+	LastUsedAt *time.Time    `json:"last_used_at,omitempty"`
+}
+
+// two-factor authentication state of the user
+type TOTP struct {
+	// empty until user starts enrollment
+	Secret  string
+	Enabled bool
+	// last accepted step, codes can't be reused
+	LastStep int64
 }